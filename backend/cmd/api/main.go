@@ -2,17 +2,32 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // fusos horários das preferências, mesmo sem zoneinfo no sistema
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
 
 	"gofolio/backend/internal/api"
+	"gofolio/backend/internal/auth"
+	"gofolio/backend/internal/models"
+	appServices "gofolio/backend/internal/services"
+	"gofolio/backend/internal/services/backtest"
+	"gofolio/backend/internal/services/fx"
+	"gofolio/backend/internal/services/market"
+	"gofolio/backend/internal/services/scheduler"
+	"gofolio/backend/internal/services/scraper"
+	"gofolio/backend/internal/services/strategy"
+	"gofolio/backend/internal/storage/inmemory"
 )
 
 func main() {
@@ -27,6 +42,92 @@ func main() {
 		port = "8080"
 	}
 
+	// Conectar ao PostgreSQL, se configurado
+	db, err := openDatabase()
+	if err != nil {
+		log.Fatalf("Erro ao conectar ao banco de dados: %v\n", err)
+	}
+	if db != nil {
+		defer db.Close()
+	}
+
+	// Carregar chaves de assinatura dos tokens
+	keySet, err := auth.LoadKeySetFromEnv()
+	if err != nil {
+		log.Fatalf("Erro ao carregar chaves de assinatura: %v\n", err)
+	}
+	auth.SetKeySet(keySet)
+
+	// Configurar repositórios de usuários, tokens e portfólios
+	services := api.Services{
+		Scraper: scraper.NewScraperService(),
+	}
+
+	// Cotações de mercado usadas para valorizar os portfólios
+	marketService := market.NewService(inmemory.NewCryptoRepository())
+
+	// Taxas de câmbio para expressar os valores dos portfólios noutras moedas
+	fxProvider := fx.NewFrankfurterProvider(os.Getenv("FX_API_URL"))
+
+	if db != nil {
+		for _, schema := range []string{auth.UserSchema, auth.TokenSchema, auth.APIKeySchema} {
+			if _, err := db.Exec(schema); err != nil {
+				log.Fatalf("Erro ao criar tabelas de autenticação: %v\n", err)
+			}
+		}
+		tokenStore := auth.NewPostgresTokenStore(db)
+		services.Users = auth.NewPostgresUserRepository(db)
+		services.Tokens = tokenStore
+		services.APIKeys = auth.NewPostgresAPIKeyRepository(db)
+		go cleanupExpiredTokens(tokenStore)
+
+		if _, err := db.Exec(models.PortfolioSchema); err != nil {
+			log.Fatalf("Erro ao criar tabelas de portfólios: %v\n", err)
+		}
+		if _, err := db.Exec(models.HistoricalDataSchema); err != nil {
+			log.Fatalf("Erro ao criar tabela de dados históricos: %v\n", err)
+		}
+		if _, err := db.Exec(models.FXRateSchema); err != nil {
+			log.Fatalf("Erro ao criar tabela de taxas de câmbio: %v\n", err)
+		}
+		if _, err := db.Exec(models.StrategySchema); err != nil {
+			log.Fatalf("Erro ao criar tabela de estratégias: %v\n", err)
+		}
+		if _, err := db.Exec(models.BacktestSchema); err != nil {
+			log.Fatalf("Erro ao criar tabela de backtests: %v\n", err)
+		}
+		historicalData := models.NewPostgresHistoricalDataRepository(db)
+		rates := fx.NewService(fxProvider, models.NewPostgresFXRateRepository(db))
+		services.Portfolios = appServices.NewPortfolioService(models.NewPostgresPortfolioRepository(db), marketService, historicalData, rates)
+		strategies := models.NewPostgresStrategyRepository(db)
+		services.Strategies = strategy.NewService(strategies, services.Scraper)
+		services.Backtests = backtest.NewService(historicalData, models.NewPostgresBacktestRepository(db), strategies)
+
+		// Agendador de coleta de dados (requer o histórico em PostgreSQL)
+		services.Scheduler = scheduler.NewSchedulerService(services.Scraper, historicalData, services.Strategies)
+		services.Scheduler.Start()
+		defer services.Scheduler.Stop()
+	} else {
+		log.Println("Aviso: DB_HOST não definido, usando repositórios em memória")
+		services.Users = inmemory.NewUserRepository()
+		services.Tokens = inmemory.NewTokenStore()
+		services.APIKeys = inmemory.NewAPIKeyRepository()
+		rates := fx.NewService(fxProvider, inmemory.NewFXRateRepository())
+		services.Portfolios = appServices.NewPortfolioService(inmemory.NewPortfolioRepository(), marketService, nil, rates)
+		strategies := inmemory.NewStrategyRepository()
+		services.Strategies = strategy.NewService(strategies, services.Scraper)
+		services.Backtests = backtest.NewService(nil, inmemory.NewBacktestRepository(), strategies)
+	}
+
+	auth.SetUserRepository(services.Users)
+	auth.SetTokenStore(services.Tokens)
+	auth.SetAPIKeyRepository(services.APIKeys)
+
+	// Emails com papel de administrador (separados por vírgula)
+	if adminEmails := os.Getenv("ADMIN_EMAILS"); adminEmails != "" {
+		auth.SetAdminEmails(strings.Split(adminEmails, ","))
+	}
+
 	// Criar router
 	router := mux.NewRouter()
 
@@ -36,12 +137,12 @@ func main() {
 	// Configurar CORS
 	router.Use(corsMiddleware)
 
-	// Configurar rotas da API
-	api.SetupRoutes()
-
 	// Rota de saúde
 	router.HandleFunc("/health", healthCheckHandler).Methods("GET")
 
+	// Rotas da API com autenticação
+	router.PathPrefix("/api/").Handler(api.NewRouter(services))
+
 	// Configurar servidor HTTP
 	srv := &http.Server{
 		Addr:         ":" + port,
@@ -79,6 +180,48 @@ func main() {
 	log.Println("Servidor encerrado com sucesso")
 }
 
+// openDatabase abre a conexão com o PostgreSQL a partir das variáveis DB_*.
+// Retorna nil se DB_HOST não estiver definido.
+func openDatabase() (*sql.DB, error) {
+	host := os.Getenv("DB_HOST")
+	if host == "" {
+		return nil, nil
+	}
+
+	dsn := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		host,
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_NAME"),
+	)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// cleanupExpiredTokens remove periodicamente refresh tokens e revogações expirados
+func cleanupExpiredTokens(store *auth.PostgresTokenStore) {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := store.DeleteExpired(); err != nil {
+			log.Printf("Erro ao limpar tokens expirados: %v\n", err)
+		}
+	}
+}
+
 // healthCheckHandler é o handler para verificação de saúde da API
 func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -111,7 +254,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 		// Configurar cabeçalhos CORS
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 
 		// Tratar requisições OPTIONS (preflight)
 		if r.Method == "OPTIONS" {
@@ -1,15 +1,21 @@
 module gofolio/backend
 
-go 1.21
+go 1.25.0
 
 require (
+	github.com/PuerkitoBio/goquery v1.13.0
+	github.com/golang-jwt/jwt/v4 v4.5.2
 	github.com/google/uuid v1.6.0
 	github.com/gorilla/handlers v1.5.1
 	github.com/gorilla/mux v1.8.1
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
+	golang.org/x/crypto v0.55.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/andybalholm/cascadia v1.3.4 // indirect
 	github.com/felixge/httpsnoop v1.0.1 // indirect
-) 
\ No newline at end of file
+	golang.org/x/net v0.58.0 // indirect
+)
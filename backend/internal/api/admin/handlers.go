@@ -0,0 +1,194 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"gofolio/backend/internal/auth"
+	"gofolio/backend/internal/services/scheduler"
+	"gofolio/backend/internal/services/scraper"
+)
+
+// Handler implementa os endpoints de administração
+type Handler struct {
+	users     auth.UserRepository
+	tokens    auth.TokenStore
+	scraper   *scraper.ScraperService
+	scheduler *scheduler.SchedulerService
+}
+
+// NewHandler cria um novo handler para os endpoints de administração.
+// O agendador é opcional (nil quando não há banco de dados configurado).
+func NewHandler(users auth.UserRepository, tokens auth.TokenStore, scraper *scraper.ScraperService, scheduler *scheduler.SchedulerService) *Handler {
+	return &Handler{
+		users:     users,
+		tokens:    tokens,
+		scraper:   scraper,
+		scheduler: scheduler,
+	}
+}
+
+// ListUsersHandler retorna a lista paginada de usuários
+func (h *Handler) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+	}
+
+	offset := 0
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	users, err := h.users.List(limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, users)
+}
+
+// SetUserDisabledHandler ativa ou desativa uma conta. Desativar termina todas as sessões do usuário.
+func (h *Handler) SetUserDisabledHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Disabled bool `json:"disabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Formato de requisição inválido", http.StatusBadRequest)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	// Evitar que um administrador se bloqueie a si próprio
+	if current, err := auth.GetUserFromContext(r.Context()); err == nil && current.ID == id && request.Disabled {
+		http.Error(w, "Não é possível desativar a própria conta", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.users.GetByID(id)
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user.Disabled = request.Disabled
+	if err := h.users.UpdateAccess(user); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if request.Disabled {
+		if err := h.tokens.DeleteUserRefreshTokens(user.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// RefreshScraperHandler força a atualização dos dados de mercado e, opcionalmente,
+// das análises de alguns ativos, ignorando o cache
+func (h *Handler) RefreshScraperHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Symbols []string `json:"symbols"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Formato de requisição inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := r.Context()
+
+	data, err := h.scraper.RefreshMarketData(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	errorsBySymbol := map[string]string{}
+	for _, symbol := range request.Symbols {
+		if err := h.scraper.RefreshSymbol(ctx, symbol); err != nil {
+			errorsBySymbol[symbol] = err.Error()
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"marketDataCount": len(data),
+		"symbols":         request.Symbols,
+		"errors":          errorsBySymbol,
+	})
+}
+
+// SchedulerStatusHandler retorna o estado das tarefas agendadas
+func (h *Handler) SchedulerStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if h.scheduler == nil {
+		http.Error(w, "Agendador não configurado", http.StatusServiceUnavailable)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"running": h.scheduler.IsRunning(),
+		"jobs":    h.scheduler.Status(),
+	})
+}
+
+// RunSchedulerJobHandler executa imediatamente uma tarefa agendada
+func (h *Handler) RunSchedulerJobHandler(w http.ResponseWriter, r *http.Request) {
+	if h.scheduler == nil {
+		http.Error(w, "Agendador não configurado", http.StatusServiceUnavailable)
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if err := h.scheduler.RunJob(name); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]string{
+		"job":    name,
+		"status": "started",
+	})
+}
+
+// RegisterRoutes registra as rotas de administração. O router deve estar
+// protegido por auth.JWTMiddleware e auth.RequireRole(auth.RoleAdmin).
+func RegisterRoutes(router *mux.Router, handler *Handler) {
+	router.HandleFunc("/users", handler.ListUsersHandler).Methods("GET")
+	router.HandleFunc("/users/{id}/disabled", handler.SetUserDisabledHandler).Methods("PUT")
+
+	router.HandleFunc("/scraper/refresh", handler.RefreshScraperHandler).Methods("POST")
+
+	router.HandleFunc("/scheduler", handler.SchedulerStatusHandler).Methods("GET")
+	router.HandleFunc("/scheduler/jobs/{name}/run", handler.RunSchedulerJobHandler).Methods("POST")
+}
+
+// respondWithJSON envia uma resposta JSON com o status e os dados fornecidos
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"gofolio/backend/internal/auth"
+	"gofolio/backend/internal/services/backtest"
+)
+
+// BacktestHandler gerencia as requisições HTTP de backtests
+type BacktestHandler struct {
+	backtestService *backtest.Service
+}
+
+// NewBacktestHandler cria uma nova instância do handler de backtests
+func NewBacktestHandler(backtestService *backtest.Service) *BacktestHandler {
+	return &BacktestHandler{
+		backtestService: backtestService,
+	}
+}
+
+// RegisterRoutes registra as rotas de backtests. O router deve estar protegido
+// por auth.JWTMiddleware, que coloca o usuário no contexto.
+func (h *BacktestHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/backtests", h.ListBacktests).Methods("GET")
+	r.HandleFunc("/backtests", h.RunBacktest).Methods("POST")
+	r.HandleFunc("/backtests/strategies", h.ListStrategies).Methods("GET")
+	r.HandleFunc("/backtests/{id}", h.GetBacktest).Methods("GET")
+	r.HandleFunc("/backtests/{id}", h.DeleteBacktest).Methods("DELETE")
+}
+
+// ListBacktests retorna os backtests do usuário autenticado, apenas com as métricas
+func (h *BacktestHandler) ListBacktests(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	backtests, err := h.backtestService.List(user.ID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, backtests)
+}
+
+// RunBacktest executa um backtest sobre as cotações guardadas e guarda o resultado
+func (h *BacktestHandler) RunBacktest(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request backtest.Request
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.backtestService.Run(user.ID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, result)
+}
+
+// ListStrategies retorna as estratégias disponíveis e os seus parâmetros por omissão
+func (h *BacktestHandler) ListStrategies(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, backtest.Strategies())
+}
+
+// GetBacktest retorna um backtest completo, com as operações e a curva de capital
+func (h *BacktestHandler) GetBacktest(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := h.backtestService.Get(user.ID, mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// DeleteBacktest remove um backtest
+func (h *BacktestHandler) DeleteBacktest(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.backtestService.Delete(user.ID, mux.Vars(r)["id"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
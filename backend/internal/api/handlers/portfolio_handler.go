@@ -1,12 +1,33 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gorilla/mux"
+
+	"gofolio/backend/internal/auth"
+	"gofolio/backend/internal/models"
 	"gofolio/backend/internal/services"
+	"gofolio/backend/internal/services/backtest"
+	"gofolio/backend/internal/services/candles"
+	"gofolio/backend/internal/services/exporter"
+	"gofolio/backend/internal/services/fx"
+	"gofolio/backend/internal/services/importer"
+	"gofolio/backend/internal/services/strategy"
 )
 
+// maxImportSize é o tamanho máximo de um ficheiro importado
+const maxImportSize = 10 << 20
+
 // PortfolioHandler gerencia as requisições HTTP relacionadas ao portfólio
 type PortfolioHandler struct {
 	portfolioService *services.PortfolioService
@@ -19,94 +40,688 @@ func NewPortfolioHandler(portfolioService *services.PortfolioService) *Portfolio
 	}
 }
 
+// RegisterRoutes registra as rotas de portfólio. O router deve estar protegido
+// por auth.JWTMiddleware, que coloca o usuário no contexto.
+func (h *PortfolioHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/portfolios", h.ListPortfolios).Methods("GET")
+	r.HandleFunc("/portfolios/export", h.ExportPortfolios).Methods("GET")
+	r.HandleFunc("/portfolio", h.GetOverview).Methods("GET")
+	r.HandleFunc("/portfolio", h.CreatePortfolio).Methods("POST")
+	r.HandleFunc("/portfolio/{id}", h.GetPortfolio).Methods("GET")
+	r.HandleFunc("/portfolio/{id}", h.UpdatePortfolio).Methods("PUT")
+	r.HandleFunc("/portfolio/{id}", h.DeletePortfolio).Methods("DELETE")
+	r.HandleFunc("/portfolio/{id}/export", h.ExportPortfolio).Methods("GET")
+
+	r.HandleFunc("/portfolio/{id}/assets", h.AddAsset).Methods("POST")
+	r.HandleFunc("/portfolio/{id}/assets/{assetId}", h.UpdateAsset).Methods("PUT")
+	r.HandleFunc("/portfolio/{id}/assets/{assetId}", h.RemoveAsset).Methods("DELETE")
+
+	r.HandleFunc("/portfolio/{id}/transactions", h.ListTransactions).Methods("GET")
+	r.HandleFunc("/portfolio/{id}/transactions", h.AddTransaction).Methods("POST")
+	r.HandleFunc("/portfolio/{id}/transactions/{txId}", h.UpdateTransaction).Methods("PUT")
+	r.HandleFunc("/portfolio/{id}/transactions/{txId}", h.DeleteTransaction).Methods("DELETE")
+	r.HandleFunc("/portfolio/{id}/transactions/export", h.ExportTransactions).Methods("GET")
+	r.HandleFunc("/portfolio/{id}/import", h.ImportTransactions).Methods("POST")
+	r.HandleFunc("/portfolio/{id}/holdings", h.GetHoldings).Methods("GET")
+	r.HandleFunc("/portfolio/{id}/holdings/export", h.ExportHoldings).Methods("GET")
+	r.HandleFunc("/portfolio/{id}/cost-basis", h.GetCostBasis).Methods("GET")
+	r.HandleFunc("/portfolio/{id}/tax-report", h.GetTaxReport).Methods("GET")
+
+	r.HandleFunc("/portfolio/{id}/stats", h.GetPortfolioStats).Methods("GET")
+	r.HandleFunc("/portfolio/{id}/history", h.GetPortfolioHistory).Methods("GET")
+	r.HandleFunc("/portfolio/{id}/risk", h.GetPortfolioRisk).Methods("GET")
+	r.HandleFunc("/portfolio/{id}/forecast", h.GetPortfolioForecast).Methods("GET")
+	r.HandleFunc("/portfolio/{id}/simulate", h.SimulateTransaction).Methods("POST")
+
+	r.HandleFunc("/portfolio/{id}/targets", h.GetTargets).Methods("GET")
+	r.HandleFunc("/portfolio/{id}/targets", h.SetTargets).Methods("PUT")
+	r.HandleFunc("/portfolio/{id}/rebalance", h.GetRebalancePlan).Methods("GET")
+}
+
+type portfolioRequest struct {
+	Name            string                 `json:"name"`
+	Description     string                 `json:"description"`
+	CostBasisMethod models.CostBasisMethod `json:"costBasisMethod"` // opcional
+}
+
+type assetRequest struct {
+	Symbol        string    `json:"symbol"`
+	Amount        float64   `json:"amount"`
+	PurchasePrice float64   `json:"purchasePrice"`
+	PurchaseDate  time.Time `json:"purchaseDate"` // opcional
+}
+
+// ListPortfolios retorna os portfólios do usuário autenticado
+func (h *PortfolioHandler) ListPortfolios(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	portfolios, err := h.portfolioService.ListPortfolios(user.ID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, portfolios)
+}
+
+// GetOverview retorna o valor total e o desempenho dos portfólios do usuário autenticado.
+// Parâmetro opcional: currency (ver requestCurrency).
+func (h *PortfolioHandler) GetOverview(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	currency, ok := h.requestCurrency(w, r)
+	if !ok {
+		return
+	}
+
+	overview, err := h.portfolioService.GetOverview(user.ID, currency)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, overview)
+}
+
 // CreatePortfolio cria um novo portfólio
 func (h *PortfolioHandler) CreatePortfolio(w http.ResponseWriter, r *http.Request) {
-	var request struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
 
+	var request portfolioRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// TODO: Obter userID do contexto de autenticação
-	userID := "user123" // Temporário
-
-	portfolio, err := h.portfolioService.CreatePortfolio(userID, request.Name, request.Description)
+	portfolio, err := h.portfolioService.CreatePortfolio(user.ID, request.Name, request.Description, request.CostBasisMethod)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(portfolio)
+	respondWithJSON(w, http.StatusCreated, portfolio)
 }
 
-// GetPortfolio retorna um portfólio específico
+// GetPortfolio retorna um portfólio específico, com as posições derivadas do livro de transações
 func (h *PortfolioHandler) GetPortfolio(w http.ResponseWriter, r *http.Request) {
-	portfolioID := r.URL.Query().Get("id")
-	if portfolioID == "" {
-		http.Error(w, "Portfolio ID is required", http.StatusBadRequest)
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
 		return
 	}
 
-	portfolio, err := h.portfolioService.GetPortfolio(portfolioID)
+	holdings, err := h.portfolioService.GetHoldings(portfolio.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
+	portfolio.Holdings = holdings
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(portfolio)
+	respondWithJSON(w, http.StatusOK, portfolio)
+}
+
+// UpdatePortfolio atualiza o nome e a descrição de um portfólio
+func (h *PortfolioHandler) UpdatePortfolio(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	var request portfolioRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.portfolioService.UpdatePortfolio(portfolio.ID, request.Name, request.Description, request.CostBasisMethod)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, updated)
 }
 
-// GetPortfolioStats retorna estatísticas do portfólio
+// DeletePortfolio remove um portfólio e os seus ativos
+func (h *PortfolioHandler) DeletePortfolio(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.portfolioService.DeletePortfolio(portfolio.ID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddAsset adiciona um ativo ao portfólio
+func (h *PortfolioHandler) AddAsset(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	var request assetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	asset, err := h.portfolioService.AddAsset(portfolio.ID, request.Symbol, request.Amount, request.PurchasePrice, request.PurchaseDate)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, asset)
+}
+
+// UpdateAsset atualiza a quantidade, o preço e a data de compra de um ativo
+func (h *PortfolioHandler) UpdateAsset(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	var request assetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	asset, err := h.portfolioService.UpdateAsset(portfolio.ID, mux.Vars(r)["assetId"], request.Amount, request.PurchasePrice, request.PurchaseDate)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, asset)
+}
+
+// RemoveAsset remove um ativo do portfólio
+func (h *PortfolioHandler) RemoveAsset(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.portfolioService.RemoveAsset(portfolio.ID, mux.Vars(r)["assetId"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListTransactions retorna o livro de transações do portfólio
+func (h *PortfolioHandler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	transactions, err := h.portfolioService.ListTransactions(portfolio.ID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	// Filtro opcional por símbolo
+	if symbol := strings.ToUpper(r.URL.Query().Get("symbol")); symbol != "" {
+		filtered := []models.Transaction{}
+		for _, tx := range transactions {
+			if tx.Symbol == symbol || tx.FeeCurrency == symbol {
+				filtered = append(filtered, tx)
+			}
+		}
+		transactions = filtered
+	}
+
+	respondWithJSON(w, http.StatusOK, transactions)
+}
+
+// AddTransaction regista uma transação no portfólio
+func (h *PortfolioHandler) AddTransaction(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	var request models.Transaction
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.portfolioService.AddTransaction(portfolio.ID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, tx)
+}
+
+// UpdateTransaction substitui os dados de uma transação
+func (h *PortfolioHandler) UpdateTransaction(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	var request models.Transaction
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.portfolioService.UpdateTransaction(portfolio.ID, mux.Vars(r)["txId"], request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tx)
+}
+
+// DeleteTransaction remove uma transação
+func (h *PortfolioHandler) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.portfolioService.DeleteTransaction(portfolio.ID, mux.Vars(r)["txId"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImportTransactions importa um ficheiro CSV de transações exportado por uma corretora. O ficheiro
+// vem no corpo do pedido ou no campo "file" de um formulário multipart. Parâmetros: format
+// (binance, coinbase, kraken ou generic), dryRun (true para pré-visualizar sem gravar) e, no
+// formato genérico, mapping (JSON com os nomes das colunas, no parâmetro ou no formulário).
+func (h *PortfolioHandler) ImportTransactions(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	dryRun := false
+	if value := query.Get("dryRun"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "Invalid dryRun", http.StatusBadRequest)
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	data := io.Reader(r.Body)
+	mappingParam := query.Get("mapping")
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data = file
+		if value := r.FormValue("mapping"); value != "" {
+			mappingParam = value
+		}
+	}
+
+	var mapping *importer.Mapping
+	if mappingParam != "" {
+		mapping = &importer.Mapping{}
+		if err := json.Unmarshal([]byte(mappingParam), mapping); err != nil {
+			http.Error(w, "Invalid mapping", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := h.portfolioService.ImportTransactions(portfolio.ID, importer.Format(query.Get("format")), data, mapping, dryRun)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if result.Imported > 0 {
+		status = http.StatusCreated
+	}
+	respondWithJSON(w, status, result)
+}
+
+// GetHoldings retorna as posições atuais do portfólio
+func (h *PortfolioHandler) GetHoldings(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	holdings, err := h.portfolioService.GetHoldings(portfolio.ID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, holdings)
+}
+
+// GetCostBasis retorna os lotes em aberto, as alienações e o P&L realizado do portfólio.
+// O parâmetro opcional method permite comparar com outro método de custo; currency indica a
+// moeda dos valores (ver requestCurrency).
+func (h *PortfolioHandler) GetCostBasis(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	currency, ok := h.requestCurrency(w, r)
+	if !ok {
+		return
+	}
+
+	method := models.CostBasisMethod(strings.ToLower(r.URL.Query().Get("method")))
+
+	report, err := h.portfolioService.GetCostBasis(portfolio.ID, method, currency)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// ExportPortfolios exporta os portfólios do usuário autenticado: completos em JSON, ou uma linha
+// por portfólio em CSV (parâmetro format)
+func (h *PortfolioHandler) ExportPortfolios(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	exports, err := h.portfolioService.ExportPortfolios(user.ID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithExport(w, format, "portfolios", exports, func(out io.Writer) error {
+		return exporter.WritePortfoliosCSV(out, exports)
+	})
+}
+
+// ExportPortfolio exporta um portfólio: completo em JSON (dados, ativos, posições, transações e
+// pesos alvo), ou numa linha em CSV (parâmetro format)
+func (h *PortfolioHandler) ExportPortfolio(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.portfolioService.ExportPortfolio(portfolio.ID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithExport(w, format, "portfolio-"+portfolio.ID, export, func(out io.Writer) error {
+		return exporter.WritePortfoliosCSV(out, []models.PortfolioExport{*export})
+	})
+}
+
+// ExportTransactions exporta o livro de transações do portfólio em JSON ou CSV (parâmetro format).
+// O CSV usa as colunas do formato genérico de importação.
+func (h *PortfolioHandler) ExportTransactions(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	transactions, err := h.portfolioService.ListTransactions(portfolio.ID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithExport(w, format, "transactions-"+portfolio.ID, transactions, func(out io.Writer) error {
+		return exporter.WriteTransactionsCSV(out, transactions)
+	})
+}
+
+// ExportHoldings exporta as posições atuais do portfólio em JSON ou CSV (parâmetro format)
+func (h *PortfolioHandler) ExportHoldings(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	holdings, err := h.portfolioService.GetHoldings(portfolio.ID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithExport(w, format, "holdings-"+portfolio.ID, holdings, func(out io.Writer) error {
+		return exporter.WriteHoldingsCSV(out, holdings)
+	})
+}
+
+// GetTaxReport retorna as mais-valias realizadas no ano fiscal, em JSON ou CSV. Parâmetros
+// opcionais: year (por omissão o ano atual), method (por omissão o do portfólio), currency
+// (ver requestCurrency) e format.
+func (h *PortfolioHandler) GetTaxReport(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	currency, ok := h.requestCurrency(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	year := time.Now().Year()
+	if value := query.Get("year"); value != "" {
+		var err error
+		if year, err = strconv.Atoi(value); err != nil {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+	}
+	method := models.CostBasisMethod(strings.ToLower(query.Get("method")))
+
+	report, err := h.portfolioService.GetTaxReport(portfolio.ID, year, method, currency)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithExport(w, format, fmt.Sprintf("tax-report-%d-%s", year, portfolio.ID), report, func(out io.Writer) error {
+		return exporter.WriteTaxReportCSV(out, report)
+	})
+}
+
+// GetPortfolioStats retorna estatísticas do portfólio, com os retornos TWR e MWR entre os
+// parâmetros opcionais from e to (RFC 3339; por omissão, desde a primeira transação até agora).
+// Parâmetro opcional: currency (ver requestCurrency).
 func (h *PortfolioHandler) GetPortfolioStats(w http.ResponseWriter, r *http.Request) {
-	portfolioID := r.URL.Query().Get("id")
-	if portfolioID == "" {
-		http.Error(w, "Portfolio ID is required", http.StatusBadRequest)
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	currency, ok := h.requestCurrency(w, r)
+	if !ok {
 		return
 	}
 
-	stats, err := h.portfolioService.GetPortfolioStats(portfolioID)
+	query := r.URL.Query()
+	from, err := parseTimeParam(query.Get("from"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Invalid from date", http.StatusBadRequest)
+		return
+	}
+	to, err := parseTimeParam(query.Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to date", http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(stats)
+	stats, err := h.portfolioService.GetPortfolioStats(portfolio.ID, currency)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	// Os retornos requerem o histórico de preços; sem ele, as estatísticas seguem sem retornos
+	stats.Returns, err = h.portfolioService.GetPortfolioReturns(portfolio.ID, from, to, currency)
+	if err != nil && !errors.Is(err, services.ErrHistoryUnavailable) {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, stats)
+}
+
+// GetPortfolioHistory retorna a evolução do valor do portfólio. Parâmetros opcionais:
+// range (ex.: "30d", "1y" ou "all"), interval (ex.: "1h", "1d", "1w") e currency (ver requestCurrency).
+func (h *PortfolioHandler) GetPortfolioHistory(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	currency, ok := h.requestCurrency(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	history, err := h.portfolioService.GetPortfolioHistory(portfolio.ID, query.Get("range"), query.Get("interval"), currency)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, history)
+}
+
+// GetPortfolioRisk retorna as métricas de risco do portfólio. Parâmetros opcionais: range,
+// interval, benchmark (símbolo), riskFreeRate (percentagem anual) e confidence (ex.: 0.95).
+func (h *PortfolioHandler) GetPortfolioRisk(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	options := services.RiskOptions{
+		Range:     query.Get("range"),
+		Interval:  query.Get("interval"),
+		Benchmark: query.Get("benchmark"),
+	}
+
+	var err error
+	if options.RiskFreeRate, err = parseFloatParam(query.Get("riskFreeRate")); err != nil {
+		http.Error(w, "Invalid riskFreeRate", http.StatusBadRequest)
+		return
+	}
+	if options.Confidence, err = parseFloatParam(query.Get("confidence")); err != nil {
+		http.Error(w, "Invalid confidence", http.StatusBadRequest)
+		return
+	}
+
+	risk, err := h.portfolioService.GetPortfolioRisk(portfolio.ID, options)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, risk)
 }
 
-// GetPortfolioForecast retorna previsões para o portfólio
+// GetPortfolioForecast retorna a previsão do valor do portfólio. Parâmetro opcional:
+// timeFrame (7d, 30d ou 90d; por omissão 30d).
 func (h *PortfolioHandler) GetPortfolioForecast(w http.ResponseWriter, r *http.Request) {
-	portfolioID := r.URL.Query().Get("id")
-	timeFrame := r.URL.Query().Get("timeFrame")
-	if portfolioID == "" {
-		http.Error(w, "Portfolio ID is required", http.StatusBadRequest)
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
 		return
 	}
 
-	forecast, err := h.portfolioService.GetPortfolioForecast(portfolioID, timeFrame)
+	timeFrame := r.URL.Query().Get("timeFrame")
+
+	forecast, err := h.portfolioService.GetPortfolioForecast(portfolio.ID, timeFrame)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(forecast)
+	respondWithJSON(w, http.StatusOK, forecast)
 }
 
-// SimulateTransaction simula uma transação no portfólio
+// SimulateTransaction simula uma compra ou venda no portfólio sem a registar. Um preço
+// vazio corresponde ao preço de mercado atual.
 func (h *PortfolioHandler) SimulateTransaction(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
 	var request struct {
-		PortfolioID string  `json:"portfolioId"`
 		Symbol      string  `json:"symbol"`
 		Amount      float64 `json:"amount"`
 		Price       float64 `json:"price"`
 		Type        string  `json:"type"`
+		Fee         float64 `json:"fee"`
+		FeeCurrency string  `json:"feeCurrency"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -114,18 +729,233 @@ func (h *PortfolioHandler) SimulateTransaction(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	simulation, err := h.portfolioService.SimulateTransaction(
-		request.PortfolioID,
-		request.Symbol,
-		request.Amount,
-		request.Price,
-		request.Type,
-	)
+	simulation, err := h.portfolioService.SimulateTransaction(portfolio.ID, models.Transaction{
+		Type:        models.TransactionType(strings.ToLower(request.Type)),
+		Symbol:      request.Symbol,
+		Quantity:    request.Amount,
+		Price:       request.Price,
+		Fee:         request.Fee,
+		FeeCurrency: request.FeeCurrency,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, simulation)
+}
+
+// GetTargets retorna os pesos alvo do portfólio
+func (h *PortfolioHandler) GetTargets(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	targets, err := h.portfolioService.GetTargets(portfolio.ID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, targets)
+}
+
+// SetTargets substitui os pesos alvo do portfólio
+func (h *PortfolioHandler) SetTargets(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Targets []models.AllocationTarget `json:"targets"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	targets, err := h.portfolioService.SetTargets(portfolio.ID, request.Targets)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, targets)
+}
+
+// GetRebalancePlan retorna as transações que repõem os pesos alvo do portfólio. Parâmetros
+// opcionais: minTradeSize (valor mínimo de cada transação), cash (dinheiro novo a investir)
+// e cashOnly (true para investir apenas o dinheiro novo, sem vendas).
+func (h *PortfolioHandler) GetRebalancePlan(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.authorizedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	var options services.RebalanceOptions
+
+	var err error
+	if options.MinTradeSize, err = parseFloatParam(query.Get("minTradeSize")); err != nil {
+		http.Error(w, "Invalid minTradeSize", http.StatusBadRequest)
+		return
+	}
+	if options.Cash, err = parseFloatParam(query.Get("cash")); err != nil {
+		http.Error(w, "Invalid cash", http.StatusBadRequest)
+		return
+	}
+	if value := query.Get("cashOnly"); value != "" {
+		if options.CashOnly, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "Invalid cashOnly", http.StatusBadRequest)
+			return
+		}
+	}
+
+	plan, err := h.portfolioService.GetRebalancePlan(portfolio.ID, options)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, plan)
+}
+
+// exportFormat lê o parâmetro format das exportações: "json" (por omissão) ou "csv"
+func exportFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	switch format {
+	case "":
+		return "json", true
+	case "json", "csv":
+		return format, true
+	}
+
+	http.Error(w, "Invalid format", http.StatusBadRequest)
+	return "", false
+}
+
+// respondWithExport envia uma exportação como anexo, em JSON ou no CSV escrito por writeCSV
+func respondWithExport(w http.ResponseWriter, format, filename string, payload interface{}, writeCSV func(io.Writer) error) {
+	if format != "csv" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+		respondWithJSON(w, http.StatusOK, payload)
+		return
+	}
+
+	var buffer bytes.Buffer
+	if err := writeCSV(&buffer); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buffer.Bytes())
+}
+
+// parseTimeParam interpreta um parâmetro de data opcional no formato RFC 3339
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseFloatParam interpreta um parâmetro numérico opcional; vazio corresponde a zero
+func parseFloatParam(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// authorizedPortfolio obtém o portfólio indicado no path e verifica que pertence ao
+// usuário autenticado. Portfólios de outros usuários são tratados como inexistentes.
+func (h *PortfolioHandler) authorizedPortfolio(w http.ResponseWriter, r *http.Request) (*models.Portfolio, bool) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	portfolio, err := h.portfolioService.GetPortfolio(mux.Vars(r)["id"])
+	if err == nil && portfolio.UserID != user.ID {
+		err = models.ErrPortfolioNotFound
+	}
+	if err != nil {
+		writeServiceError(w, err)
+		return nil, false
+	}
+
+	return portfolio, true
+}
+
+// requestCurrency retorna a moeda em que os valores são pedidos: o parâmetro currency (ex.: "EUR")
+// ou, na falta dele, a moeda base das preferências do usuário. Se a moeda das preferências não
+// estiver disponível (ex.: sem taxas de câmbio), os valores seguem na moeda por omissão.
+func (h *PortfolioHandler) requestCurrency(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if currency := r.URL.Query().Get("currency"); currency != "" {
+		resolved, err := h.portfolioService.ResolveCurrency(currency)
+		if err != nil {
+			writeServiceError(w, err)
+			return "", false
+		}
+		return resolved, true
+	}
+
+	if user, err := auth.GetUserFromContext(r.Context()); err == nil && user.Preferences.BaseCurrency != "" {
+		resolved, err := h.portfolioService.ResolveCurrency(user.Preferences.BaseCurrency)
+		if err == nil {
+			return resolved, true
+		}
+		log.Printf("Moeda base %s indisponível, usando %s: %v\n", user.Preferences.BaseCurrency, models.DefaultQuoteCurrency, err)
+	}
+
+	return models.DefaultQuoteCurrency, true
+}
+
+// writeServiceError converte um erro do serviço no status HTTP correspondente
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrPortfolioNotFound), errors.Is(err, models.ErrAssetNotFound),
+		errors.Is(err, models.ErrTransactionNotFound), errors.Is(err, models.ErrBacktestNotFound),
+		errors.Is(err, models.ErrStrategyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrPortfolioNameRequired), errors.Is(err, services.ErrInvalidAsset),
+		errors.Is(err, services.ErrInvalidTransaction), errors.Is(err, services.ErrUnknownCostBasisMethod),
+		errors.Is(err, services.ErrInvalidHistoryRange), errors.Is(err, services.ErrInvalidRiskOptions),
+		errors.Is(err, services.ErrInvalidTimeFrame), errors.Is(err, services.ErrInvalidTargets),
+		errors.Is(err, services.ErrInvalidRebalanceOptions), errors.Is(err, importer.ErrUnknownFormat),
+		errors.Is(err, importer.ErrInvalidFile), errors.Is(err, services.ErrInvalidTaxYear),
+		errors.Is(err, fx.ErrUnsupportedCurrency), errors.Is(err, backtest.ErrInvalidBacktest),
+		errors.Is(err, backtest.ErrUnknownStrategy), errors.Is(err, candles.ErrInvalidInterval),
+		errors.Is(err, strategy.ErrInvalidStrategy), errors.Is(err, strategy.ErrUnsupportedFormat):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrInsufficientBalance), errors.Is(err, services.ErrInsufficientHistory),
+		errors.Is(err, services.ErrNoTargets), errors.Is(err, services.ErrTargetPriceMissing),
+		errors.Is(err, backtest.ErrInsufficientData):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, services.ErrHistoryUnavailable), errors.Is(err, fx.ErrRatesUnavailable),
+		errors.Is(err, backtest.ErrHistoryUnavailable), errors.Is(err, strategy.ErrCandlesUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// respondWithJSON envia uma resposta JSON com o status e os dados fornecidos
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(simulation)
+	w.WriteHeader(code)
+	w.Write(response)
 }
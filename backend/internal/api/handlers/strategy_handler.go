@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"gofolio/backend/internal/auth"
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/strategy"
+)
+
+// maxStrategySize é o tamanho máximo de uma definição de estratégia
+const maxStrategySize = 64 << 10
+
+// StrategyHandler gerencia as requisições HTTP das estratégias declarativas
+type StrategyHandler struct {
+	strategyService *strategy.Service
+}
+
+// NewStrategyHandler cria uma nova instância do handler de estratégias
+func NewStrategyHandler(strategyService *strategy.Service) *StrategyHandler {
+	return &StrategyHandler{
+		strategyService: strategyService,
+	}
+}
+
+// RegisterRoutes registra as rotas de estratégias. O router deve estar protegido
+// por auth.JWTMiddleware, que coloca o usuário no contexto.
+func (h *StrategyHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/strategies", h.ListStrategies).Methods("GET")
+	r.HandleFunc("/strategies", h.CreateStrategy).Methods("POST")
+	r.HandleFunc("/strategies/validate", h.ValidateStrategy).Methods("POST")
+	r.HandleFunc("/strategies/{id}", h.GetStrategy).Methods("GET")
+	r.HandleFunc("/strategies/{id}", h.UpdateStrategy).Methods("PUT")
+	r.HandleFunc("/strategies/{id}", h.DeleteStrategy).Methods("DELETE")
+	r.HandleFunc("/strategies/{id}/export", h.ExportStrategy).Methods("GET")
+	r.HandleFunc("/strategies/{id}/signals", h.GetSignals).Methods("GET")
+}
+
+// ListStrategies retorna as estratégias do usuário autenticado
+func (h *StrategyHandler) ListStrategies(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	strategies, err := h.strategyService.List(user.ID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, strategies)
+}
+
+// CreateStrategy valida e guarda uma estratégia, em JSON ou YAML (ver readDefinition)
+func (h *StrategyHandler) CreateStrategy(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	definition, ok := readDefinition(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := h.strategyService.Create(user.ID, *definition)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+// ValidateStrategy valida uma estratégia sem a guardar e retorna a definição normalizada e o
+// número de velas de aquecimento das regras
+func (h *StrategyHandler) ValidateStrategy(w http.ResponseWriter, r *http.Request) {
+	definition, ok := readDefinition(w, r)
+	if !ok {
+		return
+	}
+
+	compiled, err := strategy.Compile(*definition)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"definition": compiled.Definition,
+		"lookback":   compiled.Lookback(),
+	})
+}
+
+// GetStrategy retorna uma estratégia
+func (h *StrategyHandler) GetStrategy(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	found, err := h.strategyService.Get(user.ID, mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, found)
+}
+
+// UpdateStrategy valida e substitui a definição de uma estratégia
+func (h *StrategyHandler) UpdateStrategy(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	definition, ok := readDefinition(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := h.strategyService.Update(user.ID, mux.Vars(r)["id"], *definition)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+// DeleteStrategy remove uma estratégia
+func (h *StrategyHandler) DeleteStrategy(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.strategyService.Delete(user.ID, mux.Vars(r)["id"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ExportStrategy exporta a definição de uma estratégia como anexo, em JSON ou YAML (parâmetro
+// format), pronta a ser editada e enviada de novo
+func (h *StrategyHandler) ExportStrategy(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = strategy.FormatJSON
+	}
+	contentTypes := map[string]string{
+		strategy.FormatJSON: "application/json",
+		strategy.FormatYAML: "application/yaml",
+	}
+	contentType, ok := contentTypes[format]
+	if !ok {
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+		return
+	}
+
+	found, err := h.strategyService.Get(user.ID, mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	data, err := strategy.Encode(found.Definition, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "strategy-"+found.ID+"."+format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetSignals avalia a estratégia nas velas mais recentes dos ativos do parâmetro symbols
+// (separados por vírgula) ou, sem ele, dos ativos que a estratégia acompanha
+func (h *StrategyHandler) GetSignals(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var symbols []string
+	for _, symbol := range strings.Split(r.URL.Query().Get("symbols"), ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	signals, err := h.strategyService.Signals(r.Context(), user.ID, mux.Vars(r)["id"], symbols)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, signals)
+}
+
+// readDefinition lê a definição do corpo do pedido: em YAML quando o Content-Type o indica
+// (application/yaml, application/x-yaml ou text/yaml) e em JSON nos outros casos
+func readDefinition(w http.ResponseWriter, r *http.Request) (*models.StrategyDefinition, bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxStrategySize))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+
+	format := strategy.FormatJSON
+	if strings.Contains(strings.ToLower(r.Header.Get("Content-Type")), "yaml") {
+		format = strategy.FormatYAML
+	}
+
+	definition, err := strategy.Decode(data, format)
+	if err != nil {
+		writeServiceError(w, err)
+		return nil, false
+	}
+	return definition, true
+}
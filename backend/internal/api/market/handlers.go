@@ -8,7 +8,7 @@ import (
 	"strings"
 
 	"github.com/gorilla/mux"
-	marketService "github.com/tiagofernandes/gofolio/internal/services/market"
+	marketService "gofolio/backend/internal/services/market"
 )
 
 // Handler contém os handlers para as rotas relacionadas a dados de mercado
@@ -5,11 +5,31 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
-	"github.com/tiagofernandes/gofolio/internal/auth"
+	"gofolio/backend/internal/api/admin"
+	"gofolio/backend/internal/api/handlers"
+	scraperAPI "gofolio/backend/internal/api/scraper"
+	"gofolio/backend/internal/auth"
+	"gofolio/backend/internal/services"
+	"gofolio/backend/internal/services/backtest"
+	"gofolio/backend/internal/services/scheduler"
+	"gofolio/backend/internal/services/scraper"
+	"gofolio/backend/internal/services/strategy"
 )
 
+// Services agrupa as dependências usadas pelas rotas da API
+type Services struct {
+	Users      auth.UserRepository
+	Tokens     auth.TokenStore
+	APIKeys    auth.APIKeyRepository
+	Portfolios *services.PortfolioService
+	Backtests  *backtest.Service
+	Strategies *strategy.Service
+	Scraper    *scraper.ScraperService
+	Scheduler  *scheduler.SchedulerService // opcional
+}
+
 // NewRouter retorna um novo router configurado com as rotas da API
-func NewRouter() *mux.Router {
+func NewRouter(services Services) *mux.Router {
 	r := mux.NewRouter()
 
 	// Middleware de CORS
@@ -21,23 +41,57 @@ func NewRouter() *mux.Router {
 	// Rotas públicas
 	api.HandleFunc("/health", healthCheckHandler).Methods("GET")
 	api.HandleFunc("/auth/login", auth.LoginHandler).Methods("POST")
+	api.HandleFunc("/auth/login/mfa", auth.MFALoginHandler).Methods("POST")
 	api.HandleFunc("/auth/register", auth.RegisterHandler).Methods("POST")
+	api.HandleFunc("/auth/refresh", auth.RefreshHandler).Methods("POST")
+	api.HandleFunc("/auth/logout", auth.LogoutHandler).Methods("POST")
+	api.HandleFunc("/auth/jwks", auth.JWKSHandler).Methods("GET")
 
 	// Rotas protegidas
 	protected := api.PathPrefix("").Subrouter()
 	protected.Use(auth.JWTMiddleware)
 
+	// Rotas de 2FA
+	protected.HandleFunc("/auth/mfa/enroll", auth.MFAEnrollHandler).Methods("POST")
+	protected.HandleFunc("/auth/mfa/verify", auth.MFAVerifyHandler).Methods("POST")
+	protected.HandleFunc("/auth/mfa/recovery-codes", auth.MFARecoveryCodesHandler).Methods("POST")
+	protected.HandleFunc("/auth/mfa/disable", auth.MFADisableHandler).Methods("POST")
+
+	// Perfil e preferências do usuário
+	protected.HandleFunc("/me", auth.GetProfileHandler).Methods("GET")
+	protected.HandleFunc("/me", auth.UpdateProfileHandler).Methods("PUT")
+	protected.HandleFunc("/me/preferences", auth.GetPreferencesHandler).Methods("GET")
+	protected.HandleFunc("/me/preferences", auth.UpdatePreferencesHandler).Methods("PUT")
+
+	// Rotas de chaves de API
+	protected.HandleFunc("/auth/api-keys", auth.ListAPIKeysHandler).Methods("GET")
+	protected.HandleFunc("/auth/api-keys", auth.CreateAPIKeyHandler).Methods("POST")
+	protected.HandleFunc("/auth/api-keys/{id}", auth.DeleteAPIKeyHandler).Methods("DELETE")
+
 	// Rotas de portfólio
-	protected.HandleFunc("/portfolio", getPortfolioHandler).Methods("GET")
-	protected.HandleFunc("/portfolio/assets", getAssetsHandler).Methods("GET")
+	handlers.NewPortfolioHandler(services.Portfolios).RegisterRoutes(protected)
+
+	// Rotas de backtests e estratégias declarativas
+	handlers.NewBacktestHandler(services.Backtests).RegisterRoutes(protected)
+	handlers.NewStrategyHandler(services.Strategies).RegisterRoutes(protected)
 	
 	// Rotas de análise técnica
-	protected.HandleFunc("/technical/{symbol}", getTechnicalDataHandler).Methods("GET")
+	scraperHandler := scraperAPI.NewHandler(services.Scraper)
+	protected.HandleFunc("/technical/{symbol}", scraperHandler.GetTechnicalAnalysisHandler).Methods("GET")
+	
+	// Rotas de dados históricos (velas OHLCV) e padrões
+	protected.HandleFunc("/historical/{symbol}", scraperHandler.GetHistoricalDataHandler).Methods("GET")
+	protected.HandleFunc("/patterns/{symbol}", scraperHandler.GetPatternsHandler).Methods("GET")
 	
 	// Rotas de análise sentimental
 	protected.HandleFunc("/sentiment", getSentimentDataHandler).Methods("GET")
 	protected.HandleFunc("/sentiment/{symbol}", getSentimentBySymbolHandler).Methods("GET")
 
+	// Rotas de administração (apenas administradores)
+	adminRouter := protected.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(auth.RequireRole(auth.RoleAdmin))
+	admin.RegisterRoutes(adminRouter, admin.NewHandler(services.Users, services.Tokens, services.Scraper, services.Scheduler))
+
 	return r
 }
 
@@ -46,7 +100,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -70,63 +124,6 @@ func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // Handlers para as rotas protegidas (implementação básica)
-func getPortfolioHandler(w http.ResponseWriter, r *http.Request) {
-	// Placeholder - seria implementado com acesso a banco de dados
-	portfolio := map[string]interface{}{
-		"totalBalance": 24560.45,
-		"performance": map[string]float64{
-			"day": 2.3,
-			"week": -1.5,
-			"month": 5.7,
-			"year": 22.4,
-		},
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(portfolio)
-}
-
-func getAssetsHandler(w http.ResponseWriter, r *http.Request) {
-	// Placeholder - seria implementado com acesso a carteira e APIs de mercado
-	assets := []map[string]interface{}{
-		{
-			"symbol": "BTC",
-			"name": "Bitcoin",
-			"balance": 0.5,
-			"price": 35000,
-			"change24h": 2.3,
-		},
-		{
-			"symbol": "ETH",
-			"name": "Ethereum",
-			"balance": 5.2,
-			"price": 2000,
-			"change24h": -1.1,
-		},
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(assets)
-}
-
-func getTechnicalDataHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	symbol := vars["symbol"]
-
-	// Placeholder - seria implementado com acesso a API de dados técnicos
-	technicalData := map[string]interface{}{
-		"symbol": symbol,
-		"indicators": map[string]float64{
-			"rsi": 65.4,
-			"macd": 1.2,
-			"volume": 45000000,
-		},
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(technicalData)
-}
-
 func getSentimentDataHandler(w http.ResponseWriter, r *http.Request) {
 	// Placeholder - seria implementado com acesso a dados de sentimento
 	sentimentData := []map[string]interface{}{
@@ -4,10 +4,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/tiagofernandes/gofolio/internal/services/scraper"
+	"gofolio/backend/internal/services/candles"
+	"gofolio/backend/internal/services/scraper"
 )
 
 // Handler implementa os endpoints para o serviço de raspagem
@@ -73,7 +73,8 @@ func (h *Handler) GetSentimentAnalysisHandler(w http.ResponseWriter, r *http.Req
 	respondWithJSON(w, http.StatusOK, sentiment)
 }
 
-// GetHistoricalDataHandler retorna dados históricos para um ativo específico
+// GetHistoricalDataHandler retorna as velas OHLCV de um ativo específico no intervalo pedido
+// (1m, 5m, 1h, 4h, 1d ou 1w)
 func (h *Handler) GetHistoricalDataHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
@@ -99,6 +100,11 @@ func (h *Handler) GetHistoricalDataHandler(w http.ResponseWriter, r *http.Reques
 		}
 	}
 	
+	if _, err := candles.ParseInterval(interval); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
 	data, err := h.service.GetHistoricalData(ctx, symbol, interval, limit)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -108,6 +114,36 @@ func (h *Handler) GetHistoricalDataHandler(w http.ResponseWriter, r *http.Reques
 	respondWithJSON(w, http.StatusOK, data)
 }
 
+// GetPatternsHandler retorna os padrões de velas e gráficos detetados para um ativo específico
+// no intervalo pedido (1m, 5m, 1h, 4h, 1d ou 1w)
+func (h *Handler) GetPatternsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	symbol := vars["symbol"]
+	
+	if symbol == "" {
+		http.Error(w, "Symbol é obrigatório", http.StatusBadRequest)
+		return
+	}
+	
+	interval := r.URL.Query().Get("interval")
+	if interval == "" {
+		interval = "1d"
+	}
+	if _, err := candles.ParseInterval(interval); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	analysis, err := h.service.GetPatterns(ctx, symbol, interval)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	respondWithJSON(w, http.StatusOK, analysis)
+}
+
 // GetFearAndGreedIndexHandler retorna o índice de medo e ganância do mercado
 func (h *Handler) GetFearAndGreedIndexHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -135,6 +171,10 @@ func RegisterRoutes(router *mux.Router, service *scraper.ScraperService) {
 	technical := router.PathPrefix("/technical").Subrouter()
 	technical.HandleFunc("/{symbol}", handler.GetTechnicalAnalysisHandler).Methods("GET")
 	
+	// Criar subrouter para os endpoints de padrões
+	patterns := router.PathPrefix("/patterns").Subrouter()
+	patterns.HandleFunc("/{symbol}", handler.GetPatternsHandler).Methods("GET")
+	
 	// Criar subrouter para os endpoints de análise de sentimento
 	sentiment := router.PathPrefix("/sentiment").Subrouter()
 	sentiment.HandleFunc("/{symbol}", handler.GetSentimentAnalysisHandler).Methods("GET")
@@ -0,0 +1,418 @@
+package auth
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// Escopos das chaves de API
+const (
+	ScopeRead  = "read"  // requisições GET/HEAD
+	ScopeWrite = "write" // requisições que alteram dados
+	ScopeAdmin = "admin" // rotas de administração (apenas para administradores)
+)
+
+// Constantes das chaves de API
+const (
+	apiKeyHeader       = "X-API-Key"
+	apiKeyPrefix       = "gfk_"
+	apiKeyDisplayChars = 12 // caracteres guardados em claro para identificar a chave
+	apiKeyTouchEvery   = time.Minute
+	maxAPIKeysPerUser  = 20
+)
+
+// ErrAPIKeyNotFound é retornado quando a chave não existe
+var ErrAPIKeyNotFound = errors.New("chave de API não encontrada")
+
+// APIKey representa uma chave de API pessoal. Apenas o hash SHA-256 é persistido.
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"userId"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // início da chave, para o usuário a reconhecer
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// Expired indica se a chave já expirou
+func (k *APIKey) Expired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
+
+// APIKeyRepository define a interface para persistência de chaves de API
+type APIKeyRepository interface {
+	Create(key *APIKey) error
+	GetByHash(keyHash string) (*APIKey, error)
+	ListByUser(userID string) ([]APIKey, error)
+	Delete(userID, id string) error
+	TouchLastUsed(id string, at time.Time) error
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays"` // 0 = sem expiração
+}
+
+type CreateAPIKeyResponse struct {
+	Key    string `json:"key"` // exibida apenas uma vez
+	APIKey APIKey `json:"apiKey"`
+}
+
+// apiKeyRepository guarda as chaves de API. Configurado através de SetAPIKeyRepository.
+var apiKeyRepository APIKeyRepository
+
+// SetAPIKeyRepository configura o repositório de chaves de API
+func SetAPIKeyRepository(repo APIKeyRepository) {
+	apiKeyRepository = repo
+}
+
+// ListAPIKeysHandler lista as chaves de API do usuário autenticado
+func ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := apiKeyRepository.ListByUser(user.ID)
+	if err != nil {
+		http.Error(w, "Erro ao obter chaves de API", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+// CreateAPIKeyHandler gera uma nova chave de API para o usuário autenticado
+func CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Formato de requisição inválido", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "O nome da chave é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Scopes) == 0 {
+		req.Scopes = []string{ScopeRead}
+	}
+	for _, scope := range req.Scopes {
+		switch scope {
+		case ScopeRead, ScopeWrite:
+		case ScopeAdmin:
+			if !user.HasRole(RoleAdmin) {
+				http.Error(w, "Apenas administradores podem criar chaves com escopo admin", http.StatusForbidden)
+				return
+			}
+		default:
+			http.Error(w, "Escopo inválido: "+scope, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.ExpiresInDays < 0 {
+		http.Error(w, "expiresInDays inválido", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := apiKeyRepository.ListByUser(user.ID)
+	if err != nil {
+		http.Error(w, "Erro ao obter chaves de API", http.StatusInternalServerError)
+		return
+	}
+	if len(existing) >= maxAPIKeysPerUser {
+		http.Error(w, "Limite de chaves de API atingido", http.StatusConflict)
+		return
+	}
+
+	// Gerar a chave
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		http.Error(w, "Erro ao gerar chave", http.StatusInternalServerError)
+		return
+	}
+	rawKey := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	key := APIKey{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Name:      req.Name,
+		Prefix:    rawKey[:apiKeyDisplayChars],
+		KeyHash:   hashToken(rawKey),
+		Scopes:    req.Scopes,
+		CreatedAt: time.Now(),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := key.CreatedAt.AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := apiKeyRepository.Create(&key); err != nil {
+		http.Error(w, "Erro ao salvar chave de API", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateAPIKeyResponse{Key: rawKey, APIKey: key})
+}
+
+// DeleteAPIKeyHandler revoga uma chave de API do usuário autenticado
+func DeleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if err := apiKeyRepository.Delete(user.ID, id); err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Erro ao remover chave de API", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireSession obtém o usuário do contexto e garante que a requisição foi
+// autenticada com JWT. A gestão de chaves não é permitida com uma chave de API,
+// para que uma chave roubada não possa criar outras.
+func requireSession(w http.ResponseWriter, r *http.Request) (User, bool) {
+	user, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Autorização necessária", http.StatusUnauthorized)
+		return User{}, false
+	}
+
+	if _, viaAPIKey := GetScopesFromContext(r.Context()); viaAPIKey {
+		http.Error(w, "Operação não permitida com chave de API", http.StatusForbidden)
+		return User{}, false
+	}
+
+	if apiKeyRepository == nil {
+		http.Error(w, "Repositório de chaves de API não configurado", http.StatusInternalServerError)
+		return User{}, false
+	}
+
+	return user, true
+}
+
+// authenticateAPIKey valida uma chave de API e devolve a chave guardada
+func authenticateAPIKey(rawKey string) (*APIKey, error) {
+	if apiKeyRepository == nil {
+		return nil, errors.New("repositório de chaves de API não configurado")
+	}
+
+	key, err := apiKeyRepository.GetByHash(hashToken(rawKey))
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Expired() {
+		return nil, errors.New("chave de API expirada")
+	}
+
+	// Atualizar o último uso no máximo uma vez por minuto
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchEvery {
+		if err := apiKeyRepository.TouchLastUsed(key.ID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// requiredScope devolve o escopo necessário para o método HTTP
+func requiredScope(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ScopeRead
+	default:
+		return ScopeWrite
+	}
+}
+
+// hasScope indica se a lista contém o escopo. O escopo write inclui read.
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope || (s == ScopeWrite && scope == ScopeRead) {
+			return true
+		}
+	}
+	return false
+}
+
+// PostgresAPIKeyRepository implementação do repositório de chaves de API para PostgreSQL
+type PostgresAPIKeyRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresAPIKeyRepository cria um novo repositório PostgreSQL de chaves de API
+func NewPostgresAPIKeyRepository(db *sql.DB) *PostgresAPIKeyRepository {
+	return &PostgresAPIKeyRepository{db: db}
+}
+
+// Create insere uma nova chave de API
+func (r *PostgresAPIKeyRepository) Create(key *APIKey) error {
+	query := `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.Exec(
+		query,
+		key.ID,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		pq.Array(key.Scopes),
+		key.ExpiresAt,
+		key.LastUsedAt,
+		key.CreatedAt,
+	)
+	return err
+}
+
+// GetByHash obtém uma chave de API pelo hash
+func (r *PostgresAPIKeyRepository) GetByHash(keyHash string) (*APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at
+		FROM api_keys
+		WHERE key_hash = $1
+	`
+
+	key, err := scanAPIKey(r.db.QueryRow(query, keyHash))
+	if err == sql.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	return key, err
+}
+
+// ListByUser obtém as chaves de API de um usuário
+func (r *PostgresAPIKeyRepository) ListByUser(userID string) ([]APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Delete remove uma chave de API de um usuário
+func (r *PostgresAPIKeyRepository) Delete(userID, id string) error {
+	result, err := r.db.Exec(`DELETE FROM api_keys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchLastUsed atualiza a data do último uso de uma chave
+func (r *PostgresAPIKeyRepository) TouchLastUsed(id string, at time.Time) error {
+	_, err := r.db.Exec(`UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, at)
+	return err
+}
+
+// scanAPIKey converte uma linha da tabela api_keys numa APIKey
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var key APIKey
+	var expiresAt, lastUsedAt sql.NullTime
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		pq.Array(&key.Scopes),
+		&expiresAt,
+		&lastUsedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return &key, nil
+}
+
+// Esquema SQL para criação da tabela de chaves de API
+const APIKeySchema = `
+CREATE TABLE IF NOT EXISTS api_keys (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{read}',
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
+`
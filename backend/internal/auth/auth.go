@@ -2,6 +2,10 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,24 +14,81 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Constantes
 const (
-	secretKey = "seu-segredo-muito-seguro" // Em produção, deve vir de variável de ambiente
-	tokenExpiration = 24 * time.Hour
+	accessTokenExpiration = 15 * time.Minute
+	refreshTokenExpiration = 30 * 24 * time.Hour
 	userContextKey = "user"
+	scopesContextKey = "scopes"
+	minPasswordLength = 8
 )
 
+// Papéis de usuário
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// userRepository guarda os usuários registados. Deve ser configurado na
+// inicialização da aplicação através de SetUserRepository.
+var userRepository UserRepository
+
+// tokenStore guarda os refresh tokens e a lista de revogação de access tokens.
+// Deve ser configurado na inicialização da aplicação através de SetTokenStore.
+var tokenStore TokenStore
+
+// adminEmails lista os emails que recebem o papel de administrador no
+// registo ou no login. Configurado através de SetAdminEmails.
+var adminEmails = map[string]bool{}
+
+// keySet guarda as chaves de assinatura e verificação dos tokens.
+// Deve ser configurado na inicialização da aplicação através de SetKeySet.
+var keySet *KeySet
+
+// SetUserRepository configura o repositório de usuários usado pelos handlers
+func SetUserRepository(repo UserRepository) {
+	userRepository = repo
+}
+
+// SetTokenStore configura o armazenamento de refresh tokens e revogações
+func SetTokenStore(store TokenStore) {
+	tokenStore = store
+}
+
+// SetKeySet configura as chaves usadas para assinar e verificar tokens
+func SetKeySet(ks *KeySet) {
+	keySet = ks
+}
+
+// SetAdminEmails configura os emails que devem ter o papel de administrador
+func SetAdminEmails(emails []string) {
+	adminEmails = make(map[string]bool, len(emails))
+	for _, email := range emails {
+		if email = normalizeEmail(email); email != "" {
+			adminEmails[email] = true
+		}
+	}
+}
+
 // Estruturas de dados
 type User struct {
-	ID        string `json:"id"`
-	Email     string `json:"email"`
-	Password  string `json:"-"` // não será exibido nas respostas JSON
-	Preferences struct {
-		Theme    string `json:"theme"`
-		Language string `json:"language"`
-	} `json:"preferences"`
+	ID               string      `json:"id"`
+	Email            string      `json:"email"`
+	Name             string      `json:"name"`
+	PasswordHash     string      `json:"-"` // hash bcrypt, não será exibido nas respostas JSON
+	Preferences      Preferences `json:"preferences"`
+	Roles            []string  `json:"roles"`
+	Disabled         bool      `json:"disabled"` // contas desativadas não podem autenticar
+	MFAEnabled       bool      `json:"mfaEnabled"`
+	MFASecret        string    `json:"-"` // segredo TOTP em base32
+	MFARecoveryCodes []string  `json:"-"` // hashes SHA-256 dos códigos de recuperação por usar
+	MFALastUsedStep  int64     `json:"-"` // último passo TOTP aceite, impede reutilização de códigos
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
 }
 
 type LoginRequest struct {
@@ -41,17 +102,42 @@ type RegisterRequest struct {
 	ConfirmPassword string `json:"confirmPassword"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+	AllSessions  bool   `json:"allSessions"` // termina todas as sessões do usuário
+}
+
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refreshToken"`
+	ExpiresAt    time.Time `json:"expiresAt"` // expiração do access token
+	User         User      `json:"user"`
 }
 
 // Claims personalizado para o JWT
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID  string   `json:"user_id"`
+	Roles   []string `json:"roles,omitempty"`   // incluídos para serviços que verificam o token via JWKS
+	Purpose string   `json:"purpose,omitempty"` // vazio para access tokens; "mfa_pending" no login em dois passos
 	jwt.RegisteredClaims
 }
 
+// HasRole indica se o usuário tem algum dos papéis indicados
+func (u User) HasRole(roles ...string) bool {
+	for _, have := range u.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // LoginHandler processa requisições de login
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
@@ -66,34 +152,52 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Em uma implementação real, buscaríamos no banco de dados
-	// e verificaríamos a senha com bcrypt
-	
-	// Simulação de autenticação para desenvolvimento
-	if req.Email != "user@example.com" || req.Password != "password" {
+	if userRepository == nil {
+		http.Error(w, "Repositório de usuários não configurado", http.StatusInternalServerError)
+		return
+	}
+
+	// Buscar o usuário e verificar a senha
+	user, err := userRepository.GetByEmail(normalizeEmail(req.Email))
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			http.Error(w, "Credenciais inválidas", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Erro ao obter usuário", http.StatusInternalServerError)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
 		http.Error(w, "Credenciais inválidas", http.StatusUnauthorized)
 		return
 	}
 
-	// Criar um usuário de teste
-	user := User{
-		ID:    "1234",
-		Email: req.Email,
+	if user.Disabled {
+		http.Error(w, "Conta desativada", http.StatusForbidden)
+		return
 	}
-	user.Preferences.Theme = "light"
-	user.Preferences.Language = "pt"
 
-	// Gerar token JWT
-	token, err := generateToken(user.ID)
-	if err != nil {
-		http.Error(w, "Erro ao gerar token", http.StatusInternalServerError)
+	// Promover administradores configurados que ainda não têm o papel
+	if adminEmails[user.Email] && !user.HasRole(RoleAdmin) {
+		user.Roles = append(user.Roles, RoleAdmin)
+		if err := userRepository.UpdateAccess(user); err != nil {
+			http.Error(w, "Erro ao salvar usuário", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Com 2FA ativo, o token definitivo só é emitido em MFALoginHandler
+	if user.MFAEnabled {
+		writeMFAChallenge(w, user)
 		return
 	}
 
-	// Preparar resposta
-	response := AuthResponse{
-		Token: token,
-		User:  user,
+	// Gerar tokens
+	response, err := issueTokens(*user, "")
+	if err != nil {
+		http.Error(w, "Erro ao gerar token", http.StatusInternalServerError)
+		return
 	}
 
 	// Enviar resposta
@@ -121,77 +225,284 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Em uma implementação real, verificaríamos se o email já existe
-	// e salvaríamos o novo usuário no banco de dados com senha hashada
+	if len(req.Password) < minPasswordLength {
+		http.Error(w, fmt.Sprintf("A senha deve ter pelo menos %d caracteres", minPasswordLength), http.StatusBadRequest)
+		return
+	}
+
+	if userRepository == nil {
+		http.Error(w, "Repositório de usuários não configurado", http.StatusInternalServerError)
+		return
+	}
 
-	// Criar um ID único para o usuário (simulado)
-	userID := fmt.Sprintf("user_%d", time.Now().Unix())
+	// Gerar o hash da senha
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		http.Error(w, "Erro ao processar senha", http.StatusInternalServerError)
+		return
+	}
 
 	// Criar um usuário
+	now := time.Now()
 	user := User{
-		ID:    userID,
-		Email: req.Email,
+		ID:           uuid.New().String(),
+		Email:        normalizeEmail(req.Email),
+		PasswordHash: string(hash),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Roles:        []string{RoleUser},
+	}
+	if adminEmails[user.Email] {
+		user.Roles = append(user.Roles, RoleAdmin)
 	}
-	user.Preferences.Theme = "light"
-	user.Preferences.Language = "pt"
+	user.Preferences = DefaultPreferences()
 
-	// Gerar token JWT
-	token, err := generateToken(user.ID)
+	// Salvar o usuário, rejeitando emails já registados
+	if err := userRepository.Create(&user); err != nil {
+		if errors.Is(err, ErrEmailAlreadyExists) {
+			http.Error(w, "Email já registado", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Erro ao salvar usuário", http.StatusInternalServerError)
+		return
+	}
+
+	// Gerar tokens
+	response, err := issueTokens(user, "")
 	if err != nil {
 		http.Error(w, "Erro ao gerar token", http.StatusInternalServerError)
 		return
 	}
 
-	// Preparar resposta
-	response := AuthResponse{
-		Token: token,
-		User:  user,
+	// Enviar resposta
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RefreshHandler troca um refresh token válido por um novo par de tokens.
+// O refresh token usado é invalidado (rotação); se for reutilizado, toda a
+// família de tokens é revogada, pois indica que o token foi roubado.
+func RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Formato de requisição inválido", http.StatusBadRequest)
+		return
+	}
+
+	if req.RefreshToken == "" {
+		http.Error(w, "Refresh token é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	if userRepository == nil || tokenStore == nil {
+		http.Error(w, "Autenticação não configurada", http.StatusInternalServerError)
+		return
+	}
+
+	// Buscar o refresh token pelo hash
+	stored, err := tokenStore.GetRefreshToken(hashToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenNotFound) {
+			http.Error(w, "Refresh token inválido", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Erro ao validar refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	// Detetar reutilização de um token já trocado. A marcação falha com ErrRefreshTokenReused
+	// quando outro pedido com o mesmo token a fez primeiro.
+	err = ErrRefreshTokenReused
+	if !stored.Used {
+		err = tokenStore.MarkRefreshTokenUsed(stored.TokenHash)
+	}
+	if errors.Is(err, ErrRefreshTokenReused) {
+		tokenStore.DeleteRefreshTokenFamily(stored.FamilyID)
+		http.Error(w, "Refresh token reutilizado, sessão terminada", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao validar refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := userRepository.GetByID(stored.UserID)
+	if err != nil {
+		http.Error(w, "Usuário não encontrado", http.StatusUnauthorized)
+		return
+	}
+
+	if user.Disabled {
+		http.Error(w, "Conta desativada", http.StatusForbidden)
+		return
+	}
+
+	// Gerar novos tokens na mesma família
+	response, err := issueTokens(*user, stored.FamilyID)
+	if err != nil {
+		http.Error(w, "Erro ao gerar token", http.StatusInternalServerError)
+		return
 	}
 
 	// Enviar resposta
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
-// JWTMiddleware verifica se o token JWT é válido
+// LogoutHandler termina a sessão: revoga o access token enviado no cabeçalho
+// Authorization e remove o refresh token (ou todas as sessões do usuário)
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	var req LogoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Formato de requisição inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if tokenStore == nil {
+		http.Error(w, "Autenticação não configurada", http.StatusInternalServerError)
+		return
+	}
+
+	userID := ""
+
+	// Revogar o access token, se presente e ainda válido
+	if tokenString, err := extractBearerToken(r); err == nil {
+		if claims, err := validateToken(tokenString, ""); err == nil {
+			userID = claims.UserID
+			if err := tokenStore.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+				http.Error(w, "Erro ao revogar token", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	// Remover a família do refresh token
+	if req.RefreshToken != "" {
+		stored, err := tokenStore.GetRefreshToken(hashToken(req.RefreshToken))
+		if err == nil {
+			userID = stored.UserID
+			if err := tokenStore.DeleteRefreshTokenFamily(stored.FamilyID); err != nil {
+				http.Error(w, "Erro ao remover refresh token", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	// Terminar todas as sessões do usuário
+	if req.AllSessions && userID != "" {
+		if err := tokenStore.DeleteUserRefreshTokens(userID); err != nil {
+			http.Error(w, "Erro ao terminar sessões", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JWTMiddleware verifica se o token JWT é válido. Em alternativa ao token, aceita
+// uma chave de API pessoal no cabeçalho X-API-Key; nesse caso os escopos da chave
+// limitam os métodos HTTP permitidos.
 func JWTMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Extrair o token do cabeçalho Authorization
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Autorização necessária", http.StatusUnauthorized)
+		if userRepository == nil {
+			http.Error(w, "Repositório de usuários não configurado", http.StatusInternalServerError)
 			return
 		}
 
-		// Verificar o formato do cabeçalho
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Formato de autorização inválido", http.StatusUnauthorized)
-			return
+		var userID string
+		var scopes []string
+
+		if rawKey := r.Header.Get(apiKeyHeader); rawKey != "" {
+			// Autenticação por chave de API
+			key, err := authenticateAPIKey(rawKey)
+			if err != nil {
+				http.Error(w, "Chave de API inválida", http.StatusUnauthorized)
+				return
+			}
+
+			if !hasScope(key.Scopes, requiredScope(r.Method)) {
+				http.Error(w, "Escopo insuficiente para esta operação", http.StatusForbidden)
+				return
+			}
+
+			userID = key.UserID
+			scopes = key.Scopes
+		} else {
+			// Extrair o token do cabeçalho Authorization
+			tokenString, err := extractBearerToken(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			// Validar o token
+			claims, err := validateToken(tokenString, "")
+			if err != nil {
+				http.Error(w, "Token inválido", http.StatusUnauthorized)
+				return
+			}
+
+			userID = claims.UserID
 		}
 
-		// Validar o token
-		userID, err := validateToken(parts[1])
+		// Buscar o usuário no repositório
+		user, err := userRepository.GetByID(userID)
 		if err != nil {
-			http.Error(w, "Token inválido", http.StatusUnauthorized)
+			http.Error(w, "Usuário não encontrado", http.StatusUnauthorized)
 			return
 		}
 
-		// Em uma implementação real, buscaríamos o usuário no banco de dados
-		user := User{
-			ID:    userID,
-			Email: "user@example.com", // simulado
+		if user.Disabled {
+			http.Error(w, "Conta desativada", http.StatusForbidden)
+			return
 		}
-		user.Preferences.Theme = "light"
-		user.Preferences.Language = "pt"
 
-		// Adicionar o usuário ao contexto da requisição
-		ctx := context.WithValue(r.Context(), userContextKey, user)
+		// Adicionar o usuário (e os escopos da chave, se for o caso) ao contexto da requisição
+		ctx := context.WithValue(r.Context(), userContextKey, *user)
+		if scopes != nil {
+			ctx = context.WithValue(ctx, scopesContextKey, scopes)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireRole restringe o acesso a usuários com algum dos papéis indicados.
+// Deve ser usado depois de JWTMiddleware, que coloca o usuário no contexto.
+// Os papéis são lidos do usuário carregado do repositório, e não das claims,
+// para que a remoção de um papel tenha efeito imediato. Chaves de API só passam
+// em rotas de administração se tiverem o escopo admin.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := GetUserFromContext(r.Context())
+			if err != nil {
+				http.Error(w, "Autorização necessária", http.StatusUnauthorized)
+				return
+			}
+
+			if !user.HasRole(roles...) {
+				http.Error(w, "Permissão insuficiente", http.StatusForbidden)
+				return
+			}
+
+			if scopes, viaAPIKey := GetScopesFromContext(r.Context()); viaAPIKey && user.HasRole(RoleAdmin) {
+				for _, role := range roles {
+					if role == RoleAdmin && !hasScope(scopes, ScopeAdmin) {
+						http.Error(w, "Escopo insuficiente para esta operação", http.StatusForbidden)
+						return
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // GetUserFromContext retorna o usuário do contexto
 func GetUserFromContext(ctx context.Context) (User, error) {
 	user, ok := ctx.Value(userContextKey).(User)
@@ -201,45 +512,179 @@ func GetUserFromContext(ctx context.Context) (User, error) {
 	return user, nil
 }
 
+// GetScopesFromContext retorna os escopos da chave de API usada na requisição.
+// O segundo valor é false quando a requisição foi autenticada com JWT.
+func GetScopesFromContext(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(scopesContextKey).([]string)
+	return scopes, ok
+}
+
 // Funções auxiliares para geração e validação de tokens
 
-func generateToken(userID string) (string, error) {
-	expirationTime := time.Now().Add(tokenExpiration)
+// normalizeEmail remove espaços e converte o email para minúsculas
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// extractBearerToken extrai o token do cabeçalho Authorization
+func extractBearerToken(r *http.Request) (string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", errors.New("Autorização necessária")
+	}
+
+	// Verificar o formato do cabeçalho
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", errors.New("Formato de autorização inválido")
+	}
+
+	return parts[1], nil
+}
+
+// issueTokens gera um access token e um novo refresh token para o usuário.
+// Um familyID vazio inicia uma nova família (nova sessão).
+func issueTokens(user User, familyID string) (*AuthResponse, error) {
+	token, expiresAt, err := generateToken(user, "", accessTokenExpiration)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateRefreshToken(user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+		User:         user,
+	}, nil
+}
+
+// generateToken assina um token para o usuário. Um purpose vazio gera um access token.
+func generateToken(user User, purpose string, ttl time.Duration) (string, time.Time, error) {
+	if keySet == nil {
+		return "", time.Time{}, errors.New("chaves de assinatura não configuradas")
+	}
+
+	key, err := keySet.SigningKey()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expirationTime := time.Now().Add(ttl)
 	
 	claims := &Claims{
-		UserID: userID,
+		UserID:  user.ID,
+		Purpose: purpose,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "gofolio-api",
-			Subject:   userID,
+			Subject:   user.ID,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secretKey))
+	// Papéis apenas em access tokens
+	if purpose == "" {
+		claims.Roles = user.Roles
+	}
+
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	tokenString, err := token.SignedString(key.signKey)
 	
-	return tokenString, err
+	return tokenString, expirationTime, err
+}
+
+// generateRefreshToken cria um refresh token aleatório e guarda o seu hash
+func generateRefreshToken(userID, familyID string) (string, error) {
+	if tokenStore == nil {
+		return "", errors.New("armazenamento de tokens não configurado")
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	tokenString := base64.RawURLEncoding.EncodeToString(buf)
+
+	if familyID == "" {
+		familyID = uuid.New().String()
+	}
+
+	now := time.Now()
+	err := tokenStore.SaveRefreshToken(&RefreshToken{
+		TokenHash: hashToken(tokenString),
+		UserID:    userID,
+		FamilyID:  familyID,
+		ExpiresAt: now.Add(refreshTokenExpiration),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
 }
 
-func validateToken(tokenString string) (string, error) {
+// hashToken calcula o hash SHA-256 de um token opaco
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// validateToken valida a assinatura, o propósito e a revogação de um token
+func validateToken(tokenString, purpose string) (*Claims, error) {
 	claims := &Claims{}
 
+	if keySet == nil {
+		return nil, errors.New("chaves de assinatura não configuradas")
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, err := keySet.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// O algoritmo do token tem de corresponder ao da chave
+		if token.Method.Alg() != key.Algorithm {
 			return nil, fmt.Errorf("método de assinatura inesperado: %v", token.Header["alg"])
 		}
-		return []byte(secretKey), nil
+		return key.verifyKey, nil
 	})
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if !token.Valid {
-		return "", errors.New("token inválido")
+		return nil, errors.New("token inválido")
+	}
+
+	// Impedir que um token "mfa pending" seja usado como access token e vice-versa
+	if claims.Purpose != purpose {
+		return nil, errors.New("token com propósito inválido")
+	}
+
+	// Verificar a lista de revogação
+	if tokenStore == nil {
+		return nil, errors.New("armazenamento de tokens não configurado")
 	}
 
-	return claims.UserID, nil
-} 
\ No newline at end of file
+	revoked, err := tokenStore.IsTokenRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("token revogado")
+	}
+
+	return claims, nil
+}
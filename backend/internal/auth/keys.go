@@ -0,0 +1,363 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Algoritmos de assinatura suportados
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// SigningKey representa uma chave usada para assinar ou verificar tokens.
+// Chaves sem parte privada servem apenas para verificação.
+type SigningKey struct {
+	ID        string // kid incluído no cabeçalho do token
+	Algorithm string
+	signKey   interface{} // []byte, *rsa.PrivateKey ou ed25519.PrivateKey
+	verifyKey interface{} // []byte, *rsa.PublicKey ou ed25519.PublicKey
+}
+
+// CanSign indica se a chave tem a parte privada necessária para assinar
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+// method devolve o método de assinatura JWT correspondente ao algoritmo
+func (k *SigningKey) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// KeySet guarda as chaves ativas. Apenas uma chave assina novos tokens;
+// as restantes continuam a verificar tokens emitidos antes da rotação.
+type KeySet struct {
+	keys     map[string]*SigningKey
+	activeID string
+	mu       sync.RWMutex
+}
+
+// NewKeySet cria um conjunto de chaves vazio
+func NewKeySet() *KeySet {
+	return &KeySet{
+		keys: make(map[string]*SigningKey),
+	}
+}
+
+// AddKey adiciona uma chave ao conjunto. Se active for true, a chave passa
+// a ser usada para assinar novos tokens. Um kid já existente é rejeitado, para que
+// uma chave não seja substituída sem aviso.
+func (ks *KeySet) AddKey(key *SigningKey, active bool) error {
+	if key.ID == "" {
+		return errors.New("a chave precisa de um kid")
+	}
+	if active && !key.CanSign() {
+		return fmt.Errorf("a chave %s não tem parte privada e não pode ser ativa", key.ID)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, exists := ks.keys[key.ID]; exists {
+		return fmt.Errorf("kid duplicado: %s", key.ID)
+	}
+
+	ks.keys[key.ID] = key
+	if active {
+		ks.activeID = key.ID
+	}
+
+	return nil
+}
+
+// SigningKey devolve a chave ativa para assinatura
+func (ks *KeySet) SigningKey() (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok := ks.keys[ks.activeID]
+	if !ok {
+		return nil, errors.New("nenhuma chave de assinatura ativa")
+	}
+
+	return key, nil
+}
+
+// VerificationKey devolve a chave correspondente ao kid do token.
+// Tokens sem kid são verificados com a chave ativa.
+func (ks *KeySet) VerificationKey(kid string) (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if kid == "" {
+		kid = ks.activeID
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("chave desconhecida: %s", kid)
+	}
+
+	return key, nil
+}
+
+// JWK representa uma chave pública no formato JSON Web Key
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS representa um conjunto de chaves públicas
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS devolve as chaves públicas do conjunto. Chaves HMAC nunca são expostas.
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	result := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			result.Keys = append(result.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Algorithm: key.Algorithm,
+				Use:       "sig",
+				N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			result.Keys = append(result.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Algorithm: key.Algorithm,
+				Use:       "sig",
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	return result
+}
+
+// keyFileEntry representa uma chave no ficheiro de configuração JWT_KEYS_FILE
+type keyFileEntry struct {
+	ID             string `json:"kid"`
+	Algorithm      string `json:"alg"`
+	Secret         string `json:"secret,omitempty"`
+	PrivateKey     string `json:"privateKey,omitempty"` // PEM
+	PrivateKeyFile string `json:"privateKeyFile,omitempty"`
+	PublicKey      string `json:"publicKey,omitempty"` // PEM
+	PublicKeyFile  string `json:"publicKeyFile,omitempty"`
+}
+
+// keyFile representa o conteúdo do ficheiro JWT_KEYS_FILE
+type keyFile struct {
+	Active string         `json:"active"`
+	Keys   []keyFileEntry `json:"keys"`
+}
+
+// LoadKeySetFromEnv carrega as chaves de assinatura a partir do ambiente:
+//   - JWT_KEYS_FILE: ficheiro JSON com várias chaves (HS256, RS256 ou EdDSA) e o kid ativo
+//   - JWT_SECRET / JWT_KEY_ID: uma chave HS256 ativa
+//   - JWT_PREVIOUS_SECRETS: chaves HS256 antigas no formato "kid=segredo,kid=segredo"
+//
+// Sem nenhuma configuração, é gerada uma chave aleatória (os tokens deixam
+// de ser válidos ao reiniciar o servidor).
+func LoadKeySetFromEnv() (*KeySet, error) {
+	if path := os.Getenv("JWT_KEYS_FILE"); path != "" {
+		return LoadKeySetFromFile(path)
+	}
+
+	ks := NewKeySet()
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		log.Println("Aviso: JWT_SECRET não definido, usando chave aleatória temporária")
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		secret = string(buf)
+	}
+
+	kid := os.Getenv("JWT_KEY_ID")
+	if kid == "" {
+		kid = "default"
+	}
+
+	if err := ks.AddKey(newHMACKey(kid, []byte(secret)), true); err != nil {
+		return nil, err
+	}
+
+	// Chaves antigas, mantidas apenas para verificação
+	if previous := os.Getenv("JWT_PREVIOUS_SECRETS"); previous != "" {
+		for _, entry := range strings.Split(previous, ",") {
+			parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return nil, fmt.Errorf("entrada inválida em JWT_PREVIOUS_SECRETS: %q", entry)
+			}
+			if err := ks.AddKey(newHMACKey(parts[0], []byte(parts[1])), false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return ks, nil
+}
+
+// LoadKeySetFromFile carrega as chaves de assinatura de um ficheiro JSON
+func LoadKeySetFromFile(path string) (*KeySet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler ficheiro de chaves: %w", err)
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("ficheiro de chaves inválido: %w", err)
+	}
+
+	ks := NewKeySet()
+	for _, entry := range file.Keys {
+		key, err := parseKeyFileEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("chave %s: %w", entry.ID, err)
+		}
+		if err := ks.AddKey(key, entry.ID == file.Active); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := ks.SigningKey(); err != nil {
+		return nil, fmt.Errorf("kid ativo %q não encontrado no ficheiro de chaves", file.Active)
+	}
+
+	return ks, nil
+}
+
+// parseKeyFileEntry converte uma entrada do ficheiro de chaves numa SigningKey
+func parseKeyFileEntry(entry keyFileEntry) (*SigningKey, error) {
+	privatePEM, err := readPEM(entry.PrivateKey, entry.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	publicPEM, err := readPEM(entry.PublicKey, entry.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: entry.ID, Algorithm: entry.Algorithm}
+
+	switch entry.Algorithm {
+	case AlgorithmHS256:
+		if entry.Secret == "" {
+			return nil, errors.New("chaves HS256 precisam de secret")
+		}
+		return newHMACKey(entry.ID, []byte(entry.Secret)), nil
+
+	case AlgorithmRS256:
+		if privatePEM != nil {
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = priv
+			key.verifyKey = &priv.PublicKey
+		} else if publicPEM != nil {
+			pub, err := jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = pub
+		}
+
+	case AlgorithmEdDSA:
+		if privatePEM != nil {
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, err
+			}
+			edPriv, ok := priv.(ed25519.PrivateKey)
+			if !ok {
+				return nil, errors.New("chave privada EdDSA inválida")
+			}
+			key.signKey = edPriv
+			key.verifyKey = edPriv.Public()
+		} else if publicPEM != nil {
+			pub, err := jwt.ParseEdPublicKeyFromPEM(publicPEM)
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = pub
+		}
+
+	default:
+		return nil, fmt.Errorf("algoritmo não suportado: %s", entry.Algorithm)
+	}
+
+	if key.verifyKey == nil {
+		return nil, errors.New("é necessária uma chave privada ou pública")
+	}
+
+	return key, nil
+}
+
+// readPEM lê um PEM inline ou de ficheiro. Retorna nil se nenhum for definido.
+func readPEM(inline, path string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if path != "" {
+		return os.ReadFile(path)
+	}
+	return nil, nil
+}
+
+// newHMACKey cria uma chave HS256
+func newHMACKey(kid string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:        kid,
+		Algorithm: AlgorithmHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// JWKSHandler expõe as chaves públicas para que outros serviços possam verificar tokens
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	if keySet == nil {
+		http.Error(w, "Chaves de assinatura não configuradas", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keySet.JWKS())
+}
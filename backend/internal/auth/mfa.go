@@ -0,0 +1,417 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Constantes de 2FA
+const (
+	purposeMFAPending     = "mfa_pending"
+	mfaPendingExpiration  = 5 * time.Minute
+	recoveryCodeCount     = 10
+	recoveryCodeByteCount = 6                // 10 caracteres em base32
+	maxMFAAttempts        = 5                // códigos aceites por token "mfa pending" ou por usuário e janela
+	mfaAttemptWindow      = 15 * time.Minute // janela das tentativas nas rotas autenticadas
+)
+
+// errMFAAttemptsExhausted indica que o usuário esgotou as tentativas de 2FA da janela atual
+var errMFAAttemptsExhausted = errors.New("demasiados códigos inválidos")
+
+type MFACodeRequest struct {
+	Code string `json:"code"` // código TOTP ou código de recuperação
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfaToken"`
+	Code     string `json:"code"` // código TOTP ou código de recuperação
+}
+
+// MFAChallengeResponse é devolvida pelo login quando o usuário tem 2FA ativo
+type MFAChallengeResponse struct {
+	MFARequired bool      `json:"mfaRequired"`
+	MFAToken    string    `json:"mfaToken"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+type MFAEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"` // exibidos apenas uma vez
+}
+
+// MFALoginHandler conclui o login em dois passos: troca o token "mfa pending"
+// e um código válido pelo par de tokens definitivo
+func MFALoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req MFALoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Formato de requisição inválido", http.StatusBadRequest)
+		return
+	}
+
+	if req.MFAToken == "" || req.Code == "" {
+		http.Error(w, "Token MFA e código são obrigatórios", http.StatusBadRequest)
+		return
+	}
+
+	if userRepository == nil || tokenStore == nil {
+		http.Error(w, "Autenticação não configurada", http.StatusInternalServerError)
+		return
+	}
+
+	claims, err := validateToken(req.MFAToken, purposeMFAPending)
+	if err != nil {
+		http.Error(w, "Token MFA inválido", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := userRepository.GetByID(claims.UserID)
+	if err != nil {
+		http.Error(w, "Usuário não encontrado", http.StatusUnauthorized)
+		return
+	}
+
+	if user.Disabled {
+		http.Error(w, "Conta desativada", http.StatusForbidden)
+		return
+	}
+
+	// A tentativa é reservada antes de validar o código, para que pedidos em paralelo não
+	// ultrapassem o limite; ao fim de maxMFAAttempts o token é revogado, obrigando a repetir o
+	// login com a senha
+	attempt, err := tokenStore.IncrementMFAAttempts("token:"+claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		http.Error(w, "Erro ao verificar código", http.StatusInternalServerError)
+		return
+	}
+	if attempt > maxMFAAttempts {
+		rejectMFAToken(w, claims)
+		return
+	}
+
+	ok, err := verifyMFACode(user, req.Code)
+	if err != nil {
+		http.Error(w, "Erro ao verificar código", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		if attempt == maxMFAAttempts {
+			rejectMFAToken(w, claims)
+			return
+		}
+		http.Error(w, "Código inválido", http.StatusUnauthorized)
+		return
+	}
+
+	// O token "mfa pending" só pode ser usado uma vez
+	if err := tokenStore.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		http.Error(w, "Erro ao revogar token", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := issueTokens(*user, "")
+	if err != nil {
+		http.Error(w, "Erro ao gerar token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// rejectMFAToken revoga um token "mfa pending" que esgotou as tentativas
+func rejectMFAToken(w http.ResponseWriter, claims *Claims) {
+	if err := tokenStore.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		http.Error(w, "Erro ao revogar token", http.StatusInternalServerError)
+		return
+	}
+	http.Error(w, "Demasiados códigos inválidos, faça login novamente", http.StatusUnauthorized)
+}
+
+// MFAEnrollHandler gera um novo segredo TOTP para o usuário autenticado.
+// O 2FA só fica ativo depois de confirmado em MFAVerifyHandler.
+func MFAEnrollHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Autorização necessária", http.StatusUnauthorized)
+		return
+	}
+
+	if user.MFAEnabled {
+		http.Error(w, "2FA já está ativo", http.StatusConflict)
+		return
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		http.Error(w, "Erro ao gerar segredo", http.StatusInternalServerError)
+		return
+	}
+
+	user.MFASecret = secret
+	if err := userRepository.UpdateMFA(&user); err != nil {
+		http.Error(w, "Erro ao salvar usuário", http.StatusInternalServerError)
+		return
+	}
+
+	response := MFAEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: totpURI(secret, user.Email),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// MFAVerifyHandler confirma a ativação do 2FA com um código TOTP e devolve os códigos de recuperação
+func MFAVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Formato de requisição inválido", http.StatusBadRequest)
+		return
+	}
+
+	user, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Autorização necessária", http.StatusUnauthorized)
+		return
+	}
+
+	if user.MFAEnabled {
+		http.Error(w, "2FA já está ativo", http.StatusConflict)
+		return
+	}
+
+	if user.MFASecret == "" {
+		http.Error(w, "Inicie a ativação do 2FA primeiro", http.StatusBadRequest)
+		return
+	}
+
+	step, ok := validateTOTP(user.MFASecret, req.Code, time.Now())
+	if !ok {
+		http.Error(w, "Código inválido", http.StatusUnauthorized)
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Erro ao gerar códigos de recuperação", http.StatusInternalServerError)
+		return
+	}
+
+	user.MFAEnabled = true
+	user.MFALastUsedStep = step
+	user.MFARecoveryCodes = hashes
+	if err := userRepository.UpdateMFA(&user); err != nil {
+		http.Error(w, "Erro ao salvar usuário", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// MFARecoveryCodesHandler gera novos códigos de recuperação, invalidando os anteriores
+func MFARecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Formato de requisição inválido", http.StatusBadRequest)
+		return
+	}
+
+	user, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Autorização necessária", http.StatusUnauthorized)
+		return
+	}
+
+	if !user.MFAEnabled {
+		http.Error(w, "2FA não está ativo", http.StatusBadRequest)
+		return
+	}
+
+	ok, err := verifyLimitedMFACode(&user, req.Code)
+	if errors.Is(err, errMFAAttemptsExhausted) {
+		http.Error(w, "Demasiados códigos inválidos, tente mais tarde", http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao verificar código", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Código inválido", http.StatusUnauthorized)
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Erro ao gerar códigos de recuperação", http.StatusInternalServerError)
+		return
+	}
+
+	user.MFARecoveryCodes = hashes
+	if err := userRepository.UpdateMFA(&user); err != nil {
+		http.Error(w, "Erro ao salvar usuário", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// MFADisableHandler desativa o 2FA mediante um código válido
+func MFADisableHandler(w http.ResponseWriter, r *http.Request) {
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Formato de requisição inválido", http.StatusBadRequest)
+		return
+	}
+
+	user, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Autorização necessária", http.StatusUnauthorized)
+		return
+	}
+
+	if !user.MFAEnabled {
+		http.Error(w, "2FA não está ativo", http.StatusBadRequest)
+		return
+	}
+
+	ok, err := verifyLimitedMFACode(&user, req.Code)
+	if errors.Is(err, errMFAAttemptsExhausted) {
+		http.Error(w, "Demasiados códigos inválidos, tente mais tarde", http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao verificar código", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Código inválido", http.StatusUnauthorized)
+		return
+	}
+
+	user.MFAEnabled = false
+	user.MFASecret = ""
+	user.MFARecoveryCodes = nil
+	if err := userRepository.UpdateMFA(&user); err != nil {
+		http.Error(w, "Erro ao salvar usuário", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verifyMFACode valida um código TOTP ou de recuperação e consome-o de forma atómica, para
+// impedir a sua reutilização mesmo com pedidos em paralelo
+func verifyMFACode(user *User, code string) (bool, error) {
+	if user.MFASecret == "" {
+		return false, nil
+	}
+
+	if step, ok := validateTOTP(user.MFASecret, code, time.Now()); ok {
+		// Rejeitar códigos de um passo já usado
+		used, err := userRepository.ConsumeMFAStep(user.ID, step)
+		if err != nil || !used {
+			return false, err
+		}
+		user.MFALastUsedStep = step
+		return true, nil
+	}
+
+	// Tentar como código de recuperação (uso único)
+	hash := hashToken(normalizeRecoveryCode(code))
+	for i, stored := range user.MFARecoveryCodes {
+		if stored == hash {
+			used, err := userRepository.ConsumeRecoveryCode(user.ID, hash)
+			if err != nil || !used {
+				return false, err
+			}
+			user.MFARecoveryCodes = append(user.MFARecoveryCodes[:i:i], user.MFARecoveryCodes[i+1:]...)
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// verifyLimitedMFACode valida um código como verifyMFACode, com um limite de maxMFAAttempts
+// tentativas por usuário em cada mfaAttemptWindow. A tentativa é reservada antes da validação e
+// o contador recomeça depois de um código válido.
+func verifyLimitedMFACode(user *User, code string) (bool, error) {
+	key := "user:" + user.ID
+	attempt, err := tokenStore.IncrementMFAAttempts(key, time.Now().Add(mfaAttemptWindow))
+	if err != nil {
+		return false, err
+	}
+	if attempt > maxMFAAttempts {
+		return false, errMFAAttemptsExhausted
+	}
+
+	ok, err := verifyMFACode(user, code)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	return true, tokenStore.ResetMFAAttempts(key)
+}
+
+// generateRecoveryCodes gera códigos de recuperação no formato xxxxx-xxxxx.
+// Devolve os códigos em claro (para o usuário) e os respetivos hashes (para guardar).
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, recoveryCodeByteCount)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(buf))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode remove separadores e converte para minúsculas
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// writeMFAChallenge responde ao primeiro passo do login com um token "mfa pending"
+func writeMFAChallenge(w http.ResponseWriter, user *User) {
+	token, expiresAt, err := generateToken(*user, purposeMFAPending, mfaPendingExpiration)
+	if err != nil {
+		http.Error(w, "Erro ao gerar token", http.StatusInternalServerError)
+		return
+	}
+
+	response := MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresAt:   expiresAt,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
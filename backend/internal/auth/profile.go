@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Widgets disponíveis no dashboard
+var dashboardWidgets = map[string]bool{
+	"portfolio-stats":     true,
+	"asset-allocation":    true,
+	"portfolio-forecast":  true,
+	"portfolio-insights":  true,
+	"transaction-history": true,
+	"technical-chart":     true,
+	"sentiment":           true,
+	"news":                true,
+}
+
+// Moedas fiduciárias aceites como moeda base
+var supportedCurrencies = map[string]bool{
+	"USD": true, "EUR": true, "GBP": true, "BRL": true,
+	"CHF": true, "JPY": true, "CAD": true, "AUD": true,
+}
+
+const maxNameLength = 100
+
+// Preferences guarda as preferências de interface e de apresentação do usuário
+type Preferences struct {
+	Theme            string   `json:"theme"`        // light ou dark
+	Language         string   `json:"language"`     // pt ou en
+	BaseCurrency     string   `json:"baseCurrency"` // moeda fiduciária usada nas avaliações
+	Timezone         string   `json:"timezone"`     // nome IANA, ex.: Europe/Lisbon
+	DashboardWidgets []string `json:"dashboardWidgets"`
+}
+
+// DefaultPreferences retorna as preferências atribuídas a novos usuários
+func DefaultPreferences() Preferences {
+	return Preferences{
+		Theme:            "light",
+		Language:         "pt",
+		BaseCurrency:     "EUR",
+		Timezone:         "Europe/Lisbon",
+		DashboardWidgets: []string{"portfolio-stats", "asset-allocation", "technical-chart", "news"},
+	}
+}
+
+// Validate normaliza e verifica as preferências
+func (p *Preferences) Validate() error {
+	switch p.Theme {
+	case "light", "dark":
+	default:
+		return fmt.Errorf("tema inválido: %s", p.Theme)
+	}
+
+	switch p.Language {
+	case "pt", "en":
+	default:
+		return fmt.Errorf("idioma inválido: %s", p.Language)
+	}
+
+	p.BaseCurrency = strings.ToUpper(strings.TrimSpace(p.BaseCurrency))
+	if !supportedCurrencies[p.BaseCurrency] {
+		return fmt.Errorf("moeda não suportada: %s", p.BaseCurrency)
+	}
+
+	if _, err := time.LoadLocation(p.Timezone); err != nil || p.Timezone == "" {
+		return fmt.Errorf("fuso horário inválido: %s", p.Timezone)
+	}
+
+	seen := make(map[string]bool, len(p.DashboardWidgets))
+	widgets := make([]string, 0, len(p.DashboardWidgets))
+	for _, widget := range p.DashboardWidgets {
+		if !dashboardWidgets[widget] {
+			return fmt.Errorf("widget desconhecido: %s", widget)
+		}
+		if !seen[widget] {
+			seen[widget] = true
+			widgets = append(widgets, widget)
+		}
+	}
+	p.DashboardWidgets = widgets
+
+	return nil
+}
+
+type UpdateProfileRequest struct {
+	Name            *string `json:"name"`
+	Email           *string `json:"email"`
+	CurrentPassword string  `json:"currentPassword"` // obrigatória para alterar o email
+}
+
+// GetProfileHandler retorna o perfil do usuário autenticado
+func GetProfileHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Autorização necessária", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateProfileHandler atualiza o nome e o email do usuário autenticado.
+// Campos omitidos mantêm o valor atual. O email identifica a conta no login, por
+// isso só pode ser alterado numa sessão (não com uma chave de API) e com a senha atual.
+func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Formato de requisição inválido", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if len(name) > maxNameLength {
+			http.Error(w, fmt.Sprintf("O nome deve ter no máximo %d caracteres", maxNameLength), http.StatusBadRequest)
+			return
+		}
+		user.Name = name
+	}
+
+	if req.Email != nil {
+		email := normalizeEmail(*req.Email)
+		if email == "" || !strings.Contains(email, "@") {
+			http.Error(w, "Email inválido", http.StatusBadRequest)
+			return
+		}
+		if email != user.Email {
+			if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+				http.Error(w, "Senha atual inválida", http.StatusUnauthorized)
+				return
+			}
+		}
+		user.Email = email
+	}
+
+	if err := userRepository.UpdateProfile(&user); err != nil {
+		if errors.Is(err, ErrEmailAlreadyExists) {
+			http.Error(w, "Email já registado", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Erro ao atualizar perfil", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// GetPreferencesHandler retorna as preferências do usuário autenticado
+func GetPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Autorização necessária", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user.Preferences)
+}
+
+// UpdatePreferencesHandler atualiza as preferências do usuário autenticado.
+// Campos omitidos mantêm o valor atual.
+func UpdatePreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Autorização necessária", http.StatusUnauthorized)
+		return
+	}
+
+	// Descodificar sobre as preferências atuais
+	prefs := user.Preferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		http.Error(w, "Formato de requisição inválido", http.StatusBadRequest)
+		return
+	}
+
+	if err := prefs.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user.Preferences = prefs
+	if err := userRepository.UpdatePreferences(&user); err != nil {
+		http.Error(w, "Erro ao salvar preferências", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user.Preferences)
+}
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Erros retornados pelos armazenamentos de tokens
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token não encontrado")
+	ErrRefreshTokenReused   = errors.New("refresh token já utilizado")
+)
+
+// RefreshToken representa um refresh token guardado no servidor.
+// Apenas o hash SHA-256 do token é persistido.
+type RefreshToken struct {
+	TokenHash string    `json:"-"`
+	UserID    string    `json:"userId"`
+	FamilyID  string    `json:"familyId"` // tokens obtidos por rotação partilham a mesma família
+	Used      bool      `json:"used"`     // true após ter sido trocado por um novo token
+	ExpiresAt time.Time `json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TokenStore define a interface para persistência de refresh tokens e da lista de revogação
+type TokenStore interface {
+	// Métodos para refresh tokens
+	SaveRefreshToken(token *RefreshToken) error
+	GetRefreshToken(tokenHash string) (*RefreshToken, error)
+	MarkRefreshTokenUsed(tokenHash string) error // ErrRefreshTokenReused se já tiver sido trocado
+	DeleteRefreshTokenFamily(familyID string) error
+	DeleteUserRefreshTokens(userID string) error
+
+	// Métodos para a lista de revogação de access tokens (por jti)
+	RevokeToken(jti string, expiresAt time.Time) error
+	IsTokenRevoked(jti string) (bool, error)
+
+	// Métodos para os contadores de tentativas de 2FA. IncrementMFAAttempts soma uma tentativa
+	// de forma atómica e devolve a contagem resultante; um contador expirado recomeça em 1 com
+	// a nova expiração.
+	IncrementMFAAttempts(key string, expiresAt time.Time) (int, error)
+	ResetMFAAttempts(key string) error
+}
+
+// PostgresTokenStore implementação do TokenStore para PostgreSQL
+type PostgresTokenStore struct {
+	db *sql.DB
+}
+
+// NewPostgresTokenStore cria um novo armazenamento de tokens PostgreSQL
+func NewPostgresTokenStore(db *sql.DB) *PostgresTokenStore {
+	return &PostgresTokenStore{db: db}
+}
+
+// SaveRefreshToken salva um novo refresh token
+func (s *PostgresTokenStore) SaveRefreshToken(token *RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (token_hash, user_id, family_id, used, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := s.db.Exec(
+		query,
+		token.TokenHash,
+		token.UserID,
+		token.FamilyID,
+		token.Used,
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+	return err
+}
+
+// GetRefreshToken obtém um refresh token válido pelo hash
+func (s *PostgresTokenStore) GetRefreshToken(tokenHash string) (*RefreshToken, error) {
+	query := `
+		SELECT token_hash, user_id, family_id, used, expires_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1 AND expires_at > $2
+	`
+
+	var token RefreshToken
+	err := s.db.QueryRow(query, tokenHash, time.Now()).Scan(
+		&token.TokenHash,
+		&token.UserID,
+		&token.FamilyID,
+		&token.Used,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed marca um refresh token como já trocado. A verificação e a marcação são
+// feitas numa só instrução, para que dois pedidos simultâneos com o mesmo token não sejam ambos
+// aceites.
+func (s *PostgresTokenStore) MarkRefreshTokenUsed(tokenHash string) error {
+	query := `UPDATE refresh_tokens SET used = TRUE WHERE token_hash = $1 AND used = FALSE`
+	result, err := s.db.Exec(query, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrRefreshTokenReused
+	}
+
+	return nil
+}
+
+// DeleteRefreshTokenFamily remove todos os refresh tokens de uma família
+func (s *PostgresTokenStore) DeleteRefreshTokenFamily(familyID string) error {
+	query := `DELETE FROM refresh_tokens WHERE family_id = $1`
+	_, err := s.db.Exec(query, familyID)
+	return err
+}
+
+// DeleteUserRefreshTokens remove todos os refresh tokens de um usuário
+func (s *PostgresTokenStore) DeleteUserRefreshTokens(userID string) error {
+	query := `DELETE FROM refresh_tokens WHERE user_id = $1`
+	_, err := s.db.Exec(query, userID)
+	return err
+}
+
+// RevokeToken adiciona o jti de um access token à lista de revogação
+func (s *PostgresTokenStore) RevokeToken(jti string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+	_, err := s.db.Exec(query, jti, expiresAt)
+	return err
+}
+
+// IsTokenRevoked verifica se o jti está na lista de revogação
+func (s *PostgresTokenStore) IsTokenRevoked(jti string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)`
+
+	var revoked bool
+	if err := s.db.QueryRow(query, jti).Scan(&revoked); err != nil {
+		return false, err
+	}
+
+	return revoked, nil
+}
+
+// IncrementMFAAttempts soma uma tentativa ao contador indicado e devolve a contagem resultante
+func (s *PostgresTokenStore) IncrementMFAAttempts(key string, expiresAt time.Time) (int, error) {
+	query := `
+		INSERT INTO mfa_attempts (attempt_key, count, expires_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (attempt_key) DO UPDATE SET
+			count = CASE WHEN mfa_attempts.expires_at < $3 THEN 1 ELSE mfa_attempts.count + 1 END,
+			expires_at = CASE WHEN mfa_attempts.expires_at < $3 THEN EXCLUDED.expires_at ELSE mfa_attempts.expires_at END
+		RETURNING count
+	`
+
+	var count int
+	if err := s.db.QueryRow(query, key, expiresAt, time.Now()).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ResetMFAAttempts apaga o contador indicado
+func (s *PostgresTokenStore) ResetMFAAttempts(key string) error {
+	_, err := s.db.Exec(`DELETE FROM mfa_attempts WHERE attempt_key = $1`, key)
+	return err
+}
+
+// DeleteExpired remove refresh tokens, revogações e contadores de 2FA já expirados
+func (s *PostgresTokenStore) DeleteExpired() error {
+	now := time.Now()
+	if _, err := s.db.Exec(`DELETE FROM refresh_tokens WHERE expires_at < $1`, now); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at < $1`, now); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM mfa_attempts WHERE expires_at < $1`, now)
+	return err
+}
+
+// Esquema SQL para criação das tabelas de tokens
+const TokenSchema = `
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id VARCHAR(64) NOT NULL,
+    used BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS mfa_attempts (
+    attempt_key VARCHAR(128) PRIMARY KEY,
+    count INTEGER NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+`
@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Erros retornados pelos repositórios de usuários
+var (
+	ErrUserNotFound       = errors.New("usuário não encontrado")
+	ErrEmailAlreadyExists = errors.New("email já registado")
+)
+
+// UserRepository define a interface para persistência de usuários
+type UserRepository interface {
+	Create(user *User) error
+	GetByID(id string) (*User, error)
+	GetByEmail(email string) (*User, error)
+	List(limit, offset int) ([]User, error)
+
+	// Cada atualização grava apenas as suas colunas, para que pedidos concorrentes sobre outras
+	// partes do usuário não sejam desfeitos
+	UpdateProfile(user *User) error     // nome e email; ErrEmailAlreadyExists se o email já existir
+	UpdatePreferences(user *User) error // preferências
+	UpdateAccess(user *User) error      // papéis e conta desativada
+	UpdateMFA(user *User) error         // estado do 2FA (ativação, segredo, códigos); o passo TOTP não recua
+
+	// Consumo atómico dos códigos de 2FA; false se o passo ou o código já tiverem sido usados
+	ConsumeMFAStep(userID string, step int64) (bool, error)
+	ConsumeRecoveryCode(userID, codeHash string) (bool, error)
+}
+
+// PostgresUserRepository implementação do repositório de usuários para PostgreSQL
+type PostgresUserRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresUserRepository cria um novo repositório PostgreSQL de usuários
+func NewPostgresUserRepository(db *sql.DB) *PostgresUserRepository {
+	return &PostgresUserRepository{db: db}
+}
+
+// Create insere um novo usuário, rejeitando emails duplicados
+func (r *PostgresUserRepository) Create(user *User) error {
+	query := `
+		INSERT INTO users (id, email, name, password_hash, theme, language, base_currency, timezone, dashboard_widgets, roles, disabled, mfa_enabled, mfa_secret, mfa_recovery_codes, mfa_last_used_step, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+	`
+
+	_, err := r.db.Exec(
+		query,
+		user.ID,
+		user.Email,
+		user.Name,
+		user.PasswordHash,
+		user.Preferences.Theme,
+		user.Preferences.Language,
+		user.Preferences.BaseCurrency,
+		user.Preferences.Timezone,
+		pq.Array(user.Preferences.DashboardWidgets),
+		pq.Array(user.Roles),
+		user.Disabled,
+		user.MFAEnabled,
+		user.MFASecret,
+		pq.Array(nonNil(user.MFARecoveryCodes)),
+		user.MFALastUsedStep,
+		user.CreatedAt,
+		user.UpdatedAt,
+	)
+	if err != nil {
+		// 23505 = unique_violation
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrEmailAlreadyExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetByID obtém um usuário pelo ID
+func (r *PostgresUserRepository) GetByID(id string) (*User, error) {
+	query := `
+		SELECT id, email, name, password_hash, theme, language, base_currency, timezone, dashboard_widgets, roles, disabled, mfa_enabled, mfa_secret, mfa_recovery_codes, mfa_last_used_step, created_at, updated_at
+		FROM users
+		WHERE id = $1
+	`
+
+	return r.scanUser(r.db.QueryRow(query, id))
+}
+
+// GetByEmail obtém um usuário pelo email
+func (r *PostgresUserRepository) GetByEmail(email string) (*User, error) {
+	query := `
+		SELECT id, email, name, password_hash, theme, language, base_currency, timezone, dashboard_widgets, roles, disabled, mfa_enabled, mfa_secret, mfa_recovery_codes, mfa_last_used_step, created_at, updated_at
+		FROM users
+		WHERE email = $1
+	`
+
+	return r.scanUser(r.db.QueryRow(query, email))
+}
+
+// UpdateProfile atualiza o nome e o email de um usuário
+func (r *PostgresUserRepository) UpdateProfile(user *User) error {
+	query := `UPDATE users SET email = $2, name = $3, updated_at = $4 WHERE id = $1`
+
+	err := r.update(query, user.ID, user.Email, user.Name, time.Now())
+	// 23505 = unique_violation
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrEmailAlreadyExists
+	}
+	return err
+}
+
+// UpdatePreferences atualiza as preferências de um usuário
+func (r *PostgresUserRepository) UpdatePreferences(user *User) error {
+	query := `
+		UPDATE users
+		SET theme = $2, language = $3, base_currency = $4, timezone = $5, dashboard_widgets = $6, updated_at = $7
+		WHERE id = $1
+	`
+
+	return r.update(
+		query,
+		user.ID,
+		user.Preferences.Theme,
+		user.Preferences.Language,
+		user.Preferences.BaseCurrency,
+		user.Preferences.Timezone,
+		pq.Array(user.Preferences.DashboardWidgets),
+		time.Now(),
+	)
+}
+
+// UpdateAccess atualiza os papéis e o estado da conta de um usuário
+func (r *PostgresUserRepository) UpdateAccess(user *User) error {
+	query := `UPDATE users SET roles = $2, disabled = $3, updated_at = $4 WHERE id = $1`
+
+	return r.update(query, user.ID, pq.Array(user.Roles), user.Disabled, time.Now())
+}
+
+// UpdateMFA atualiza o estado do 2FA de um usuário. O último passo TOTP usado nunca recua, para
+// que um passo consumido em paralelo não volte a ser aceite.
+func (r *PostgresUserRepository) UpdateMFA(user *User) error {
+	query := `
+		UPDATE users
+		SET mfa_enabled = $2, mfa_secret = $3, mfa_recovery_codes = $4,
+			mfa_last_used_step = GREATEST(mfa_last_used_step, $5), updated_at = $6
+		WHERE id = $1
+	`
+
+	return r.update(
+		query,
+		user.ID,
+		user.MFAEnabled,
+		user.MFASecret,
+		pq.Array(nonNil(user.MFARecoveryCodes)),
+		user.MFALastUsedStep,
+		time.Now(),
+	)
+}
+
+// ConsumeMFAStep regista o passo TOTP usado, se for posterior ao último aceite
+func (r *PostgresUserRepository) ConsumeMFAStep(userID string, step int64) (bool, error) {
+	query := `UPDATE users SET mfa_last_used_step = $2 WHERE id = $1 AND mfa_last_used_step < $2`
+
+	return r.consume(query, userID, step)
+}
+
+// ConsumeRecoveryCode remove um código de recuperação, se ainda estiver por usar
+func (r *PostgresUserRepository) ConsumeRecoveryCode(userID, codeHash string) (bool, error) {
+	query := `
+		UPDATE users
+		SET mfa_recovery_codes = array_remove(mfa_recovery_codes, $2)
+		WHERE id = $1 AND $2 = ANY(mfa_recovery_codes)
+	`
+
+	return r.consume(query, userID, codeHash)
+}
+
+// update executa uma atualização de um usuário, devolvendo ErrUserNotFound se não existir
+func (r *PostgresUserRepository) update(query string, args ...interface{}) error {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// consume executa uma atualização condicional e indica se alterou a linha
+func (r *PostgresUserRepository) consume(query string, args ...interface{}) (bool, error) {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// List obtém usuários ordenados pela data de criação
+func (r *PostgresUserRepository) List(limit, offset int) ([]User, error) {
+	query := `
+		SELECT id, email, name, password_hash, theme, language, base_currency, timezone, dashboard_widgets, roles, disabled, mfa_enabled, mfa_secret, mfa_recovery_codes, mfa_last_used_step, created_at, updated_at
+		FROM users
+		ORDER BY created_at ASC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []User{}
+	for rows.Next() {
+		user, err := r.scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// rowScanner é implementado por *sql.Row e *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser converte uma linha da tabela users num User
+func (r *PostgresUserRepository) scanUser(row rowScanner) (*User, error) {
+	var user User
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Name,
+		&user.PasswordHash,
+		&user.Preferences.Theme,
+		&user.Preferences.Language,
+		&user.Preferences.BaseCurrency,
+		&user.Preferences.Timezone,
+		pq.Array(&user.Preferences.DashboardWidgets),
+		pq.Array(&user.Roles),
+		&user.Disabled,
+		&user.MFAEnabled,
+		&user.MFASecret,
+		pq.Array(&user.MFARecoveryCodes),
+		&user.MFALastUsedStep,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// nonNil substitui uma lista nil por uma vazia: pq.Array envia nil como NULL, que as colunas
+// TEXT[] NOT NULL rejeitam
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// Esquema SQL para criação da tabela de usuários
+const UserSchema = `
+CREATE TABLE IF NOT EXISTS users (
+    id VARCHAR(64) PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    password_hash VARCHAR(255) NOT NULL,
+    theme VARCHAR(20) NOT NULL DEFAULT 'light',
+    language VARCHAR(10) NOT NULL DEFAULT 'pt',
+    base_currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+    timezone VARCHAR(64) NOT NULL DEFAULT 'Europe/Lisbon',
+    dashboard_widgets TEXT[] NOT NULL DEFAULT '{portfolio-stats,asset-allocation,technical-chart,news}',
+    roles TEXT[] NOT NULL DEFAULT '{user}',
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    mfa_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    mfa_secret VARCHAR(64) NOT NULL DEFAULT '',
+    mfa_recovery_codes TEXT[] NOT NULL DEFAULT '{}',
+    mfa_last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Colunas adicionadas depois da versão inicial da tabela
+ALTER TABLE users ADD COLUMN IF NOT EXISTS roles TEXT[] NOT NULL DEFAULT '{user}';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_secret VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_recovery_codes TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_last_used_step BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS name VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS base_currency VARCHAR(3) NOT NULL DEFAULT 'EUR';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'Europe/Lisbon';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS dashboard_widgets TEXT[] NOT NULL DEFAULT '{portfolio-stats,asset-allocation,technical-chart,news}';
+`
@@ -0,0 +1,274 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrBacktestNotFound é retornado quando o backtest não existe
+var ErrBacktestNotFound = errors.New("backtest não encontrado")
+
+// Modos de dimensionamento das posições
+const (
+	SizingPercent = "percent" // fração do capital disponível
+	SizingFixed   = "fixed"   // valor fixo na moeda de cotação
+)
+
+// PositionSizing define o valor investido em cada entrada
+type PositionSizing struct {
+	Mode  string  `json:"mode" yaml:"mode"`   // SizingPercent ou SizingFixed
+	Value float64 `json:"value" yaml:"value"` // fração entre 0 e 1 ou valor na moeda de cotação
+}
+
+// BacktestConfig são as condições de execução de um backtest
+type BacktestConfig struct {
+	InitialCapital float64        `json:"initialCapital"`
+	FeeRate        float64        `json:"feeRate"`  // comissão por operação, como fração do valor
+	Slippage       float64        `json:"slippage"` // desvio do preço de execução, como fração do preço
+	Sizing         PositionSizing `json:"sizing"`
+	StopLoss       float64        `json:"stopLoss,omitempty"`   // queda face ao preço de entrada que fecha a posição, como fração
+	TakeProfit     float64        `json:"takeProfit,omitempty"` // subida face ao preço de entrada que fecha a posição, como fração
+	RiskFreeRate   float64        `json:"riskFreeRate"`         // taxa sem risco anual, em percentagem
+}
+
+// BacktestTrade é uma operação completa (entrada e saída) de um backtest
+type BacktestTrade struct {
+	EntryTime  time.Time `json:"entryTime"`
+	EntryPrice float64   `json:"entryPrice"`
+	ExitTime   time.Time `json:"exitTime"`
+	ExitPrice  float64   `json:"exitPrice"`
+	Quantity   float64   `json:"quantity"`
+	Fees       float64   `json:"fees"`
+	PnL        float64   `json:"pnl"`        // resultado líquido de comissões
+	Return     float64   `json:"return"`     // resultado face ao valor investido, em percentagem
+	ExitReason string    `json:"exitReason"` // "signal", "stop_loss", "take_profit" ou "end"
+}
+
+// EquityPoint é o valor da conta (dinheiro e posição ao fecho) no fim de uma vela
+type EquityPoint struct {
+	Time   time.Time `json:"time"`
+	Equity float64   `json:"equity"`
+}
+
+// BacktestMetrics são as métricas de desempenho de um backtest. Os valores relativos estão em
+// percentagem.
+type BacktestMetrics struct {
+	FinalEquity      float64  `json:"finalEquity"`
+	TotalReturn      float64  `json:"totalReturn"`
+	AnnualizedReturn float64  `json:"annualizedReturn"`
+	BuyAndHold       float64  `json:"buyAndHold"` // variação do preço no período, para comparação
+	Trades           int      `json:"trades"`
+	WinRate          float64  `json:"winRate"`
+	ProfitFactor     *float64 `json:"profitFactor,omitempty"` // vazio sem operações com perda
+	AverageTrade     float64  `json:"averageTrade"`
+	TotalFees        float64  `json:"totalFees"`
+	Exposure         float64  `json:"exposure"` // parte das velas com posição aberta
+	SharpeRatio      float64  `json:"sharpeRatio"`
+	MaxDrawdown      Drawdown `json:"maxDrawdown"`
+}
+
+// BacktestResult é o resultado da simulação de uma estratégia
+type BacktestResult struct {
+	Metrics BacktestMetrics `json:"metrics"`
+	Trades  []BacktestTrade `json:"trades"`
+	Equity  []EquityPoint   `json:"equity"`
+}
+
+// Backtest é um backtest executado por um usuário, guardado com o seu resultado
+type Backtest struct {
+	ID         string              `json:"id"`
+	UserID     string              `json:"userId"`
+	Symbol     string              `json:"symbol"`
+	Interval   string              `json:"interval"`
+	From       time.Time           `json:"from"`
+	To         time.Time           `json:"to"`
+	Strategy   string              `json:"strategy"` // estratégia incorporada ou nome da estratégia declarativa
+	StrategyID string              `json:"strategyId,omitempty"`
+	Definition *StrategyDefinition `json:"definition,omitempty"` // definição declarativa usada, tal como estava
+	Parameters map[string]float64  `json:"parameters,omitempty"`
+	Config     BacktestConfig      `json:"config"`
+	Result     BacktestResult      `json:"result"`
+	CreatedAt  time.Time           `json:"createdAt"`
+}
+
+// BacktestRepository interface para persistência de backtests
+type BacktestRepository interface {
+	SaveBacktest(backtest *Backtest) error
+	GetBacktest(id string) (*Backtest, error)
+	ListBacktests(userID string) ([]Backtest, error) // sem as operações nem a curva de capital
+	DeleteBacktest(id string) error
+}
+
+// PostgresBacktestRepository implementação do repositório de backtests para PostgreSQL. A
+// configuração e o resultado são guardados em JSON.
+type PostgresBacktestRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresBacktestRepository cria um novo repositório PostgreSQL
+func NewPostgresBacktestRepository(db *sql.DB) *PostgresBacktestRepository {
+	return &PostgresBacktestRepository{db: db}
+}
+
+// SaveBacktest grava um novo backtest
+func (r *PostgresBacktestRepository) SaveBacktest(backtest *Backtest) error {
+	parameters, err := json.Marshal(backtest.Parameters)
+	if err != nil {
+		return err
+	}
+	definition, err := json.Marshal(backtest.Definition)
+	if err != nil {
+		return err
+	}
+	config, err := json.Marshal(backtest.Config)
+	if err != nil {
+		return err
+	}
+	result, err := json.Marshal(backtest.Result)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO backtests (id, user_id, symbol, candle_interval, from_time, to_time, strategy, strategy_id, definition, parameters, config, result, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`,
+		backtest.ID,
+		backtest.UserID,
+		backtest.Symbol,
+		backtest.Interval,
+		backtest.From,
+		backtest.To,
+		backtest.Strategy,
+		backtest.StrategyID,
+		definition,
+		parameters,
+		config,
+		result,
+		backtest.CreatedAt,
+	)
+	return err
+}
+
+// GetBacktest obtém um backtest completo
+func (r *PostgresBacktestRepository) GetBacktest(id string) (*Backtest, error) {
+	row := r.db.QueryRow(`
+		SELECT id, user_id, symbol, candle_interval, from_time, to_time, strategy, strategy_id, definition, parameters, config, result, created_at
+		FROM backtests
+		WHERE id = $1
+	`, id)
+
+	backtest, err := scanBacktest(row, true)
+	if err == sql.ErrNoRows {
+		return nil, ErrBacktestNotFound
+	}
+	return backtest, err
+}
+
+// ListBacktests obtém os backtests de um usuário, do mais recente para o mais antigo, apenas
+// com as métricas do resultado
+func (r *PostgresBacktestRepository) ListBacktests(userID string) ([]Backtest, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, symbol, candle_interval, from_time, to_time, strategy, strategy_id, definition, parameters, config,
+		       json_build_object('metrics', result->'metrics'), created_at
+		FROM backtests
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Backtest{}
+	for rows.Next() {
+		backtest, err := scanBacktest(rows, false)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *backtest)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteBacktest remove um backtest
+func (r *PostgresBacktestRepository) DeleteBacktest(id string) error {
+	result, err := r.db.Exec(`DELETE FROM backtests WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, ErrBacktestNotFound)
+}
+
+// scanBacktest converte uma linha da tabela backtests num Backtest. Sem full, as operações e a
+// curva de capital ficam vazias.
+func scanBacktest(row rowScanner, full bool) (*Backtest, error) {
+	var backtest Backtest
+	var definition, parameters, config, result []byte
+	err := row.Scan(
+		&backtest.ID,
+		&backtest.UserID,
+		&backtest.Symbol,
+		&backtest.Interval,
+		&backtest.From,
+		&backtest.To,
+		&backtest.Strategy,
+		&backtest.StrategyID,
+		&definition,
+		&parameters,
+		&config,
+		&result,
+		&backtest.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(definition, &backtest.Definition); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(parameters, &backtest.Parameters); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(config, &backtest.Config); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(result, &backtest.Result); err != nil {
+		return nil, err
+	}
+	if !full {
+		backtest.Result.Trades = nil
+		backtest.Result.Equity = nil
+	}
+
+	return &backtest, nil
+}
+
+// Esquema SQL para criação da tabela de backtests
+const BacktestSchema = `
+CREATE TABLE IF NOT EXISTS backtests (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL,
+    symbol VARCHAR(20) NOT NULL,
+    candle_interval VARCHAR(10) NOT NULL,
+    from_time TIMESTAMP NOT NULL,
+    to_time TIMESTAMP NOT NULL,
+    strategy VARCHAR(100) NOT NULL,
+    parameters JSONB NOT NULL,
+    config JSONB NOT NULL,
+    result JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE backtests ADD COLUMN IF NOT EXISTS strategy_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE backtests ADD COLUMN IF NOT EXISTS definition JSONB NOT NULL DEFAULT 'null';
+
+CREATE INDEX IF NOT EXISTS idx_backtests_user_created ON backtests (user_id, created_at);
+`
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Candle representa uma vela OHLCV: abertura, máximo, mínimo, fecho e volume de um intervalo
+// de tempo que começa em Time
+type Candle struct {
+	Time   time.Time `json:"time"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+	Filled bool      `json:"filled,omitempty"` // vela sintética que preenche um intervalo sem dados
+}
+
+// Candles é uma série de velas por ordem cronológica
+type Candles []Candle
+
+// Times retorna o início de cada vela
+func (c Candles) Times() []time.Time {
+	out := make([]time.Time, len(c))
+	for i, candle := range c {
+		out[i] = candle.Time
+	}
+	return out
+}
+
+// Opens retorna os preços de abertura
+func (c Candles) Opens() []float64 {
+	return c.series(func(candle Candle) float64 { return candle.Open })
+}
+
+// Highs retorna os preços máximos
+func (c Candles) Highs() []float64 {
+	return c.series(func(candle Candle) float64 { return candle.High })
+}
+
+// Lows retorna os preços mínimos
+func (c Candles) Lows() []float64 {
+	return c.series(func(candle Candle) float64 { return candle.Low })
+}
+
+// Closes retorna os preços de fecho
+func (c Candles) Closes() []float64 {
+	return c.series(func(candle Candle) float64 { return candle.Close })
+}
+
+// Volumes retorna os volumes
+func (c Candles) Volumes() []float64 {
+	return c.series(func(candle Candle) float64 { return candle.Volume })
+}
+
+// series extrai um campo de cada vela
+func (c Candles) series(field func(Candle) float64) []float64 {
+	out := make([]float64, len(c))
+	for i, candle := range c {
+		out[i] = field(candle)
+	}
+	return out
+}
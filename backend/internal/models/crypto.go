@@ -79,8 +79,9 @@ type Links struct {
 	Github        []string `json:"github,omitempty"`
 }
 
-// HistoricalData representa dados históricos de preço para uma criptomoeda
-type HistoricalData struct {
+// MarketHistoricalData representa as séries de preço, capitalização e volume de uma criptomoeda
+// obtidas dos fornecedores de mercado (ver HistoricalData para as cotações guardadas pelo agendador)
+type MarketHistoricalData struct {
 	ID      string           `json:"id"`
 	Symbol  string           `json:"symbol"`
 	Prices  [][2]float64     `json:"prices"`      // [timestamp, price]
@@ -112,11 +113,11 @@ type CryptoRepository interface {
 	GetGlobalMarketData() (*GlobalMarketData, error)
 	
 	// Métodos para dados históricos
-	GetHistoricalData(id, currency string, days int) (*HistoricalData, error)
+	GetHistoricalData(id, currency string, days int) (*MarketHistoricalData, error)
 	
 	// Métodos para persistência dos dados obtidos por scraping
 	SaveMarketData(data []CryptoData) error
 	SaveCoinDetails(data *CoinDetails) error
-	SaveHistoricalData(data *HistoricalData) error
+	SaveHistoricalData(data *MarketHistoricalData) error
 	SaveGlobalMarketData(data *GlobalMarketData) error
 } 
\ No newline at end of file
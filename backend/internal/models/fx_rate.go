@@ -0,0 +1,99 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// FXRate é a taxa de câmbio diária de uma moeda fiduciária: quantas unidades de Currency
+// vale uma unidade de Base na data indicada
+type FXRate struct {
+	Base     string    `json:"base"`
+	Currency string    `json:"currency"`
+	Date     time.Time `json:"date"` // meia-noite UTC do dia da taxa
+	Rate     float64   `json:"rate"`
+}
+
+// FXRateRepository interface para persistência do histórico de taxas de câmbio
+type FXRateRepository interface {
+	// SaveRates grava as taxas, substituindo as que já existam para a mesma moeda e data
+	SaveRates(rates []FXRate) error
+	// GetRates retorna as taxas de base entre from e to (inclusive), por ordem cronológica
+	GetRates(base string, from, to time.Time) ([]FXRate, error)
+}
+
+// PostgresFXRateRepository implementação do repositório de taxas de câmbio para PostgreSQL
+type PostgresFXRateRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresFXRateRepository cria um novo repositório PostgreSQL de taxas de câmbio
+func NewPostgresFXRateRepository(db *sql.DB) *PostgresFXRateRepository {
+	return &PostgresFXRateRepository{db: db}
+}
+
+// SaveRates grava as taxas numa única transação
+func (r *PostgresFXRateRepository) SaveRates(rates []FXRate) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO fx_rates (base, currency, date, rate)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (base, currency, date) DO UPDATE SET rate = EXCLUDED.rate
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, rate := range rates {
+		if _, err := stmt.Exec(rate.Base, rate.Currency, rate.Date, rate.Rate); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetRates obtém as taxas de base num intervalo de datas
+func (r *PostgresFXRateRepository) GetRates(base string, from, to time.Time) ([]FXRate, error) {
+	query := `
+		SELECT base, currency, date, rate
+		FROM fx_rates
+		WHERE base = $1 AND date BETWEEN $2 AND $3
+		ORDER BY date ASC, currency ASC
+	`
+
+	rows, err := r.db.Query(query, base, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []FXRate
+	for rows.Next() {
+		var rate FXRate
+		if err := rows.Scan(&rate.Base, &rate.Currency, &rate.Date, &rate.Rate); err != nil {
+			return nil, err
+		}
+		rate.Date = rate.Date.UTC()
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+// Esquema SQL para criação da tabela do histórico de taxas de câmbio
+const FXRateSchema = `
+CREATE TABLE IF NOT EXISTS fx_rates (
+    base VARCHAR(10) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    date DATE NOT NULL,
+    rate NUMERIC(24, 10) NOT NULL,
+    PRIMARY KEY (base, currency, date)
+);
+`
@@ -170,11 +170,10 @@ CREATE TABLE IF NOT EXISTS historical_data (
     volume NUMERIC(30, 2),
     market_cap NUMERIC(30, 2),
     timestamp TIMESTAMP NOT NULL,
-    created_at TIMESTAMP NOT NULL,
-    
-    -- Índices para melhorar a performance de consultas
-    INDEX idx_historical_data_symbol (symbol),
-    INDEX idx_historical_data_timestamp (timestamp),
-    INDEX idx_historical_data_symbol_timestamp (symbol, timestamp)
+    created_at TIMESTAMP NOT NULL
 );
+
+-- Índices para melhorar a performance de consultas (o composto serve também as consultas por símbolo)
+CREATE INDEX IF NOT EXISTS idx_historical_data_timestamp ON historical_data (timestamp);
+CREATE INDEX IF NOT EXISTS idx_historical_data_symbol_timestamp ON historical_data (symbol, timestamp);
 ` 
\ No newline at end of file
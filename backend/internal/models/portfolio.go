@@ -4,18 +4,45 @@ import (
 	"time"
 )
 
+// CostBasisMethod define como as vendas são associadas aos lotes comprados
+type CostBasisMethod string
+
+// Métodos de custo suportados
+const (
+	CostBasisFIFO    CostBasisMethod = "fifo"    // primeiro a entrar, primeiro a sair
+	CostBasisLIFO    CostBasisMethod = "lifo"    // último a entrar, primeiro a sair
+	CostBasisHIFO    CostBasisMethod = "hifo"    // lote de custo mais alto primeiro
+	CostBasisAverage CostBasisMethod = "average" // custo médio ponderado
+)
+
+// DefaultCostBasisMethod é o método usado quando o portfólio não indica outro
+const DefaultCostBasisMethod = CostBasisFIFO
+
+// Valid indica se o método de custo é conhecido
+func (m CostBasisMethod) Valid() bool {
+	switch m {
+	case CostBasisFIFO, CostBasisLIFO, CostBasisHIFO, CostBasisAverage:
+		return true
+	}
+	return false
+}
+
 // Portfolio representa um portfólio de criptomoedas
 type Portfolio struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"userId"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	Assets      []Asset   `json:"assets"`
+	ID              string          `json:"id"`
+	UserID          string          `json:"userId"`
+	Name            string          `json:"name"`
+	Description     string          `json:"description"`
+	CostBasisMethod CostBasisMethod `json:"costBasisMethod"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+	Assets          []Asset         `json:"assets"`
+	Holdings        []Holding       `json:"holdings,omitempty"` // derivadas do livro de transações
 }
 
-// Asset representa um ativo no portfólio
+// Asset representa um ativo no portfólio. As posições registadas desta forma
+// são tratadas como compras de abertura no cálculo das posições a partir do
+// livro de transações (ver Transaction).
 type Asset struct {
 	ID            string    `json:"id"`
 	PortfolioID   string    `json:"portfolioId"`
@@ -27,31 +54,322 @@ type Asset struct {
 	UpdatedAt     time.Time `json:"updatedAt"`
 }
 
-// PortfolioStats representa estatísticas do portfólio
+// PriceStatus indica a qualidade do preço usado para valorizar um ativo
+type PriceStatus string
+
+// Estados do preço de um ativo
+const (
+	PriceOK      PriceStatus = "ok"
+	PriceStale   PriceStatus = "stale"   // preço mais antigo do que o limite aceite; usado, mas assinalado
+	PriceMissing PriceStatus = "missing" // sem preço; o ativo não entra no valor total
+)
+
+// PortfolioStats representa estatísticas do portfólio. Os totais só incluem ativos com preço.
 type PortfolioStats struct {
-	TotalValue       float64   `json:"totalValue"`
-	TotalProfit      float64   `json:"totalProfit"`
-	ProfitPercentage float64   `json:"profitPercentage"`
-	AssetCount       int       `json:"assetCount"`
-	LastUpdated      time.Time `json:"lastUpdated"`
+	Currency            string            `json:"currency"` // moeda dos valores
+	TotalValue          float64           `json:"totalValue"`
+	TotalCost           float64           `json:"totalCost"`        // custo das posições valorizadas
+	TotalProfit         float64           `json:"totalProfit"`      // P&L não realizado
+	ProfitPercentage    float64           `json:"profitPercentage"` // TotalProfit face a TotalCost
+	RealizedPnL         float64           `json:"realizedPnl"`
+	Change24h           float64           `json:"change24h"`           // variação do valor nas últimas 24h
+	Change24hPercentage float64           `json:"change24hPercentage"` // soma das contribuições dos ativos
+	AssetCount          int               `json:"assetCount"`
+	PricesComplete      bool              `json:"pricesComplete"` // falso se algum preço estiver em falta ou desatualizado
+	Assets              []AssetStats      `json:"assets"`
+	Returns             *PortfolioReturns `json:"returns,omitempty"` // requer o histórico de preços
+	LastUpdated         time.Time         `json:"lastUpdated"`
+}
+
+// AssetStats representa a contribuição de um ativo para as estatísticas do portfólio
+type AssetStats struct {
+	Symbol                string      `json:"symbol"`
+	Quantity              float64     `json:"quantity"`
+	Price                 float64     `json:"price"`
+	Value                 float64     `json:"value"`
+	CostBasis             float64     `json:"costBasis"`
+	AverageCost           float64     `json:"averageCost"`
+	Weight                float64     `json:"weight"`                // fração do valor total (0-1)
+	PriceChange24h        float64     `json:"priceChange24h"`        // variação percentual do preço
+	Change24hContribution float64     `json:"change24hContribution"` // pontos percentuais na variação do portfólio
+	UnrealizedPnL         float64     `json:"unrealizedPnl"`
+	UnrealizedPnLPercent  float64     `json:"unrealizedPnlPercentage"`
+	RealizedPnL           float64     `json:"realizedPnl"`
+	PriceStatus           PriceStatus `json:"priceStatus"`
+	PriceUpdatedAt        *time.Time  `json:"priceUpdatedAt,omitempty"`
+}
+
+// PortfolioHistory representa a evolução do valor do portfólio num intervalo de tempo
+type PortfolioHistory struct {
+	PortfolioID      string                  `json:"portfolioId"`
+	Currency         string                  `json:"currency"` // moeda dos valores
+	Range            string                  `json:"range"`
+	Interval         string                  `json:"interval"`
+	From             time.Time               `json:"from"`
+	To               time.Time               `json:"to"`
+	StartValue       float64                 `json:"startValue"`
+	EndValue         float64                 `json:"endValue"`
+	Change           float64                 `json:"change"`           // variação do valor no intervalo, incluindo entradas e saídas
+	ChangePercentage float64                 `json:"changePercentage"` // Change face a StartValue
+	PricesComplete   bool                    `json:"pricesComplete"`   // falso se algum ponto tiver ativos sem preço
+	Returns          *PortfolioReturns       `json:"returns"`
+	Points           []PortfolioHistoryPoint `json:"points"`
+}
+
+// PortfolioHistoryPoint representa o valor do portfólio num instante
+type PortfolioHistoryPoint struct {
+	Timestamp      time.Time `json:"timestamp"`
+	Value          float64   `json:"value"`     // valor das posições com preço
+	CostBasis      float64   `json:"costBasis"` // custo das posições com preço
+	ProfitLoss     float64   `json:"profitLoss"`
+	MissingSymbols []string  `json:"missingSymbols,omitempty"` // posições sem preço neste instante, fora do valor
+}
+
+// PortfolioReturns representa o retorno do portfólio num período, descontando as entradas e
+// saídas de capital. Os retornos são percentuais e referem-se ao período, salvo os anualizados.
+type PortfolioReturns struct {
+	From                time.Time `json:"from"`
+	To                  time.Time `json:"to"`
+	StartValue          float64   `json:"startValue"`
+	EndValue            float64   `json:"endValue"`
+	NetFlows            float64   `json:"netFlows"`                                // capital que entrou (positivo) ou saiu (negativo)
+	TimeWeightedReturn  float64   `json:"timeWeightedReturn"`                      // não depende do momento das entradas e saídas
+	MoneyWeightedReturn *float64  `json:"moneyWeightedReturn,omitempty"`           // a partir da taxa interna de rentabilidade (XIRR)
+	AnnualizedTWR       *float64  `json:"annualizedTimeWeightedReturn,omitempty"`  // só em períodos de pelo menos um ano
+	AnnualizedMWR       *float64  `json:"annualizedMoneyWeightedReturn,omitempty"` // só em períodos de pelo menos um ano
+	Benchmark           string    `json:"benchmark"`
+	BenchmarkReturn     *float64  `json:"benchmarkReturn,omitempty"` // variação do preço do benchmark no período
+	PricesComplete      bool      `json:"pricesComplete"`
+}
+
+// PortfolioRisk representa métricas de risco do portfólio, calculadas sobre os retornos por
+// intervalo da série de valor. Retornos, volatilidade, quedas e VaR são percentuais.
+type PortfolioRisk struct {
+	PortfolioID          string      `json:"portfolioId"`
+	Range                string      `json:"range"`
+	Interval             string      `json:"interval"`
+	From                 time.Time   `json:"from"`
+	To                   time.Time   `json:"to"`
+	Observations         int         `json:"observations"` // número de retornos usados
+	AnnualizedReturn     float64     `json:"annualizedReturn"`
+	AnnualizedVolatility float64     `json:"annualizedVolatility"`
+	RiskFreeRate         float64     `json:"riskFreeRate"` // anual
+	SharpeRatio          *float64    `json:"sharpeRatio,omitempty"`
+	SortinoRatio         *float64    `json:"sortinoRatio,omitempty"`
+	MaxDrawdown          Drawdown    `json:"maxDrawdown"`
+	Benchmark            string      `json:"benchmark"`
+	Beta                 *float64    `json:"beta,omitempty"`
+	Correlation          *float64    `json:"correlation,omitempty"`
+	ValueAtRisk          ValueAtRisk `json:"valueAtRisk"`
+	PricesComplete       bool        `json:"pricesComplete"`
+}
+
+// Drawdown representa a maior queda do valor acumulado face a um máximo anterior
+type Drawdown struct {
+	Depth       float64    `json:"depth"` // negativa, 0 se não houve queda
+	PeakAt      time.Time  `json:"peakAt"`
+	TroughAt    time.Time  `json:"troughAt"`
+	RecoveredAt *time.Time `json:"recoveredAt,omitempty"` // vazio enquanto o máximo não for recuperado
+}
+
+// ValueAtRisk representa a perda de um intervalo que não é excedida com a confiança indicada (VaR)
+// e a perda média para lá desse limite (CVaR), como percentagens positivas
+type ValueAtRisk struct {
+	Confidence     float64 `json:"confidence"`
+	Horizon        string  `json:"horizon"` // intervalo dos retornos, ex.: "1d"
+	HistoricalVaR  float64 `json:"historicalVar"`
+	HistoricalCVaR float64 `json:"historicalCvar"`
+	ParametricVaR  float64 `json:"parametricVar"` // distribuição normal
+	ParametricCVaR float64 `json:"parametricCvar"`
+}
+
+// PortfolioOverview resume o valor e o desempenho de todos os portfólios de um usuário
+type PortfolioOverview struct {
+	Currency       string             `json:"currency"` // moeda dos valores
+	TotalBalance   float64            `json:"totalBalance"`
+	Performance    map[string]float64 `json:"performance,omitempty"` // variação percentual por período: day, week, month, year
+	PortfolioCount int                `json:"portfolioCount"`
+	PricesComplete bool               `json:"pricesComplete"`
 }
 
 // PortfolioForecast representa previsões para o portfólio
 type PortfolioForecast struct {
-	PortfolioID    string    `json:"portfolioId"`
-	PredictedValue float64   `json:"predictedValue"`
-	Confidence     float64   `json:"confidence"`
-	TimeFrame      string    `json:"timeFrame"`
-	GeneratedAt    time.Time `json:"generatedAt"`
+	PortfolioID       string         `json:"portfolioId"`
+	CurrentValue      float64        `json:"currentValue"`
+	PredictedValue    float64        `json:"predictedValue"` // mediana dos valores simulados no fim do horizonte
+	ExpectedValue     float64        `json:"expectedValue"`  // média dos valores simulados
+	ExpectedReturn    float64        `json:"expectedReturn"` // PredictedValue face a CurrentValue, em percentagem
+	ProbabilityOfLoss float64        `json:"probabilityOfLoss"`
+	Confidence        float64        `json:"confidence"` // probabilidade de o valor final ficar a ±10% do previsto
+	TimeFrame         string         `json:"timeFrame"`
+	Simulations       int            `json:"simulations"`
+	Observations      int            `json:"observations"` // retornos diários usados na estimativa
+	Bands             []ForecastBand `json:"bands"`        // uma por dia do horizonte
+	UnmodeledSymbols  []string       `json:"unmodeledSymbols,omitempty"`
+	GeneratedAt       time.Time      `json:"generatedAt"`
+}
+
+// ForecastBand representa os percentis do valor simulado do portfólio num dia do horizonte
+type ForecastBand struct {
+	Timestamp time.Time `json:"timestamp"`
+	P5        float64   `json:"p5"`
+	P25       float64   `json:"p25"`
+	P50       float64   `json:"p50"`
+	P75       float64   `json:"p75"`
+	P95       float64   `json:"p95"`
 }
 
-// TransactionSimulation representa uma simulação de transação
+// TransactionSimulation representa o efeito de uma compra ou venda hipotética no portfólio,
+// valorizado aos preços atuais. Nada é registado no livro.
 type TransactionSimulation struct {
-	PortfolioID     string  `json:"portfolioId"`
-	Symbol          string  `json:"symbol"`
-	Amount          float64 `json:"amount"`
-	Price           float64 `json:"price"`
-	Type            string  `json:"type"` // "buy" ou "sell"
-	SimulatedValue  float64 `json:"simulatedValue"`
-	SimulatedProfit float64 `json:"simulatedProfit"`
+	PortfolioID     string             `json:"portfolioId"`
+	Symbol          string             `json:"symbol"`
+	Amount          float64            `json:"amount"`
+	Price           float64            `json:"price"`
+	Type            string             `json:"type"` // "buy" ou "sell"
+	Fee             float64            `json:"fee"`
+	FeeCurrency     string             `json:"feeCurrency"`
+	TradeValue      float64            `json:"tradeValue"`      // quantidade × preço
+	FeeImpact       float64            `json:"feeImpact"`       // taxa convertida para a moeda de cotação
+	FeePercentage   float64            `json:"feePercentage"`   // FeeImpact face a TradeValue
+	CurrentValue    float64            `json:"currentValue"`    // valor das posições antes da transação
+	SimulatedValue  float64            `json:"simulatedValue"`  // valor das posições depois da transação
+	SimulatedProfit float64            `json:"simulatedProfit"` // P&L realizado pela transação segundo o método de custo
+	Disposals       []Disposal         `json:"disposals"`       // lotes consumidos pela transação
+	Holdings        []Holding          `json:"holdings"`        // posições depois da transação
+	Allocation      []AllocationChange `json:"allocation"`
+	Risk            *RiskImpact        `json:"risk,omitempty"` // requer o histórico de preços
+	PricesComplete  bool               `json:"pricesComplete"`
+}
+
+// AllocationChange representa a posição num ativo antes e depois de uma transação simulada
+type AllocationChange struct {
+	Symbol         string  `json:"symbol"`
+	QuantityBefore float64 `json:"quantityBefore"`
+	QuantityAfter  float64 `json:"quantityAfter"`
+	ValueBefore    float64 `json:"valueBefore"`
+	ValueAfter     float64 `json:"valueAfter"`
+	WeightBefore   float64 `json:"weightBefore"` // fração do valor total (0-1)
+	WeightAfter    float64 `json:"weightAfter"`
+}
+
+// RiskImpact compara o risco das posições antes e depois de uma transação simulada
+type RiskImpact struct {
+	Before           RiskSnapshot `json:"before"`
+	After            RiskSnapshot `json:"after"`
+	Benchmark        string       `json:"benchmark"`
+	UnmodeledSymbols []string     `json:"unmodeledSymbols,omitempty"` // sem histórico; contam com risco nulo
+}
+
+// RiskSnapshot representa o risco estimado de um conjunto de posições a partir da covariância
+// histórica dos retornos diários dos ativos
+type RiskSnapshot struct {
+	AnnualizedVolatility float64  `json:"annualizedVolatility"` // percentual
+	ValueAtRisk          float64  `json:"valueAtRisk"`          // perda percentual a 1 dia, 95%, paramétrica
+	Beta                 *float64 `json:"beta,omitempty"`
+}
+
+// AllocationTarget representa o peso alvo de um ativo no portfólio
+type AllocationTarget struct {
+	Symbol         string  `json:"symbol"`
+	Weight         float64 `json:"weight"`         // fração do valor total (0-1)
+	DriftThreshold float64 `json:"driftThreshold"` // desvio absoluto do peso tolerado antes de rebalancear (0-1)
+}
+
+// RebalancePlan representa as transações necessárias para repor os pesos alvo do portfólio,
+// valorizadas aos preços de mercado atuais
+type RebalancePlan struct {
+	PortfolioID     string           `json:"portfolioId"`
+	TotalValue      float64          `json:"totalValue"`      // valor atual das posições com preço
+	NewCash         float64          `json:"newCash"`         // dinheiro novo a investir
+	CashOnly        bool             `json:"cashOnly"`        // só compras com o dinheiro novo, sem vendas
+	MinTradeSize    float64          `json:"minTradeSize"`    // valor mínimo de cada transação
+	NeedsRebalance  bool             `json:"needsRebalance"`  // algum ativo fora da margem de desvio
+	UnallocatedCash float64          `json:"unallocatedCash"` // dinheiro que fica por investir depois das transações
+	Assets          []RebalanceAsset `json:"assets"`
+	Trades          []RebalanceTrade `json:"trades"`
+	MissingSymbols  []string         `json:"missingSymbols,omitempty"` // posições sem preço, fora do plano
+	GeneratedAt     time.Time        `json:"generatedAt"`
+}
+
+// RebalanceAsset representa a posição num ativo face ao seu peso alvo, antes e depois do plano
+type RebalanceAsset struct {
+	Symbol         string  `json:"symbol"`
+	Price          float64 `json:"price"`
+	Quantity       float64 `json:"quantity"`
+	Value          float64 `json:"value"`
+	Weight         float64 `json:"weight"`       // fração do valor total (0-1)
+	TargetWeight   float64 `json:"targetWeight"` // zero para posições sem peso alvo
+	Drift          float64 `json:"drift"`        // Weight - TargetWeight
+	DriftThreshold float64 `json:"driftThreshold"`
+	OutOfBand      bool    `json:"outOfBand"`   // desvio acima da margem
+	WeightAfter    float64 `json:"weightAfter"` // peso depois das transações do plano
+}
+
+// RebalanceTrade representa uma transação do plano de rebalanceamento
+type RebalanceTrade struct {
+	Symbol   string          `json:"symbol"`
+	Type     TransactionType `json:"type"` // compra ou venda
+	Quantity float64         `json:"quantity"`
+	Price    float64         `json:"price"`
+	Value    float64         `json:"value"`
+}
+
+// PortfolioExport representa um portfólio completo para cópia de segurança: dados, ativos,
+// posições, livro de transações e pesos alvo
+type PortfolioExport struct {
+	Portfolio
+	Transactions []Transaction      `json:"transactions"`
+	Targets      []AllocationTarget `json:"targets"`
+	ExportedAt   time.Time          `json:"exportedAt"`
+}
+
+// TaxTerm classifica uma alienação pelo tempo de detenção do lote
+type TaxTerm string
+
+// Prazos de detenção
+const (
+	TaxTermShort TaxTerm = "short" // detido até um ano
+	TaxTermLong  TaxTerm = "long"  // detido mais de um ano
+)
+
+// TaxReport representa as mais-valias realizadas por um portfólio num ano fiscal, segundo o
+// método de custo indicado. Os valores estão na moeda de cotação das transações.
+type TaxReport struct {
+	PortfolioID string            `json:"portfolioId"`
+	Year        int               `json:"year"`
+	Method      CostBasisMethod   `json:"method"`
+	Currency    string            `json:"currency"` // moeda dos valores, convertidos à taxa da data de cada transação
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	Disposals   []TaxableDisposal `json:"disposals"`
+	ShortTerm   TaxSummary        `json:"shortTerm"`
+	LongTerm    TaxSummary        `json:"longTerm"`
+	Total       TaxSummary        `json:"total"`
+	GeneratedAt time.Time         `json:"generatedAt"`
+}
+
+// TaxableDisposal representa a alienação de (parte de) um lote no relatório fiscal
+type TaxableDisposal struct {
+	TransactionID string          `json:"transactionId"`
+	Type          TransactionType `json:"type"` // sell ou fee
+	Symbol        string          `json:"symbol"`
+	Quantity      float64         `json:"quantity"`
+	AcquiredAt    time.Time       `json:"acquiredAt"`
+	DisposedAt    time.Time       `json:"disposedAt"`
+	HoldingDays   int             `json:"holdingDays"`
+	Proceeds      float64         `json:"proceeds"`
+	CostBasis     float64         `json:"costBasis"`
+	Gain          float64         `json:"gain"` // negativo em caso de perda
+	Term          TaxTerm         `json:"term"`
+}
+
+// TaxSummary representa os totais de um conjunto de alienações
+type TaxSummary struct {
+	Disposals int     `json:"disposals"`
+	Proceeds  float64 `json:"proceeds"`
+	CostBasis float64 `json:"costBasis"`
+	Gains     float64 `json:"gains"`  // soma das alienações com ganho
+	Losses    float64 `json:"losses"` // soma das alienações com perda (negativa)
+	Net       float64 `json:"net"`
 }
@@ -0,0 +1,613 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Erros retornados pelos repositórios de portfólios
+var (
+	ErrPortfolioNotFound   = errors.New("portfólio não encontrado")
+	ErrAssetNotFound       = errors.New("ativo não encontrado")
+	ErrTransactionNotFound = errors.New("transação não encontrada")
+)
+
+// PortfolioRepository interface para persistência de portfólios e dos seus ativos
+type PortfolioRepository interface {
+	CreatePortfolio(portfolio *Portfolio) error
+	GetPortfolio(id string) (*Portfolio, error)
+	ListPortfolios(userID string) ([]Portfolio, error)
+	UpdatePortfolio(portfolio *Portfolio) error
+	DeletePortfolio(id string) error
+
+	AddAsset(asset *Asset) error
+	GetAsset(id string) (*Asset, error)
+	UpdateAsset(asset *Asset) error
+	DeleteAsset(id string) error
+
+	AddTransaction(tx *Transaction) error
+	AddTransactions(transactions []Transaction) error
+	GetTransaction(id string) (*Transaction, error)
+	ListTransactions(portfolioID string) ([]Transaction, error)
+	UpdateTransaction(tx *Transaction) error
+	DeleteTransaction(id string) error
+
+	GetTargets(portfolioID string) ([]AllocationTarget, error)
+	SetTargets(portfolioID string, targets []AllocationTarget) error
+}
+
+// PostgresPortfolioRepository implementação do repositório de portfólios para PostgreSQL
+type PostgresPortfolioRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresPortfolioRepository cria um novo repositório PostgreSQL de portfólios
+func NewPostgresPortfolioRepository(db *sql.DB) *PostgresPortfolioRepository {
+	return &PostgresPortfolioRepository{db: db}
+}
+
+// CreatePortfolio insere um novo portfólio
+func (r *PostgresPortfolioRepository) CreatePortfolio(portfolio *Portfolio) error {
+	query := `
+		INSERT INTO portfolios (id, user_id, name, description, cost_basis_method, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.Exec(
+		query,
+		portfolio.ID,
+		portfolio.UserID,
+		portfolio.Name,
+		portfolio.Description,
+		portfolio.CostBasisMethod,
+		portfolio.CreatedAt,
+		portfolio.UpdatedAt,
+	)
+	return err
+}
+
+// GetPortfolio obtém um portfólio e os seus ativos
+func (r *PostgresPortfolioRepository) GetPortfolio(id string) (*Portfolio, error) {
+	query := `
+		SELECT id, user_id, name, description, cost_basis_method, created_at, updated_at
+		FROM portfolios
+		WHERE id = $1
+	`
+
+	var p Portfolio
+	err := r.db.QueryRow(query, id).Scan(
+		&p.ID,
+		&p.UserID,
+		&p.Name,
+		&p.Description,
+		&p.CostBasisMethod,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrPortfolioNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.Assets, err = r.getAssets(p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// ListPortfolios obtém os portfólios de um usuário, com os seus ativos
+func (r *PostgresPortfolioRepository) ListPortfolios(userID string) ([]Portfolio, error) {
+	query := `
+		SELECT id, user_id, name, description, cost_basis_method, created_at, updated_at
+		FROM portfolios
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Portfolio{}
+	for rows.Next() {
+		var p Portfolio
+		if err := rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.Name,
+			&p.Description,
+			&p.CostBasisMethod,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range result {
+		result[i].Assets, err = r.getAssets(result[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// UpdatePortfolio atualiza o nome, a descrição e o método de custo de um portfólio
+func (r *PostgresPortfolioRepository) UpdatePortfolio(portfolio *Portfolio) error {
+	query := `
+		UPDATE portfolios
+		SET name = $2, description = $3, cost_basis_method = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(query, portfolio.ID, portfolio.Name, portfolio.Description, portfolio.CostBasisMethod, portfolio.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, ErrPortfolioNotFound)
+}
+
+// DeletePortfolio remove um portfólio (os ativos são removidos em cascata)
+func (r *PostgresPortfolioRepository) DeletePortfolio(id string) error {
+	result, err := r.db.Exec(`DELETE FROM portfolios WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, ErrPortfolioNotFound)
+}
+
+// AddAsset insere um ativo num portfólio
+func (r *PostgresPortfolioRepository) AddAsset(asset *Asset) error {
+	query := `
+		INSERT INTO portfolio_assets (id, portfolio_id, symbol, amount, purchase_price, purchase_date, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Exec(
+		query,
+		asset.ID,
+		asset.PortfolioID,
+		asset.Symbol,
+		asset.Amount,
+		asset.PurchasePrice,
+		asset.PurchaseDate,
+		asset.CreatedAt,
+		asset.UpdatedAt,
+	)
+	return err
+}
+
+// GetAsset obtém um ativo pelo ID
+func (r *PostgresPortfolioRepository) GetAsset(id string) (*Asset, error) {
+	query := `
+		SELECT id, portfolio_id, symbol, amount, purchase_price, purchase_date, created_at, updated_at
+		FROM portfolio_assets
+		WHERE id = $1
+	`
+
+	var a Asset
+	err := r.db.QueryRow(query, id).Scan(
+		&a.ID,
+		&a.PortfolioID,
+		&a.Symbol,
+		&a.Amount,
+		&a.PurchasePrice,
+		&a.PurchaseDate,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrAssetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// UpdateAsset atualiza a quantidade, o preço e a data de compra de um ativo
+func (r *PostgresPortfolioRepository) UpdateAsset(asset *Asset) error {
+	query := `
+		UPDATE portfolio_assets
+		SET symbol = $2, amount = $3, purchase_price = $4, purchase_date = $5, updated_at = $6
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(
+		query,
+		asset.ID,
+		asset.Symbol,
+		asset.Amount,
+		asset.PurchasePrice,
+		asset.PurchaseDate,
+		asset.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, ErrAssetNotFound)
+}
+
+// DeleteAsset remove um ativo
+func (r *PostgresPortfolioRepository) DeleteAsset(id string) error {
+	result, err := r.db.Exec(`DELETE FROM portfolio_assets WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, ErrAssetNotFound)
+}
+
+// AddTransaction insere uma transação no livro de um portfólio
+func (r *PostgresPortfolioRepository) AddTransaction(tx *Transaction) error {
+	query := `
+		INSERT INTO portfolio_transactions (id, portfolio_id, type, symbol, quantity, price, quote_currency, fee, fee_currency, timestamp, notes, import_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err := r.db.Exec(query, transactionValues(tx)...)
+	return err
+}
+
+// AddTransactions insere várias transações numa única transação da base de dados
+func (r *PostgresPortfolioRepository) AddTransactions(transactions []Transaction) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO portfolio_transactions (id, portfolio_id, type, symbol, quantity, price, quote_currency, fee, fee_currency, timestamp, notes, import_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i := range transactions {
+		if _, err := stmt.Exec(transactionValues(&transactions[i])...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// transactionValues retorna os valores de uma transação pela ordem das colunas de inserção
+func transactionValues(tx *Transaction) []interface{} {
+	return []interface{}{
+		tx.ID,
+		tx.PortfolioID,
+		tx.Type,
+		tx.Symbol,
+		tx.Quantity,
+		tx.Price,
+		tx.QuoteCurrency,
+		tx.Fee,
+		tx.FeeCurrency,
+		tx.Timestamp,
+		tx.Notes,
+		tx.ImportID,
+		tx.CreatedAt,
+		tx.UpdatedAt,
+	}
+}
+
+// GetTransaction obtém uma transação pelo ID
+func (r *PostgresPortfolioRepository) GetTransaction(id string) (*Transaction, error) {
+	query := `
+		SELECT id, portfolio_id, type, symbol, quantity, price, quote_currency, fee, fee_currency, timestamp, notes, import_id, created_at, updated_at
+		FROM portfolio_transactions
+		WHERE id = $1
+	`
+
+	tx, err := scanTransaction(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+
+	return tx, err
+}
+
+// ListTransactions obtém o livro de transações de um portfólio por ordem cronológica
+func (r *PostgresPortfolioRepository) ListTransactions(portfolioID string) ([]Transaction, error) {
+	query := `
+		SELECT id, portfolio_id, type, symbol, quantity, price, quote_currency, fee, fee_currency, timestamp, notes, import_id, created_at, updated_at
+		FROM portfolio_transactions
+		WHERE portfolio_id = $1
+		ORDER BY timestamp ASC, created_at ASC
+	`
+
+	rows, err := r.db.Query(query, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Transaction{}
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *tx)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateTransaction atualiza uma transação existente
+func (r *PostgresPortfolioRepository) UpdateTransaction(tx *Transaction) error {
+	query := `
+		UPDATE portfolio_transactions
+		SET type = $2, symbol = $3, quantity = $4, price = $5, quote_currency = $6,
+			fee = $7, fee_currency = $8, timestamp = $9, notes = $10, updated_at = $11
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(
+		query,
+		tx.ID,
+		tx.Type,
+		tx.Symbol,
+		tx.Quantity,
+		tx.Price,
+		tx.QuoteCurrency,
+		tx.Fee,
+		tx.FeeCurrency,
+		tx.Timestamp,
+		tx.Notes,
+		tx.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, ErrTransactionNotFound)
+}
+
+// DeleteTransaction remove uma transação
+func (r *PostgresPortfolioRepository) DeleteTransaction(id string) error {
+	result, err := r.db.Exec(`DELETE FROM portfolio_transactions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, ErrTransactionNotFound)
+}
+
+// GetTargets obtém os pesos alvo de um portfólio, por ordem decrescente de peso
+func (r *PostgresPortfolioRepository) GetTargets(portfolioID string) ([]AllocationTarget, error) {
+	query := `
+		SELECT symbol, weight, drift_threshold
+		FROM portfolio_targets
+		WHERE portfolio_id = $1
+		ORDER BY weight DESC, symbol ASC
+	`
+
+	rows, err := r.db.Query(query, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []AllocationTarget{}
+	for rows.Next() {
+		var t AllocationTarget
+		if err := rows.Scan(&t.Symbol, &t.Weight, &t.DriftThreshold); err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SetTargets substitui os pesos alvo de um portfólio numa única transação
+func (r *PostgresPortfolioRepository) SetTargets(portfolioID string, targets []AllocationTarget) error {
+	var exists bool
+	if err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM portfolios WHERE id = $1)`, portfolioID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrPortfolioNotFound
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM portfolio_targets WHERE portfolio_id = $1`, portfolioID); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO portfolio_targets (portfolio_id, symbol, weight, drift_threshold)
+		VALUES ($1, $2, $3, $4)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, t := range targets {
+		if _, err := stmt.Exec(portfolioID, t.Symbol, t.Weight, t.DriftThreshold); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// rowScanner é implementado por *sql.Row e *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTransaction converte uma linha da tabela portfolio_transactions numa Transaction
+func scanTransaction(row rowScanner) (*Transaction, error) {
+	var tx Transaction
+	err := row.Scan(
+		&tx.ID,
+		&tx.PortfolioID,
+		&tx.Type,
+		&tx.Symbol,
+		&tx.Quantity,
+		&tx.Price,
+		&tx.QuoteCurrency,
+		&tx.Fee,
+		&tx.FeeCurrency,
+		&tx.Timestamp,
+		&tx.Notes,
+		&tx.ImportID,
+		&tx.CreatedAt,
+		&tx.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
+
+// getAssets obtém os ativos de um portfólio
+func (r *PostgresPortfolioRepository) getAssets(portfolioID string) ([]Asset, error) {
+	query := `
+		SELECT id, portfolio_id, symbol, amount, purchase_price, purchase_date, created_at, updated_at
+		FROM portfolio_assets
+		WHERE portfolio_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(query, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Asset{}
+	for rows.Next() {
+		var a Asset
+		if err := rows.Scan(
+			&a.ID,
+			&a.PortfolioID,
+			&a.Symbol,
+			&a.Amount,
+			&a.PurchasePrice,
+			&a.PurchaseDate,
+			&a.CreatedAt,
+			&a.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// checkAffected retorna notFound se a operação não afetou nenhuma linha
+func checkAffected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// Esquema SQL para criação das tabelas de portfólios
+const PortfolioSchema = `
+CREATE TABLE IF NOT EXISTS portfolios (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    cost_basis_method VARCHAR(10) NOT NULL DEFAULT 'fifo',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Colunas adicionadas depois da versão inicial da tabela
+ALTER TABLE portfolios ADD COLUMN IF NOT EXISTS cost_basis_method VARCHAR(10) NOT NULL DEFAULT 'fifo';
+
+CREATE INDEX IF NOT EXISTS idx_portfolios_user_id ON portfolios (user_id);
+
+CREATE TABLE IF NOT EXISTS portfolio_assets (
+    id VARCHAR(64) PRIMARY KEY,
+    portfolio_id VARCHAR(64) NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    symbol VARCHAR(20) NOT NULL,
+    amount NUMERIC(30, 10) NOT NULL,
+    purchase_price NUMERIC(20, 8) NOT NULL,
+    purchase_date TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_portfolio_assets_portfolio_id ON portfolio_assets (portfolio_id);
+
+CREATE TABLE IF NOT EXISTS portfolio_transactions (
+    id VARCHAR(64) PRIMARY KEY,
+    portfolio_id VARCHAR(64) NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL,
+    symbol VARCHAR(20) NOT NULL,
+    quantity NUMERIC(30, 10) NOT NULL,
+    price NUMERIC(20, 8) NOT NULL DEFAULT 0,
+    quote_currency VARCHAR(10) NOT NULL DEFAULT 'USD',
+    fee NUMERIC(30, 10) NOT NULL DEFAULT 0,
+    fee_currency VARCHAR(20) NOT NULL DEFAULT '',
+    timestamp TIMESTAMP NOT NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    import_id VARCHAR(200) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Colunas adicionadas depois da versão inicial da tabela
+ALTER TABLE portfolio_transactions ADD COLUMN IF NOT EXISTS import_id VARCHAR(200) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_portfolio_transactions_portfolio_timestamp ON portfolio_transactions (portfolio_id, timestamp);
+
+CREATE TABLE IF NOT EXISTS portfolio_targets (
+    portfolio_id VARCHAR(64) NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    symbol VARCHAR(20) NOT NULL,
+    weight NUMERIC(10, 8) NOT NULL,
+    drift_threshold NUMERIC(10, 8) NOT NULL,
+    PRIMARY KEY (portfolio_id, symbol)
+);
+`
@@ -0,0 +1,203 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrStrategyNotFound é retornado quando a estratégia não existe
+var ErrStrategyNotFound = errors.New("estratégia não encontrada")
+
+// StrategyDefinition é uma estratégia declarativa, escrita em JSON ou YAML: as regras de entrada
+// e de saída (na linguagem do pacote rules), a gestão de risco e o dimensionamento das posições
+type StrategyDefinition struct {
+	Name        string          `json:"name" yaml:"name"`
+	Description string          `json:"description,omitempty" yaml:"description,omitempty"`
+	Interval    string          `json:"interval,omitempty" yaml:"interval,omitempty"` // velas avaliadas, por omissão 1d
+	Symbols     []string        `json:"symbols,omitempty" yaml:"symbols,omitempty"`   // ativos acompanhados nos sinais periódicos
+	Entry       string          `json:"entry" yaml:"entry"`
+	Exit        string          `json:"exit,omitempty" yaml:"exit,omitempty"`             // sem regra, a posição só fecha por stop-loss ou take-profit
+	StopLoss    float64         `json:"stopLoss,omitempty" yaml:"stopLoss,omitempty"`     // queda face ao preço de entrada, como fração
+	TakeProfit  float64         `json:"takeProfit,omitempty" yaml:"takeProfit,omitempty"` // subida face ao preço de entrada, como fração
+	Sizing      *PositionSizing `json:"sizing,omitempty" yaml:"sizing,omitempty"`         // sem dimensionamento, cada entrada usa todo o capital
+}
+
+// Strategy é uma estratégia declarativa guardada por um usuário
+type Strategy struct {
+	ID         string             `json:"id"`
+	UserID     string             `json:"userId"`
+	Definition StrategyDefinition `json:"definition"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	UpdatedAt  time.Time          `json:"updatedAt"`
+}
+
+// StrategyRepository interface para persistência de estratégias
+type StrategyRepository interface {
+	CreateStrategy(strategy *Strategy) error
+	GetStrategy(id string) (*Strategy, error)
+	ListStrategies(userID string) ([]Strategy, error)
+	ListWatchedStrategies() ([]Strategy, error) // de todos os usuários, com ativos acompanhados
+	UpdateStrategy(strategy *Strategy) error
+	DeleteStrategy(id string) error
+}
+
+// PostgresStrategyRepository implementação do repositório de estratégias para PostgreSQL. A
+// definição é guardada em JSON.
+type PostgresStrategyRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresStrategyRepository cria um novo repositório PostgreSQL
+func NewPostgresStrategyRepository(db *sql.DB) *PostgresStrategyRepository {
+	return &PostgresStrategyRepository{db: db}
+}
+
+// CreateStrategy grava uma nova estratégia
+func (r *PostgresStrategyRepository) CreateStrategy(strategy *Strategy) error {
+	definition, err := json.Marshal(strategy.Definition)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO strategies (id, user_id, name, definition, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`,
+		strategy.ID,
+		strategy.UserID,
+		strategy.Definition.Name,
+		definition,
+		strategy.CreatedAt,
+		strategy.UpdatedAt,
+	)
+	return err
+}
+
+// GetStrategy obtém uma estratégia
+func (r *PostgresStrategyRepository) GetStrategy(id string) (*Strategy, error) {
+	row := r.db.QueryRow(`
+		SELECT id, user_id, definition, created_at, updated_at
+		FROM strategies
+		WHERE id = $1
+	`, id)
+
+	strategy, err := scanStrategy(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrStrategyNotFound
+	}
+	return strategy, err
+}
+
+// ListStrategies obtém as estratégias de um usuário, por nome
+func (r *PostgresStrategyRepository) ListStrategies(userID string) ([]Strategy, error) {
+	return r.queryStrategies(`
+		SELECT id, user_id, definition, created_at, updated_at
+		FROM strategies
+		WHERE user_id = $1
+		ORDER BY name
+	`, userID)
+}
+
+// ListWatchedStrategies obtém as estratégias de todos os usuários que acompanham algum ativo
+func (r *PostgresStrategyRepository) ListWatchedStrategies() ([]Strategy, error) {
+	return r.queryStrategies(`
+		SELECT id, user_id, definition, created_at, updated_at
+		FROM strategies
+		WHERE jsonb_array_length(COALESCE(definition->'symbols', '[]'::jsonb)) > 0
+		ORDER BY user_id, name
+	`)
+}
+
+// UpdateStrategy substitui a definição de uma estratégia
+func (r *PostgresStrategyRepository) UpdateStrategy(strategy *Strategy) error {
+	definition, err := json.Marshal(strategy.Definition)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec(`
+		UPDATE strategies
+		SET name = $2, definition = $3, updated_at = $4
+		WHERE id = $1
+	`,
+		strategy.ID,
+		strategy.Definition.Name,
+		definition,
+		strategy.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, ErrStrategyNotFound)
+}
+
+// DeleteStrategy remove uma estratégia
+func (r *PostgresStrategyRepository) DeleteStrategy(id string) error {
+	result, err := r.db.Exec(`DELETE FROM strategies WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, ErrStrategyNotFound)
+}
+
+// queryStrategies executa uma consulta que retorna linhas da tabela strategies
+func (r *PostgresStrategyRepository) queryStrategies(query string, args ...interface{}) ([]Strategy, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Strategy{}
+	for rows.Next() {
+		strategy, err := scanStrategy(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *strategy)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// scanStrategy converte uma linha da tabela strategies numa Strategy
+func scanStrategy(row rowScanner) (*Strategy, error) {
+	var strategy Strategy
+	var definition []byte
+	err := row.Scan(
+		&strategy.ID,
+		&strategy.UserID,
+		&definition,
+		&strategy.CreatedAt,
+		&strategy.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(definition, &strategy.Definition); err != nil {
+		return nil, err
+	}
+
+	return &strategy, nil
+}
+
+// Esquema SQL para criação da tabela de estratégias
+const StrategySchema = `
+CREATE TABLE IF NOT EXISTS strategies (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    definition JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_strategies_user ON strategies (user_id, name);
+`
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"time"
+)
+
+// TransactionType identifica o tipo de movimento no livro de transações
+type TransactionType string
+
+// Tipos de transação suportados
+const (
+	TransactionBuy           TransactionType = "buy"
+	TransactionSell          TransactionType = "sell"
+	TransactionDeposit       TransactionType = "deposit"    // entrada de ativos vindos de fora (custo = preço indicado)
+	TransactionWithdrawal    TransactionType = "withdrawal" // saída de ativos para fora do portfólio
+	TransactionTransfer      TransactionType = "transfer"   // movimento entre carteiras próprias; só a taxa altera o saldo
+	TransactionFee           TransactionType = "fee"        // taxa isolada, paga em unidades do ativo
+	TransactionStakingReward TransactionType = "staking_reward"
+)
+
+// DefaultQuoteCurrency é a moeda de cotação usada quando a transação não indica outra
+const DefaultQuoteCurrency = "USD"
+
+// Valid indica se o tipo de transação é conhecido
+func (t TransactionType) Valid() bool {
+	switch t {
+	case TransactionBuy, TransactionSell, TransactionDeposit, TransactionWithdrawal,
+		TransactionTransfer, TransactionFee, TransactionStakingReward:
+		return true
+	}
+	return false
+}
+
+// Transaction representa um movimento no livro de transações de um portfólio
+type Transaction struct {
+	ID            string          `json:"id"`
+	PortfolioID   string          `json:"portfolioId"`
+	Type          TransactionType `json:"type"`
+	Symbol        string          `json:"symbol"`
+	Quantity      float64         `json:"quantity"`
+	Price         float64         `json:"price"`         // preço unitário na moeda de cotação
+	QuoteCurrency string          `json:"quoteCurrency"` // moeda do preço, ex.: USD
+	Fee           float64         `json:"fee"`
+	FeeCurrency   string          `json:"feeCurrency"` // moeda de cotação, o próprio ativo ou outro ativo (ex.: BNB)
+	Timestamp     time.Time       `json:"timestamp"`
+	Notes         string          `json:"notes,omitempty"`
+	ImportID      string          `json:"importId,omitempty"` // origem da transação importada, para detetar repetições
+	CreatedAt     time.Time       `json:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt"`
+}
+
+// Holding representa a posição atual num ativo, derivada do livro de transações
+type Holding struct {
+	Symbol        string    `json:"symbol"`
+	Quantity      float64   `json:"quantity"`
+	CostBasis     float64   `json:"costBasis"`   // custo total das unidades em carteira
+	AverageCost   float64   `json:"averageCost"` // custo médio por unidade
+	FeesPaid      float64   `json:"feesPaid"`    // taxas pagas na moeda de cotação
+	FirstAcquired time.Time `json:"firstAcquired"`
+	LastActivity  time.Time `json:"lastActivity"`
+}
+
+// Lot representa unidades adquiridas numa transação e ainda não alienadas
+type Lot struct {
+	TransactionID string    `json:"transactionId"`
+	Symbol        string    `json:"symbol"`
+	Quantity      float64   `json:"quantity"`
+	UnitCost      float64   `json:"unitCost"`
+	CostBasis     float64   `json:"costBasis"`
+	AcquiredAt    time.Time `json:"acquiredAt"`
+	CurrentPrice  float64   `json:"currentPrice,omitempty"`
+	MarketValue   float64   `json:"marketValue,omitempty"`
+	UnrealizedPnL float64   `json:"unrealizedPnl"`
+}
+
+// Disposal representa a alienação de (parte de) um lote numa venda ou no pagamento de uma taxa
+type Disposal struct {
+	TransactionID string          `json:"transactionId"`
+	Type          TransactionType `json:"type"` // sell ou fee
+	Symbol        string          `json:"symbol"`
+	Quantity      float64         `json:"quantity"`
+	AcquiredAt    time.Time       `json:"acquiredAt"`
+	DisposedAt    time.Time       `json:"disposedAt"`
+	Proceeds      float64         `json:"proceeds"`
+	CostBasis     float64         `json:"costBasis"`
+	RealizedPnL   float64         `json:"realizedPnl"`
+}
+
+// ImportRowStatus indica o resultado da importação de uma linha de um ficheiro
+type ImportRowStatus string
+
+// Estados das linhas importadas
+const (
+	ImportRowOK        ImportRowStatus = "ok"
+	ImportRowDuplicate ImportRowStatus = "duplicate" // já existe no livro ou repete uma linha anterior do ficheiro
+	ImportRowSkipped   ImportRowStatus = "skipped"   // movimento sem efeito nas posições (ex.: depósitos em moeda fiduciária)
+	ImportRowError     ImportRowStatus = "error"
+)
+
+// ImportRow representa uma linha de um ficheiro importado e as transações que lhe correspondem
+type ImportRow struct {
+	Line         int             `json:"line"`
+	Status       ImportRowStatus `json:"status"`
+	Error        string          `json:"error,omitempty"` // motivo do erro ou da linha ignorada
+	Transactions []Transaction   `json:"transactions"`
+}
+
+// ImportResult representa o resultado (ou a pré-visualização) da importação de um ficheiro
+type ImportResult struct {
+	PortfolioID string      `json:"portfolioId"`
+	Format      string      `json:"format"`
+	DryRun      bool        `json:"dryRun"`
+	Imported    int         `json:"imported"` // transações gravadas; zero em dry run
+	Valid       int         `json:"valid"`    // linhas prontas a importar
+	Duplicates  int         `json:"duplicates"`
+	Skipped     int         `json:"skipped"`
+	Errors      int         `json:"errors"`
+	Rows        []ImportRow `json:"rows"`
+}
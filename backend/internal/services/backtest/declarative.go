@@ -0,0 +1,53 @@
+package backtest
+
+import (
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/strategy"
+)
+
+// declarative simula uma estratégia declarativa: entra quando a regra de entrada é verdadeira e
+// sai quando a de saída o é
+type declarative struct {
+	compiled       *strategy.Compiled
+	entries, exits []bool
+}
+
+// Declarative adapta uma estratégia declarativa validada à interface Strategy. O stop-loss, o
+// take-profit e o dimensionamento da definição entram pela configuração (ver ApplyDefinition).
+func Declarative(compiled *strategy.Compiled) Strategy {
+	return &declarative{compiled: compiled}
+}
+
+func (d *declarative) Prepare(candles models.Candles) error {
+	entries, exits, err := d.compiled.Evaluate(candles)
+	if err != nil {
+		return err
+	}
+	d.entries, d.exits = entries, exits
+	return nil
+}
+
+func (d *declarative) Next(i int, position Position) Action {
+	switch {
+	case !position.Open && d.entries[i]:
+		return Enter
+	case position.Open && d.exits[i]:
+		return Exit
+	}
+	return Hold
+}
+
+// ApplyDefinition completa a configuração de um backtest com a gestão de risco e o
+// dimensionamento da definição, que prevalecem sobre os da configuração quando indicados
+func ApplyDefinition(config models.BacktestConfig, definition models.StrategyDefinition) models.BacktestConfig {
+	if definition.StopLoss > 0 {
+		config.StopLoss = definition.StopLoss
+	}
+	if definition.TakeProfit > 0 {
+		config.TakeProfit = definition.TakeProfit
+	}
+	if definition.Sizing != nil {
+		config.Sizing = *definition.Sizing
+	}
+	return config
+}
@@ -0,0 +1,308 @@
+// Package backtest simula estratégias de negociação sobre velas históricas: as decisões são
+// tomadas no fecho de cada vela e executadas na abertura da vela seguinte, com comissões,
+// desvio de preço (slippage), dimensionamento das posições e stop-loss/take-profit.
+//
+// As estratégias negoceiam um único ativo, apenas com posições compradas.
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/stats"
+)
+
+// Erros do backtest
+var (
+	ErrInvalidBacktest    = errors.New("backtest inválido")
+	ErrInsufficientData   = errors.New("velas insuficientes para o backtest")
+	ErrHistoryUnavailable = errors.New("histórico de preços indisponível")
+)
+
+// DefaultInitialCapital é o capital inicial quando a configuração não o indica
+const DefaultInitialCapital = 10000
+
+// year é a duração usada para anualizar as métricas
+const year = 365 * 24 * time.Hour
+
+// Motivos de saída de uma operação
+const (
+	ExitSignal     = "signal"
+	ExitStopLoss   = "stop_loss"
+	ExitTakeProfit = "take_profit"
+	ExitEnd        = "end"
+)
+
+// Action é a decisão de uma estratégia no fecho de uma vela
+type Action int
+
+const (
+	Hold  Action = iota // manter a situação atual
+	Enter               // abrir uma posição, se não houver nenhuma
+	Exit                // fechar a posição aberta
+)
+
+// Position é a posição aberta no momento de uma decisão
+type Position struct {
+	Open       bool
+	EntryIndex int     // vela em que a posição foi aberta
+	EntryPrice float64 // preço de execução, com slippage
+	Quantity   float64
+}
+
+// Strategy decide as entradas e saídas de uma simulação
+type Strategy interface {
+	// Prepare recebe todas as velas antes da simulação, por exemplo para calcular indicadores.
+	// As decisões de Next só podem usar dados até à vela indicada.
+	Prepare(candles models.Candles) error
+	// Next decide, no fecho da vela i, o que fazer na abertura da vela seguinte
+	Next(i int, position Position) Action
+}
+
+// NormalizeConfig preenche os campos a zero com os valores por omissão e valida a configuração.
+// Sem dimensionamento, cada entrada usa todo o dinheiro disponível.
+func NormalizeConfig(config models.BacktestConfig) (models.BacktestConfig, error) {
+	if config.InitialCapital == 0 {
+		config.InitialCapital = DefaultInitialCapital
+	}
+	if config.Sizing.Mode == "" {
+		config.Sizing = models.PositionSizing{Mode: models.SizingPercent, Value: 1}
+	}
+
+	switch {
+	case config.InitialCapital < 0:
+		return config, fmt.Errorf("%w: o capital inicial deve ser positivo", ErrInvalidBacktest)
+	case config.FeeRate < 0 || config.FeeRate >= 1:
+		return config, fmt.Errorf("%w: a comissão deve estar entre 0 e 1", ErrInvalidBacktest)
+	case config.Slippage < 0 || config.Slippage >= 1:
+		return config, fmt.Errorf("%w: o slippage deve estar entre 0 e 1", ErrInvalidBacktest)
+	case config.StopLoss < 0 || config.StopLoss >= 1:
+		return config, fmt.Errorf("%w: o stop-loss deve estar entre 0 e 1", ErrInvalidBacktest)
+	case config.TakeProfit < 0:
+		return config, fmt.Errorf("%w: o take-profit não pode ser negativo", ErrInvalidBacktest)
+	}
+
+	switch config.Sizing.Mode {
+	case models.SizingPercent:
+		if config.Sizing.Value <= 0 || config.Sizing.Value > 1 {
+			return config, fmt.Errorf("%w: a fração do capital deve estar entre 0 e 1", ErrInvalidBacktest)
+		}
+	case models.SizingFixed:
+		if config.Sizing.Value <= 0 {
+			return config, fmt.Errorf("%w: o valor de cada entrada deve ser positivo", ErrInvalidBacktest)
+		}
+	default:
+		return config, fmt.Errorf("%w: dimensionamento desconhecido: %s", ErrInvalidBacktest, config.Sizing.Mode)
+	}
+
+	return config, nil
+}
+
+// Run simula a estratégia sobre as velas, com a configuração indicada (ver NormalizeConfig) e a
+// duração de cada vela, usada para anualizar as métricas. As ordens não são executadas em velas
+// sintéticas (models.Candle.Filled), que não tiveram negociação: ficam para a vela seguinte. Uma
+// posição aberta no fim é fechada ao último fecho.
+func Run(candles models.Candles, strategy Strategy, config models.BacktestConfig, interval time.Duration) (*models.BacktestResult, error) {
+	config, err := NormalizeConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(candles) < 2 {
+		return nil, ErrInsufficientData
+	}
+	if err := strategy.Prepare(candles); err != nil {
+		return nil, err
+	}
+
+	sim := &simulation{config: config, cash: config.InitialCapital}
+	result := &models.BacktestResult{
+		Trades: []models.BacktestTrade{},
+		Equity: make([]models.EquityPoint, 0, len(candles)),
+	}
+
+	pending, exposed := Hold, 0
+	for i, candle := range candles {
+		if !candle.Filled {
+			switch {
+			case pending == Enter && !sim.position.Open:
+				sim.enter(i, candle, candle.Open*(1+config.Slippage))
+			case pending == Exit && sim.position.Open:
+				result.Trades = append(result.Trades, sim.exit(candles, candle, candle.Open*(1-config.Slippage), ExitSignal))
+			}
+			pending = Hold
+
+			if trade, ok := sim.checkStops(candles, i); ok {
+				result.Trades = append(result.Trades, trade)
+			}
+		}
+
+		if sim.position.Open {
+			exposed++
+		}
+		result.Equity = append(result.Equity, models.EquityPoint{Time: candle.Time, Equity: sim.equity(candle.Close)})
+
+		if i < len(candles)-1 {
+			if action := strategy.Next(i, sim.position); action != Hold {
+				pending = action
+			}
+		}
+	}
+
+	last := candles[len(candles)-1]
+	if sim.position.Open {
+		result.Trades = append(result.Trades, sim.exit(candles, last, last.Close*(1-config.Slippage), ExitEnd))
+		result.Equity[len(result.Equity)-1].Equity = sim.cash
+	}
+
+	result.Metrics = metrics(candles, result, config, interval)
+	result.Metrics.Exposure = float64(exposed) / float64(len(candles)) * 100
+	return result, nil
+}
+
+// simulation é o estado da conta durante a simulação
+type simulation struct {
+	config    models.BacktestConfig
+	cash      float64
+	position  Position
+	entryFees float64
+}
+
+// equity é o valor da conta ao preço indicado
+func (s *simulation) equity(price float64) float64 {
+	return s.cash + s.position.Quantity*price
+}
+
+// enter abre uma posição ao preço indicado, com o valor definido pelo dimensionamento e a
+// comissão incluída nesse valor
+func (s *simulation) enter(i int, candle models.Candle, price float64) {
+	amount := s.cash * s.config.Sizing.Value
+	if s.config.Sizing.Mode == models.SizingFixed {
+		amount = math.Min(s.config.Sizing.Value, s.cash)
+	}
+	if amount <= 0 || price <= 0 {
+		return
+	}
+
+	quantity := amount / (price * (1 + s.config.FeeRate))
+	fee := quantity * price * s.config.FeeRate
+	s.cash -= quantity*price + fee
+	s.entryFees = fee
+	s.position = Position{Open: true, EntryIndex: i, EntryPrice: price, Quantity: quantity}
+}
+
+// exit fecha a posição ao preço indicado e retorna a operação
+func (s *simulation) exit(candles models.Candles, candle models.Candle, price float64, reason string) models.BacktestTrade {
+	position := s.position
+	proceeds := position.Quantity * price
+	fee := proceeds * s.config.FeeRate
+	cost := position.Quantity*position.EntryPrice + s.entryFees
+
+	s.cash += proceeds - fee
+	s.position = Position{}
+
+	pnl := proceeds - fee - cost
+	return models.BacktestTrade{
+		EntryTime:  candles[position.EntryIndex].Time,
+		EntryPrice: position.EntryPrice,
+		ExitTime:   candle.Time,
+		ExitPrice:  price,
+		Quantity:   position.Quantity,
+		Fees:       s.entryFees + fee,
+		PnL:        pnl,
+		Return:     pnl / cost * 100,
+		ExitReason: reason,
+	}
+}
+
+// checkStops fecha a posição se a vela i tocar o stop-loss ou o take-profit. Se a vela tocar os
+// dois, assume-se o stop-loss. Uma abertura para lá do nível é executada à abertura; o stop-loss
+// é uma ordem a mercado, com slippage, e o take-profit uma ordem limitada, sem slippage.
+func (s *simulation) checkStops(candles models.Candles, i int) (models.BacktestTrade, bool) {
+	if !s.position.Open {
+		return models.BacktestTrade{}, false
+	}
+	candle := candles[i]
+	entry := s.position.EntryPrice
+
+	if s.config.StopLoss > 0 {
+		if stop := entry * (1 - s.config.StopLoss); candle.Low <= stop {
+			return s.exit(candles, candle, math.Min(candle.Open, stop)*(1-s.config.Slippage), ExitStopLoss), true
+		}
+	}
+	if s.config.TakeProfit > 0 {
+		if take := entry * (1 + s.config.TakeProfit); candle.High >= take {
+			return s.exit(candles, candle, math.Max(candle.Open, take), ExitTakeProfit), true
+		}
+	}
+	return models.BacktestTrade{}, false
+}
+
+// metrics calcula as métricas de desempenho da simulação
+func metrics(candles models.Candles, result *models.BacktestResult, config models.BacktestConfig, interval time.Duration) models.BacktestMetrics {
+	final := result.Equity[len(result.Equity)-1].Equity
+	m := models.BacktestMetrics{
+		FinalEquity: final,
+		TotalReturn: (final/config.InitialCapital - 1) * 100,
+		Trades:      len(result.Trades),
+	}
+
+	if first := candles[0].Close; first > 0 {
+		m.BuyAndHold = (candles[len(candles)-1].Close/first - 1) * 100
+	}
+	if years := float64(candles[len(candles)-1].Time.Sub(candles[0].Time)+interval) / float64(year); years > 0 && final > 0 {
+		m.AnnualizedReturn = (math.Pow(final/config.InitialCapital, 1/years) - 1) * 100
+	}
+
+	var wins int
+	var grossProfit, grossLoss, totalPnL float64
+	for _, trade := range result.Trades {
+		m.TotalFees += trade.Fees
+		totalPnL += trade.PnL
+		if trade.PnL > 0 {
+			wins++
+			grossProfit += trade.PnL
+		} else {
+			grossLoss -= trade.PnL
+		}
+	}
+	if m.Trades > 0 {
+		m.WinRate = float64(wins) / float64(m.Trades) * 100
+		m.AverageTrade = totalPnL / float64(m.Trades)
+	}
+	if grossLoss > 0 {
+		factor := grossProfit / grossLoss
+		m.ProfitFactor = &factor
+	}
+
+	// Retornos da conta por vela, a partir do capital inicial
+	returns := make([]float64, len(result.Equity))
+	previous := config.InitialCapital
+	for i, point := range result.Equity {
+		returns[i] = point.Equity/previous - 1
+		previous = point.Equity
+	}
+	if interval > 0 {
+		periodsPerYear := float64(year) / float64(interval)
+		mean, stdDev := stats.MeanStdDev(returns)
+		if stdDev > 0 {
+			m.SharpeRatio = (mean - config.RiskFreeRate/100/periodsPerYear) / stdDev * math.Sqrt(periodsPerYear)
+		}
+	}
+
+	m.MaxDrawdown = maxDrawdown(candles[0].Time, config.InitialCapital, result.Equity)
+	return m
+}
+
+// maxDrawdown encontra a maior queda do capital face ao máximo anterior, começando no capital
+// inicial no instante start
+func maxDrawdown(start time.Time, initial float64, equity []models.EquityPoint) models.Drawdown {
+	times := []time.Time{start}
+	values := []float64{initial}
+	for _, point := range equity {
+		times, values = append(times, point.Time), append(values, point.Equity)
+	}
+	return stats.MaxDrawdown(times, values)
+}
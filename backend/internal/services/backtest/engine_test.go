@@ -0,0 +1,238 @@
+package backtest
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// scripted é uma estratégia com as decisões fixadas por vela
+type scripted map[int]Action
+
+func (s scripted) Prepare(models.Candles) error { return nil }
+
+func (s scripted) Next(i int, _ Position) Action { return s[i] }
+
+// series constrói velas diárias a partir de (abertura, máximo, mínimo, fecho)
+func series(ohlc ...[4]float64) models.Candles {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make(models.Candles, len(ohlc))
+	for i, c := range ohlc {
+		candles[i] = models.Candle{
+			Time:  start.AddDate(0, 0, i),
+			Open:  c[0],
+			High:  c[1],
+			Low:   c[2],
+			Close: c[3],
+		}
+	}
+	return candles
+}
+
+// fill é o preço e o motivo esperados de uma operação
+type fill struct {
+	entry, exit float64
+	reason      string
+}
+
+// TestRunFills verifica os preços de execução das entradas e saídas, com comissões, slippage,
+// stop-loss e take-profit
+func TestRunFills(t *testing.T) {
+	// Entrada na abertura da vela 1 e saída na abertura da vela 3
+	roundTrip := series(
+		[4]float64{100, 100, 100, 100},
+		[4]float64{100, 104, 98, 102},
+		[4]float64{102, 108, 101, 106},
+		[4]float64{106, 111, 104, 110},
+		[4]float64{110, 112, 107, 108},
+	)
+	synthetic := series(
+		[4]float64{100, 100, 100, 100},
+		[4]float64{100, 100, 100, 100},
+		[4]float64{103, 104, 102, 103},
+	)
+	synthetic[1].Filled = true
+
+	tests := []struct {
+		name      string
+		candles   models.Candles
+		actions   scripted
+		config    models.BacktestConfig
+		want      []fill
+		wantFinal float64
+		wantFees  float64
+	}{
+		{
+			name:      "sem custos",
+			candles:   roundTrip,
+			actions:   scripted{0: Enter, 2: Exit},
+			want:      []fill{{100, 106, ExitSignal}},
+			wantFinal: 1060,
+		},
+		{
+			// 1000 / (100 × 1,01) unidades, com 1% de comissão na entrada e na saída
+			name:      "comissão",
+			candles:   roundTrip,
+			actions:   scripted{0: Enter, 2: Exit},
+			config:    models.BacktestConfig{FeeRate: 0.01},
+			want:      []fill{{100, 106, ExitSignal}},
+			wantFinal: 1000 / 1.01 * 1.06 * 0.99,
+			wantFees:  1000.0/101 + 1000.0/101*1.06,
+		},
+		{
+			name:      "slippage",
+			candles:   roundTrip,
+			actions:   scripted{0: Enter, 2: Exit},
+			config:    models.BacktestConfig{Slippage: 0.01},
+			want:      []fill{{101, 104.94, ExitSignal}},
+			wantFinal: 1000 / 101.0 * 104.94,
+		},
+		{
+			name: "stop-loss ao nível",
+			candles: series(
+				[4]float64{100, 100, 100, 100},
+				[4]float64{100, 103, 99, 101},
+				[4]float64{101, 102, 96, 97},
+				[4]float64{97, 99, 95, 98},
+			),
+			actions:   scripted{0: Enter},
+			config:    models.BacktestConfig{StopLoss: 0.03},
+			want:      []fill{{100, 97, ExitStopLoss}},
+			wantFinal: 970,
+		},
+		{
+			name: "stop-loss com abertura abaixo do nível",
+			candles: series(
+				[4]float64{100, 100, 100, 100},
+				[4]float64{100, 103, 99, 101},
+				[4]float64{92, 94, 90, 93},
+			),
+			actions:   scripted{0: Enter},
+			config:    models.BacktestConfig{StopLoss: 0.03, Slippage: 0.01},
+			want:      []fill{{101, 92 * 0.99, ExitStopLoss}},
+			wantFinal: 1000 / 101.0 * 92 * 0.99,
+		},
+		{
+			name: "take-profit sem slippage",
+			candles: series(
+				[4]float64{100, 100, 100, 100},
+				[4]float64{100, 103, 99, 101},
+				[4]float64{102, 107, 101, 106},
+			),
+			actions:   scripted{0: Enter},
+			config:    models.BacktestConfig{TakeProfit: 0.05, Slippage: 0.01},
+			want:      []fill{{101, 106.05, ExitTakeProfit}},
+			wantFinal: 1050,
+		},
+		{
+			name: "take-profit com abertura acima do nível",
+			candles: series(
+				[4]float64{100, 100, 100, 100},
+				[4]float64{100, 103, 99, 101},
+				[4]float64{108, 109, 107, 108},
+			),
+			actions:   scripted{0: Enter},
+			config:    models.BacktestConfig{TakeProfit: 0.05},
+			want:      []fill{{100, 108, ExitTakeProfit}},
+			wantFinal: 1080,
+		},
+		{
+			name: "stop-loss e take-profit na mesma vela",
+			candles: series(
+				[4]float64{100, 100, 100, 100},
+				[4]float64{100, 103, 99, 101},
+				[4]float64{101, 107, 96, 100},
+			),
+			actions:   scripted{0: Enter},
+			config:    models.BacktestConfig{StopLoss: 0.03, TakeProfit: 0.05},
+			want:      []fill{{100, 97, ExitStopLoss}},
+			wantFinal: 970,
+		},
+		{
+			name:      "vela sintética adia a ordem",
+			candles:   synthetic,
+			actions:   scripted{0: Enter},
+			want:      []fill{{103, 103, ExitEnd}},
+			wantFinal: 1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.InitialCapital = 1000
+			result, err := Run(tt.candles, tt.actions, tt.config, 24*time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.Trades) != len(tt.want) {
+				t.Fatalf("%d operações, esperadas %d: %+v", len(result.Trades), len(tt.want), result.Trades)
+			}
+			for i, want := range tt.want {
+				trade := result.Trades[i]
+				if !near(trade.EntryPrice, want.entry) || !near(trade.ExitPrice, want.exit) || trade.ExitReason != want.reason {
+					t.Errorf("operação %d: entrada %v, saída %v (%s), esperado %v, %v (%s)",
+						i, trade.EntryPrice, trade.ExitPrice, trade.ExitReason, want.entry, want.exit, want.reason)
+				}
+			}
+			if !near(result.Metrics.FinalEquity, tt.wantFinal) {
+				t.Errorf("capital final = %v, esperado %v", result.Metrics.FinalEquity, tt.wantFinal)
+			}
+			if !near(result.Metrics.TotalFees, tt.wantFees) {
+				t.Errorf("comissões = %v, esperado %v", result.Metrics.TotalFees, tt.wantFees)
+			}
+		})
+	}
+}
+
+// TestRunMaxDrawdown verifica a maior queda do capital, medida a partir do capital inicial
+func TestRunMaxDrawdown(t *testing.T) {
+	candles := series(
+		[4]float64{100, 100, 100, 100},
+		[4]float64{100, 100, 100, 100},
+		[4]float64{100, 120, 100, 120},
+		[4]float64{120, 120, 90, 90},
+		[4]float64{90, 125, 90, 125},
+	)
+	result, err := Run(candles, scripted{0: Enter}, models.BacktestConfig{InitialCapital: 1000}, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drawdown := result.Metrics.MaxDrawdown
+	if !near(drawdown.Depth, -25) {
+		t.Errorf("queda = %v, esperado -25", drawdown.Depth)
+	}
+	if !drawdown.PeakAt.Equal(candles[2].Time) || !drawdown.TroughAt.Equal(candles[3].Time) {
+		t.Errorf("queda entre %v e %v, esperado %v e %v", drawdown.PeakAt, drawdown.TroughAt, candles[2].Time, candles[3].Time)
+	}
+	if drawdown.RecoveredAt == nil || !drawdown.RecoveredAt.Equal(candles[4].Time) {
+		t.Errorf("recuperação = %v, esperado %v", drawdown.RecoveredAt, candles[4].Time)
+	}
+}
+
+// TestNormalizeConfigErrors verifica que as configurações inválidas são rejeitadas
+func TestNormalizeConfigErrors(t *testing.T) {
+	tests := []models.BacktestConfig{
+		{InitialCapital: -1},
+		{FeeRate: 1},
+		{Slippage: -0.1},
+		{StopLoss: 1},
+		{TakeProfit: -0.5},
+		{Sizing: models.PositionSizing{Mode: models.SizingPercent, Value: 1.5}},
+		{Sizing: models.PositionSizing{Mode: models.SizingFixed}},
+		{Sizing: models.PositionSizing{Mode: "kelly", Value: 0.5}},
+	}
+	for _, config := range tests {
+		if _, err := NormalizeConfig(config); err == nil {
+			t.Errorf("%+v: esperado erro", config)
+		}
+	}
+}
+
+// near compara dois valores com uma tolerância para os erros de arredondamento
+func near(got, want float64) bool {
+	return math.Abs(got-want) < 1e-9
+}
@@ -0,0 +1,193 @@
+package backtest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/candles"
+	"gofolio/backend/internal/services/strategy"
+
+	"github.com/google/uuid"
+)
+
+// Valores por omissão de um pedido de backtest
+const (
+	DefaultInterval = "1d"
+	DefaultRange    = 90 * 24 * time.Hour // o agendador guarda 90 dias de cotações
+)
+
+// Request é um pedido de backtest. A estratégia é uma das incorporadas (Strategy, com
+// Parameters), uma estratégia declarativa guardada pelo usuário (StrategyID) ou uma definição
+// declarativa enviada com o pedido (Definition).
+type Request struct {
+	Symbol     string                     `json:"symbol"`
+	Interval   string                     `json:"interval"` // por omissão o da definição declarativa ou DefaultInterval
+	From       time.Time                  `json:"from"`
+	To         time.Time                  `json:"to"`
+	Strategy   string                     `json:"strategy"`
+	Parameters map[string]float64         `json:"parameters"`
+	StrategyID string                     `json:"strategyId"`
+	Definition *models.StrategyDefinition `json:"definition"`
+	Config     models.BacktestConfig      `json:"config"`
+}
+
+// Service executa backtests sobre as cotações guardadas pelo agendador e guarda os resultados
+type Service struct {
+	history    models.HistoricalDataRepository
+	repo       models.BacktestRepository
+	strategies models.StrategyRepository
+}
+
+// NewService cria o serviço de backtests. Sem repositório de cotações (nil), os backtests
+// falham com ErrHistoryUnavailable; sem repositório de estratégias (nil), só é possível simular
+// as estratégias incorporadas e as definições enviadas com o pedido.
+func NewService(history models.HistoricalDataRepository, repo models.BacktestRepository, strategies models.StrategyRepository) *Service {
+	return &Service{history: history, repo: repo, strategies: strategies}
+}
+
+// Run executa e guarda um backtest. Sem datas, simula os últimos DefaultRange.
+func (s *Service) Run(userID string, request Request) (*models.Backtest, error) {
+	if s.history == nil {
+		return nil, ErrHistoryUnavailable
+	}
+
+	request.Symbol = strings.ToUpper(strings.TrimSpace(request.Symbol))
+	if request.Symbol == "" {
+		return nil, fmt.Errorf("%w: símbolo obrigatório", ErrInvalidBacktest)
+	}
+
+	var simulated Strategy
+	var parameters map[string]float64
+	var definition *models.StrategyDefinition
+	config := request.Config
+	if request.StrategyID != "" || request.Definition != nil {
+		compiled, err := s.declarative(userID, request)
+		if err != nil {
+			return nil, err
+		}
+		simulated = Declarative(compiled)
+		definition = &compiled.Definition
+		request.Strategy = compiled.Definition.Name
+		config = ApplyDefinition(config, compiled.Definition)
+		if strings.TrimSpace(request.Interval) == "" {
+			request.Interval = compiled.Definition.Interval
+		}
+	} else {
+		var err error
+		if simulated, parameters, err = NewStrategy(request.Strategy, request.Parameters); err != nil {
+			return nil, err
+		}
+	}
+
+	request.Interval = strings.ToLower(strings.TrimSpace(request.Interval))
+	if request.Interval == "" {
+		request.Interval = DefaultInterval
+	}
+	interval, err := candles.ParseInterval(request.Interval)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.To.IsZero() {
+		request.To = time.Now()
+	}
+	if request.From.IsZero() {
+		request.From = request.To.Add(-DefaultRange)
+	}
+	if !request.From.Before(request.To) {
+		return nil, fmt.Errorf("%w: o início deve ser anterior ao fim", ErrInvalidBacktest)
+	}
+
+	config, err = NormalizeConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.history.GetHistoricalData(request.Symbol, request.From, request.To)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrHistoryUnavailable, err)
+	}
+	series, err := candles.FromHistory(data, interval, candles.GapFill)
+	if err != nil {
+		return nil, err
+	}
+	if len(series) < 2 {
+		return nil, fmt.Errorf("%w: %s tem %d velas de %s no período", ErrInsufficientData, request.Symbol, len(series), request.Interval)
+	}
+
+	result, err := Run(series, simulated, config, interval.Duration())
+	if err != nil {
+		return nil, err
+	}
+
+	backtest := &models.Backtest{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		Symbol:     request.Symbol,
+		Interval:   request.Interval,
+		From:       request.From,
+		To:         request.To,
+		Strategy:   request.Strategy,
+		StrategyID: request.StrategyID,
+		Definition: definition,
+		Parameters: parameters,
+		Config:     config,
+		Result:     *result,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.repo.SaveBacktest(backtest); err != nil {
+		return nil, err
+	}
+
+	return backtest, nil
+}
+
+// Get obtém um backtest do usuário
+func (s *Service) Get(userID, id string) (*models.Backtest, error) {
+	backtest, err := s.repo.GetBacktest(id)
+	if err != nil {
+		return nil, err
+	}
+	if backtest.UserID != userID {
+		return nil, models.ErrBacktestNotFound
+	}
+	return backtest, nil
+}
+
+// List obtém os backtests do usuário, apenas com as métricas
+func (s *Service) List(userID string) ([]models.Backtest, error) {
+	return s.repo.ListBacktests(userID)
+}
+
+// Delete remove um backtest do usuário
+func (s *Service) Delete(userID, id string) error {
+	if _, err := s.Get(userID, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteBacktest(id)
+}
+
+// declarative valida a estratégia declarativa de um pedido: a guardada pelo usuário com o
+// identificador indicado ou a definição enviada
+func (s *Service) declarative(userID string, request Request) (*strategy.Compiled, error) {
+	if request.StrategyID != "" && request.Definition != nil {
+		return nil, fmt.Errorf("%w: indique a estratégia guardada ou a definição, não ambas", ErrInvalidBacktest)
+	}
+	if request.Definition != nil {
+		return strategy.Compile(*request.Definition)
+	}
+
+	if s.strategies == nil {
+		return nil, models.ErrStrategyNotFound
+	}
+	stored, err := s.strategies.GetStrategy(request.StrategyID)
+	if err != nil {
+		return nil, err
+	}
+	if stored.UserID != userID {
+		return nil, models.ErrStrategyNotFound
+	}
+	return strategy.Compile(stored.Definition)
+}
@@ -0,0 +1,224 @@
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/indicators"
+)
+
+// ErrUnknownStrategy é retornado quando a estratégia pedida não existe
+var ErrUnknownStrategy = errors.New("estratégia desconhecida")
+
+// StrategyInfo descreve uma estratégia incorporada e os seus parâmetros por omissão
+type StrategyInfo struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Parameters  map[string]float64 `json:"parameters"`
+}
+
+// builtin é uma estratégia incorporada: a descrição e o construtor a partir dos parâmetros,
+// já completados com os valores por omissão
+type builtin struct {
+	info  StrategyInfo
+	build func(params map[string]float64) (Strategy, error)
+}
+
+var builtins = map[string]builtin{
+	"buy_and_hold": {
+		info: StrategyInfo{
+			Description: "Mantém a posição comprada durante todo o período",
+			Parameters:  map[string]float64{},
+		},
+		build: func(map[string]float64) (Strategy, error) {
+			return buyAndHold{}, nil
+		},
+	},
+	"sma_cross": {
+		info: StrategyInfo{
+			Description: "Compra quando a média móvel rápida cruza acima da lenta e vende no cruzamento inverso",
+			Parameters:  map[string]float64{"fast": 20, "slow": 50},
+		},
+		build: func(params map[string]float64) (Strategy, error) {
+			if params["fast"] >= params["slow"] {
+				return nil, fmt.Errorf("%w: o período rápido deve ser menor que o lento", ErrInvalidBacktest)
+			}
+			return &smaCross{fast: int(params["fast"]), slow: int(params["slow"])}, nil
+		},
+	},
+	"rsi": {
+		info: StrategyInfo{
+			Description: "Compra quando o RSI desce abaixo do nível de sobrevenda e vende acima do de sobrecompra",
+			Parameters:  map[string]float64{"period": 14, "oversold": 30, "overbought": 70},
+		},
+		build: func(params map[string]float64) (Strategy, error) {
+			if params["oversold"] <= 0 || params["overbought"] >= 100 || params["oversold"] >= params["overbought"] {
+				return nil, fmt.Errorf("%w: os níveis do RSI devem estar entre 0 e 100, com a sobrevenda abaixo da sobrecompra", ErrInvalidBacktest)
+			}
+			return &rsiReversion{period: int(params["period"]), oversold: params["oversold"], overbought: params["overbought"]}, nil
+		},
+	},
+	"macd": {
+		info: StrategyInfo{
+			Description: "Compra quando a linha MACD cruza acima da linha de sinal e vende no cruzamento inverso",
+			Parameters:  map[string]float64{"fast": 12, "slow": 26, "signal": 9},
+		},
+		build: func(params map[string]float64) (Strategy, error) {
+			if params["fast"] >= params["slow"] {
+				return nil, fmt.Errorf("%w: o período rápido deve ser menor que o lento", ErrInvalidBacktest)
+			}
+			return &macdCross{fast: int(params["fast"]), slow: int(params["slow"]), signal: int(params["signal"])}, nil
+		},
+	},
+}
+
+// Strategies lista as estratégias incorporadas, por nome
+func Strategies() []StrategyInfo {
+	result := make([]StrategyInfo, 0, len(builtins))
+	for name, strategy := range builtins {
+		info := strategy.info
+		info.Name = name
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// NewStrategy cria uma estratégia incorporada. Os parâmetros em falta tomam os valores por
+// omissão; retorna também os parâmetros completos, para guardar com o resultado. Todos os
+// parâmetros das estratégias incorporadas são períodos ou níveis positivos.
+func NewStrategy(name string, params map[string]float64) (Strategy, map[string]float64, error) {
+	strategy, ok := builtins[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, name)
+	}
+
+	resolved := make(map[string]float64, len(strategy.info.Parameters))
+	for key, value := range strategy.info.Parameters {
+		resolved[key] = value
+	}
+	for key, value := range params {
+		if _, ok := resolved[key]; !ok {
+			return nil, nil, fmt.Errorf("%w: parâmetro desconhecido para %s: %s", ErrInvalidBacktest, name, key)
+		}
+		if value <= 0 {
+			return nil, nil, fmt.Errorf("%w: o parâmetro %s deve ser positivo", ErrInvalidBacktest, key)
+		}
+		resolved[key] = value
+	}
+
+	built, err := strategy.build(resolved)
+	if err != nil {
+		return nil, nil, err
+	}
+	return built, resolved, nil
+}
+
+// buyAndHold compra na primeira vela e nunca vende. Depois de um stop-loss ou take-profit
+// volta a comprar.
+type buyAndHold struct{}
+
+func (buyAndHold) Prepare(models.Candles) error { return nil }
+
+func (buyAndHold) Next(_ int, position Position) Action {
+	if position.Open {
+		return Hold
+	}
+	return Enter
+}
+
+// crossStrategy decide pelo cruzamento de duas séries: entra quando a primeira cruza acima da
+// segunda e sai quando cruza abaixo
+type crossStrategy struct {
+	line, signal []float64
+}
+
+func (s *crossStrategy) Next(i int, position Position) Action {
+	if i < 1 || !defined(s.line[i-1], s.line[i], s.signal[i-1], s.signal[i]) {
+		return Hold
+	}
+	switch {
+	case !position.Open && s.line[i-1] <= s.signal[i-1] && s.line[i] > s.signal[i]:
+		return Enter
+	case position.Open && s.line[i-1] >= s.signal[i-1] && s.line[i] < s.signal[i]:
+		return Exit
+	}
+	return Hold
+}
+
+// smaCross cruza as médias móveis simples rápida e lenta
+type smaCross struct {
+	crossStrategy
+	fast, slow int
+}
+
+func (s *smaCross) Prepare(candles models.Candles) error {
+	fast, err := indicators.SMA(candles.Closes(), s.fast)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBacktest, err)
+	}
+	slow, err := indicators.SMA(candles.Closes(), s.slow)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBacktest, err)
+	}
+	s.line, s.signal = fast, slow
+	return nil
+}
+
+// macdCross cruza a linha MACD com a linha de sinal
+type macdCross struct {
+	crossStrategy
+	fast, slow, signal int
+}
+
+func (s *macdCross) Prepare(candles models.Candles) error {
+	macd, err := indicators.MACD(candles.Closes(), s.fast, s.slow, s.signal)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBacktest, err)
+	}
+	s.line, s.crossStrategy.signal = macd.MACD, macd.Signal
+	return nil
+}
+
+// rsiReversion compra em sobrevenda e vende em sobrecompra
+type rsiReversion struct {
+	period               int
+	oversold, overbought float64
+	rsi                  []float64
+}
+
+func (s *rsiReversion) Prepare(candles models.Candles) error {
+	rsi, err := indicators.RSI(candles.Closes(), s.period)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBacktest, err)
+	}
+	s.rsi = rsi
+	return nil
+}
+
+func (s *rsiReversion) Next(i int, position Position) Action {
+	if !indicators.Valid(s.rsi[i]) {
+		return Hold
+	}
+	switch {
+	case !position.Open && s.rsi[i] < s.oversold:
+		return Enter
+	case position.Open && s.rsi[i] > s.overbought:
+		return Exit
+	}
+	return Hold
+}
+
+// defined indica se todos os valores estão definidos
+func defined(values ...float64) bool {
+	for _, v := range values {
+		if !indicators.Valid(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,184 @@
+// Package candles agrega preços em velas OHLCV (models.Candle) com intervalos fixos, a partir
+// de cotações pontuais ou de velas de um intervalo menor.
+//
+// Os intervalos são alinhados em UTC: as velas horárias começam à hora certa, as de 4 horas às
+// 0h, 4h, 8h, ..., as diárias à meia-noite e as semanais à segunda-feira.
+package candles
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// ErrInvalidInterval é retornado para intervalos não suportados
+var ErrInvalidInterval = errors.New("intervalo inválido")
+
+// Interval é a duração de cada vela
+type Interval string
+
+// Intervalos suportados
+const (
+	Interval1m Interval = "1m"
+	Interval5m Interval = "5m"
+	Interval1h Interval = "1h"
+	Interval4h Interval = "4h"
+	Interval1d Interval = "1d"
+	Interval1w Interval = "1w"
+)
+
+var intervalDurations = map[Interval]time.Duration{
+	Interval1m: time.Minute,
+	Interval5m: 5 * time.Minute,
+	Interval1h: time.Hour,
+	Interval4h: 4 * time.Hour,
+	Interval1d: 24 * time.Hour,
+	Interval1w: 7 * 24 * time.Hour,
+}
+
+// ParseInterval valida um intervalo ("1m", "5m", "1h", "4h", "1d" ou "1w")
+func ParseInterval(value string) (Interval, error) {
+	interval := Interval(value)
+	if _, ok := intervalDurations[interval]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrInvalidInterval, value)
+	}
+	return interval, nil
+}
+
+// Duration retorna a duração de uma vela do intervalo
+func (i Interval) Duration() time.Duration {
+	return intervalDurations[i]
+}
+
+// Start retorna o início da vela do intervalo que contém t. O truncamento é feito a partir do
+// instante zero do Go (uma segunda-feira, em UTC), pelo que as semanas começam à segunda-feira.
+func (i Interval) Start(t time.Time) time.Time {
+	return t.UTC().Truncate(i.Duration())
+}
+
+// GapPolicy indica o que fazer com os intervalos sem dados entre a primeira e a última vela
+type GapPolicy int
+
+const (
+	// GapSkip omite os intervalos sem dados; as velas deixam de estar igualmente espaçadas
+	GapSkip GapPolicy = iota
+	// GapFill preenche cada intervalo sem dados com uma vela sintética (Filled) ao preço do
+	// fecho anterior e sem volume, mantendo as velas igualmente espaçadas
+	GapFill
+)
+
+// Tick é uma cotação pontual, com o volume negociado desde a cotação anterior
+type Tick struct {
+	Time   time.Time
+	Price  float64
+	Volume float64
+}
+
+// FromTicks agrega cotações pontuais em velas do intervalo indicado
+func FromTicks(ticks []Tick, interval Interval, gaps GapPolicy) (models.Candles, error) {
+	points := make(models.Candles, len(ticks))
+	for i, tick := range ticks {
+		points[i] = models.Candle{
+			Time:   tick.Time,
+			Open:   tick.Price,
+			High:   tick.Price,
+			Low:    tick.Price,
+			Close:  tick.Price,
+			Volume: tick.Volume,
+		}
+	}
+	return Resample(points, interval, gaps)
+}
+
+// FromHistory agrega as cotações guardadas pelo agendador em velas do intervalo indicado. O
+// volume das cotações é o volume das últimas 24 horas, pelo que o volume de cada vela é uma
+// estimativa: a média desses volumes proporcional à duração do intervalo.
+func FromHistory(data []models.HistoricalData, interval Interval, gaps GapPolicy) (models.Candles, error) {
+	ticks := make([]Tick, len(data))
+	dailyVolumes := map[time.Time][]float64{}
+	for i, d := range data {
+		ticks[i] = Tick{Time: d.Timestamp, Price: d.Price}
+		start := interval.Start(d.Timestamp)
+		dailyVolumes[start] = append(dailyVolumes[start], d.Volume)
+	}
+
+	result, err := FromTicks(ticks, interval, gaps)
+	if err != nil {
+		return nil, err
+	}
+
+	share := float64(interval.Duration()) / float64(24*time.Hour)
+	for i := range result {
+		volumes := dailyVolumes[result[i].Time]
+		if len(volumes) == 0 {
+			continue
+		}
+		total := 0.0
+		for _, volume := range volumes {
+			total += volume
+		}
+		result[i].Volume = total / float64(len(volumes)) * share
+	}
+	return result, nil
+}
+
+// Resample agrega velas num intervalo maior: cada vela resultante tem a abertura da primeira vela
+// do intervalo, o fecho da última, o máximo e o mínimo de todas e a soma dos volumes. As velas
+// são atribuídas ao intervalo pelo seu início, pelo que devem ter uma duração não superior à do
+// intervalo pedido. A entrada não precisa de estar ordenada e não é alterada.
+func Resample(input models.Candles, interval Interval, gaps GapPolicy) (models.Candles, error) {
+	if _, ok := intervalDurations[interval]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidInterval, interval)
+	}
+
+	sorted := make(models.Candles, len(input))
+	copy(sorted, input)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	result := models.Candles{}
+	for _, candle := range sorted {
+		start := interval.Start(candle.Time)
+		if n := len(result); n > 0 && result[n-1].Time.Equal(start) {
+			merge(&result[n-1], candle)
+			continue
+		}
+		if n := len(result); n > 0 && gaps == GapFill {
+			result = fill(result, start, interval)
+		}
+		candle.Time = start
+		result = append(result, candle)
+	}
+	return result, nil
+}
+
+// merge junta uma vela posterior à vela do intervalo
+func merge(bucket *models.Candle, candle models.Candle) {
+	if candle.High > bucket.High {
+		bucket.High = candle.High
+	}
+	if candle.Low < bucket.Low {
+		bucket.Low = candle.Low
+	}
+	bucket.Close = candle.Close
+	bucket.Volume += candle.Volume
+	bucket.Filled = bucket.Filled && candle.Filled
+}
+
+// fill acrescenta velas sintéticas para cada intervalo sem dados entre a última vela e next
+func fill(result models.Candles, next time.Time, interval Interval) models.Candles {
+	last := result[len(result)-1]
+	for t := last.Time.Add(interval.Duration()); t.Before(next); t = t.Add(interval.Duration()) {
+		result = append(result, models.Candle{
+			Time:   t,
+			Open:   last.Close,
+			High:   last.Close,
+			Low:    last.Close,
+			Close:  last.Close,
+			Filled: true,
+		})
+	}
+	return result
+}
@@ -0,0 +1,182 @@
+package candles
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// at retorna o instante de 2024-01-d às h horas, em UTC. O dia 1 é uma segunda-feira.
+func at(d, h int) time.Time {
+	return time.Date(2024, 1, d, h, 0, 0, 0, time.UTC)
+}
+
+// candle constrói uma vela com a abertura, o máximo, o mínimo, o fecho e o volume indicados
+func candle(t time.Time, open, high, low, close, volume float64) models.Candle {
+	return models.Candle{Time: t, Open: open, High: high, Low: low, Close: close, Volume: volume}
+}
+
+// assertCandles compara duas séries de velas
+func assertCandles(t *testing.T, name string, got, want models.Candles) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d velas, esperadas %d: %+v", name, len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) || got[i].Open != want[i].Open || got[i].High != want[i].High ||
+			got[i].Low != want[i].Low || got[i].Close != want[i].Close ||
+			math.Abs(got[i].Volume-want[i].Volume) > 1e-9 || got[i].Filled != want[i].Filled {
+			t.Errorf("%s[%d] = %+v, esperado %+v", name, i, got[i], want[i])
+		}
+	}
+}
+
+// TestResampleAlignment verifica o alinhamento das velas de 4 horas às 0h, 4h, 8h, ... e das
+// semanais à segunda-feira, incluindo velas com outro fuso horário
+func TestResampleAlignment(t *testing.T) {
+	plus2 := time.FixedZone("UTC+2", 2*60*60)
+
+	tests := []struct {
+		name     string
+		input    models.Candles
+		interval Interval
+		want     models.Candles
+	}{
+		{
+			name: "4h",
+			input: models.Candles{
+				candle(at(1, 1), 10, 12, 9, 11, 1),
+				candle(at(1, 3), 11, 15, 10, 14, 2),
+				candle(at(1, 4), 14, 14, 13, 13, 3),
+				candle(at(1, 7), 13, 16, 8, 15, 4),
+				candle(at(1, 8), 15, 15, 15, 15, 5),
+			},
+			interval: Interval4h,
+			want: models.Candles{
+				candle(at(1, 0), 10, 15, 9, 14, 3),
+				candle(at(1, 4), 14, 16, 8, 15, 7),
+				candle(at(1, 8), 15, 15, 15, 15, 5),
+			},
+		},
+		{
+			// Domingo às 23h em UTC é segunda-feira à 1h em UTC+2
+			name: "semana a começar à segunda-feira",
+			input: models.Candles{
+				candle(at(3, 0), 10, 11, 9, 10, 1),
+				candle(time.Date(2024, 1, 8, 1, 0, 0, 0, plus2), 10, 12, 10, 12, 1),
+				candle(at(8, 0), 12, 13, 7, 8, 1),
+				candle(at(14, 23), 8, 9, 8, 9, 1),
+			},
+			interval: Interval1w,
+			want: models.Candles{
+				candle(at(1, 0), 10, 12, 9, 12, 2),
+				candle(at(8, 0), 12, 13, 7, 9, 2),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Resample(tt.input, tt.interval, GapSkip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCandles(t, tt.name, got, tt.want)
+		if got[0].Time.Location() != time.UTC {
+			t.Errorf("%s: velas em %v, esperado UTC", tt.name, got[0].Time.Location())
+		}
+	}
+}
+
+// TestResampleGaps compara o preenchimento dos dias sem dados com a sua omissão
+func TestResampleGaps(t *testing.T) {
+	input := models.Candles{
+		candle(at(1, 0), 10, 12, 9, 11, 5),
+		candle(at(4, 6), 11, 13, 10, 12, 2),
+		candle(at(5, 0), 12, 12, 11, 11, 1),
+	}
+
+	skipped, err := Resample(input, Interval1d, GapSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCandles(t, "GapSkip", skipped, models.Candles{
+		candle(at(1, 0), 10, 12, 9, 11, 5),
+		candle(at(4, 0), 11, 13, 10, 12, 2),
+		candle(at(5, 0), 12, 12, 11, 11, 1),
+	})
+
+	// Os dias 2 e 3 repetem o fecho do dia 1, sem volume
+	filled, err := Resample(input, Interval1d, GapFill)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gap := candle(at(2, 0), 11, 11, 11, 11, 0)
+	gap.Filled = true
+	nextGap := gap
+	nextGap.Time = at(3, 0)
+	assertCandles(t, "GapFill", filled, models.Candles{
+		candle(at(1, 0), 10, 12, 9, 11, 5),
+		gap,
+		nextGap,
+		candle(at(4, 0), 11, 13, 10, 12, 2),
+		candle(at(5, 0), 12, 12, 11, 11, 1),
+	})
+}
+
+// TestResampleUnsorted verifica que a entrada é ordenada sem ser alterada, com a abertura e o
+// fecho tirados da primeira e da última vela de cada intervalo
+func TestResampleUnsorted(t *testing.T) {
+	input := models.Candles{
+		candle(at(1, 2), 12, 14, 11, 13, 1),
+		candle(at(1, 5), 20, 21, 19, 20, 1),
+		candle(at(1, 0), 10, 11, 9, 11, 1),
+		candle(at(1, 1), 11, 12, 10, 12, 1),
+	}
+	original := make(models.Candles, len(input))
+	copy(original, input)
+
+	got, err := Resample(input, Interval4h, GapSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCandles(t, "Resample", got, models.Candles{
+		candle(at(1, 0), 10, 14, 9, 13, 3),
+		candle(at(1, 4), 20, 21, 19, 20, 1),
+	})
+	assertCandles(t, "entrada", input, original)
+
+	if _, err := Resample(input, Interval("2h"), GapSkip); !errors.Is(err, ErrInvalidInterval) {
+		t.Errorf("erro %v, esperado ErrInvalidInterval", err)
+	}
+}
+
+// TestFromHistory verifica as velas de 4 horas das cotações do agendador, com o volume
+// estimado como a média dos volumes de 24 horas proporcional às 4 horas
+func TestFromHistory(t *testing.T) {
+	data := []models.HistoricalData{
+		{Symbol: "BTC", Price: 102, Volume: 2400, Timestamp: at(1, 2)},
+		{Symbol: "BTC", Price: 100, Volume: 1200, Timestamp: at(1, 0)},
+		{Symbol: "BTC", Price: 99, Volume: 3600, Timestamp: at(1, 3)},
+		{Symbol: "BTC", Price: 105, Volume: 6000, Timestamp: at(1, 13)},
+	}
+
+	got, err := FromHistory(data, Interval4h, GapFill)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gap := func(h int) models.Candle {
+		c := candle(at(1, h), 99, 99, 99, 99, 0)
+		c.Filled = true
+		return c
+	}
+	assertCandles(t, "FromHistory", got, models.Candles{
+		candle(at(1, 0), 100, 102, 99, 99, 400),
+		gap(4),
+		gap(8),
+		candle(at(1, 12), 105, 105, 105, 105, 1000),
+	})
+}
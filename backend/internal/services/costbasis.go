@@ -0,0 +1,362 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// ErrUnknownCostBasisMethod é retornado quando o método de custo não é suportado
+var ErrUnknownCostBasisMethod = errors.New("método de custo desconhecido")
+
+// LotMatcher define a ordem pela qual os lotes em aberto são consumidos numa alienação
+type LotMatcher interface {
+	// Order ordena os lotes pela ordem de consumo
+	Order(lots []*models.Lot)
+	// Pooled indica se todos os lotes partilham o mesmo custo unitário (custo médio)
+	Pooled() bool
+}
+
+type fifoMatcher struct{}
+
+func (fifoMatcher) Order(lots []*models.Lot) {
+	sort.SliceStable(lots, func(i, j int) bool { return lots[i].AcquiredAt.Before(lots[j].AcquiredAt) })
+}
+func (fifoMatcher) Pooled() bool { return false }
+
+type lifoMatcher struct{}
+
+func (lifoMatcher) Order(lots []*models.Lot) {
+	sort.SliceStable(lots, func(i, j int) bool { return lots[i].AcquiredAt.After(lots[j].AcquiredAt) })
+}
+func (lifoMatcher) Pooled() bool { return false }
+
+type hifoMatcher struct{}
+
+func (hifoMatcher) Order(lots []*models.Lot) {
+	sort.SliceStable(lots, func(i, j int) bool {
+		if lots[i].UnitCost != lots[j].UnitCost {
+			return lots[i].UnitCost > lots[j].UnitCost
+		}
+		return lots[i].AcquiredAt.Before(lots[j].AcquiredAt)
+	})
+}
+func (hifoMatcher) Pooled() bool { return false }
+
+// averageMatcher consome os lotes por ordem de aquisição (para as datas de aquisição),
+// mas todos têm o custo médio ponderado da posição
+type averageMatcher struct{ fifoMatcher }
+
+func (averageMatcher) Pooled() bool { return true }
+
+// lotMatchers associa cada método de custo à sua estratégia
+var lotMatchers = map[models.CostBasisMethod]LotMatcher{
+	models.CostBasisFIFO:    fifoMatcher{},
+	models.CostBasisLIFO:    lifoMatcher{},
+	models.CostBasisHIFO:    hifoMatcher{},
+	models.CostBasisAverage: averageMatcher{},
+}
+
+// CostBasisReport é o resultado da associação de lotes sobre o livro de transações
+type CostBasisReport struct {
+	Method          models.CostBasisMethod `json:"method"`
+	Currency        string                 `json:"currency"`
+	Lots            []models.Lot           `json:"lots"`      // lotes em aberto
+	Disposals       []models.Disposal      `json:"disposals"` // alienações, uma por lote consumido
+	Holdings        []models.Holding       `json:"holdings"`
+	RealizedPnL     float64                `json:"realizedPnl"`
+	UnrealizedPnL   float64                `json:"unrealizedPnl"`
+	UnpricedSymbols []string               `json:"unpricedSymbols,omitempty"` // ativos sem preço atual
+}
+
+// CostBasisEngine associa as alienações aos lotes adquiridos segundo um LotMatcher
+type CostBasisEngine struct {
+	method  models.CostBasisMethod
+	matcher LotMatcher
+}
+
+// NewCostBasisEngine cria um motor de custo para o método indicado
+func NewCostBasisEngine(method models.CostBasisMethod) (*CostBasisEngine, error) {
+	matcher, ok := lotMatchers[method]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCostBasisMethod, method)
+	}
+
+	return &CostBasisEngine{method: method, matcher: matcher}, nil
+}
+
+// costBasisState guarda os lotes em aberto e os totais durante a leitura do livro
+type costBasisState struct {
+	matcher      LotMatcher
+	lots         map[string][]*models.Lot
+	fees         map[string]float64
+	lastActivity map[string]time.Time
+	disposals    []models.Disposal
+}
+
+// Run percorre o livro por ordem cronológica e produz os lotes em aberto e as alienações.
+// Retorna ErrInsufficientBalance se alguma saída exceder o saldo disponível nessa data.
+func (e *CostBasisEngine) Run(transactions []models.Transaction) (*CostBasisReport, error) {
+	state := &costBasisState{
+		matcher:      e.matcher,
+		lots:         map[string][]*models.Lot{},
+		fees:         map[string]float64{},
+		lastActivity: map[string]time.Time{},
+	}
+
+	for _, tx := range sortTransactions(transactions) {
+		if err := state.apply(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	return state.report(e.method), nil
+}
+
+// apply aplica uma transação ao estado
+func (s *costBasisState) apply(tx models.Transaction) error {
+	feeInAsset := tx.Fee > 0 && tx.FeeCurrency == tx.Symbol
+	s.lastActivity[tx.Symbol] = tx.Timestamp
+
+	switch tx.Type {
+	case models.TransactionBuy, models.TransactionDeposit, models.TransactionStakingReward:
+		quantity := tx.Quantity
+		cost := tx.Quantity * tx.Price
+		if feeInAsset {
+			// A taxa reduz as unidades recebidas; o custo pago mantém-se
+			quantity -= tx.Fee
+		} else if tx.Type == models.TransactionBuy && isQuoteFee(tx) {
+			// Taxas de compra fazem parte do custo de aquisição
+			cost += tx.Fee
+		}
+		s.acquire(tx, quantity, cost)
+	case models.TransactionSell:
+		proceeds := tx.Quantity * tx.Price
+		if tx.Fee > 0 && isQuoteFee(tx) {
+			proceeds -= tx.Fee
+		}
+		if err := s.dispose(tx, tx.Symbol, tx.Quantity, proceeds, models.TransactionSell); err != nil {
+			return err
+		}
+	case models.TransactionWithdrawal:
+		// Os lotes saem do portfólio sem gerar ganho ou perda
+		if err := s.dispose(tx, tx.Symbol, tx.Quantity, 0, ""); err != nil {
+			return err
+		}
+	case models.TransactionFee:
+		if err := s.dispose(tx, tx.Symbol, tx.Quantity, 0, models.TransactionFee); err != nil {
+			return err
+		}
+		s.fees[tx.Symbol] += tx.Quantity * tx.Price
+		return nil
+	}
+
+	if tx.Fee <= 0 {
+		return nil
+	}
+
+	switch {
+	case isQuoteFee(tx):
+		s.fees[tx.Symbol] += tx.Fee
+	case feeInAsset:
+		s.fees[tx.Symbol] += tx.Fee * tx.Price
+		if !isAcquisition(tx.Type) {
+			return s.dispose(tx, tx.Symbol, tx.Fee, 0, models.TransactionFee)
+		}
+	default:
+		// Taxa paga noutro ativo (ex.: BNB)
+		s.lastActivity[tx.FeeCurrency] = tx.Timestamp
+		return s.dispose(tx, tx.FeeCurrency, tx.Fee, 0, models.TransactionFee)
+	}
+
+	return nil
+}
+
+// acquire abre um novo lote
+func (s *costBasisState) acquire(tx models.Transaction, quantity, cost float64) {
+	if quantity <= quantityEpsilon {
+		return
+	}
+
+	lots := append(s.lots[tx.Symbol], &models.Lot{
+		TransactionID: tx.ID,
+		Symbol:        tx.Symbol,
+		Quantity:      quantity,
+		UnitCost:      cost / quantity,
+		AcquiredAt:    tx.Timestamp,
+	})
+
+	if s.matcher.Pooled() {
+		var totalQuantity, totalCost float64
+		for _, lot := range lots {
+			totalQuantity += lot.Quantity
+			totalCost += lot.Quantity * lot.UnitCost
+		}
+		for _, lot := range lots {
+			lot.UnitCost = totalCost / totalQuantity
+		}
+	}
+
+	s.lots[tx.Symbol] = lots
+}
+
+// dispose consome lotes em aberto. Quando disposalType não é vazio, regista uma
+// alienação por lote consumido, repartindo o valor recebido proporcionalmente.
+func (s *costBasisState) dispose(tx models.Transaction, symbol string, quantity, proceeds float64, disposalType models.TransactionType) error {
+	lots := s.lots[symbol]
+
+	var available float64
+	for _, lot := range lots {
+		available += lot.Quantity
+	}
+	if quantity > available+quantityEpsilon {
+		return fmt.Errorf("%w: %s em %s (disponível %g, pedido %g)",
+			ErrInsufficientBalance, symbol, tx.Timestamp.Format(time.RFC3339), available, quantity)
+	}
+
+	s.matcher.Order(lots)
+
+	remaining := quantity
+	for _, lot := range lots {
+		if remaining <= quantityEpsilon {
+			break
+		}
+
+		take := lot.Quantity
+		if remaining < take {
+			take = remaining
+		}
+
+		if disposalType != "" {
+			cost := take * lot.UnitCost
+			share := proceeds * (take / quantity)
+			s.disposals = append(s.disposals, models.Disposal{
+				TransactionID: tx.ID,
+				Type:          disposalType,
+				Symbol:        symbol,
+				Quantity:      take,
+				AcquiredAt:    lot.AcquiredAt,
+				DisposedAt:    tx.Timestamp,
+				Proceeds:      share,
+				CostBasis:     cost,
+				RealizedPnL:   share - cost,
+			})
+		}
+
+		lot.Quantity -= take
+		remaining -= take
+	}
+
+	// Remover lotes esgotados
+	open := lots[:0]
+	for _, lot := range lots {
+		if lot.Quantity > quantityEpsilon {
+			open = append(open, lot)
+		}
+	}
+	s.lots[symbol] = open
+
+	return nil
+}
+
+// report agrega os lotes em aberto em posições e totais
+func (s *costBasisState) report(method models.CostBasisMethod) *CostBasisReport {
+	report := &CostBasisReport{
+		Method:    method,
+		Lots:      []models.Lot{},
+		Disposals: s.disposals,
+		Holdings:  []models.Holding{},
+	}
+	if report.Disposals == nil {
+		report.Disposals = []models.Disposal{}
+	}
+
+	for _, d := range s.disposals {
+		report.RealizedPnL += d.RealizedPnL
+	}
+
+	for symbol, lots := range s.lots {
+		if len(lots) == 0 {
+			continue
+		}
+
+		fifoMatcher{}.Order(lots)
+		holding := models.Holding{
+			Symbol:        symbol,
+			FeesPaid:      s.fees[symbol],
+			FirstAcquired: lots[0].AcquiredAt,
+			LastActivity:  s.lastActivity[symbol],
+		}
+		for _, lot := range lots {
+			lot.CostBasis = lot.Quantity * lot.UnitCost
+			holding.Quantity += lot.Quantity
+			holding.CostBasis += lot.CostBasis
+			report.Lots = append(report.Lots, *lot)
+		}
+		holding.AverageCost = holding.CostBasis / holding.Quantity
+		report.Holdings = append(report.Holdings, holding)
+	}
+
+	sort.SliceStable(report.Lots, func(i, j int) bool {
+		if report.Lots[i].Symbol != report.Lots[j].Symbol {
+			return report.Lots[i].Symbol < report.Lots[j].Symbol
+		}
+		return report.Lots[i].AcquiredAt.Before(report.Lots[j].AcquiredAt)
+	})
+	sort.Slice(report.Holdings, func(i, j int) bool {
+		return report.Holdings[i].Symbol < report.Holdings[j].Symbol
+	})
+
+	return report
+}
+
+// ApplyPrices valoriza os lotes em aberto aos preços atuais e calcula o P&L não realizado.
+// Ativos sem preço ficam listados em UnpricedSymbols e não contam para o total.
+func (r *CostBasisReport) ApplyPrices(prices map[string]float64) {
+	r.UnrealizedPnL = 0
+	r.UnpricedSymbols = nil
+	unpriced := map[string]bool{}
+
+	for i := range r.Lots {
+		lot := &r.Lots[i]
+		price, ok := prices[lot.Symbol]
+		if !ok || price <= 0 {
+			lot.CurrentPrice, lot.MarketValue, lot.UnrealizedPnL = 0, 0, 0
+			unpriced[lot.Symbol] = true
+			continue
+		}
+
+		lot.CurrentPrice = price
+		lot.MarketValue = lot.Quantity * price
+		lot.UnrealizedPnL = lot.MarketValue - lot.CostBasis
+		r.UnrealizedPnL += lot.UnrealizedPnL
+	}
+
+	for symbol := range unpriced {
+		r.UnpricedSymbols = append(r.UnpricedSymbols, symbol)
+	}
+	sort.Strings(r.UnpricedSymbols)
+}
+
+// ComputeHoldings deriva as posições atuais a partir do livro de transações com o método indicado
+func ComputeHoldings(transactions []models.Transaction, method models.CostBasisMethod) ([]models.Holding, error) {
+	engine, err := NewCostBasisEngine(method)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := engine.Run(transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	return report.Holdings, nil
+}
+
+// isAcquisition indica se o tipo de transação abre um lote
+func isAcquisition(t models.TransactionType) bool {
+	return t == models.TransactionBuy || t == models.TransactionDeposit || t == models.TransactionStakingReward
+}
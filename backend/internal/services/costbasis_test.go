@@ -0,0 +1,156 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// day é o instante do dia indicado de janeiro de 2024
+func day(d int) time.Time {
+	return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+}
+
+// trade constrói uma transação de BTC cotada em USD, sem taxas
+func trade(id string, txType models.TransactionType, quantity, price float64, d int) models.Transaction {
+	return models.Transaction{
+		ID:            id,
+		Type:          txType,
+		Symbol:        "BTC",
+		Quantity:      quantity,
+		Price:         price,
+		QuoteCurrency: "USD",
+		Timestamp:     day(d),
+	}
+}
+
+// consumed é a parte de um lote esperada numa alienação
+type consumed struct {
+	acquired int // dia de aquisição do lote
+	quantity float64
+	cost     float64
+}
+
+// TestCostBasisDisposals compara os lotes consumidos por cada método numa venda de 1,5 BTC a 400,
+// depois de compras de 1 BTC a 100, 300 e 200
+func TestCostBasisDisposals(t *testing.T) {
+	ledger := []models.Transaction{
+		trade("b1", models.TransactionBuy, 1, 100, 1),
+		trade("b2", models.TransactionBuy, 1, 300, 2),
+		trade("b3", models.TransactionBuy, 1, 200, 3),
+		trade("s1", models.TransactionSell, 1.5, 400, 4),
+	}
+
+	tests := []struct {
+		method        models.CostBasisMethod
+		want          []consumed
+		wantRealized  float64
+		wantRemaining float64 // custo das unidades em carteira
+	}{
+		{models.CostBasisFIFO, []consumed{{1, 1, 100}, {2, 0.5, 150}}, 350, 350},
+		{models.CostBasisLIFO, []consumed{{3, 1, 200}, {2, 0.5, 150}}, 250, 250},
+		{models.CostBasisHIFO, []consumed{{2, 1, 300}, {3, 0.5, 100}}, 200, 200},
+		{models.CostBasisAverage, []consumed{{1, 1, 200}, {2, 0.5, 100}}, 300, 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method), func(t *testing.T) {
+			engine, err := NewCostBasisEngine(tt.method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			report, err := engine.Run(ledger)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(report.Disposals) != len(tt.want) {
+				t.Fatalf("%d alienações, esperadas %d: %+v", len(report.Disposals), len(tt.want), report.Disposals)
+			}
+			for i, want := range tt.want {
+				d := report.Disposals[i]
+				if !d.AcquiredAt.Equal(day(want.acquired)) || !approx(d.Quantity, want.quantity) || !approx(d.CostBasis, want.cost) {
+					t.Errorf("alienação %d: lote de %s, %v unidades, custo %v; esperado dia %d, %v, %v",
+						i, d.AcquiredAt.Format("2006-01-02"), d.Quantity, d.CostBasis, want.acquired, want.quantity, want.cost)
+				}
+				if !approx(d.Proceeds, want.quantity*400) {
+					t.Errorf("alienação %d: valor recebido %v, esperado %v", i, d.Proceeds, want.quantity*400)
+				}
+			}
+
+			if !approx(report.RealizedPnL, tt.wantRealized) {
+				t.Errorf("P&L realizado = %v, esperado %v", report.RealizedPnL, tt.wantRealized)
+			}
+			if len(report.Holdings) != 1 || !approx(report.Holdings[0].Quantity, 1.5) || !approx(report.Holdings[0].CostBasis, tt.wantRemaining) {
+				t.Errorf("posições = %+v, esperado 1,5 BTC com custo %v", report.Holdings, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+// TestCostBasisFees verifica que as taxas em moeda de cotação entram no custo das compras e
+// reduzem o valor das vendas, e que as taxas no próprio ativo reduzem as unidades recebidas
+func TestCostBasisFees(t *testing.T) {
+	buy := trade("b1", models.TransactionBuy, 2, 100, 1)
+	buy.Fee, buy.FeeCurrency = 10, "USD"
+	sell := trade("s1", models.TransactionSell, 1, 150, 2)
+	sell.Fee, sell.FeeCurrency = 5, "USD"
+	reward := trade("r1", models.TransactionStakingReward, 0.5, 120, 3)
+	reward.Fee, reward.FeeCurrency = 0.1, "BTC"
+
+	engine, err := NewCostBasisEngine(models.CostBasisFIFO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := engine.Run([]models.Transaction{buy, sell, reward})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Custo unitário (200 + 10) / 2 = 105; venda de 150 - 5
+	if len(report.Disposals) != 1 || !approx(report.Disposals[0].CostBasis, 105) || !approx(report.Disposals[0].Proceeds, 145) {
+		t.Fatalf("alienações = %+v, esperado custo 105 e valor 145", report.Disposals)
+	}
+	if !approx(report.RealizedPnL, 40) {
+		t.Errorf("P&L realizado = %v, esperado 40", report.RealizedPnL)
+	}
+
+	// Restam 1 BTC a 105 e 0,4 BTC recebidos com o custo de 0,5 × 120
+	holding := report.Holdings[0]
+	if !approx(holding.Quantity, 1.4) || !approx(holding.CostBasis, 165) {
+		t.Errorf("posição = %v BTC com custo %v, esperado 1,4 e 165", holding.Quantity, holding.CostBasis)
+	}
+	if !approx(holding.FeesPaid, 10+5+0.1*120) {
+		t.Errorf("taxas = %v, esperado %v", holding.FeesPaid, 10+5+0.1*120)
+	}
+}
+
+// TestCostBasisInsufficientBalance verifica que uma venda acima do saldo na data é rejeitada
+func TestCostBasisInsufficientBalance(t *testing.T) {
+	ledger := []models.Transaction{
+		trade("b1", models.TransactionBuy, 1, 100, 2),
+		trade("s1", models.TransactionSell, 0.5, 150, 1), // anterior à compra
+	}
+
+	for _, method := range []models.CostBasisMethod{models.CostBasisFIFO, models.CostBasisAverage} {
+		engine, err := NewCostBasisEngine(method)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := engine.Run(ledger); !errors.Is(err, ErrInsufficientBalance) {
+			t.Errorf("%s: erro %v, esperado ErrInsufficientBalance", method, err)
+		}
+	}
+
+	if _, err := NewCostBasisEngine("lowest"); !errors.Is(err, ErrUnknownCostBasisMethod) {
+		t.Errorf("erro %v, esperado ErrUnknownCostBasisMethod", err)
+	}
+}
+
+// approx compara dois valores com uma tolerância para os erros de arredondamento
+func approx(got, want float64) bool {
+	return math.Abs(got-want) < 1e-9
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/fx"
+)
+
+// CurrencyConverter fornece as taxas de câmbio entre moedas fiduciárias numa data. É implementada por fx.Service.
+type CurrencyConverter interface {
+	Rate(from, to string, at time.Time) (float64, error)
+}
+
+// ResolveCurrency normaliza a moeda em que os valores são pedidos (ver fx.Normalize) e verifica
+// que é suportada. Uma moeda vazia corresponde a models.DefaultQuoteCurrency.
+func (s *PortfolioService) ResolveCurrency(currency string) (string, error) {
+	currency = fx.Normalize(currency)
+	if currency == "" || currency == models.DefaultQuoteCurrency {
+		return models.DefaultQuoteCurrency, nil
+	}
+
+	if _, err := s.rate(models.DefaultQuoteCurrency, currency, time.Now()); err != nil {
+		return "", err
+	}
+	return currency, nil
+}
+
+// ledgerIn é como ledger, mas com o livro convertido para a moeda pedida (ver convertLedger).
+// Retorna também a moeda, normalizada por ResolveCurrency.
+func (s *PortfolioService) ledgerIn(portfolioID, currency string) (*models.Portfolio, []models.Transaction, string, error) {
+	currency, err := s.ResolveCurrency(currency)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	portfolio, ledger, err := s.ledger(portfolioID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	ledger, err = s.convertLedger(ledger, currency)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return portfolio, ledger, currency, nil
+}
+
+// rate retorna a taxa de câmbio de from para to na data de at
+func (s *PortfolioService) rate(from, to string, at time.Time) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if s.rates == nil {
+		return 0, fmt.Errorf("%w: %s (sem taxas de câmbio)", fx.ErrUnsupportedCurrency, to)
+	}
+	return s.rates.Rate(from, to, at)
+}
+
+// convertLedger retorna uma cópia do livro com os preços e as taxas pagas na moeda de cotação
+// convertidos para currency, à taxa da data de cada transação, para que o custo e o P&L realizado
+// reflitam o câmbio do momento. Moedas de cotação sem taxa de câmbio (ex.: pares cotados em BTC)
+// são tratadas como models.DefaultQuoteCurrency, como no resto do livro.
+func (s *PortfolioService) convertLedger(ledger []models.Transaction, currency string) ([]models.Transaction, error) {
+	converted := make([]models.Transaction, len(ledger))
+	for i, tx := range ledger {
+		quote := fx.Normalize(tx.QuoteCurrency)
+		if quote == "" {
+			quote = models.DefaultQuoteCurrency
+		}
+		// Sem taxas de câmbio, só a moeda por omissão é aceite e o livro fica como está
+		if quote == currency || s.rates == nil {
+			converted[i] = tx
+			continue
+		}
+
+		rate, err := s.rate(quote, currency, tx.Timestamp)
+		if errors.Is(err, fx.ErrUnsupportedCurrency) {
+			rate, err = s.rate(models.DefaultQuoteCurrency, currency, tx.Timestamp)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if isQuoteFee(tx) {
+			tx.Fee *= rate
+			tx.FeeCurrency = currency
+		}
+		tx.Price *= rate
+		tx.QuoteCurrency = currency
+		converted[i] = tx
+	}
+	return converted, nil
+}
+
+// convertPrices converte para currency, à taxa atual, preços atuais na moeda por omissão
+func (s *PortfolioService) convertPrices(prices map[string]float64, currency string) error {
+	rate, err := s.rate(models.DefaultQuoteCurrency, currency, time.Now())
+	if err != nil {
+		return err
+	}
+	for symbol := range prices {
+		prices[symbol] *= rate
+	}
+	return nil
+}
+
+// convertQuotes é como convertPrices, mas para cotações; só o preço atual é convertido
+func (s *PortfolioService) convertQuotes(quotes map[string]models.CryptoData, currency string) error {
+	rate, err := s.rate(models.DefaultQuoteCurrency, currency, time.Now())
+	if err != nil {
+		return err
+	}
+	for symbol, quote := range quotes {
+		quote.CurrentPrice *= rate
+		quotes[symbol] = quote
+	}
+	return nil
+}
+
+// convertSeries converte para currency os preços históricos (na moeda por omissão), cada um
+// à taxa da sua data
+func (s *PortfolioService) convertSeries(prices map[string]priceSeries, currency string) error {
+	if currency == models.DefaultQuoteCurrency {
+		return nil
+	}
+
+	for symbol, series := range prices {
+		converted := make(priceSeries, len(series))
+		for i, point := range series {
+			rate, err := s.rate(models.DefaultQuoteCurrency, currency, point.Timestamp)
+			if err != nil {
+				return err
+			}
+			point.Price *= rate
+			point.MarketCap *= rate
+			converted[i] = point
+		}
+		prices[symbol] = converted
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// ErrInvalidTaxYear é retornado quando o ano fiscal pedido não é válido
+var ErrInvalidTaxYear = errors.New("ano fiscal inválido")
+
+// ExportPortfolio retorna o portfólio completo, com as posições, o livro de transações e os
+// pesos alvo, para cópia de segurança
+func (s *PortfolioService) ExportPortfolio(portfolioID string) (*models.PortfolioExport, error) {
+	portfolio, ledger, err := s.ledger(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolio.Holdings, err = ComputeHoldings(ledger, portfolioMethod(portfolio))
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.repo.ListTransactions(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := s.repo.GetTargets(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PortfolioExport{
+		Portfolio:    *portfolio,
+		Transactions: transactions,
+		Targets:      targets,
+		ExportedAt:   time.Now(),
+	}, nil
+}
+
+// ExportPortfolios retorna todos os portfólios de um usuário, completos (ver ExportPortfolio)
+func (s *PortfolioService) ExportPortfolios(userID string) ([]models.PortfolioExport, error) {
+	portfolios, err := s.repo.ListPortfolios(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	exports := make([]models.PortfolioExport, 0, len(portfolios))
+	for _, portfolio := range portfolios {
+		export, err := s.ExportPortfolio(portfolio.ID)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, *export)
+	}
+
+	return exports, nil
+}
+
+// GetTaxReport retorna as mais-valias realizadas pelo portfólio no ano fiscal indicado (em UTC),
+// a partir das alienações do motor de custo. Os lotes detidos mais de um ano são de longo prazo.
+// Um método vazio corresponde ao método do portfólio. Os valores são expressos na moeda indicada
+// (vazia para a moeda por omissão), à taxa de câmbio da data de cada aquisição e alienação.
+func (s *PortfolioService) GetTaxReport(portfolioID string, year int, method models.CostBasisMethod, currency string) (*models.TaxReport, error) {
+	now := time.Now()
+	if year < 1970 || year > now.Year() {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidTaxYear, year)
+	}
+
+	portfolio, ledger, currency, err := s.ledgerIn(portfolioID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if method == "" {
+		method = portfolioMethod(portfolio)
+	}
+
+	engine, err := NewCostBasisEngine(method)
+	if err != nil {
+		return nil, err
+	}
+
+	costBasis, err := engine.Run(ledger)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.TaxReport{
+		PortfolioID: portfolioID,
+		Year:        year,
+		Method:      method,
+		Currency:    currency,
+		From:        time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Disposals:   []models.TaxableDisposal{},
+		GeneratedAt: now,
+	}
+
+	for _, disposal := range costBasis.Disposals {
+		if disposal.DisposedAt.Before(report.From) || !disposal.DisposedAt.Before(report.To) {
+			continue
+		}
+
+		taxable := models.TaxableDisposal{
+			TransactionID: disposal.TransactionID,
+			Type:          disposal.Type,
+			Symbol:        disposal.Symbol,
+			Quantity:      disposal.Quantity,
+			AcquiredAt:    disposal.AcquiredAt,
+			DisposedAt:    disposal.DisposedAt,
+			HoldingDays:   int(disposal.DisposedAt.Sub(disposal.AcquiredAt) / (24 * time.Hour)),
+			Proceeds:      disposal.Proceeds,
+			CostBasis:     disposal.CostBasis,
+			Gain:          disposal.RealizedPnL,
+			Term:          models.TaxTermShort,
+		}
+		if disposal.DisposedAt.After(disposal.AcquiredAt.AddDate(1, 0, 0)) {
+			taxable.Term = models.TaxTermLong
+		}
+
+		report.Disposals = append(report.Disposals, taxable)
+		addToTaxSummary(&report.Total, taxable)
+		if taxable.Term == models.TaxTermLong {
+			addToTaxSummary(&report.LongTerm, taxable)
+		} else {
+			addToTaxSummary(&report.ShortTerm, taxable)
+		}
+	}
+
+	sort.SliceStable(report.Disposals, func(i, j int) bool {
+		return report.Disposals[i].DisposedAt.Before(report.Disposals[j].DisposedAt)
+	})
+
+	return report, nil
+}
+
+// addToTaxSummary acumula uma alienação nos totais
+func addToTaxSummary(summary *models.TaxSummary, disposal models.TaxableDisposal) {
+	summary.Disposals++
+	summary.Proceeds += disposal.Proceeds
+	summary.CostBasis += disposal.CostBasis
+	summary.Net += disposal.Gain
+	if disposal.Gain >= 0 {
+		summary.Gains += disposal.Gain
+	} else {
+		summary.Losses += disposal.Gain
+	}
+}
@@ -0,0 +1,120 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// TransactionColumns são as colunas da exportação do livro de transações. Coincidem com as
+// colunas por omissão do formato genérico de importação, para que o ficheiro possa ser reimportado
+// sem repetir transações: a coluna importId conserva a origem das transações importadas.
+var TransactionColumns = []string{"id", "type", "symbol", "quantity", "price", "quoteCurrency", "fee", "feeCurrency", "timestamp", "notes", "importId"}
+
+// WritePortfoliosCSV escreve uma linha por portfólio, com o número de ativos, posições e transações
+func WritePortfoliosCSV(w io.Writer, portfolios []models.PortfolioExport) error {
+	header := []string{"id", "name", "description", "costBasisMethod", "assets", "holdings", "transactions", "createdAt", "updatedAt"}
+	return writeCSV(w, header, len(portfolios), func(i int) []string {
+		p := portfolios[i]
+		return []string{
+			p.ID,
+			p.Name,
+			p.Description,
+			string(p.CostBasisMethod),
+			strconv.Itoa(len(p.Assets)),
+			strconv.Itoa(len(p.Holdings)),
+			strconv.Itoa(len(p.Transactions)),
+			formatTime(p.CreatedAt),
+			formatTime(p.UpdatedAt),
+		}
+	})
+}
+
+// WriteHoldingsCSV escreve uma linha por posição
+func WriteHoldingsCSV(w io.Writer, holdings []models.Holding) error {
+	header := []string{"symbol", "quantity", "costBasis", "averageCost", "feesPaid", "firstAcquired", "lastActivity"}
+	return writeCSV(w, header, len(holdings), func(i int) []string {
+		h := holdings[i]
+		return []string{
+			h.Symbol,
+			formatFloat(h.Quantity),
+			formatFloat(h.CostBasis),
+			formatFloat(h.AverageCost),
+			formatFloat(h.FeesPaid),
+			formatTime(h.FirstAcquired),
+			formatTime(h.LastActivity),
+		}
+	})
+}
+
+// WriteTransactionsCSV escreve uma linha por transação, com as colunas TransactionColumns
+func WriteTransactionsCSV(w io.Writer, transactions []models.Transaction) error {
+	return writeCSV(w, TransactionColumns, len(transactions), func(i int) []string {
+		tx := transactions[i]
+		return []string{
+			tx.ID,
+			string(tx.Type),
+			tx.Symbol,
+			formatFloat(tx.Quantity),
+			formatFloat(tx.Price),
+			tx.QuoteCurrency,
+			formatFloat(tx.Fee),
+			tx.FeeCurrency,
+			formatTime(tx.Timestamp),
+			tx.Notes,
+			tx.ImportID,
+		}
+	})
+}
+
+// WriteTaxReportCSV escreve uma linha por alienação do relatório fiscal
+func WriteTaxReportCSV(w io.Writer, report *models.TaxReport) error {
+	header := []string{"symbol", "quantity", "acquiredAt", "disposedAt", "holdingDays", "term", "proceeds", "costBasis", "gain", "type", "transactionId"}
+	return writeCSV(w, header, len(report.Disposals), func(i int) []string {
+		d := report.Disposals[i]
+		return []string{
+			d.Symbol,
+			formatFloat(d.Quantity),
+			formatTime(d.AcquiredAt),
+			formatTime(d.DisposedAt),
+			strconv.Itoa(d.HoldingDays),
+			string(d.Term),
+			formatFloat(d.Proceeds),
+			formatFloat(d.CostBasis),
+			formatFloat(d.Gain),
+			string(d.Type),
+			d.TransactionID,
+		}
+	})
+}
+
+// writeCSV escreve o cabeçalho e as linhas produzidas por record
+func writeCSV(w io.Writer, header []string, rows int, record func(i int) []string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for i := 0; i < rows; i++ {
+		if err := writer.Write(record(i)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatFloat escreve um número sem expoente e sem casas decimais desnecessárias
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatTime escreve uma data em RFC 3339 (UTC), ou vazio se não estiver preenchida
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// ErrInvalidTimeFrame é retornado quando o horizonte da previsão não é suportado
+var ErrInvalidTimeFrame = errors.New("horizonte de previsão inválido: use 7d, 30d ou 90d")
+
+// DefaultForecastTimeFrame é o horizonte usado quando o pedido não indica outro
+const DefaultForecastTimeFrame = "30d"
+
+// forecastTimeFrames associa os horizontes suportados ao número de dias simulados
+var forecastTimeFrames = map[string]int{
+	"7d":  7,
+	"30d": 30,
+	"90d": 90,
+}
+
+// Parâmetros da simulação de Monte Carlo
+const (
+	forecastSimulations     = 5000
+	forecastLookbackDays    = 365  // dias de histórico usados para estimar as distribuições
+	minForecastObservations = 30   // retornos diários mínimos para estimar as distribuições
+	forecastConfidenceBand  = 0.10 // margem em torno do valor previsto usada na confiança
+)
+
+// forecastPercentiles são os percentis das bandas da previsão
+var forecastPercentiles = []float64{0.05, 0.25, 0.50, 0.75, 0.95}
+
+// GetPortfolioForecast prevê o valor do portfólio no horizonte indicado por simulação de Monte Carlo.
+// Os retornos diários (logarítmicos) de cada ativo seguem uma normal multivariada cuja média e
+// covariância são estimadas sobre o último ano de histórico, preservando as correlações entre ativos.
+// Ativos sem histórico suficiente mantêm o valor atual e ativos sem preço ficam de fora; ambos
+// são listados em UnmodeledSymbols.
+func (s *PortfolioService) GetPortfolioForecast(portfolioID string, timeFrame string) (*models.PortfolioForecast, error) {
+	if s.history == nil {
+		return nil, ErrHistoryUnavailable
+	}
+
+	timeFrame = strings.ToLower(strings.TrimSpace(timeFrame))
+	if timeFrame == "" {
+		timeFrame = DefaultForecastTimeFrame
+	}
+	days, ok := forecastTimeFrames[timeFrame]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimeFrame, timeFrame)
+	}
+
+	holdings, err := s.GetHoldings(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	symbols := holdingSymbols(holdings)
+	prices, err := s.priceHistory(symbols, now.AddDate(0, 0, -forecastLookbackDays-1), now)
+	if err != nil {
+		return nil, err
+	}
+	current := s.currentPrices(symbols)
+
+	// Separar os ativos modelados dos que não têm histórico
+	forecast := &models.PortfolioForecast{
+		PortfolioID: portfolioID,
+		TimeFrame:   timeFrame,
+		Simulations: forecastSimulations,
+		GeneratedAt: now,
+	}
+	var modeled []string
+	var values []float64 // valor atual de cada ativo modelado
+	var fixedValue float64
+	for _, holding := range holdings {
+		price, ok := current[holding.Symbol]
+		if !ok {
+			price, ok = prices[holding.Symbol].at(now, minPriceTolerance)
+		}
+		if !ok {
+			forecast.UnmodeledSymbols = append(forecast.UnmodeledSymbols, holding.Symbol)
+			continue
+		}
+
+		value := holding.Quantity * price
+		forecast.CurrentValue += value
+		if len(dailyLogReturns(prices, []string{holding.Symbol}, now)) < minForecastObservations {
+			forecast.UnmodeledSymbols = append(forecast.UnmodeledSymbols, holding.Symbol)
+			fixedValue += value
+			continue
+		}
+		modeled = append(modeled, holding.Symbol)
+		values = append(values, value)
+	}
+
+	if len(modeled) == 0 {
+		return nil, ErrInsufficientHistory
+	}
+
+	returns := dailyLogReturns(prices, modeled, now)
+	forecast.Observations = len(returns)
+	if len(returns) < minForecastObservations {
+		return nil, ErrInsufficientHistory
+	}
+
+	mean, covariance := meanCovariance(returns)
+	cholesky := choleskyDecomposition(covariance)
+
+	// Semente derivada do portfólio e do dia, para que pedidos repetidos devolvam a mesma previsão
+	seed := fnv.New64a()
+	seed.Write([]byte(portfolioID + now.Format("2006-01-02") + timeFrame))
+	random := rand.New(rand.NewSource(int64(seed.Sum64())))
+
+	// paths[d][i] é o valor do portfólio no dia d+1 da simulação i
+	paths := make([][]float64, days)
+	for d := range paths {
+		paths[d] = make([]float64, forecastSimulations)
+	}
+
+	assetValues := make([]float64, len(values))
+	shocks := make([]float64, len(values))
+	for i := 0; i < forecastSimulations; i++ {
+		copy(assetValues, values)
+		for d := 0; d < days; d++ {
+			for k := range shocks {
+				shocks[k] = random.NormFloat64()
+			}
+
+			total := fixedValue
+			for a := range assetValues {
+				logReturn := mean[a]
+				for k := 0; k <= a; k++ {
+					logReturn += cholesky[a][k] * shocks[k]
+				}
+				assetValues[a] *= math.Exp(logReturn)
+				total += assetValues[a]
+			}
+			paths[d][i] = total
+		}
+	}
+
+	for d, outcomes := range paths {
+		sort.Float64s(outcomes)
+		forecast.Bands = append(forecast.Bands, models.ForecastBand{
+			Timestamp: now.AddDate(0, 0, d+1),
+			P5:        percentile(outcomes, forecastPercentiles[0]),
+			P25:       percentile(outcomes, forecastPercentiles[1]),
+			P50:       percentile(outcomes, forecastPercentiles[2]),
+			P75:       percentile(outcomes, forecastPercentiles[3]),
+			P95:       percentile(outcomes, forecastPercentiles[4]),
+		})
+	}
+
+	final := paths[days-1]
+	forecast.PredictedValue = percentile(final, 0.5)
+
+	var sum float64
+	var losses, near int
+	for _, value := range final {
+		sum += value
+		if value < forecast.CurrentValue {
+			losses++
+		}
+		if math.Abs(value-forecast.PredictedValue) <= forecastConfidenceBand*forecast.PredictedValue {
+			near++
+		}
+	}
+	forecast.ExpectedValue = sum / float64(len(final))
+	forecast.ProbabilityOfLoss = float64(losses) / float64(len(final))
+	forecast.Confidence = float64(near) / float64(len(final))
+	if forecast.CurrentValue > 0 {
+		forecast.ExpectedReturn = (forecast.PredictedValue/forecast.CurrentValue - 1) * 100
+	}
+
+	return forecast, nil
+}
+
+// dailyLogReturns retorna os retornos diários logarítmicos dos símbolos nos dias em que
+// todos têm preço no início e no fim, para que a covariância use observações simultâneas
+func dailyLogReturns(prices map[string]priceSeries, symbols []string, now time.Time) [][]float64 {
+	var returns [][]float64
+	for d := forecastLookbackDays; d > 0; d-- {
+		start, end := now.AddDate(0, 0, -d), now.AddDate(0, 0, -d+1)
+
+		row := make([]float64, len(symbols))
+		complete := true
+		for i, symbol := range symbols {
+			startPrice, ok := prices[symbol].at(start, minPriceTolerance)
+			if !ok {
+				complete = false
+				break
+			}
+			endPrice, ok := prices[symbol].at(end, minPriceTolerance)
+			if !ok {
+				complete = false
+				break
+			}
+			row[i] = math.Log(endPrice / startPrice)
+		}
+		if complete {
+			returns = append(returns, row)
+		}
+	}
+	return returns
+}
+
+// meanCovariance estima o vetor de médias e a matriz de covariância amostral das observações
+func meanCovariance(observations [][]float64) ([]float64, [][]float64) {
+	n, assets := len(observations), len(observations[0])
+
+	mean := make([]float64, assets)
+	for _, row := range observations {
+		for a, value := range row {
+			mean[a] += value / float64(n)
+		}
+	}
+
+	covariance := make([][]float64, assets)
+	for a := range covariance {
+		covariance[a] = make([]float64, assets)
+	}
+	for _, row := range observations {
+		for a := 0; a < assets; a++ {
+			for b := 0; b <= a; b++ {
+				covariance[a][b] += (row[a] - mean[a]) * (row[b] - mean[b]) / float64(n-1)
+			}
+		}
+	}
+	for a := 0; a < assets; a++ {
+		for b := 0; b < a; b++ {
+			covariance[b][a] = covariance[a][b]
+		}
+	}
+
+	return mean, covariance
+}
+
+// choleskyDecomposition retorna a matriz triangular inferior L tal que L·Lᵀ = matrix.
+// Matrizes quase singulares (ativos perfeitamente correlacionados) são regularizadas
+// somando um pequeno valor à diagonal.
+func choleskyDecomposition(matrix [][]float64) [][]float64 {
+	n := len(matrix)
+	jitter := 0.0
+	for attempt := 0; attempt < 10; attempt++ {
+		lower := make([][]float64, n)
+		for i := range lower {
+			lower[i] = make([]float64, n)
+		}
+
+		ok := true
+		for i := 0; i < n && ok; i++ {
+			for j := 0; j <= i; j++ {
+				sum := matrix[i][j]
+				if i == j {
+					sum += jitter
+				}
+				for k := 0; k < j; k++ {
+					sum -= lower[i][k] * lower[j][k]
+				}
+
+				if i == j {
+					if sum < 0 {
+						ok = false
+						break
+					}
+					lower[i][i] = math.Sqrt(sum)
+				} else if lower[j][j] > 0 {
+					lower[i][j] = sum / lower[j][j]
+				}
+			}
+		}
+		if ok {
+			return lower
+		}
+
+		if jitter == 0 {
+			jitter = 1e-12
+		}
+		jitter *= 10
+	}
+
+	// Último recurso: ignorar as correlações
+	lower := make([][]float64, n)
+	for i := range lower {
+		lower[i] = make([]float64, n)
+		lower[i][i] = math.Sqrt(math.Max(matrix[i][i], 0))
+	}
+	return lower
+}
+
+// percentile retorna o percentil p (0-1) de valores ordenados, por interpolação linear
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	position := p * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
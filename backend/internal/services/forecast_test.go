@@ -0,0 +1,201 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/storage/inmemory"
+)
+
+// staticHistory é um histórico de preços fixo, por símbolo e por ordem cronológica
+type staticHistory map[string][]models.HistoricalData
+
+func (h staticHistory) SaveHistoricalData(data []models.HistoricalData) error { return nil }
+
+func (h staticHistory) GetHistoricalData(symbol string, from, to time.Time) ([]models.HistoricalData, error) {
+	var data []models.HistoricalData
+	for _, point := range h[symbol] {
+		if !point.Timestamp.Before(from) && !point.Timestamp.After(to) {
+			data = append(data, point)
+		}
+	}
+	return data, nil
+}
+
+func (h staticHistory) GetSymbolData(symbol string, limit int) ([]models.HistoricalData, error) {
+	return h[symbol], nil
+}
+
+func (h staticHistory) DeleteOldData(before time.Time) error { return nil }
+
+// forecastFor prevê a 30 dias o valor de 1 BTC com os retornos diários logarítmicos indicados,
+// do mais antigo ao mais recente, a partir de um preço inicial de 100
+func forecastFor(t *testing.T, logReturns []float64) *models.PortfolioForecast {
+	t.Helper()
+	now := time.Now()
+	price := 100.0
+	points := []models.HistoricalData{{Symbol: "BTC", Price: price, Timestamp: now.AddDate(0, 0, -len(logReturns))}}
+	for i, r := range logReturns {
+		price *= math.Exp(r)
+		points = append(points, models.HistoricalData{Symbol: "BTC", Price: price, Timestamp: now.AddDate(0, 0, i+1-len(logReturns))})
+	}
+
+	service := NewPortfolioService(inmemory.NewPortfolioRepository(), nil, staticHistory{"BTC": points}, nil)
+	portfolio, err := service.CreatePortfolio("u1", "Principal", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.AddTransaction(portfolio.ID, trade("", models.TransactionBuy, 1, 100, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	forecast, err := service.GetPortfolioForecast(portfolio.ID, "30d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forecast.Bands) != 30 || !within(forecast.CurrentValue, price, 1e-9) {
+		t.Fatalf("%d bandas e valor atual %v, esperado 30 e %v", len(forecast.Bands), forecast.CurrentValue, price)
+	}
+	return forecast
+}
+
+// TestForecastBandsDeterministic verifica que sem volatilidade todas as bandas seguem o
+// crescimento constante do histórico
+func TestForecastBandsDeterministic(t *testing.T) {
+	logReturns := make([]float64, forecastLookbackDays)
+	for i := range logReturns {
+		logReturns[i] = math.Log(1.01)
+	}
+
+	forecast := forecastFor(t, logReturns)
+	for d, band := range forecast.Bands {
+		want := forecast.CurrentValue * math.Pow(1.01, float64(d+1))
+		for _, got := range []float64{band.P5, band.P25, band.P50, band.P75, band.P95} {
+			if !within(got/want, 1, 1e-9) {
+				t.Fatalf("dia %d: banda %+v, esperado %v", d+1, band, want)
+			}
+		}
+	}
+	if forecast.ProbabilityOfLoss != 0 || forecast.Confidence != 1 || !within(forecast.ExpectedReturn, (math.Pow(1.01, 30)-1)*100, 1e-6) {
+		t.Errorf("perda %v, confiança %v, retorno %v", forecast.ProbabilityOfLoss, forecast.Confidence, forecast.ExpectedReturn)
+	}
+}
+
+// TestForecastBandsPercentiles verifica que no último dia as bandas de um único ativo se
+// aproximam dos percentis da lognormal V·exp(30μ + z·√30·σ), com μ e σ estimados do histórico
+func TestForecastBandsPercentiles(t *testing.T) {
+	logReturns := make([]float64, forecastLookbackDays)
+	for i := range logReturns {
+		logReturns[i] = 0.03*math.Sin(float64(i)) + 0.001
+	}
+	mean, covariance := meanCovariance(columns(logReturns))
+	drift, spread := 30*mean[0], math.Sqrt(30*covariance[0][0])
+
+	forecast := forecastFor(t, logReturns)
+	last := forecast.Bands[29]
+	bands := []struct {
+		got, z float64
+	}{
+		{last.P5, -1.6448536269514722},
+		{last.P25, -0.6744897501960817},
+		{last.P50, 0},
+		{last.P75, 0.6744897501960817},
+		{last.P95, 1.6448536269514722},
+	}
+
+	// Com 5000 simulações, o desvio-padrão dos percentis extremos é da ordem de 1%, e a semente
+	// muda com o dia
+	for i, band := range bands {
+		want := forecast.CurrentValue * math.Exp(drift+band.z*spread)
+		if !within(band.got/want, 1, 0.05) {
+			t.Errorf("banda %d: %v, esperado %v", i, band.got, want)
+		}
+		if i > 0 && band.got <= bands[i-1].got {
+			t.Errorf("banda %d (%v) não supera a anterior (%v)", i, band.got, bands[i-1].got)
+		}
+	}
+	if forecast.PredictedValue != last.P50 {
+		t.Errorf("valor previsto %v, esperado a mediana %v", forecast.PredictedValue, last.P50)
+	}
+}
+
+// TestCholeskyDecomposition verifica o fator de matrizes conhecidas e a regularização de
+// matrizes que não são definidas positivas
+func TestCholeskyDecomposition(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix [][]float64
+		want   [][]float64
+	}{
+		{"2×2", [][]float64{{4, 2}, {2, 5}}, [][]float64{{2, 0}, {1, 2}}},
+		{"3×3", [][]float64{{4, 12, -16}, {12, 37, -43}, {-16, -43, 98}}, [][]float64{{2, 0, 0}, {6, 1, 0}, {-8, 5, 3}}},
+		// Ativos perfeitamente correlacionados: o segundo não acrescenta choque próprio
+		{"singular", [][]float64{{1, 1}, {1, 1}}, [][]float64{{1, 0}, {1, 0}}},
+		// Correlação impossível: as correlações são ignoradas
+		{"indefinida", [][]float64{{1, 2}, {2, 1}}, [][]float64{{1, 0}, {0, 1}}},
+	}
+
+	for _, tt := range tests {
+		lower := choleskyDecomposition(tt.matrix)
+		for i := range tt.want {
+			for j := range tt.want[i] {
+				if !within(lower[i][j], tt.want[i][j], 1e-9) {
+					t.Errorf("%s: L = %v, esperado %v", tt.name, lower, tt.want)
+					break
+				}
+			}
+		}
+	}
+}
+
+// TestMeanCovariance verifica as médias e a covariância amostral de duas séries
+func TestMeanCovariance(t *testing.T) {
+	mean, covariance := meanCovariance([][]float64{{1, 2}, {3, 6}, {2, 1}})
+
+	wantCovariance := [][]float64{{1, 2}, {2, 7}}
+	if !within(mean[0], 2, 1e-9) || !within(mean[1], 3, 1e-9) {
+		t.Errorf("médias %v, esperado [2 3]", mean)
+	}
+	for i := range wantCovariance {
+		for j := range wantCovariance[i] {
+			if !within(covariance[i][j], wantCovariance[i][j], 1e-9) {
+				t.Errorf("covariância %v, esperado %v", covariance, wantCovariance)
+			}
+		}
+	}
+}
+
+// TestPercentile verifica a interpolação linear entre valores ordenados
+func TestPercentile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5}
+	tests := []struct {
+		p, want float64
+	}{
+		{0, 1},
+		{0.05, 1.2},
+		{0.25, 2},
+		{0.5, 3},
+		{0.95, 4.8},
+		{1, 5},
+	}
+
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); !within(got, tt.want, 1e-9) {
+			t.Errorf("percentil %v = %v, esperado %v", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 0.5); got != 0 {
+		t.Errorf("percentil sem valores = %v, esperado 0", got)
+	}
+}
+
+// columns converte uma série numa matriz de observações de um único ativo
+func columns(values []float64) [][]float64 {
+	rows := make([][]float64, len(values))
+	for i, value := range values {
+		rows[i] = []float64{value}
+	}
+	return rows
+}
@@ -0,0 +1,74 @@
+package fx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// FrankfurterAPI é o endereço da API Frankfurter, que publica as taxas de referência do
+// Banco Central Europeu (dias úteis, desde 1999)
+const FrankfurterAPI = "https://api.frankfurter.app"
+
+// FrankfurterProvider obtém as taxas de câmbio da API Frankfurter
+type FrankfurterProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewFrankfurterProvider cria o fornecedor de taxas. Um baseURL vazio usa FrankfurterAPI.
+func NewFrankfurterProvider(baseURL string) *FrankfurterProvider {
+	if baseURL == "" {
+		baseURL = FrankfurterAPI
+	}
+	return &FrankfurterProvider{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// frankfurterSeries é a resposta da API para um intervalo de datas
+type frankfurterSeries struct {
+	Base  string                        `json:"base"`
+	Rates map[string]map[string]float64 `json:"rates"` // data -> moeda -> taxa
+}
+
+// Rates obtém as taxas de base em cada dia útil entre from e to
+func (p *FrankfurterProvider) Rates(base string, from, to time.Time) ([]models.FXRate, error) {
+	url := fmt.Sprintf("%s/%s..%s?from=%s", p.baseURL, from.UTC().Format(dateLayout), to.UTC().Format(dateLayout), base)
+
+	resp, err := p.httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status inválido: %d", resp.StatusCode)
+	}
+
+	var series frankfurterSeries
+	if err := json.NewDecoder(resp.Body).Decode(&series); err != nil {
+		return nil, err
+	}
+
+	var rates []models.FXRate
+	for day, daily := range series.Rates {
+		date, err := time.Parse(dateLayout, day)
+		if err != nil {
+			return nil, fmt.Errorf("data inválida %q: %w", day, err)
+		}
+		// A API pode devolver dias anteriores a from (o último dia útil antes do intervalo)
+		if date.Before(truncateDay(from)) {
+			continue
+		}
+		for currency, rate := range daily {
+			rates = append(rates, models.FXRate{Base: base, Currency: currency, Date: date, Rate: rate})
+		}
+	}
+
+	return rates, nil
+}
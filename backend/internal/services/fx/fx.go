@@ -0,0 +1,252 @@
+package fx
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// Erros das conversões de câmbio
+var (
+	ErrUnsupportedCurrency = errors.New("moeda não suportada")
+	ErrRatesUnavailable    = errors.New("taxas de câmbio indisponíveis")
+)
+
+// BaseCurrency é a moeda em que as taxas são obtidas e guardadas. As conversões entre
+// outras moedas são feitas por intermédio dela.
+const BaseCurrency = models.DefaultQuoteCurrency
+
+// latestRatesTTL é o tempo durante o qual as taxas do ano corrente são reutilizadas sem
+// voltar a consultar o fornecedor
+const latestRatesTTL = time.Hour
+
+// providerRetryDelay é o tempo de espera antes de voltar a consultar o fornecedor depois de uma falha
+const providerRetryDelay = 5 * time.Minute
+
+// maxRateLookback é o número de dias a recuar quando não há taxa numa data (fins de semana e feriados)
+const maxRateLookback = 7
+
+// dateLayout é o formato das datas das taxas
+const dateLayout = "2006-01-02"
+
+// stablecoins são cotadas como a moeda fiduciária a que estão indexadas
+var stablecoins = map[string]string{
+	"USDT":  "USD",
+	"USDC":  "USD",
+	"BUSD":  "USD",
+	"TUSD":  "USD",
+	"FDUSD": "USD",
+	"USDP":  "USD",
+	"DAI":   "USD",
+	"EURT":  "EUR",
+	"EURC":  "EUR",
+}
+
+// Normalize retorna o código da moeda em maiúsculas, com as stablecoins substituídas pela
+// moeda fiduciária a que estão indexadas (ex.: "usdt" -> "USD")
+func Normalize(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if fiat, ok := stablecoins[currency]; ok {
+		return fiat
+	}
+	return currency
+}
+
+// Provider obtém as taxas de câmbio diárias de uma fonte externa
+type Provider interface {
+	// Rates retorna as taxas de base para as outras moedas em cada dia útil entre from e to
+	Rates(base string, from, to time.Time) ([]models.FXRate, error)
+}
+
+// Service converte valores entre moedas fiduciárias com as taxas diárias do fornecedor.
+// As taxas são carregadas por ano civil, guardadas no repositório (se existir) e mantidas
+// em memória; as do ano corrente são renovadas a cada latestRatesTTL.
+type Service struct {
+	provider Provider
+	repo     models.FXRateRepository
+
+	mu       sync.Mutex
+	years    map[int]*yearRates
+	failedAt time.Time // última falha do fornecedor
+}
+
+// yearRates são as taxas de um ano civil, por data e moeda, face a BaseCurrency
+type yearRates struct {
+	rates    map[string]map[string]float64
+	loadedAt time.Time
+}
+
+// NewService cria o serviço de câmbio. Sem repositório (nil), as taxas ficam apenas em memória.
+func NewService(provider Provider, repo models.FXRateRepository) *Service {
+	return &Service{
+		provider: provider,
+		repo:     repo,
+		years:    make(map[int]*yearRates),
+	}
+}
+
+// Rate retorna quantas unidades de to vale uma unidade de from na data de at, usando a última
+// taxa publicada até essa data. Datas futuras usam a taxa mais recente.
+func (s *Service) Rate(from, to string, at time.Time) (float64, error) {
+	from, to = Normalize(from), Normalize(to)
+	if from == to {
+		return 1, nil
+	}
+
+	today := truncateDay(time.Now())
+	day := truncateDay(at)
+	if day.After(today) {
+		day = today
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for back := 0; back <= maxRateLookback; back++ {
+		date := day.AddDate(0, 0, -back)
+		rates, err := s.loadYear(date.Year())
+		if err != nil {
+			return 0, err
+		}
+
+		daily, ok := rates.rates[date.Format(dateLayout)]
+		if !ok {
+			continue
+		}
+
+		fromRate, fromOK := baseRate(daily, from)
+		toRate, toOK := baseRate(daily, to)
+		if !fromOK {
+			return 0, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, from)
+		}
+		if !toOK {
+			return 0, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, to)
+		}
+		return toRate / fromRate, nil
+	}
+
+	return 0, fmt.Errorf("%w: sem taxas até %s", ErrRatesUnavailable, day.Format(dateLayout))
+}
+
+// Convert converte amount de from para to com a taxa da data de at (ver Rate)
+func (s *Service) Convert(amount float64, from, to string, at time.Time) (float64, error) {
+	rate, err := s.Rate(from, to, at)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+// baseRate retorna a taxa de BaseCurrency para currency num dia
+func baseRate(daily map[string]float64, currency string) (float64, bool) {
+	if currency == BaseCurrency {
+		return 1, true
+	}
+	rate, ok := daily[currency]
+	return rate, ok && rate > 0
+}
+
+// loadYear retorna as taxas de um ano, carregando-as do repositório ou do fornecedor se ainda
+// não estiverem em memória (ou, no ano corrente, se já tiverem expirado). Deve ser chamado com
+// s.mu bloqueado.
+func (s *Service) loadYear(year int) (*yearRates, error) {
+	now := time.Now()
+	current := year == now.UTC().Year()
+
+	cached, ok := s.years[year]
+	if ok && (!current || now.Sub(cached.loadedAt) < latestRatesTTL) {
+		return cached, nil
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+	if current {
+		to = truncateDay(now)
+	}
+
+	// Os anos anteriores não mudam: se o repositório já tiver o ano completo, não é preciso o fornecedor
+	var stored []models.FXRate
+	if s.repo != nil {
+		var err error
+		stored, err = s.repo.GetRates(BaseCurrency, from, to)
+		if err != nil {
+			log.Printf("Erro ao ler taxas de câmbio de %d: %v\n", year, err)
+		}
+		if !current && completeYear(stored, to) {
+			return s.cacheYear(year, stored, now), nil
+		}
+	}
+
+	fetched, err := s.fetch(from, to)
+	if err != nil {
+		// Sem fornecedor, as taxas guardadas ou em memória ainda servem
+		switch {
+		case ok:
+			cached.loadedAt = now
+			return cached, nil
+		case len(stored) > 0:
+			return s.cacheYear(year, stored, now), nil
+		default:
+			return nil, fmt.Errorf("%w: %v", ErrRatesUnavailable, err)
+		}
+	}
+
+	if s.repo != nil && len(fetched) > 0 {
+		if err := s.repo.SaveRates(fetched); err != nil {
+			log.Printf("Erro ao guardar taxas de câmbio de %d: %v\n", year, err)
+		}
+	}
+
+	return s.cacheYear(year, fetched, now), nil
+}
+
+// fetch obtém as taxas do fornecedor, exceto se este tiver falhado há menos de providerRetryDelay
+func (s *Service) fetch(from, to time.Time) ([]models.FXRate, error) {
+	if !s.failedAt.IsZero() && time.Since(s.failedAt) < providerRetryDelay {
+		return nil, errors.New("fornecedor indisponível")
+	}
+
+	rates, err := s.provider.Rates(BaseCurrency, from, to)
+	if err != nil {
+		s.failedAt = time.Now()
+		log.Printf("Erro ao obter taxas de câmbio de %d: %v\n", from.Year(), err)
+		return nil, err
+	}
+
+	s.failedAt = time.Time{}
+	return rates, nil
+}
+
+// cacheYear guarda em memória as taxas de um ano
+func (s *Service) cacheYear(year int, rates []models.FXRate, loadedAt time.Time) *yearRates {
+	cached := &yearRates{
+		rates:    make(map[string]map[string]float64),
+		loadedAt: loadedAt,
+	}
+	for _, rate := range rates {
+		date := rate.Date.UTC().Format(dateLayout)
+		if cached.rates[date] == nil {
+			cached.rates[date] = make(map[string]float64)
+		}
+		cached.rates[date][rate.Currency] = rate.Rate
+	}
+
+	s.years[year] = cached
+	return cached
+}
+
+// completeYear indica se as taxas guardadas chegam até à última semana do ano
+func completeYear(rates []models.FXRate, end time.Time) bool {
+	return len(rates) > 0 && !rates[len(rates)-1].Date.Before(end.AddDate(0, 0, -maxRateLookback))
+}
+
+// truncateDay retorna a meia-noite UTC do dia de t
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,400 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// Erros do histórico do portfólio
+var (
+	ErrHistoryUnavailable  = errors.New("histórico de preços indisponível")
+	ErrInvalidHistoryRange = errors.New("intervalo de histórico inválido")
+)
+
+// Valores por omissão do histórico do portfólio
+const (
+	DefaultHistoryRange    = "30d"
+	DefaultHistoryInterval = "1d"
+)
+
+// HistoryRangeAll pede o histórico desde a primeira transação do portfólio
+const HistoryRangeAll = "all"
+
+// maxHistoryPoints limita o número de pontos de uma série
+const maxHistoryPoints = 2000
+
+// minPriceTolerance é a idade máxima de um preço histórico usado num ponto da série,
+// alargada para o passo da série quando este é maior
+const minPriceTolerance = 24 * time.Hour
+
+// year é a duração de um ano usada em períodos e na anualização de retornos
+const year = 365 * 24 * time.Hour
+
+// periodUnits associa as unidades aceites em intervalos e períodos à sua duração
+var periodUnits = map[byte]time.Duration{
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+	'y': year,
+}
+
+// ParsePeriod interpreta um período no formato "<n><unidade>", com unidade h, d, w ou y (ex.: "30d")
+func ParsePeriod(value string) (time.Duration, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidHistoryRange, value)
+	}
+
+	unit, ok := periodUnits[value[len(value)-1]]
+	if !ok {
+		return 0, fmt.Errorf("%w: unidade desconhecida em %q", ErrInvalidHistoryRange, value)
+	}
+
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidHistoryRange, value)
+	}
+
+	return time.Duration(n) * unit, nil
+}
+
+// priceSeries é uma série de preços históricos por ordem cronológica
+type priceSeries []models.HistoricalData
+
+// at retorna o último preço até t, desde que não seja mais antigo do que tolerance
+func (p priceSeries) at(t time.Time, tolerance time.Duration) (float64, bool) {
+	i := sort.Search(len(p), func(i int) bool { return p[i].Timestamp.After(t) })
+	if i == 0 {
+		return 0, false
+	}
+
+	point := p[i-1]
+	if t.Sub(point.Timestamp) > tolerance || point.Price <= 0 {
+		return 0, false
+	}
+	return point.Price, true
+}
+
+// GetPortfolioHistory reconstrói o valor do portfólio ao longo do tempo, combinando o livro
+// de transações com os preços do histórico. rangeParam é um período (ex.: "30d") ou
+// HistoryRangeAll; interval é o passo da série (ex.: "1d"). Os pontos terminam no instante atual.
+// Posições sem preço num ponto ficam fora do valor e são listadas nesse ponto. Os valores são
+// expressos na moeda indicada (vazia para a moeda por omissão), à taxa de câmbio de cada data.
+func (s *PortfolioService) GetPortfolioHistory(portfolioID, rangeParam, interval, currency string) (*models.PortfolioHistory, error) {
+	if s.history == nil {
+		return nil, ErrHistoryUnavailable
+	}
+
+	portfolio, ledger, currency, err := s.ledgerIn(portfolioID, currency)
+	if err != nil {
+		return nil, err
+	}
+	ledger = sortTransactions(ledger)
+
+	window, err := newSeriesWindow(rangeParam, interval, DefaultHistoryRange, DefaultHistoryInterval, ledger, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	valuation, err := s.newValuation(portfolio, ledger, currency, window.from().Add(-window.tolerance), window.to(), window.tolerance, ReturnsBenchmark)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &models.PortfolioHistory{
+		PortfolioID:    portfolioID,
+		Currency:       currency,
+		Range:          window.rangeParam,
+		Interval:       window.interval,
+		From:           window.from(),
+		To:             window.to(),
+		PricesComplete: true,
+		Points:         make([]models.PortfolioHistoryPoint, 0, len(window.timestamps)),
+	}
+
+	for _, t := range window.timestamps {
+		point, err := valuation.at(t, true)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(point.MissingSymbols) > 0 {
+			history.PricesComplete = false
+		}
+		history.Points = append(history.Points, point)
+	}
+
+	history.StartValue = history.Points[0].Value
+	history.EndValue = history.Points[len(history.Points)-1].Value
+	history.Change = history.EndValue - history.StartValue
+	if history.StartValue > 0 {
+		history.ChangePercentage = history.Change / history.StartValue * 100
+	}
+
+	history.Returns, err = valuation.returns(history.From, history.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// seriesWindow descreve os instantes de uma série temporal do portfólio
+type seriesWindow struct {
+	rangeParam string
+	interval   string
+	step       time.Duration
+	tolerance  time.Duration // idade máxima dos preços usados em cada ponto
+	timestamps []time.Time   // por ordem cronológica
+}
+
+// newSeriesWindow interpreta o período (um período ou HistoryRangeAll) e o passo de uma série,
+// com os valores por omissão indicados, e calcula os seus instantes: alinhados com end e a
+// recuar até ao início do período
+func newSeriesWindow(rangeParam, interval, defaultRange, defaultInterval string, ledger []models.Transaction, end time.Time) (*seriesWindow, error) {
+	rangeParam = strings.ToLower(strings.TrimSpace(rangeParam))
+	if rangeParam == "" {
+		rangeParam = defaultRange
+	}
+	interval = strings.ToLower(strings.TrimSpace(interval))
+	if interval == "" {
+		interval = defaultInterval
+	}
+
+	step, err := ParsePeriod(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	start := end
+	if rangeParam == HistoryRangeAll {
+		if len(ledger) > 0 {
+			start = ledger[0].Timestamp
+		}
+	} else {
+		period, err := ParsePeriod(rangeParam)
+		if err != nil {
+			return nil, err
+		}
+		start = end.Add(-period)
+	}
+
+	if end.Sub(start)/step+1 > maxHistoryPoints {
+		return nil, fmt.Errorf("%w: mais de %d pontos; aumente o intervalo", ErrInvalidHistoryRange, maxHistoryPoints)
+	}
+
+	var timestamps []time.Time
+	for t := end; !t.Before(start); t = t.Add(-step) {
+		timestamps = append(timestamps, t)
+	}
+	for i, j := 0, len(timestamps)-1; i < j; i, j = i+1, j-1 {
+		timestamps[i], timestamps[j] = timestamps[j], timestamps[i]
+	}
+
+	tolerance := step
+	if tolerance < minPriceTolerance {
+		tolerance = minPriceTolerance
+	}
+
+	return &seriesWindow{
+		rangeParam: rangeParam,
+		interval:   interval,
+		step:       step,
+		tolerance:  tolerance,
+		timestamps: timestamps,
+	}, nil
+}
+
+// from retorna o primeiro instante da série
+func (w *seriesWindow) from() time.Time { return w.timestamps[0] }
+
+// to retorna o último instante da série
+func (w *seriesWindow) to() time.Time { return w.timestamps[len(w.timestamps)-1] }
+
+// valuation reconstrói as posições do portfólio em qualquer instante a partir do livro
+// (por ordem cronológica) e valoriza-as com o histórico de preços
+type valuation struct {
+	ledger    []models.Transaction
+	engine    *CostBasisEngine
+	prices    map[string]priceSeries
+	tolerance time.Duration
+
+	// Últimas posições calculadas e o número de transações que as originaram
+	applied  int
+	holdings []models.Holding
+}
+
+// newValuation prepara a valorização do portfólio com os preços entre from e to dos
+// símbolos do livro e dos símbolos extra indicados (ex.: o benchmark), convertidos para
+// currency, a moeda do livro
+func (s *PortfolioService) newValuation(portfolio *models.Portfolio, ledger []models.Transaction, currency string, from, to time.Time, tolerance time.Duration, extra ...string) (*valuation, error) {
+	engine, err := NewCostBasisEngine(portfolioMethod(portfolio))
+	if err != nil {
+		return nil, err
+	}
+
+	prices, err := s.priceHistory(ledgerSymbols(ledger, extra...), from, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.convertSeries(prices, currency); err != nil {
+		return nil, err
+	}
+
+	return &valuation{
+		ledger:    ledger,
+		engine:    engine,
+		prices:    prices,
+		tolerance: tolerance,
+		applied:   -1,
+	}, nil
+}
+
+// at valoriza as posições no instante t. Com inclusive, as transações feitas em t já contam.
+func (v *valuation) at(t time.Time, inclusive bool) (models.PortfolioHistoryPoint, error) {
+	count := sort.Search(len(v.ledger), func(i int) bool {
+		if inclusive {
+			return v.ledger[i].Timestamp.After(t)
+		}
+		return !v.ledger[i].Timestamp.Before(t)
+	})
+
+	// Só é preciso recalcular as posições quando entram novas transações
+	if count != v.applied {
+		report, err := v.engine.Run(v.ledger[:count])
+		if err != nil {
+			return models.PortfolioHistoryPoint{}, err
+		}
+		v.holdings = report.Holdings
+		v.applied = count
+	}
+
+	point := models.PortfolioHistoryPoint{Timestamp: t}
+	for _, holding := range v.holdings {
+		price, ok := v.prices[holding.Symbol].at(t, v.tolerance)
+		if !ok {
+			point.MissingSymbols = append(point.MissingSymbols, holding.Symbol)
+			continue
+		}
+		point.Value += holding.Quantity * price
+		point.CostBasis += holding.CostBasis
+	}
+	point.ProfitLoss = point.Value - point.CostBasis
+
+	return point, nil
+}
+
+// overviewPeriods são os períodos de desempenho da visão geral, em dias
+var overviewPeriods = []struct {
+	name string
+	days int
+}{
+	{"day", 1},
+	{"week", 7},
+	{"month", 30},
+	{"year", 365},
+}
+
+// GetOverview soma o valor atual dos portfólios do usuário e a variação do valor agregado
+// no último dia, semana, mês e ano, na moeda indicada (vazia para a moeda por omissão).
+// Sem histórico de preços, o desempenho é omitido.
+func (s *PortfolioService) GetOverview(userID, currency string) (*models.PortfolioOverview, error) {
+	currency, err := s.ResolveCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolios, err := s.repo.ListPortfolios(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &models.PortfolioOverview{
+		Currency:       currency,
+		PortfolioCount: len(portfolios),
+		PricesComplete: true,
+	}
+
+	// Valores diários do último ano, somados entre portfólios a contar do fim da série
+	var daily []float64
+	for _, portfolio := range portfolios {
+		stats, err := s.GetPortfolioStats(portfolio.ID, currency)
+		if err != nil {
+			return nil, err
+		}
+		overview.TotalBalance += stats.TotalValue
+		overview.PricesComplete = overview.PricesComplete && stats.PricesComplete
+
+		if s.history == nil {
+			continue
+		}
+
+		history, err := s.GetPortfolioHistory(portfolio.ID, "1y", "1d", currency)
+		if err != nil {
+			return nil, err
+		}
+		overview.PricesComplete = overview.PricesComplete && history.PricesComplete
+
+		for i := range history.Points {
+			point := history.Points[len(history.Points)-1-i]
+			if i == len(daily) {
+				daily = append(daily, 0)
+			}
+			daily[i] += point.Value
+		}
+	}
+
+	if len(daily) == 0 {
+		return overview, nil
+	}
+
+	overview.Performance = map[string]float64{}
+	for _, period := range overviewPeriods {
+		if period.days >= len(daily) || daily[period.days] <= 0 {
+			continue
+		}
+		overview.Performance[period.name] = (daily[0] - daily[period.days]) / daily[period.days] * 100
+	}
+
+	return overview, nil
+}
+
+// priceHistory obtém os preços históricos de cada símbolo no intervalo indicado
+func (s *PortfolioService) priceHistory(symbols []string, from, to time.Time) (map[string]priceSeries, error) {
+	prices := make(map[string]priceSeries, len(symbols))
+	for _, symbol := range symbols {
+		data, err := s.history.GetHistoricalData(symbol, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao obter histórico de %s: %w", symbol, err)
+		}
+		prices[symbol] = data
+	}
+	return prices, nil
+}
+
+// ledgerSymbols retorna os símbolos movimentados no livro e os símbolos extra não vazios,
+// sem repetições e por ordem alfabética
+func ledgerSymbols(transactions []models.Transaction, extra ...string) []string {
+	seen := map[string]bool{}
+	var symbols []string
+	for _, symbol := range extra {
+		if symbol != "" && !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	for _, tx := range transactions {
+		if !seen[tx.Symbol] {
+			seen[tx.Symbol] = true
+			symbols = append(symbols, tx.Symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
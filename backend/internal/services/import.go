@@ -0,0 +1,189 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/importer"
+
+	"github.com/google/uuid"
+)
+
+// ImportTransactions importa para o livro do portfólio as transações de um ficheiro CSV exportado
+// por uma corretora (ver importer.Parse). Linhas já importadas antes, detetadas pelo ImportID, são
+// marcadas como repetidas; linhas inválidas ou que deixariam o saldo negativo ficam com o erro e
+// não são importadas. Em dryRun nada é gravado, para que o resultado sirva de pré-visualização.
+func (s *PortfolioService) ImportTransactions(portfolioID string, format importer.Format, data io.Reader, mapping *importer.Mapping, dryRun bool) (*models.ImportResult, error) {
+	_, ledger, err := s.ledger(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := importer.Parse(format, data, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	// Além da origem das transações importadas, a coluna id de uma exportação do próprio livro
+	// identifica as transações registadas à mão
+	imported := map[string]bool{}
+	for _, tx := range ledger {
+		if tx.ImportID != "" {
+			imported[tx.ImportID] = true
+		}
+		imported[fmt.Sprintf("%s:%s", importer.FormatGeneric, tx.ID)] = true
+	}
+
+	// As transações recebem instantes de criação sucessivos, para que as que partilham a data
+	// mantenham a ordem do ficheiro no livro
+	now := time.Now()
+	created := 0
+
+	var accepted []int // índices das linhas a importar
+	for i := range rows {
+		row := &rows[i]
+		if row.Status != models.ImportRowOK {
+			continue
+		}
+
+		for j := range row.Transactions {
+			tx := &row.Transactions[j]
+			if err := normalizeTransaction(tx); err != nil {
+				row.Status = models.ImportRowError
+				row.Error = err.Error()
+				break
+			}
+			tx.ID = uuid.New().String()
+			tx.PortfolioID = portfolioID
+			tx.CreatedAt = now.Add(time.Duration(created) * time.Microsecond)
+			tx.UpdatedAt = tx.CreatedAt
+			created++
+		}
+		if row.Status != models.ImportRowOK {
+			continue
+		}
+
+		for _, tx := range row.Transactions {
+			if imported[tx.ImportID] {
+				row.Status = models.ImportRowDuplicate
+			}
+		}
+		if row.Status != models.ImportRowOK {
+			continue
+		}
+
+		for _, tx := range row.Transactions {
+			imported[tx.ImportID] = true
+		}
+		accepted = append(accepted, i)
+	}
+
+	accepted, err = checkImportBalance(ledger, rows, accepted)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ImportResult{
+		PortfolioID: portfolioID,
+		Format:      strings.ToLower(string(format)),
+		DryRun:      dryRun,
+		Valid:       len(accepted),
+		Rows:        rows,
+	}
+	for _, row := range rows {
+		switch row.Status {
+		case models.ImportRowDuplicate:
+			result.Duplicates++
+		case models.ImportRowSkipped:
+			result.Skipped++
+		case models.ImportRowError:
+			result.Errors++
+		}
+	}
+
+	if dryRun || len(accepted) == 0 {
+		return result, nil
+	}
+
+	var transactions []models.Transaction
+	for _, i := range accepted {
+		transactions = append(transactions, rows[i].Transactions...)
+	}
+	if err := s.repo.AddTransactions(transactions); err != nil {
+		return nil, err
+	}
+	result.Imported = len(transactions)
+
+	return result, nil
+}
+
+// importedTransaction é uma transação a importar e a linha de onde vem
+type importedTransaction struct {
+	row int
+	tx  models.Transaction
+}
+
+// checkImportBalance garante que o livro continua consistente com as linhas aceites. Enquanto
+// houver saídas sem saldo, procura por bisseção a primeira transação, por ordem cronológica,
+// que torna o livro inconsistente, marca a sua linha com o erro e retira-a. Retorna as linhas
+// que continuam aceites, ou um erro se o livro atual já for inconsistente, caso em que nenhuma
+// linha pode ser responsabilizada.
+func checkImportBalance(ledger []models.Transaction, rows []models.ImportRow, accepted []int) ([]int, error) {
+	if _, err := ComputeHoldings(ledger, models.DefaultCostBasisMethod); err != nil {
+		return nil, fmt.Errorf("o livro atual do portfólio é inconsistente, corrija-o antes de importar: %w", err)
+	}
+
+	for {
+		var pending []importedTransaction
+		for _, i := range accepted {
+			for _, tx := range rows[i].Transactions {
+				pending = append(pending, importedTransaction{row: i, tx: tx})
+			}
+		}
+		sort.SliceStable(pending, func(a, b int) bool {
+			return pending[a].tx.Timestamp.Before(pending[b].tx.Timestamp)
+		})
+
+		check := func(n int) error {
+			changed := make([]models.Transaction, 0, len(ledger)+n)
+			changed = append(changed, ledger...)
+			for _, p := range pending[:n] {
+				changed = append(changed, p.tx)
+			}
+			_, err := ComputeHoldings(changed, models.DefaultCostBasisMethod)
+			return err
+		}
+
+		failure := check(len(pending))
+		if failure == nil || len(pending) == 0 {
+			return accepted, nil
+		}
+
+		// O livro atual é consistente: procurar o menor prefixo que deixa de o ser
+		low, high := 0, len(pending)
+		for high-low > 1 {
+			mid := (low + high) / 2
+			if err := check(mid); err != nil {
+				high, failure = mid, err
+			} else {
+				low = mid
+			}
+		}
+
+		culprit := pending[high-1].row
+		rows[culprit].Status = models.ImportRowError
+		rows[culprit].Error = failure.Error()
+
+		remaining := accepted[:0]
+		for _, i := range accepted {
+			if i != culprit {
+				remaining = append(remaining, i)
+			}
+		}
+		accepted = remaining
+	}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/exporter"
+	"gofolio/backend/internal/services/importer"
+	"gofolio/backend/internal/storage/inmemory"
+)
+
+// newImportPortfolio cria um portfólio vazio num repositório em memória
+func newImportPortfolio(t *testing.T) (*PortfolioService, string) {
+	t.Helper()
+	service := NewPortfolioService(inmemory.NewPortfolioRepository(), nil, nil, nil)
+	portfolio, err := service.CreatePortfolio("u1", "Principal", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service, portfolio.ID
+}
+
+// TestImportExportRoundTrip verifica que reimportar a exportação do livro, ou o ficheiro original
+// da corretora depois de reimportar a exportação noutro portfólio, não repete transações
+func TestImportExportRoundTrip(t *testing.T) {
+	binance := "Date(UTC),Pair,Side,Price,Executed,Amount,Fee\n" +
+		"2024-01-02 00:00:00,BTCUSDT,BUY,100,1BTC,100USDT,0.001BTC\n"
+
+	service, portfolioID := newImportPortfolio(t)
+	if _, err := service.AddTransaction(portfolioID, trade("", models.TransactionBuy, 1, 100, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.ImportTransactions(portfolioID, importer.FormatBinance, strings.NewReader(binance), nil, false); err != nil {
+		t.Fatal(err)
+	}
+
+	ledger, err := service.ListTransactions(portfolioID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported bytes.Buffer
+	if err := exporter.WriteTransactionsCSV(&exported, ledger); err != nil {
+		t.Fatal(err)
+	}
+
+	// No mesmo portfólio, a transação manual é reconhecida pelo id e a importada pela origem
+	result, err := service.ImportTransactions(portfolioID, importer.FormatGeneric, bytes.NewReader(exported.Bytes()), nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Duplicates != 2 || result.Valid != 0 {
+		t.Errorf("%d repetidas e %d válidas, esperado 2 e 0: %+v", result.Duplicates, result.Valid, result.Rows)
+	}
+
+	// Noutro portfólio, a exportação é importada com a origem conservada
+	other, otherID := newImportPortfolio(t)
+	if _, err := other.ImportTransactions(otherID, importer.FormatGeneric, bytes.NewReader(exported.Bytes()), nil, false); err != nil {
+		t.Fatal(err)
+	}
+	result, err = other.ImportTransactions(otherID, importer.FormatBinance, strings.NewReader(binance), nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Duplicates != 1 {
+		t.Errorf("%d repetidas, esperado 1: %+v", result.Duplicates, result.Rows)
+	}
+}
+
+// TestImportDuplicates verifica as linhas repetidas no próprio ficheiro e as já importadas
+func TestImportDuplicates(t *testing.T) {
+	data := "id,type,symbol,quantity,price,quoteCurrency,timestamp\n" +
+		"a,buy,BTC,1,100,USD,2024-01-01\n" +
+		"a,buy,BTC,1,100,USD,2024-01-01\n" +
+		"b,sell,BTC,0.5,150,USD,2024-01-02\n"
+
+	service, portfolioID := newImportPortfolio(t)
+	result, err := service.ImportTransactions(portfolioID, importer.FormatGeneric, strings.NewReader(data), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 2 || result.Duplicates != 1 || result.Rows[1].Status != models.ImportRowDuplicate {
+		t.Errorf("%d importadas e %d repetidas, esperado 2 e 1: %+v", result.Imported, result.Duplicates, result.Rows)
+	}
+
+	result, err = service.ImportTransactions(portfolioID, importer.FormatGeneric, strings.NewReader(data), nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Duplicates != 3 || result.Valid != 0 {
+		t.Errorf("reimportação: %d repetidas e %d válidas, esperado 3 e 0", result.Duplicates, result.Valid)
+	}
+}
+
+// TestCheckImportBalance verifica que a bisseção retira apenas as linhas que deixam o saldo
+// negativo, pela ordem cronológica das transações
+func TestCheckImportBalance(t *testing.T) {
+	ledger := []models.Transaction{trade("b0", models.TransactionBuy, 1, 100, 1)}
+	rows := importRows(
+		trade("s1", models.TransactionSell, 0.5, 150, 2),
+		trade("s2", models.TransactionSell, 1, 150, 3), // só resta 0,5
+		trade("b3", models.TransactionBuy, 2, 120, 5),
+		trade("s4", models.TransactionSell, 1, 130, 6),
+		trade("s5", models.TransactionSell, 3, 130, 7), // só resta 1,5
+	)
+
+	accepted, err := checkImportBalance(ledger, rows, []int{0, 1, 2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accepted) != 3 || accepted[0] != 0 || accepted[1] != 2 || accepted[2] != 3 {
+		t.Errorf("linhas aceites = %v, esperado [0 2 3]", accepted)
+	}
+	for _, i := range []int{1, 4} {
+		if rows[i].Status != models.ImportRowError || rows[i].Error == "" {
+			t.Errorf("linha %d: %s (%q), esperado erro", i, rows[i].Status, rows[i].Error)
+		}
+	}
+
+	// As linhas são avaliadas pela data e não pela ordem do ficheiro
+	rows = importRows(
+		trade("s1", models.TransactionSell, 2, 150, 4),
+		trade("b2", models.TransactionBuy, 1, 100, 3),
+	)
+	if accepted, err := checkImportBalance(ledger, rows, []int{0, 1}); err != nil || len(accepted) != 2 {
+		t.Errorf("linhas aceites = %v (%v), esperado [0 1]", accepted, err)
+	}
+
+	// Com o livro atual inconsistente, nenhuma linha é responsabilizada
+	broken := []models.Transaction{trade("s0", models.TransactionSell, 1, 100, 1)}
+	_, err = checkImportBalance(broken, importRows(trade("b1", models.TransactionBuy, 1, 100, 2)), []int{0})
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("erro %v, esperado ErrInsufficientBalance", err)
+	}
+}
+
+// importRows constrói uma linha aceite por transação
+func importRows(transactions ...models.Transaction) []models.ImportRow {
+	rows := make([]models.ImportRow, len(transactions))
+	for i, tx := range transactions {
+		rows[i] = models.ImportRow{Line: i + 2, Status: models.ImportRowOK, Transactions: []models.Transaction{tx}}
+	}
+	return rows
+}
@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gofolio/backend/internal/models"
+)
+
+// parseBinance lê o histórico de trades exportado pela Binance. São aceites as duas versões da
+// exportação: a atual (Pair, Side, Executed, Amount e Fee com o código do ativo junto ao valor)
+// e a antiga (Market, Type, Amount, Total, Fee e Fee Coin).
+func parseBinance(data io.Reader, _ *Mapping) ([]models.ImportRow, error) {
+	t, err := readTable(data, "Date(UTC)", "Price")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case t.has("Pair") && t.has("Side") && t.has("Executed"):
+		return t.rows(t.binanceTrade), nil
+	case t.has("Market") && t.has("Type") && t.has("Amount"):
+		return t.rows(t.binanceLegacyTrade), nil
+	}
+	return nil, fmt.Errorf("%w: colunas da Binance não reconhecidas", ErrInvalidFile)
+}
+
+// binanceTrade converte uma linha da exportação atual da Binance
+func (t *table) binanceTrade(record []string) ([]models.Transaction, string, error) {
+	timestamp, err := parseTime(t.value(record, "Date(UTC)"))
+	if err != nil {
+		return nil, "", err
+	}
+	txType, err := tradeSide(t.value(record, "Side"))
+	if err != nil {
+		return nil, "", err
+	}
+	price, err := parseNumber(t.value(record, "Price"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Os códigos do par delimitam o número nos valores; o código dos ativos pode faltar nos
+	// valores e, nesse caso, vem do par
+	base, pairQuote, _ := splitPair(t.value(record, "Pair"))
+
+	quantity, symbol, err := parseAmount(t.value(record, "Executed"), base, pairQuote)
+	if err != nil {
+		return nil, "", err
+	}
+	_, quote, err := parseAmount(t.value(record, "Amount"), base, pairQuote)
+	if err != nil {
+		return nil, "", err
+	}
+	fee, feeCurrency, err := parseAmount(t.value(record, "Fee"), base, pairQuote)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if symbol == "" {
+		symbol = base
+	}
+	if quote == "" {
+		quote = pairQuote
+	}
+	if symbol == "" || quote == "" {
+		return nil, "", fmt.Errorf("par desconhecido %q", t.value(record, "Pair"))
+	}
+	if feeCurrency == "" {
+		feeCurrency = quote
+	}
+
+	return []models.Transaction{{
+		Type:          txType,
+		Symbol:        symbol,
+		Quantity:      quantity,
+		Price:         price,
+		QuoteCurrency: quote,
+		Fee:           fee,
+		FeeCurrency:   feeCurrency,
+		Timestamp:     timestamp,
+	}}, "", nil
+}
+
+// binanceLegacyTrade converte uma linha da exportação antiga da Binance
+func (t *table) binanceLegacyTrade(record []string) ([]models.Transaction, string, error) {
+	timestamp, err := parseTime(t.value(record, "Date(UTC)"))
+	if err != nil {
+		return nil, "", err
+	}
+	txType, err := tradeSide(t.value(record, "Type"))
+	if err != nil {
+		return nil, "", err
+	}
+	symbol, quote, ok := splitPair(t.value(record, "Market"))
+	if !ok {
+		return nil, "", fmt.Errorf("par desconhecido %q", t.value(record, "Market"))
+	}
+	price, err := parseNumber(t.value(record, "Price"))
+	if err != nil {
+		return nil, "", err
+	}
+	quantity, err := parseNumber(t.value(record, "Amount"))
+	if err != nil {
+		return nil, "", err
+	}
+	fee, err := parseNumber(t.value(record, "Fee"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	feeCurrency := NormalizeSymbol(t.value(record, "Fee Coin"))
+	if feeCurrency == "" {
+		feeCurrency = quote
+	}
+
+	return []models.Transaction{{
+		Type:          txType,
+		Symbol:        symbol,
+		Quantity:      quantity,
+		Price:         price,
+		QuoteCurrency: quote,
+		Fee:           fee,
+		FeeCurrency:   feeCurrency,
+		Timestamp:     timestamp,
+	}}, "", nil
+}
+
+// tradeSide converte o lado de uma ordem ("BUY", "sell", ...) no tipo de transação
+func tradeSide(side string) (models.TransactionType, error) {
+	switch strings.ToLower(strings.TrimSpace(side)) {
+	case "buy", "b":
+		return models.TransactionBuy, nil
+	case "sell", "s":
+		return models.TransactionSell, nil
+	}
+	return "", fmt.Errorf("lado da ordem desconhecido %q", side)
+}
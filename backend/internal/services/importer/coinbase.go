@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+
+	"gofolio/backend/internal/models"
+)
+
+// coinbaseConversion interpreta as notas das conversões (ex.: "Converted 0.5 ETH to 0.02 BTC")
+var coinbaseConversion = regexp.MustCompile(`(?i)converted\s+([\d.,]+)\s+(\S+)\s+to\s+([\d.,]+)\s+(\S+)`)
+
+// coinbaseTypes associa os tipos de transação da Coinbase aos tipos do livro
+var coinbaseTypes = map[string]models.TransactionType{
+	"buy":                 models.TransactionBuy,
+	"advanced trade buy":  models.TransactionBuy,
+	"sell":                models.TransactionSell,
+	"advanced trade sell": models.TransactionSell,
+	"receive":             models.TransactionDeposit,
+	"deposit":             models.TransactionDeposit,
+	"learning reward":     models.TransactionDeposit,
+	"coinbase earn":       models.TransactionDeposit,
+	"send":                models.TransactionWithdrawal,
+	"withdrawal":          models.TransactionWithdrawal,
+	"rewards income":      models.TransactionStakingReward,
+	"staking income":      models.TransactionStakingReward,
+	"inflation reward":    models.TransactionStakingReward,
+}
+
+// parseCoinbase lê o relatório de transações da Coinbase. As linhas de introdução antes do
+// cabeçalho são ignoradas, as conversões dão origem a uma venda e a uma compra, e os movimentos
+// em moeda fiduciária são ignorados.
+func parseCoinbase(data io.Reader, _ *Mapping) ([]models.ImportRow, error) {
+	t, err := readTable(data, "Timestamp", "Transaction Type", "Asset", "Quantity Transacted")
+	if err != nil {
+		return nil, err
+	}
+	return t.rows(t.coinbaseTransaction), nil
+}
+
+// coinbaseTransaction converte uma linha do relatório da Coinbase
+func (t *table) coinbaseTransaction(record []string) ([]models.Transaction, string, error) {
+	kind := strings.ToLower(t.value(record, "Transaction Type"))
+	symbol := NormalizeSymbol(t.value(record, "Asset"))
+	if isFiat(symbol) {
+		return nil, "movimento em moeda fiduciária", nil
+	}
+
+	timestamp, err := parseTime(t.value(record, "Timestamp"))
+	if err != nil {
+		return nil, "", err
+	}
+	quantity, err := parseNumber(t.value(record, "Quantity Transacted"))
+	if err != nil {
+		return nil, "", err
+	}
+	price, err := parseNumber(t.value(record, "Spot Price at Transaction", "Price at Transaction"))
+	if err != nil {
+		return nil, "", err
+	}
+	fee, err := parseNumber(t.value(record, "Fees and/or Spread", "Fees"))
+	if err != nil {
+		return nil, "", err
+	}
+	subtotal, err := parseNumber(t.value(record, "Subtotal"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	quote := NormalizeSymbol(t.value(record, "Spot Price Currency", "Price Currency"))
+	if quote == "" {
+		quote = models.DefaultQuoteCurrency
+	}
+
+	// As exportações recentes registam as saídas com quantidades e valores negativos
+	tx := models.Transaction{
+		Symbol:        symbol,
+		Quantity:      math.Abs(quantity),
+		Price:         math.Abs(price),
+		QuoteCurrency: quote,
+		Fee:           math.Abs(fee),
+		FeeCurrency:   quote,
+		Timestamp:     timestamp,
+		Notes:         t.value(record, "Notes"),
+		ImportID:      t.value(record, "ID"),
+	}
+
+	if kind == "convert" {
+		return coinbaseConvert(tx, math.Abs(subtotal))
+	}
+
+	txType, ok := coinbaseTypes[kind]
+	if !ok {
+		return nil, fmt.Sprintf("tipo de transação não suportado %q", t.value(record, "Transaction Type")), nil
+	}
+	tx.Type = txType
+
+	return []models.Transaction{tx}, "", nil
+}
+
+// coinbaseConvert divide uma conversão numa venda do ativo de origem e numa compra do ativo
+// de destino, ao valor da conversão (o subtotal, já sem taxas)
+func coinbaseConvert(sell models.Transaction, subtotal float64) ([]models.Transaction, string, error) {
+	match := coinbaseConversion.FindStringSubmatch(sell.Notes)
+	if match == nil {
+		return nil, "", fmt.Errorf("conversão sem ativo de destino nas notas")
+	}
+
+	target := NormalizeSymbol(match[4])
+	targetQuantity, err := parseNumber(match[3])
+	if err != nil {
+		return nil, "", err
+	}
+	if targetQuantity <= 0 {
+		return nil, "", fmt.Errorf("conversão com quantidade de destino inválida")
+	}
+
+	if subtotal == 0 {
+		subtotal = sell.Quantity * sell.Price
+	}
+
+	sell.Type = models.TransactionSell
+	buy := models.Transaction{
+		Type:          models.TransactionBuy,
+		Symbol:        target,
+		Quantity:      targetQuantity,
+		Price:         subtotal / targetQuantity,
+		QuoteCurrency: sell.QuoteCurrency,
+		FeeCurrency:   sell.QuoteCurrency,
+		Timestamp:     sell.Timestamp,
+		Notes:         sell.Notes,
+		ImportID:      sell.ImportID,
+	}
+
+	return []models.Transaction{sell, buy}, "", nil
+}
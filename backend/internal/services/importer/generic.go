@@ -0,0 +1,150 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gofolio/backend/internal/models"
+)
+
+// Mapping indica as colunas do formato genérico. Colunas vazias usam o nome por omissão
+// (o nome do campo em JSON, ex.: "symbol"); as colunas opcionais podem não existir no ficheiro.
+type Mapping struct {
+	ID            string `json:"id"` // opcional; identificador da linha na origem
+	Type          string `json:"type"`
+	Symbol        string `json:"symbol"` // o símbolo ou um par (ex.: "BTC/USD")
+	Quantity      string `json:"quantity"`
+	Price         string `json:"price"`         // opcional
+	QuoteCurrency string `json:"quoteCurrency"` // opcional
+	Fee           string `json:"fee"`           // opcional
+	FeeCurrency   string `json:"feeCurrency"`   // opcional
+	Timestamp     string `json:"timestamp"`
+	Notes         string `json:"notes"`      // opcional
+	ImportID      string `json:"importId"`   // opcional; origem conservada de uma exportação do livro
+	TimeLayout    string `json:"timeLayout"` // formato das datas em Go (ex.: "02/01/2006 15:04"); por omissão vários formatos comuns
+}
+
+// withDefaults retorna o mapeamento com os nomes por omissão nas colunas vazias
+func (m *Mapping) withDefaults() Mapping {
+	var mapping Mapping
+	if m != nil {
+		mapping = *m
+	}
+
+	defaults := []struct {
+		column *string
+		name   string
+	}{
+		{&mapping.ID, "id"},
+		{&mapping.Type, "type"},
+		{&mapping.Symbol, "symbol"},
+		{&mapping.Quantity, "quantity"},
+		{&mapping.Price, "price"},
+		{&mapping.QuoteCurrency, "quoteCurrency"},
+		{&mapping.Fee, "fee"},
+		{&mapping.FeeCurrency, "feeCurrency"},
+		{&mapping.Timestamp, "timestamp"},
+		{&mapping.Notes, "notes"},
+		{&mapping.ImportID, "importId"},
+	}
+	for _, d := range defaults {
+		if strings.TrimSpace(*d.column) == "" {
+			*d.column = d.name
+		}
+	}
+
+	return mapping
+}
+
+// genericTypes associa sinónimos comuns aos tipos de transação
+var genericTypes = map[string]models.TransactionType{
+	"purchase": models.TransactionBuy,
+	"sale":     models.TransactionSell,
+	"receive":  models.TransactionDeposit,
+	"send":     models.TransactionWithdrawal,
+	"withdraw": models.TransactionWithdrawal,
+	"reward":   models.TransactionStakingReward,
+	"staking":  models.TransactionStakingReward,
+}
+
+// parseGeneric lê um ficheiro com as colunas indicadas no mapeamento. Os tipos aceites são
+// os do livro de transações e alguns sinónimos comuns (ex.: "purchase", "send", "reward").
+func parseGeneric(data io.Reader, mapping *Mapping) ([]models.ImportRow, error) {
+	m := mapping.withDefaults()
+
+	t, err := readTable(data, m.Type, m.Symbol, m.Quantity, m.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.rows(func(record []string) ([]models.Transaction, string, error) {
+		return t.genericTransaction(record, m)
+	}), nil
+}
+
+// genericTransaction converte uma linha do formato genérico
+func (t *table) genericTransaction(record []string, m Mapping) ([]models.Transaction, string, error) {
+	kind := strings.ToLower(t.value(record, m.Type))
+	txType := models.TransactionType(kind)
+	if synonym, ok := genericTypes[kind]; ok {
+		txType = synonym
+	}
+	if !txType.Valid() {
+		return nil, "", fmt.Errorf("tipo de transação desconhecido %q", t.value(record, m.Type))
+	}
+
+	var layouts []string
+	if m.TimeLayout != "" {
+		layouts = []string{m.TimeLayout}
+	}
+	timestamp, err := parseTime(t.value(record, m.Timestamp), layouts...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	quantity, err := parseNumber(t.value(record, m.Quantity))
+	if err != nil {
+		return nil, "", err
+	}
+	price, err := parseNumber(t.value(record, m.Price))
+	if err != nil {
+		return nil, "", err
+	}
+	fee, err := parseNumber(t.value(record, m.Fee))
+	if err != nil {
+		return nil, "", err
+	}
+
+	symbol := NormalizeSymbol(t.value(record, m.Symbol))
+	quote := NormalizeSymbol(t.value(record, m.QuoteCurrency))
+	if base, pairQuote, ok := splitPair(t.value(record, m.Symbol)); ok && strings.ContainsAny(t.value(record, m.Symbol), "/-_") {
+		symbol = base
+		if quote == "" {
+			quote = pairQuote
+		}
+	}
+	if isFiat(symbol) {
+		return nil, "movimento em moeda fiduciária", nil
+	}
+
+	// Numa exportação do livro, a origem das transações importadas é conservada, para que
+	// reimportar o ficheiro original ou a exportação não as repita
+	importID := t.value(record, m.ID)
+	if exported := t.value(record, m.ImportID); isSourceImportID(exported) {
+		importID = exported
+	}
+
+	return []models.Transaction{{
+		Type:          txType,
+		Symbol:        symbol,
+		Quantity:      quantity,
+		Price:         price,
+		QuoteCurrency: quote,
+		Fee:           fee,
+		FeeCurrency:   NormalizeSymbol(t.value(record, m.FeeCurrency)),
+		Timestamp:     timestamp,
+		Notes:         t.value(record, m.Notes),
+		ImportID:      importID,
+	}}, "", nil
+}
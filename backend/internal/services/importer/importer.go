@@ -0,0 +1,400 @@
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// Format identifica a origem de um ficheiro CSV de transações
+type Format string
+
+// Formatos suportados
+const (
+	FormatBinance  Format = "binance"  // histórico de trades da Binance
+	FormatCoinbase Format = "coinbase" // relatório de transações da Coinbase
+	FormatKraken   Format = "kraken"   // trades.csv da Kraken
+	FormatGeneric  Format = "generic"  // colunas indicadas em Mapping
+)
+
+// Erros da leitura de ficheiros
+var (
+	ErrUnknownFormat = errors.New("formato de importação desconhecido: use binance, coinbase, kraken ou generic")
+	ErrInvalidFile   = errors.New("ficheiro CSV inválido")
+)
+
+// parsers associa cada formato à função que converte as linhas do ficheiro em transações
+var parsers = map[Format]func(data io.Reader, mapping *Mapping) ([]models.ImportRow, error){
+	FormatBinance:  parseBinance,
+	FormatCoinbase: parseCoinbase,
+	FormatKraken:   parseKraken,
+	FormatGeneric:  parseGeneric,
+}
+
+// Parse lê um ficheiro CSV no formato indicado e converte cada linha nas transações
+// correspondentes, com os símbolos normalizados. Os erros de cada linha ficam na própria linha;
+// só é retornado erro se o ficheiro não puder ser lido. O mapping só é usado no formato
+// genérico e pode ser nil. Cada transação recebe um ImportID estável, derivado do ID da
+// corretora ou, na falta deste, do conteúdo da linha, para que reimportações sejam detetadas.
+func Parse(format Format, data io.Reader, mapping *Mapping) ([]models.ImportRow, error) {
+	parse, ok := parsers[Format(strings.ToLower(string(format)))]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	rows, err := parse(data, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	assignImportIDs(Format(strings.ToLower(string(format))), rows)
+	return rows, nil
+}
+
+// assignImportIDs preenche o ImportID das transações. Linhas idênticas sem ID da corretora
+// (ex.: duas ordens iguais no mesmo segundo) são distinguidas pela ordem em que aparecem. As
+// origens conservadas numa exportação do livro (formato genérico) são mantidas.
+func assignImportIDs(format Format, rows []models.ImportRow) {
+	occurrences := map[string]int{}
+	for i := range rows {
+		row := &rows[i]
+		for j := range row.Transactions {
+			tx := &row.Transactions[j]
+
+			if format == FormatGeneric && isSourceImportID(tx.ImportID) {
+				continue
+			}
+
+			if tx.ImportID != "" {
+				tx.ImportID = fmt.Sprintf("%s:%s", format, tx.ImportID)
+				if len(row.Transactions) > 1 {
+					tx.ImportID = fmt.Sprintf("%s/%d", tx.ImportID, j+1)
+				}
+				continue
+			}
+
+			fingerprint := fingerprint(*tx)
+			occurrences[fingerprint]++
+			tx.ImportID = fmt.Sprintf("%s:%s", format, fingerprint)
+			if n := occurrences[fingerprint]; n > 1 {
+				tx.ImportID = fmt.Sprintf("%s#%d", tx.ImportID, n)
+			}
+		}
+	}
+}
+
+// isSourceImportID indica se um valor é um ImportID atribuído por Parse, prefixado pelo formato
+func isSourceImportID(value string) bool {
+	format, _, ok := strings.Cut(value, ":")
+	if !ok {
+		return false
+	}
+	switch Format(format) {
+	case FormatBinance, FormatCoinbase, FormatKraken, FormatGeneric:
+		return true
+	}
+	return false
+}
+
+// fingerprint resume o conteúdo de uma transação num identificador curto
+func fingerprint(tx models.Transaction) string {
+	content := strings.Join([]string{
+		string(tx.Type),
+		tx.Symbol,
+		strconv.FormatFloat(tx.Quantity, 'g', -1, 64),
+		strconv.FormatFloat(tx.Price, 'g', -1, 64),
+		tx.QuoteCurrency,
+		strconv.FormatFloat(tx.Fee, 'g', -1, 64),
+		tx.FeeCurrency,
+		tx.Timestamp.UTC().Format(time.RFC3339Nano),
+	}, "|")
+
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:12])
+}
+
+// symbolAliases associa os códigos usados pelas corretoras ao símbolo comum
+var symbolAliases = map[string]string{
+	"XBT":  "BTC",
+	"XXBT": "BTC",
+	"XETH": "ETH",
+	"ETH2": "ETH",
+	"XXRP": "XRP",
+	"XLTC": "LTC",
+	"XXLM": "XLM",
+	"XDG":  "DOGE",
+	"XXDG": "DOGE",
+	"XETC": "ETC",
+	"XZEC": "ZEC",
+	"XXMR": "XMR",
+	"XMLN": "MLN",
+	"XREP": "REP",
+	"ZUSD": "USD",
+	"ZEUR": "EUR",
+	"ZGBP": "GBP",
+	"ZCAD": "CAD",
+	"ZJPY": "JPY",
+	"ZAUD": "AUD",
+	"ZCHF": "CHF",
+}
+
+// NormalizeSymbol converte o código de um ativo numa corretora no símbolo comum
+// (ex.: "XXBT" e "xbt" em "BTC"). Os sufixos de staking da Kraken (ex.: "DOT.S") são removidos.
+func NormalizeSymbol(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if i := strings.Index(symbol, "."); i > 0 {
+		symbol = symbol[:i]
+	}
+	if alias, ok := symbolAliases[symbol]; ok {
+		return alias
+	}
+	return symbol
+}
+
+// quoteAssets são as moedas de cotação reconhecidas no fim de um par sem separador, dos
+// sufixos mais longos para os mais curtos, para que "BTCUSDT" não seja lido como BTCUSD/T
+var quoteAssets = []string{
+	"FDUSD", "USDT", "USDC", "BUSD", "TUSD", "ZUSD", "ZEUR", "ZGBP", "ZCAD", "ZJPY", "XXBT", "XETH",
+	"USD", "EUR", "GBP", "CAD", "JPY", "AUD", "CHF", "TRY", "BRL", "DAI", "BTC", "XBT", "ETH", "BNB",
+}
+
+// splitPair separa um par de negociação (ex.: "BTCUSDT", "BTC/EUR", "XXBTZUSD") no ativo base e na
+// moeda de cotação, ambos normalizados. Uma cotação que deixaria um ativo base com menos de três
+// caracteres só é usada se nenhuma mais curta servir, para que "DOTUSD" não seja lido como DO/TUSD.
+func splitPair(pair string) (string, string, bool) {
+	pair = strings.ToUpper(strings.TrimSpace(pair))
+	for _, separator := range []string{"/", "-", "_"} {
+		if parts := strings.Split(pair, separator); len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+			return NormalizeSymbol(parts[0]), NormalizeSymbol(parts[1]), true
+		}
+	}
+
+	fallback := ""
+	for _, quote := range quoteAssets {
+		if len(pair) <= len(quote) || !strings.HasSuffix(pair, quote) {
+			continue
+		}
+		if len(pair)-len(quote) >= 3 {
+			return NormalizeSymbol(strings.TrimSuffix(pair, quote)), NormalizeSymbol(quote), true
+		}
+		if fallback == "" {
+			fallback = quote
+		}
+	}
+	if fallback != "" {
+		return NormalizeSymbol(strings.TrimSuffix(pair, fallback)), NormalizeSymbol(fallback), true
+	}
+	return "", "", false
+}
+
+// fiatCurrencies são as moedas fiduciárias, cujos movimentos não alteram as posições
+var fiatCurrencies = map[string]bool{
+	"USD": true, "EUR": true, "GBP": true, "CAD": true, "JPY": true, "AUD": true, "CHF": true, "TRY": true, "BRL": true,
+}
+
+// isFiat indica se o símbolo é uma moeda fiduciária
+func isFiat(symbol string) bool {
+	return fiatCurrencies[symbol]
+}
+
+// table é um ficheiro CSV já lido, com o cabeçalho indexado por nome de coluna
+type table struct {
+	columns map[string]int // nome em minúsculas -> índice
+	records [][]string
+	lines   []int // linha do ficheiro de cada registo
+}
+
+// readTable lê um ficheiro CSV. O cabeçalho é a primeira linha que contém todas as colunas
+// obrigatórias (os relatórios de algumas corretoras têm linhas de introdução antes dele).
+func readTable(data io.Reader, required ...string) (*table, error) {
+	reader := csv.NewReader(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	var t *table
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+
+		if t == nil {
+			columns := map[string]int{}
+			for i, name := range record {
+				name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+				if _, ok := columns[name]; !ok {
+					columns[name] = i
+				}
+			}
+			complete := true
+			for _, name := range required {
+				if _, ok := columns[strings.ToLower(name)]; !ok {
+					complete = false
+					break
+				}
+			}
+			if complete {
+				t = &table{columns: columns}
+			}
+			continue
+		}
+
+		if blankRecord(record) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		t.records = append(t.records, record)
+		t.lines = append(t.lines, line)
+	}
+
+	if t == nil {
+		return nil, fmt.Errorf("%w: cabeçalho não encontrado (colunas obrigatórias: %s)", ErrInvalidFile, strings.Join(required, ", "))
+	}
+	return t, nil
+}
+
+// blankRecord indica se todos os campos do registo estão vazios
+func blankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// has indica se o ficheiro tem a coluna indicada
+func (t *table) has(column string) bool {
+	_, ok := t.columns[strings.ToLower(column)]
+	return ok
+}
+
+// value retorna o valor da primeira das colunas indicadas que existir no ficheiro
+func (t *table) value(record []string, columns ...string) string {
+	for _, column := range columns {
+		if i, ok := t.columns[strings.ToLower(column)]; ok {
+			if i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// rows converte cada registo numa linha importada. As linhas cujo parse falha ficam com o erro.
+func (t *table) rows(parse func(record []string) ([]models.Transaction, string, error)) []models.ImportRow {
+	rows := make([]models.ImportRow, 0, len(t.records))
+	for i, record := range t.records {
+		row := models.ImportRow{Line: t.lines[i], Status: models.ImportRowOK, Transactions: []models.Transaction{}}
+
+		transactions, skipReason, err := parse(record)
+		switch {
+		case err != nil:
+			row.Status = models.ImportRowError
+			row.Error = err.Error()
+		case skipReason != "":
+			row.Status = models.ImportRowSkipped
+			row.Error = skipReason
+		default:
+			row.Transactions = transactions
+		}
+
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// parseNumber interpreta um valor numérico, ignorando símbolos de moeda e separadores de
+// milhares. Valores vazios correspondem a zero.
+func parseNumber(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	value = strings.NewReplacer("$", "", "€", "", "£", "", ",", "", " ", "").Replace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("número inválido %q", value)
+	}
+	return number, nil
+}
+
+// parseAmount interpreta um valor seguido do código do ativo (ex.: "0.0010000BTC"). Como há
+// códigos que começam ou terminam por algarismos (ex.: "1INCH", "1000SATS"), os códigos indicados
+// (os do par) são procurados primeiro no fim do valor; na falta deles, o código são os caracteres
+// finais que não fazem parte do número.
+func parseAmount(value string, codes ...string) (float64, string, error) {
+	value = strings.TrimSpace(value)
+
+	end := -1
+	for _, code := range codes {
+		if code == "" || len(code) >= len(value) || !strings.HasSuffix(strings.ToUpper(value), strings.ToUpper(code)) {
+			continue
+		}
+		if start := len(value) - len(code); end < 0 || start < end {
+			end = start // o código mais longo
+		}
+	}
+	if end < 0 {
+		end = len(value)
+		for end > 0 && !strings.ContainsRune("0123456789.", rune(value[end-1])) {
+			end--
+		}
+	}
+
+	number, err := parseNumber(value[:end])
+	if err != nil {
+		return 0, "", err
+	}
+	return number, NormalizeSymbol(value[end:]), nil
+}
+
+// timeLayouts são os formatos de data aceites quando o ficheiro não indica outro.
+// Datas sem fuso horário são interpretadas em UTC.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"01/02/2006 15:04:05",
+	"01/02/2006",
+}
+
+// parseTime interpreta uma data num dos formatos indicados, ou nos formatos por omissão
+func parseTime(value string, layouts ...string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if len(layouts) == 0 {
+		layouts = timeLayouts
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	// Datas em segundos desde a época (ex.: exportações da Kraken)
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		whole, fraction := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(fraction*1e9)).UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("data inválida %q", value)
+}
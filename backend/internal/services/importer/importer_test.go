@@ -0,0 +1,263 @@
+package importer
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// parsed é o resultado esperado de uma linha importada; Transactions só é comparado em linhas ok
+type parsed struct {
+	status       models.ImportRowStatus
+	transactions []expectedTx
+	error        string // parte esperada do erro ou do motivo da linha ignorada
+}
+
+// expectedTx são os campos comparados de cada transação
+type expectedTx struct {
+	txType      models.TransactionType
+	symbol      string
+	quantity    float64
+	price       float64
+	quote       string
+	fee         float64
+	feeCurrency string
+	importID    string // vazio para não comparar
+}
+
+// TestParseFixtures lê os ficheiros de exemplo de cada formato em testdata
+func TestParseFixtures(t *testing.T) {
+	tests := []struct {
+		file   string
+		format Format
+		want   []parsed
+	}{
+		{
+			file:   "binance.csv",
+			format: FormatBinance,
+			want: []parsed{
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionBuy, "BTC", 0.01, 42000, "USDT", 0.00001, "BTC", ""}}},
+				// Códigos que começam ou terminam por algarismos
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionBuy, "1INCH", 1000.5, 0.5, "USDT", 0.001, "BNB", ""}}},
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionSell, "1000SATS", 50000, 0.0004, "USDT", 0.02, "USDT", ""}}},
+				// Sem códigos nos valores, os ativos vêm do par
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionSell, "ETH", 2, 0.05, "BTC", 0.0001, "BTC", ""}}},
+				{status: models.ImportRowError, error: `lado da ordem desconhecido "HOLD"`},
+			},
+		},
+		{
+			file:   "binance_legacy.csv",
+			format: FormatBinance,
+			want: []parsed{
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionSell, "ETH", 2, 0.05, "BTC", 0.0001, "BTC", ""}}},
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionBuy, "BNB", 1, 300, "USDT", 0.00075, "BNB", ""}}},
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionBuy, "BNB", 1, 300, "USDT", 0.3, "USDT", ""}}},
+			},
+		},
+		{
+			file:   "coinbase.csv",
+			format: FormatCoinbase,
+			want: []parsed{
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionBuy, "BTC", 0.01, 40000, "USD", 5, "USD", "coinbase:cb1"}}},
+				// Saídas com quantidades e valores negativos
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionSell, "BTC", 0.005, 42000, "USD", 2, "USD", "coinbase:cb2"}}},
+				// A conversão é uma venda e uma compra ao valor do subtotal (990 / 0,0225)
+				{status: models.ImportRowOK, transactions: []expectedTx{
+					{models.TransactionSell, "ETH", 0.5, 2000, "USD", 10, "USD", "coinbase:cb3/1"},
+					{models.TransactionBuy, "BTC", 0.0225, 44000, "USD", 0, "USD", "coinbase:cb3/2"},
+				}},
+				{status: models.ImportRowSkipped, error: "moeda fiduciária"},
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionStakingReward, "SOL", 0.1, 100, "USD", 0, "USD", "coinbase:cb5"}}},
+				{status: models.ImportRowSkipped, error: `tipo de transação não suportado "Pro Withdrawal"`},
+				{status: models.ImportRowError, error: "conversão sem ativo de destino"},
+			},
+		},
+		{
+			file:   "kraken.csv",
+			format: FormatKraken,
+			want: []parsed{
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionBuy, "BTC", 0.01, 40000, "USD", 0.64, "USD", "kraken:TX1"}}},
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionSell, "ETH", 0.1, 2100, "EUR", 0.5, "EUR", "kraken:TX2"}}},
+				{status: models.ImportRowSkipped, error: "margem"},
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionBuy, "DOT", 10, 7.5, "USD", 0.12, "USD", "kraken:TX4"}}},
+			},
+		},
+		{
+			file:   "generic.csv",
+			format: FormatGeneric,
+			want: []parsed{
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionBuy, "BTC", 0.5, 30000, "EUR", 10, "EUR", ""}}},
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionStakingReward, "ETH", 0.01, 2000, "USD", 0, "", ""}}},
+				{status: models.ImportRowSkipped, error: "moeda fiduciária"},
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionBuy, "BTC", 1, 100, "USD", 0, "", ""}}},
+				{status: models.ImportRowOK, transactions: []expectedTx{{models.TransactionBuy, "BTC", 1, 100, "USD", 0, "", ""}}},
+				{status: models.ImportRowError, error: `tipo de transação desconhecido "swap"`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			rows := parseFixture(t, tt.file, tt.format, nil)
+			if len(rows) != len(tt.want) {
+				t.Fatalf("%d linhas, esperadas %d: %+v", len(rows), len(tt.want), rows)
+			}
+
+			for i, want := range tt.want {
+				row := rows[i]
+				if row.Status != want.status || !strings.Contains(row.Error, want.error) {
+					t.Errorf("linha %d: %s (%q), esperado %s (%q)", row.Line, row.Status, row.Error, want.status, want.error)
+					continue
+				}
+				if row.Status != models.ImportRowOK {
+					continue
+				}
+				if len(row.Transactions) != len(want.transactions) {
+					t.Errorf("linha %d: %d transações, esperadas %d", row.Line, len(row.Transactions), len(want.transactions))
+					continue
+				}
+				for j, w := range want.transactions {
+					tx := row.Transactions[j]
+					if tx.Type != w.txType || tx.Symbol != w.symbol || !approx(tx.Quantity, w.quantity) || !approx(tx.Price, w.price) ||
+						tx.QuoteCurrency != w.quote || !approx(tx.Fee, w.fee) || tx.FeeCurrency != w.feeCurrency {
+						t.Errorf("linha %d: %s %v %s a %v %s, taxa %v %s; esperado %s %v %s a %v %s, taxa %v %s", row.Line,
+							tx.Type, tx.Quantity, tx.Symbol, tx.Price, tx.QuoteCurrency, tx.Fee, tx.FeeCurrency,
+							w.txType, w.quantity, w.symbol, w.price, w.quote, w.fee, w.feeCurrency)
+					}
+					if w.importID != "" && tx.ImportID != w.importID {
+						t.Errorf("linha %d: ImportID %q, esperado %q", row.Line, tx.ImportID, w.importID)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestParseImportIDs verifica que as linhas sem ID da corretora recebem um ImportID estável,
+// distinto para linhas idênticas
+func TestParseImportIDs(t *testing.T) {
+	first := parseFixture(t, "generic.csv", FormatGeneric, nil)
+	again := parseFixture(t, "generic.csv", FormatGeneric, nil)
+
+	// As linhas 5 e 6 do ficheiro são idênticas
+	a, b := first[3].Transactions[0].ImportID, first[4].Transactions[0].ImportID
+	if !strings.HasPrefix(a, "generic:") || b != a+"#2" {
+		t.Errorf("ImportID das linhas idênticas: %q e %q", a, b)
+	}
+	for i := range first {
+		for j := range first[i].Transactions {
+			if first[i].Transactions[j].ImportID != again[i].Transactions[j].ImportID {
+				t.Errorf("linha %d: ImportID diferente ao repetir a leitura", first[i].Line)
+			}
+		}
+	}
+}
+
+// TestParseGenericMapping verifica as colunas e o formato de datas indicados no mapeamento
+func TestParseGenericMapping(t *testing.T) {
+	data := "Operação,Ativo,Qtd,Data\nsell,XBT,0.25,05/02/2024 14:30\n"
+	mapping := &Mapping{Type: "Operação", Symbol: "Ativo", Quantity: "Qtd", Timestamp: "Data", TimeLayout: "02/01/2006 15:04"}
+
+	rows, err := Parse(FormatGeneric, strings.NewReader(data), mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Status != models.ImportRowOK {
+		t.Fatalf("linhas = %+v", rows)
+	}
+	tx := rows[0].Transactions[0]
+	want := time.Date(2024, 2, 5, 14, 30, 0, 0, time.UTC)
+	if tx.Type != models.TransactionSell || tx.Symbol != "BTC" || tx.Quantity != 0.25 || !tx.Timestamp.Equal(want) {
+		t.Errorf("transação = %+v", tx)
+	}
+
+	if _, err := Parse("bitstamp", strings.NewReader(data), nil); err == nil {
+		t.Error("formato desconhecido: esperado erro")
+	}
+	if _, err := Parse(FormatKraken, strings.NewReader(data), nil); err == nil {
+		t.Error("cabeçalho da Kraken em falta: esperado erro")
+	}
+}
+
+// TestParseAmount verifica a separação do número e do código do ativo
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value    string
+		codes    []string
+		quantity float64
+		symbol   string
+	}{
+		{"0.0010000BTC", []string{"BTC", "USDT"}, 0.001, "BTC"},
+		{"0.51INCH", []string{"1INCH", "USDT"}, 0.5, "1INCH"},
+		{"3.000000001000SATS", []string{"1000SATS", "USDT"}, 3, "1000SATS"},
+		{"1,250.5USDT", []string{"BTC", "USDT"}, 1250.5, "USDT"},
+		{"0.002BNB", []string{"ETH", "BTC"}, 0.002, "BNB"},
+		{"0.5xbt", nil, 0.5, "BTC"},
+		{"12.5", []string{"ETH", "BTC"}, 12.5, ""},
+	}
+
+	for _, tt := range tests {
+		quantity, symbol, err := parseAmount(tt.value, tt.codes...)
+		if err != nil {
+			t.Errorf("%q: %v", tt.value, err)
+			continue
+		}
+		if !approx(quantity, tt.quantity) || symbol != tt.symbol {
+			t.Errorf("%q: %v %q, esperado %v %q", tt.value, quantity, symbol, tt.quantity, tt.symbol)
+		}
+	}
+}
+
+// TestSplitPair verifica os pares com e sem separador e os códigos da Kraken
+func TestSplitPair(t *testing.T) {
+	tests := []struct {
+		pair, base, quote string
+	}{
+		{"BTCUSDT", "BTC", "USDT"},
+		{"BTC/EUR", "BTC", "EUR"},
+		{"eth-btc", "ETH", "BTC"},
+		{"XXBTZUSD", "BTC", "USD"},
+		{"XETHZEUR", "ETH", "EUR"},
+		{"XDGUSD", "DOGE", "USD"},
+		{"1INCHUSDT", "1INCH", "USDT"},
+		{"DOTUSD", "DOT", "USD"},
+		{"BTCTUSD", "BTC", "TUSD"},
+		{"OPUSDT", "OP", "USDT"},
+	}
+
+	for _, tt := range tests {
+		base, quote, ok := splitPair(tt.pair)
+		if !ok || base != tt.base || quote != tt.quote {
+			t.Errorf("%q: %q/%q (%v), esperado %q/%q", tt.pair, base, quote, ok, tt.base, tt.quote)
+		}
+	}
+	if _, _, ok := splitPair("BTC"); ok {
+		t.Error(`"BTC": esperado par desconhecido`)
+	}
+}
+
+// parseFixture lê um ficheiro de testdata
+func parseFixture(t *testing.T, name string, format Format, mapping *Mapping) []models.ImportRow {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	rows, err := Parse(format, file, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+// approx compara dois valores com uma tolerância para os erros de arredondamento
+func approx(got, want float64) bool {
+	return math.Abs(got-want) < 1e-9
+}
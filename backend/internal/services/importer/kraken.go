@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+
+	"gofolio/backend/internal/models"
+)
+
+// parseKraken lê o ficheiro trades.csv exportado pela Kraken. Os pares usam os códigos da
+// Kraken (ex.: "XXBTZUSD") e a taxa é paga na moeda de cotação.
+func parseKraken(data io.Reader, _ *Mapping) ([]models.ImportRow, error) {
+	t, err := readTable(data, "txid", "pair", "time", "type", "price", "fee", "vol")
+	if err != nil {
+		return nil, err
+	}
+	return t.rows(t.krakenTrade), nil
+}
+
+// krakenTrade converte uma linha do trades.csv da Kraken
+func (t *table) krakenTrade(record []string) ([]models.Transaction, string, error) {
+	if margin, err := parseNumber(t.value(record, "margin")); err != nil || margin != 0 {
+		return nil, "trade com margem", nil
+	}
+
+	timestamp, err := parseTime(t.value(record, "time"))
+	if err != nil {
+		return nil, "", err
+	}
+	txType, err := tradeSide(t.value(record, "type"))
+	if err != nil {
+		return nil, "", err
+	}
+	symbol, quote, ok := splitPair(t.value(record, "pair"))
+	if !ok {
+		return nil, "", fmt.Errorf("par desconhecido %q", t.value(record, "pair"))
+	}
+	price, err := parseNumber(t.value(record, "price"))
+	if err != nil {
+		return nil, "", err
+	}
+	quantity, err := parseNumber(t.value(record, "vol"))
+	if err != nil {
+		return nil, "", err
+	}
+	fee, err := parseNumber(t.value(record, "fee"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return []models.Transaction{{
+		Type:          txType,
+		Symbol:        symbol,
+		Quantity:      quantity,
+		Price:         price,
+		QuoteCurrency: quote,
+		Fee:           fee,
+		FeeCurrency:   quote,
+		Timestamp:     timestamp,
+		ImportID:      t.value(record, "txid"),
+	}}, "", nil
+}
@@ -0,0 +1,101 @@
+// Package indicators calcula indicadores técnicos sobre séries OHLCV (abertura, máximo, mínimo,
+// fecho e volume), por ordem cronológica.
+//
+// Cada indicador retorna uma série com o mesmo comprimento da entrada, alinhada com ela. Os
+// pontos do período de aquecimento, em que ainda não há dados suficientes, são NaN (ver Valid).
+package indicators
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Erros de parâmetros dos indicadores
+var (
+	ErrInvalidPeriod  = errors.New("período inválido")
+	ErrLengthMismatch = errors.New("as séries têm comprimentos diferentes")
+)
+
+// Valid indica se um ponto de um indicador está definido (não é NaN)
+func Valid(value float64) bool {
+	return !math.IsNaN(value)
+}
+
+// Last retorna o último ponto definido da série e se existe algum
+func Last(series []float64) (float64, bool) {
+	for i := len(series) - 1; i >= 0; i-- {
+		if Valid(series[i]) {
+			return series[i], true
+		}
+	}
+	return math.NaN(), false
+}
+
+// checkPeriods verifica que todos os períodos são positivos
+func checkPeriods(periods ...int) error {
+	for _, period := range periods {
+		if period < 1 {
+			return fmt.Errorf("%w: %d", ErrInvalidPeriod, period)
+		}
+	}
+	return nil
+}
+
+// checkLengths verifica que as séries têm todas o mesmo comprimento
+func checkLengths(series ...[]float64) error {
+	for _, s := range series[1:] {
+		if len(s) != len(series[0]) {
+			return ErrLengthMismatch
+		}
+	}
+	return nil
+}
+
+// undefined retorna uma série de n pontos NaN
+func undefined(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
+
+// firstValid retorna o índice do primeiro ponto definido, ou len(values) se não houver nenhum
+func firstValid(values []float64) int {
+	for i, value := range values {
+		if Valid(value) {
+			return i
+		}
+	}
+	return len(values)
+}
+
+// highest retorna o máximo de values[from:to]
+func highest(values []float64, from, to int) float64 {
+	max := values[from]
+	for _, value := range values[from+1 : to] {
+		max = math.Max(max, value)
+	}
+	return max
+}
+
+// lowest retorna o mínimo de values[from:to]
+func lowest(values []float64, from, to int) float64 {
+	min := values[from]
+	for _, value := range values[from+1 : to] {
+		min = math.Min(min, value)
+	}
+	return min
+}
+
+// trueRange retorna a amplitude real do ponto i: a maior de máximo-mínimo e das distâncias
+// ao fecho anterior. O primeiro ponto não tem fecho anterior e usa apenas máximo-mínimo.
+func trueRange(highs, lows, closes []float64, i int) float64 {
+	tr := highs[i] - lows[i]
+	if i > 0 {
+		tr = math.Max(tr, math.Abs(highs[i]-closes[i-1]))
+		tr = math.Max(tr, math.Abs(lows[i]-closes[i-1]))
+	}
+	return tr
+}
@@ -0,0 +1,262 @@
+package indicators
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+var nan = math.NaN()
+
+// closes são os fechos do exemplo de RSI da StockCharts
+var closes = []float64{
+	44.3389, 44.0902, 44.1497, 43.6124, 44.3278, 44.8264, 45.0955, 45.4245, 45.8433, 46.0826, 45.8931,
+	46.0328, 45.6140, 46.2820, 46.2820, 46.0028, 46.0328, 46.4116, 46.2222, 45.6439, 46.2122, 46.2521,
+	45.7137, 46.4515, 45.7835, 45.3548, 44.0288, 44.1783, 44.2181, 44.5672, 43.4205, 42.6628, 43.1314,
+}
+
+// sample deriva de closes máximos, mínimos e volumes determinísticos
+func sample() (highs, lows, volumes []float64) {
+	for i, c := range closes {
+		highs = append(highs, c+0.25+0.05*float64(i%4))
+		lows = append(lows, c-0.20-0.04*float64(i%3))
+		volumes = append(volumes, float64(1000+37*((i*7)%11)))
+	}
+	return highs, lows, volumes
+}
+
+// assertSeries compara duas séries ponto a ponto; NaN só é igual a NaN
+func assertSeries(t *testing.T, name string, got, want []float64, tolerance float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d pontos, esperados %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) != math.IsNaN(got[i]) || math.Abs(got[i]-want[i]) > tolerance {
+			t.Errorf("%s[%d] = %v, esperado %v", name, i, got[i], want[i])
+		}
+	}
+}
+
+// TestRSIStockCharts compara o RSI com os valores publicados no exemplo da StockCharts
+func TestRSIStockCharts(t *testing.T) {
+	rsi, err := RSI(closes, 14)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []float64{
+		70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38,
+		54.71, 50.42, 39.99, 41.46, 41.87, 45.46, 37.30, 33.08, 37.77,
+	}
+	assertSeries(t, "RSI", rsi[14:], want, 0.005)
+	for i, value := range rsi[:14] {
+		if Valid(value) {
+			t.Errorf("RSI[%d] = %v no período de aquecimento", i, value)
+		}
+	}
+}
+
+// TestGolden compara cada indicador com valores de referência calculados à parte
+func TestGolden(t *testing.T) {
+	highs, lows, volumes := sample()
+	must := func(series []float64, err error) []float64 {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return series
+	}
+
+	macd, err := MACD(closes, 5, 10, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bands, err := BollingerBands(closes, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stochastic, err := Stochastic(highs, lows, closes, 5, 3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adx, err := ADX(highs, lows, closes, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ichimoku, err := Ichimoku(highs, lows, closes, 3, 5, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := []struct {
+		name string
+		got  []float64
+		want []float64
+	}{
+		{"SMA(5)", must(SMA(closes, 5)), []float64{
+			nan, nan, nan, nan, 44.1038, 44.2013, 44.40236, 44.65732, 45.1035, 45.45446, 45.6678, 45.85526,
+			45.89316, 45.9809, 46.02078, 46.04272, 46.04272, 46.20224, 46.19028, 46.06266, 46.10454,
+			46.1484, 46.00882, 46.05468, 46.0826, 45.91112, 45.46646, 45.15938, 44.7127, 44.46944,
+			44.08258, 43.80938, 43.6,
+		}},
+		{"EMA(5)", must(EMA(closes, 5)), []float64{
+			nan, nan, nan, nan, 44.1038, 44.344667, 44.594944, 44.871463, 45.195409, 45.491139, 45.625126,
+			45.761017, 45.712012, 45.902008, 46.028672, 46.020048, 46.024299, 46.153399, 46.176333,
+			45.998855, 46.06997, 46.13068, 45.991687, 46.144958, 46.024472, 45.801248, 45.210432,
+			44.866388, 44.650292, 44.622595, 44.221896, 43.702198, 43.511932,
+		}},
+		{"WMA(5)", must(WMA(closes, 5)), []float64{
+			nan, nan, nan, nan, 44.070467, 44.311333, 44.6094, 44.950113, 45.34544, 45.671807, 45.81802,
+			45.939687, 45.859267, 45.98888, 46.089247, 46.083253, 46.079947, 46.202907, 46.20956,
+			46.027433, 46.07728, 46.126467, 45.981567, 46.129127, 46.038733, 45.796133, 45.168693,
+			44.739307, 44.425547, 44.377047, 44.0274, 43.55414, 43.328147,
+		}},
+		{"RSI(14)", must(RSI(closes, 14)), []float64{
+			nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, 70.532789, 66.318562,
+			66.54983, 69.406305, 66.355169, 57.974856, 62.929607, 63.257148, 56.059299, 62.377071,
+			54.707573, 50.422774, 39.989823, 41.460482, 41.868916, 45.463212, 37.304042, 33.079523,
+			37.772952,
+		}},
+		{"MACD(5,10,4)", macd.MACD, []float64{
+			nan, nan, nan, nan, nan, nan, nan, nan, nan, 0.712009, 0.643456, 0.588233, 0.459006, 0.461912,
+			0.435503, 0.352401, 0.29026, 0.296167, 0.252743, 0.126118, 0.135513, 0.138469, 0.050114,
+			0.110671, 0.035783, -0.072188, -0.327616, -0.424433, -0.445489, -0.377081, -0.490656,
+			-0.637673, -0.608217,
+		}},
+		{"MACD sinal", macd.Signal, []float64{
+			nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, 0.600676, 0.545171, 0.501303,
+			0.441742, 0.381149, 0.347157, 0.309391, 0.236082, 0.195854, 0.1729, 0.123786, 0.11854,
+			0.085437, 0.022387, -0.117614, -0.240342, -0.322401, -0.344273, -0.402826, -0.496765,
+			-0.541345,
+		}},
+		{"MACD histograma", macd.Histogram, []float64{
+			nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, -0.14167, -0.083258, -0.065801,
+			-0.089342, -0.090889, -0.050989, -0.056648, -0.109964, -0.060342, -0.034431, -0.073672,
+			-0.007869, -0.049654, -0.094575, -0.210002, -0.184091, -0.123088, -0.032808, -0.08783,
+			-0.140908, -0.066871,
+		}},
+		{"Bollinger superior", bands.Upper, []float64{
+			nan, nan, nan, nan, nan, nan, nan, nan, nan, 46.325724, 46.582028, 46.790293, 46.819734,
+			46.725332, 46.673601, 46.57408, 46.462094, 46.494766, 46.521014, 46.551551, 46.582202,
+			46.614015, 46.589229, 46.640396, 46.614922, 46.701693, 47.181177, 47.197572, 47.123273,
+			47.070277, 46.980337, 46.866766, 46.648157,
+		}},
+		{"Bollinger inferior", bands.Lower, []float64{
+			nan, nan, nan, nan, nan, nan, nan, nan, nan, 43.232536, 43.287072, 43.467327, 43.730746,
+			44.359068, 44.801639, 45.13644, 45.435886, 45.600634, 45.650166, 45.531889, 45.565058,
+			45.577105, 45.621831, 45.604564, 45.530338, 45.313967, 44.433683, 43.970628, 43.644107,
+			43.481763, 43.013363, 42.409074, 42.111223,
+		}},
+		{"ATR(5)", must(ATR(highs, lows, closes, 5)), []float64{
+			nan, nan, nan, nan, 0.66454, 0.691352, 0.676902, 0.687321, 0.683617, 0.654754, 0.641803,
+			0.649442, 0.643314, 0.708251, 0.692601, 0.674081, 0.637265, 0.645572, 0.626457, 0.664826,
+			0.695521, 0.656417, 0.680813, 0.772211, 0.791368, 0.766835, 0.934668, 0.867734, 0.792187,
+			0.76357, 0.880196, 0.903697, 0.866677,
+		}},
+		{"Estocástico K", stochastic.K, []float64{
+			nan, nan, nan, nan, nan, nan, 81.029682, 82.899644, 84.57871, 84.871044, 79.426592, 72.900639,
+			51.556982, 53.967476, 55.070125, 64.640327, 56.803503, 55.498286, 54.962728, 44.145474,
+			42.299506, 48.341872, 51.063315, 54.582397, 40.45678, 36.224164, 15.109611, 12.228831,
+			14.46939, 25.773954, 25.208194, 21.633985, 16.984191,
+		}},
+		{"Estocástico D", stochastic.D, []float64{
+			nan, nan, nan, nan, nan, nan, nan, nan, 82.836012, 84.116466, 82.958782, 79.066092, 67.961404,
+			59.475032, 53.531527, 57.892642, 58.837985, 58.980705, 55.754839, 51.535496, 47.135903,
+			44.928951, 47.234898, 51.329195, 48.700831, 43.754447, 30.596851, 21.187535, 13.935944,
+			17.490725, 21.81718, 24.205378, 21.275457,
+		}},
+		{"OBV", must(OBV(closes, volumes)), []float64{
+			0, -1259, -148, -1518, -296, 778, 2111, 3296, 4333, 5629, 4481, 5481, 4222, 5333, 5333, 4111,
+			5185, 6518, 5333, 4296, 5592, 6740, 5740, 6999, 5888, 4518, 3296, 4370, 5703, 6888, 5851, 4555,
+			5703,
+		}},
+		{"VWAP", must(VWAP(highs, lows, closes, volumes)), []float64{
+			44.355567, 44.218817, 44.203724, 44.052082, 44.109278, 44.21976, 44.36721, 44.504963,
+			44.635024, 44.796483, 44.896292, 44.980119, 45.033672, 45.119567, 45.210959, 45.266184,
+			45.307384, 45.376567, 45.423552, 45.435564, 45.475431, 45.5111, 45.519938, 45.563117,
+			45.572062, 45.563291, 45.505641, 45.464743, 45.41662, 45.388586, 45.334331, 45.244935,
+			45.182595,
+		}},
+		{"ADX(5)", adx.ADX, []float64{
+			nan, nan, nan, nan, nan, nan, nan, nan, nan, 46.49084, 45.366772, 45.991371, 39.856418,
+			41.245239, 42.689838, 39.835874, 37.336897, 39.850098, 39.15925, 34.658835, 30.170553,
+			27.674555, 26.462467, 25.663817, 22.227353, 23.160084, 30.089929, 33.371709, 35.998093,
+			32.73677, 36.222117, 41.620062, 42.164847,
+		}},
+		{"+DI", adx.PlusDI, []float64{
+			nan, nan, nan, nan, nan, 33.32607, 36.497438, 39.657384, 39.758616, 41.986151, 34.432187,
+			33.086961, 26.809535, 39.62619, 33.908651, 27.913203, 23.645331, 31.919413, 26.33569,
+			19.870991, 27.207706, 25.804518, 19.912221, 34.433584, 26.885895, 22.199877, 14.574085,
+			17.155858, 15.034217, 22.929576, 15.914416, 12.400951, 17.6961,
+		}},
+		{"-DI", adx.MinusDI, []float64{
+			nan, nan, nan, nan, nan, 20.319778, 16.782241, 13.359031, 10.82652, 9.089649, 14.452692,
+			11.477673, 19.687733, 14.360253, 11.769337, 15.55853, 13.491699, 10.667372, 12.280863,
+			27.813955, 21.283347, 18.046948, 30.893407, 21.798642, 31.869208, 38.53103, 54.512652,
+			46.976782, 41.172279, 34.174142, 47.9521, 55.017216, 45.894873,
+		}},
+		{"Tenkan", ichimoku.Tenkan, []float64{
+			nan, nan, 44.21955, 43.95605, 43.9951, 44.2694, 44.76665, 45.18545, 45.4944, 45.78355,
+			45.97295, 46.04295, 45.9234, 45.998, 46.023, 46.2174, 46.2124, 46.2522, 46.2522, 46.05775,
+			45.98805, 45.978, 46.0129, 46.1626, 46.1626, 45.98315, 44.89115, 44.7018, 44.16355, 44.42265,
+			44.04385, 43.645, 43.09665,
+		}},
+		{"Kijun", ichimoku.Kijun, []float64{
+			nan, nan, nan, nan, 44.00065, 44.2694, 44.42895, 44.61845, 45.09055, 45.4645, 45.63905,
+			45.80865, 45.9234, 45.998, 46.023, 46.023, 46.023, 46.2522, 46.2522, 46.05775, 46.05775,
+			46.05775, 45.98805, 46.1277, 46.1626, 45.98315, 45.30015, 45.30015, 44.89115, 44.7018,
+			44.04385, 43.645, 43.645,
+		}},
+		{"Senkou A", ichimoku.SenkouA, []float64{
+			nan, nan, nan, nan, nan, nan, nan, nan, nan, 43.997875, 44.2694, 44.5978, 44.90195, 45.292475,
+			45.624025, 45.806, 45.9258, 45.9234, 45.998, 46.023, 46.1202, 46.1177, 46.2522, 46.2522,
+			46.05775, 46.0229, 46.017875, 46.000475, 46.14515, 46.1626, 45.98315, 45.09565, 45.000975,
+		}},
+		{"Senkou B", ichimoku.SenkouB, []float64{
+			nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, nan, 44.61845, 44.75285, 44.8975,
+			44.8975, 45.2603, 45.4896, 45.73875, 45.90825, 46.023, 46.023, 46.0628, 46.0628, 46.05775,
+			46.05775, 46.05775, 46.05775, 46.1277, 46.1277, 45.98315, 45.30015, 45.30015,
+		}},
+		{"Chikou", ichimoku.Chikou, []float64{
+			44.8264, 45.0955, 45.4245, 45.8433, 46.0826, 45.8931, 46.0328, 45.614, 46.282, 46.282, 46.0028,
+			46.0328, 46.4116, 46.2222, 45.6439, 46.2122, 46.2521, 45.7137, 46.4515, 45.7835, 45.3548,
+			44.0288, 44.1783, 44.2181, 44.5672, 43.4205, 42.6628, 43.1314, nan, nan, nan, nan, nan,
+		}},
+	}
+
+	for _, g := range golden {
+		assertSeries(t, g.name, g.got, g.want, 1e-5)
+	}
+}
+
+func TestShortSeries(t *testing.T) {
+	short := closes[:5]
+	for name, indicator := range map[string]func() ([]float64, error){
+		"SMA": func() ([]float64, error) { return SMA(short, 10) },
+		"EMA": func() ([]float64, error) { return EMA(short, 10) },
+		"RSI": func() ([]float64, error) { return RSI(short, 14) },
+	} {
+		series, err := indicator()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, ok := Last(series); ok || len(series) != len(short) {
+			t.Errorf("%s: %v com %d pontos", name, series, len(short))
+		}
+	}
+}
+
+func TestInvalidParameters(t *testing.T) {
+	highs, lows, _ := sample()
+
+	if _, err := SMA(closes, 0); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("SMA(0): erro %v, esperado ErrInvalidPeriod", err)
+	}
+	if _, err := MACD(closes, 12, -1, 9); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("MACD com período negativo: erro %v, esperado ErrInvalidPeriod", err)
+	}
+	if _, err := ATR(highs, lows[1:], closes, 14); !errors.Is(err, ErrLengthMismatch) {
+		t.Errorf("ATR com séries desiguais: erro %v, esperado ErrLengthMismatch", err)
+	}
+}
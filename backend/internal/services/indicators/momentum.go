@@ -0,0 +1,108 @@
+package indicators
+
+import "math"
+
+// RSI calcula o índice de força relativa de Wilder: as médias dos ganhos e das perdas entre
+// fechos são suavizadas com a média de Wilder. O primeiro ponto definido é o índice period.
+// Sem perdas no período o RSI é 100; sem ganhos nem perdas é 50.
+func RSI(closes []float64, period int) ([]float64, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+
+	out := undefined(len(closes))
+	if len(closes) <= period {
+		return out, nil
+	}
+
+	gains := make([]float64, len(closes))
+	losses := make([]float64, len(closes))
+	for i := 1; i < len(closes); i++ {
+		change := closes[i] - closes[i-1]
+		gains[i] = math.Max(change, 0)
+		losses[i] = math.Max(-change, 0)
+	}
+
+	avgGains := wilderSmooth(gains, period, 1)
+	avgLosses := wilderSmooth(losses, period, 1)
+	for i := period; i < len(closes); i++ {
+		switch {
+		case avgLosses[i] == 0 && avgGains[i] == 0:
+			out[i] = 50
+		case avgLosses[i] == 0:
+			out[i] = 100
+		default:
+			out[i] = 100 - 100/(1+avgGains[i]/avgLosses[i])
+		}
+	}
+	return out, nil
+}
+
+// MACDResult são as linhas do MACD
+type MACDResult struct {
+	MACD      []float64 // EMA rápida menos EMA lenta
+	Signal    []float64 // EMA da linha MACD
+	Histogram []float64 // MACD menos a linha de sinal
+}
+
+// MACD calcula o MACD com as EMAs rápida e lenta indicadas e a linha de sinal (tipicamente 12, 26 e 9)
+func MACD(closes []float64, fast, slow, signal int) (*MACDResult, error) {
+	if err := checkPeriods(fast, slow, signal); err != nil {
+		return nil, err
+	}
+
+	fastEMA, _ := EMA(closes, fast)
+	slowEMA, _ := EMA(closes, slow)
+
+	result := &MACDResult{
+		MACD:      undefined(len(closes)),
+		Histogram: undefined(len(closes)),
+	}
+	for i := range closes {
+		if Valid(fastEMA[i]) && Valid(slowEMA[i]) {
+			result.MACD[i] = fastEMA[i] - slowEMA[i]
+		}
+	}
+
+	result.Signal, _ = EMA(result.MACD, signal)
+	for i := range closes {
+		if Valid(result.Signal[i]) {
+			result.Histogram[i] = result.MACD[i] - result.Signal[i]
+		}
+	}
+	return result, nil
+}
+
+// StochasticResult são as linhas do oscilador estocástico, entre 0 e 100
+type StochasticResult struct {
+	K []float64
+	D []float64 // média simples de K
+}
+
+// Stochastic calcula o oscilador estocástico: a posição do fecho entre o mínimo e o máximo dos
+// últimos kPeriod pontos, suavizada por uma média simples de smooth pontos (1 para o estocástico
+// rápido, 3 para o lento), e a linha D, média simples de dPeriod pontos de K. Sem amplitude no
+// período, o fecho é tratado como estando a meio (50).
+func Stochastic(highs, lows, closes []float64, kPeriod, smooth, dPeriod int) (*StochasticResult, error) {
+	if err := checkPeriods(kPeriod, smooth, dPeriod); err != nil {
+		return nil, err
+	}
+	if err := checkLengths(highs, lows, closes); err != nil {
+		return nil, err
+	}
+
+	raw := undefined(len(closes))
+	for i := kPeriod - 1; i < len(closes); i++ {
+		high := highest(highs, i-kPeriod+1, i+1)
+		low := lowest(lows, i-kPeriod+1, i+1)
+		if high == low {
+			raw[i] = 50
+			continue
+		}
+		raw[i] = 100 * (closes[i] - low) / (high - low)
+	}
+
+	k, _ := SMA(raw, smooth)
+	d, _ := SMA(k, dPeriod)
+	return &StochasticResult{K: k, D: d}, nil
+}
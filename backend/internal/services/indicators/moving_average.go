@@ -0,0 +1,86 @@
+package indicators
+
+// SMA calcula a média móvel simples de period pontos
+func SMA(values []float64, period int) ([]float64, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+
+	out := undefined(len(values))
+	for i := period - 1; i < len(values); i++ {
+		sum := 0.0
+		for _, value := range values[i-period+1 : i+1] {
+			sum += value
+		}
+		out[i] = sum / float64(period)
+	}
+	return out, nil
+}
+
+// EMA calcula a média móvel exponencial de period pontos, com fator 2/(period+1). A primeira
+// média é a média simples dos primeiros period pontos definidos, pelo que a série pode começar
+// com NaN (ex.: a linha de sinal do MACD).
+func EMA(values []float64, period int) ([]float64, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+
+	out := undefined(len(values))
+	start := firstValid(values)
+	if len(values)-start < period {
+		return out, nil
+	}
+
+	alpha := 2 / float64(period+1)
+	seed := start + period - 1
+	sum := 0.0
+	for _, value := range values[start : seed+1] {
+		sum += value
+	}
+	out[seed] = sum / float64(period)
+
+	for i := seed + 1; i < len(values); i++ {
+		out[i] = out[i-1] + alpha*(values[i]-out[i-1])
+	}
+	return out, nil
+}
+
+// WMA calcula a média móvel ponderada linearmente de period pontos: o ponto mais recente tem
+// peso period e o mais antigo peso 1
+func WMA(values []float64, period int) ([]float64, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+
+	weights := float64(period*(period+1)) / 2
+	out := undefined(len(values))
+	for i := period - 1; i < len(values); i++ {
+		sum := 0.0
+		for j, value := range values[i-period+1 : i+1] {
+			sum += float64(j+1) * value
+		}
+		out[i] = sum / weights
+	}
+	return out, nil
+}
+
+// wilderSmooth aplica a média de Wilder (fator 1/period) a partir do índice start: o primeiro
+// ponto, em start+period-1, é a média simples dos period valores anteriores
+func wilderSmooth(values []float64, period, start int) []float64 {
+	out := undefined(len(values))
+	seed := start + period - 1
+	if seed >= len(values) {
+		return out
+	}
+
+	sum := 0.0
+	for _, value := range values[start : seed+1] {
+		sum += value
+	}
+	out[seed] = sum / float64(period)
+
+	for i := seed + 1; i < len(values); i++ {
+		out[i] = (out[i-1]*float64(period-1) + values[i]) / float64(period)
+	}
+	return out
+}
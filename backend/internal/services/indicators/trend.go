@@ -0,0 +1,123 @@
+package indicators
+
+import "math"
+
+// ADXResult são as linhas do índice direcional médio
+type ADXResult struct {
+	ADX     []float64 // força da tendência, entre 0 e 100
+	PlusDI  []float64 // indicador direcional positivo
+	MinusDI []float64 // indicador direcional negativo
+}
+
+// ADX calcula o índice direcional médio de Wilder. Os movimentos direcionais e as amplitudes
+// são somados com a suavização de Wilder a partir do segundo ponto; os indicadores direcionais
+// começam no índice period e o ADX, média de Wilder do DX, no índice 2*period-1.
+func ADX(highs, lows, closes []float64, period int) (*ADXResult, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+	if err := checkLengths(highs, lows, closes); err != nil {
+		return nil, err
+	}
+
+	n := len(closes)
+	result := &ADXResult{ADX: undefined(n), PlusDI: undefined(n), MinusDI: undefined(n)}
+	if n <= period {
+		return result, nil
+	}
+
+	dx := undefined(n)
+	var trSum, plusSum, minusSum float64
+	for i := 1; i < n; i++ {
+		up := highs[i] - highs[i-1]
+		down := lows[i-1] - lows[i]
+		plusDM, minusDM := 0.0, 0.0
+		if up > down && up > 0 {
+			plusDM = up
+		}
+		if down > up && down > 0 {
+			minusDM = down
+		}
+		tr := trueRange(highs, lows, closes, i)
+
+		if i <= period {
+			trSum += tr
+			plusSum += plusDM
+			minusSum += minusDM
+			if i < period {
+				continue
+			}
+		} else {
+			trSum = trSum - trSum/float64(period) + tr
+			plusSum = plusSum - plusSum/float64(period) + plusDM
+			minusSum = minusSum - minusSum/float64(period) + minusDM
+		}
+
+		if trSum == 0 {
+			result.PlusDI[i], result.MinusDI[i], dx[i] = 0, 0, 0
+			continue
+		}
+		result.PlusDI[i] = 100 * plusSum / trSum
+		result.MinusDI[i] = 100 * minusSum / trSum
+		if sum := result.PlusDI[i] + result.MinusDI[i]; sum > 0 {
+			dx[i] = 100 * math.Abs(result.PlusDI[i]-result.MinusDI[i]) / sum
+		} else {
+			dx[i] = 0
+		}
+	}
+
+	result.ADX = wilderSmooth(dx, period, period)
+	return result, nil
+}
+
+// IchimokuResult são as linhas do Ichimoku Kinko Hyo. As linhas da nuvem estão deslocadas para
+// a frente: SenkouA[i] e SenkouB[i] são a nuvem no ponto i, calculada kijun pontos antes. Chikou[i]
+// é o fecho kijun pontos depois, pelo que os últimos kijun pontos não estão definidos.
+type IchimokuResult struct {
+	Tenkan  []float64 // linha de conversão
+	Kijun   []float64 // linha base
+	SenkouA []float64 // primeira linha da nuvem: média de Tenkan e Kijun
+	SenkouB []float64 // segunda linha da nuvem: ponto médio do período mais longo
+	Chikou  []float64 // fecho deslocado para trás
+}
+
+// Ichimoku calcula o Ichimoku com os períodos de conversão, base e da segunda linha da nuvem
+// (tipicamente 9, 26 e 52). Cada linha é o ponto médio entre o máximo e o mínimo do seu período.
+func Ichimoku(highs, lows, closes []float64, tenkan, kijun, senkouB int) (*IchimokuResult, error) {
+	if err := checkPeriods(tenkan, kijun, senkouB); err != nil {
+		return nil, err
+	}
+	if err := checkLengths(highs, lows, closes); err != nil {
+		return nil, err
+	}
+
+	n := len(closes)
+	midpoint := func(period int) []float64 {
+		out := undefined(n)
+		for i := period - 1; i < n; i++ {
+			out[i] = (highest(highs, i-period+1, i+1) + lowest(lows, i-period+1, i+1)) / 2
+		}
+		return out
+	}
+
+	result := &IchimokuResult{
+		Tenkan:  midpoint(tenkan),
+		Kijun:   midpoint(kijun),
+		SenkouA: undefined(n),
+		SenkouB: undefined(n),
+		Chikou:  undefined(n),
+	}
+	spanB := midpoint(senkouB)
+
+	for i := kijun; i < n; i++ {
+		j := i - kijun
+		if Valid(result.Tenkan[j]) && Valid(result.Kijun[j]) {
+			result.SenkouA[i] = (result.Tenkan[j] + result.Kijun[j]) / 2
+		}
+		result.SenkouB[i] = spanB[j]
+	}
+	for i := 0; i+kijun < n; i++ {
+		result.Chikou[i] = closes[i+kijun]
+	}
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package indicators
+
+import "math"
+
+// BandsResult são as Bandas de Bollinger
+type BandsResult struct {
+	Upper  []float64
+	Middle []float64 // média simples
+	Lower  []float64
+}
+
+// PercentB retorna a posição de value entre as bandas do ponto i: 0 na banda inferior, 1 na superior
+func (b *BandsResult) PercentB(value float64, i int) float64 {
+	width := b.Upper[i] - b.Lower[i]
+	if width == 0 {
+		return 0.5
+	}
+	return (value - b.Lower[i]) / width
+}
+
+// BollingerBands calcula as Bandas de Bollinger: a média simples de period fechos e as bandas a
+// k desvios padrão (populacionais) dessa média (tipicamente 20 e 2)
+func BollingerBands(closes []float64, period int, k float64) (*BandsResult, error) {
+	middle, err := SMA(closes, period)
+	if err != nil {
+		return nil, err
+	}
+
+	bands := &BandsResult{
+		Upper:  undefined(len(closes)),
+		Middle: middle,
+		Lower:  undefined(len(closes)),
+	}
+	for i := period - 1; i < len(closes); i++ {
+		variance := 0.0
+		for _, value := range closes[i-period+1 : i+1] {
+			variance += (value - middle[i]) * (value - middle[i])
+		}
+		deviation := math.Sqrt(variance / float64(period))
+		bands.Upper[i] = middle[i] + k*deviation
+		bands.Lower[i] = middle[i] - k*deviation
+	}
+	return bands, nil
+}
+
+// ATR calcula a amplitude real média de Wilder. O primeiro ponto definido, em period-1, é a
+// média simples das primeiras amplitudes.
+func ATR(highs, lows, closes []float64, period int) ([]float64, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+	if err := checkLengths(highs, lows, closes); err != nil {
+		return nil, err
+	}
+
+	ranges := make([]float64, len(closes))
+	for i := range closes {
+		ranges[i] = trueRange(highs, lows, closes, i)
+	}
+	return wilderSmooth(ranges, period, 0), nil
+}
@@ -0,0 +1,46 @@
+package indicators
+
+import "math"
+
+// OBV calcula o volume em balanço: o volume acumulado, somado nos pontos em que o fecho sobe
+// e subtraído nos pontos em que desce. A série começa em 0.
+func OBV(closes, volumes []float64) ([]float64, error) {
+	if err := checkLengths(closes, volumes); err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, len(closes))
+	for i := 1; i < len(closes); i++ {
+		out[i] = out[i-1]
+		switch {
+		case closes[i] > closes[i-1]:
+			out[i] += volumes[i]
+		case closes[i] < closes[i-1]:
+			out[i] -= volumes[i]
+		}
+	}
+	return out, nil
+}
+
+// VWAP calcula o preço médio ponderado pelo volume, acumulado desde o início da série, com o
+// preço típico (máximo + mínimo + fecho) / 3 de cada ponto. Enquanto o volume acumulado for
+// nulo, o ponto não está definido.
+func VWAP(highs, lows, closes, volumes []float64) ([]float64, error) {
+	if err := checkLengths(highs, lows, closes, volumes); err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, len(closes))
+	value, volume := 0.0, 0.0
+	for i := range closes {
+		typical := (highs[i] + lows[i] + closes[i]) / 3
+		value += typical * volumes[i]
+		volume += volumes[i]
+		if volume == 0 {
+			out[i] = math.NaN()
+			continue
+		}
+		out[i] = value / volume
+	}
+	return out, nil
+}
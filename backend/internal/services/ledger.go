@@ -0,0 +1,112 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// quantityEpsilon absorve erros de arredondamento ao comparar quantidades
+const quantityEpsilon = 1e-9
+
+// maxFutureSkew é a tolerância para transações com data ligeiramente no futuro (relógios dessincronizados)
+const maxFutureSkew = 5 * time.Minute
+
+// Erros do livro de transações
+var (
+	ErrInvalidTransaction  = errors.New("transação inválida")
+	ErrInsufficientBalance = errors.New("saldo insuficiente")
+)
+
+// isQuoteFee indica se a taxa foi paga na moeda de cotação
+func isQuoteFee(tx models.Transaction) bool {
+	return tx.FeeCurrency == "" || tx.FeeCurrency == tx.QuoteCurrency
+}
+
+// sortTransactions retorna uma cópia do livro por ordem cronológica
+func sortTransactions(transactions []models.Transaction) []models.Transaction {
+	sorted := make([]models.Transaction, len(transactions))
+	copy(sorted, transactions)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].Timestamp.Equal(sorted[j].Timestamp) {
+			return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+		}
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	return sorted
+}
+
+// normalizeTransaction valida a transação e preenche os valores por omissão
+func normalizeTransaction(tx *models.Transaction) error {
+	if !tx.Type.Valid() {
+		return fmt.Errorf("%w: tipo desconhecido %q", ErrInvalidTransaction, tx.Type)
+	}
+
+	tx.Symbol = strings.ToUpper(strings.TrimSpace(tx.Symbol))
+	if tx.Symbol == "" {
+		return fmt.Errorf("%w: símbolo obrigatório", ErrInvalidTransaction)
+	}
+
+	if tx.Quantity <= 0 {
+		return fmt.Errorf("%w: a quantidade deve ser positiva", ErrInvalidTransaction)
+	}
+
+	if tx.Price < 0 || tx.Fee < 0 {
+		return fmt.Errorf("%w: preço e taxa não podem ser negativos", ErrInvalidTransaction)
+	}
+
+	if (tx.Type == models.TransactionBuy || tx.Type == models.TransactionSell) && tx.Price == 0 {
+		return fmt.Errorf("%w: compras e vendas requerem preço", ErrInvalidTransaction)
+	}
+
+	tx.QuoteCurrency = strings.ToUpper(strings.TrimSpace(tx.QuoteCurrency))
+	if tx.QuoteCurrency == "" {
+		tx.QuoteCurrency = models.DefaultQuoteCurrency
+	}
+
+	tx.FeeCurrency = strings.ToUpper(strings.TrimSpace(tx.FeeCurrency))
+	if tx.FeeCurrency == "" {
+		tx.FeeCurrency = tx.QuoteCurrency
+	}
+
+	now := time.Now()
+	if tx.Timestamp.IsZero() {
+		tx.Timestamp = now
+	}
+	if tx.Timestamp.After(now.Add(maxFutureSkew)) {
+		return fmt.Errorf("%w: data no futuro", ErrInvalidTransaction)
+	}
+
+	return nil
+}
+
+// openingTransactions converte os ativos registados diretamente em compras de abertura,
+// para que entrem no cálculo das posições a par das transações
+func openingTransactions(assets []models.Asset) []models.Transaction {
+	transactions := make([]models.Transaction, 0, len(assets))
+	for _, asset := range assets {
+		if asset.Amount <= 0 {
+			continue
+		}
+		transactions = append(transactions, models.Transaction{
+			ID:            asset.ID,
+			PortfolioID:   asset.PortfolioID,
+			Type:          models.TransactionBuy,
+			Symbol:        asset.Symbol,
+			Quantity:      asset.Amount,
+			Price:         asset.PurchasePrice,
+			QuoteCurrency: models.DefaultQuoteCurrency,
+			FeeCurrency:   models.DefaultQuoteCurrency,
+			Timestamp:     asset.PurchaseDate,
+			CreatedAt:     asset.CreatedAt,
+			UpdatedAt:     asset.UpdatedAt,
+		})
+	}
+	return transactions
+}
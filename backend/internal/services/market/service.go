@@ -6,8 +6,8 @@ import (
 	"sync"
 	"time"
 
-	"github.com/tiagofernandes/gofolio/internal/models"
-	"github.com/tiagofernandes/gofolio/pkg/client"
+	"gofolio/backend/internal/models"
+	"gofolio/backend/pkg/client"
 )
 
 // Service é o serviço para dados de mercado de criptomoedas
@@ -200,13 +200,13 @@ func (s *Service) GetCoinDetails(id string) (*models.CoinDetails, error) {
 }
 
 // GetHistoricalData obtém dados históricos de preço para uma criptomoeda
-func (s *Service) GetHistoricalData(id, currency string, days int) (*models.HistoricalData, error) {
+func (s *Service) GetHistoricalData(id, currency string, days int) (*models.MarketHistoricalData, error) {
 	cacheKey := fmt.Sprintf("historical_data_%s_%s_%d", id, currency, days)
 	
 	// Verificar cache
 	if s.isCacheValid(cacheKey) {
 		if data, ok := s.getCache(cacheKey); ok {
-			return data.(*models.HistoricalData), nil
+			return data.(*models.MarketHistoricalData), nil
 		}
 	}
 	
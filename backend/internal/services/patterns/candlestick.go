@@ -0,0 +1,198 @@
+package patterns
+
+import (
+	"math"
+
+	"gofolio/backend/internal/models"
+)
+
+// candlestickDetector deteta a forma de alta de um padrão de velas que termina na vela i e
+// retorna a confiança. A forma de baixa é detetada com o mesmo detetor sobre a série espelhada.
+type candlestickDetector struct {
+	bullish, bearish string
+	size             int // número de velas do padrão
+	detect           func(s *series, i int) (float64, bool)
+}
+
+var candlestickDetectors = []candlestickDetector{
+	{Hammer, ShootingStar, 1, detectHammer},
+	{BullishEngulfing, BearishEngulfing, 2, detectEngulfing},
+	{MorningStar, EveningStar, 3, detectMorningStar},
+	{ThreeWhiteSoldiers, ThreeBlackCrows, 3, detectThreeSoldiers},
+}
+
+// detectCandlesticks deteta os padrões de velas em todas as velas da série
+func detectCandlesticks(s *series) []Match {
+	mirrored := s.mirror()
+
+	matches := []Match{}
+	for i := range s.candles {
+		if confidence, implication, ok := detectDoji(s, i); ok {
+			matches = append(matches, Match{
+				Pattern:     Doji,
+				Kind:        KindCandlestick,
+				Implication: implication,
+				Confidence:  confidence,
+				StartIndex:  i,
+				EndIndex:    i,
+			})
+		}
+
+		for _, detector := range candlestickDetectors {
+			if !s.real(i-detector.size+1, i) {
+				continue
+			}
+			if confidence, ok := detector.detect(s, i); ok {
+				matches = append(matches, Match{
+					Pattern:     detector.bullish,
+					Kind:        KindCandlestick,
+					Implication: Bullish,
+					Confidence:  confidence,
+					StartIndex:  i - detector.size + 1,
+					EndIndex:    i,
+				})
+			}
+			if confidence, ok := detector.detect(mirrored, i); ok {
+				matches = append(matches, Match{
+					Pattern:     detector.bearish,
+					Kind:        KindCandlestick,
+					Implication: Bearish,
+					Confidence:  confidence,
+					StartIndex:  i - detector.size + 1,
+					EndIndex:    i,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+// mirror retorna a série com os preços simétricos: as subidas passam a descidas, os máximos a
+// mínimos, e cada padrão de alta na série espelhada corresponde ao padrão de baixa na original
+func (s *series) mirror() *series {
+	candles := make(models.Candles, len(s.candles))
+	for i, c := range s.candles {
+		candles[i] = models.Candle{
+			Time:   c.Time,
+			Open:   -c.Open,
+			High:   -c.Low,
+			Low:    -c.High,
+			Close:  -c.Close,
+			Volume: c.Volume,
+			Filled: c.Filled,
+		}
+	}
+	return &series{candles: candles, opts: s.opts, avgRange: s.avgRange}
+}
+
+// downtrendScore pontua a descida antes da vela i: 1 a partir de duas amplitudes médias
+func (s *series) downtrendScore(i int) float64 {
+	return clamp(-s.trend(i) / 2)
+}
+
+// detectDoji deteta um doji: corpo quase nulo face à amplitude, sinal de indecisão. Depois de
+// uma tendência clara sugere a inversão dessa tendência.
+func detectDoji(s *series, i int) (float64, string, bool) {
+	if !s.real(i, i) {
+		return 0, "", false
+	}
+	c := s.candles[i]
+	r := c.High - c.Low
+	if r <= 0 || s.avgRange[i] == 0 || body(c) > 0.1*r {
+		return 0, "", false
+	}
+
+	trend := s.trend(i)
+	implication := Neutral
+	if trend > 1 {
+		implication = Bearish
+	} else if trend < -1 {
+		implication = Bullish
+	}
+
+	confidence := 0.4*(1-body(c)/(0.1*r)) + 0.3*clamp(r/s.avgRange[i]) + 0.3*clamp(math.Abs(trend)/2)
+	return confidence, implication, true
+}
+
+// detectHammer deteta um martelo: depois de uma descida, uma vela com sombra inferior longa
+// (pelo menos o dobro do corpo e 60% da amplitude) e quase sem sombra superior
+func detectHammer(s *series, i int) (float64, bool) {
+	c := s.candles[i]
+	r := c.High - c.Low
+	if r <= 0 || s.avgRange[i] == 0 || s.trend(i) >= 0 {
+		return 0, false
+	}
+	lower := lowerShadow(c)
+	if lower < 2*body(c) || lower < 0.6*r || upperShadow(c) > 0.15*r {
+		return 0, false
+	}
+
+	confidence := 0.3*clamp((lower/r-0.6)/0.3) + 0.2*clamp(r/s.avgRange[i]) + 0.5*s.downtrendScore(i)
+	return confidence, true
+}
+
+// detectEngulfing deteta um engolfo de alta: uma vela de baixa seguida de uma vela de alta cujo
+// corpo cobre todo o corpo da anterior
+func detectEngulfing(s *series, i int) (float64, bool) {
+	prev, cur := s.candles[i-1], s.candles[i]
+	if !bearish(prev) || !bullish(cur) || s.avgRange[i-1] == 0 {
+		return 0, false
+	}
+	if cur.Open > prev.Close || cur.Close < prev.Open || body(cur) <= body(prev) {
+		return 0, false
+	}
+
+	confidence := 0.2 + 0.3*clamp(body(cur)/body(prev)-1) + 0.2*clamp(body(cur)/s.avgRange[i-1]) + 0.3*s.downtrendScore(i-1)
+	return confidence, true
+}
+
+// detectMorningStar deteta uma estrela da manhã: uma vela longa de baixa, uma vela de corpo
+// pequeno abaixo do fecho da primeira e uma vela de alta que fecha acima do meio do corpo da
+// primeira
+func detectMorningStar(s *series, i int) (float64, bool) {
+	first, star, last := s.candles[i-2], s.candles[i-1], s.candles[i]
+	avg := s.avgRange[i-2]
+	if avg == 0 || !bearish(first) || body(first) < 0.5*avg {
+		return 0, false
+	}
+	if body(star) > 0.3*body(first) || math.Max(star.Open, star.Close) > first.Close+0.1*body(first) {
+		return 0, false
+	}
+	if !bullish(last) || last.Close <= (first.Open+first.Close)/2 {
+		return 0, false
+	}
+
+	penetration := (last.Close - first.Close) / body(first)
+	confidence := 0.2 + 0.3*clamp(penetration) + 0.2*(1-body(star)/(0.3*body(first))) + 0.3*s.downtrendScore(i-2)
+	return confidence, true
+}
+
+// detectThreeSoldiers deteta três soldados brancos: três velas longas de alta seguidas, cada uma
+// a abrir dentro do corpo da anterior e a fechar mais acima, com sombras superiores curtas
+func detectThreeSoldiers(s *series, i int) (float64, bool) {
+	avg := s.avgRange[i-2]
+	if avg == 0 {
+		return 0, false
+	}
+
+	minBody, maxShadow := -1.0, 0.0
+	for j := i - 2; j <= i; j++ {
+		c := s.candles[j]
+		if !bullish(c) || body(c) < 0.5*avg || upperShadow(c) > 0.3*body(c) {
+			return 0, false
+		}
+		if j > i-2 {
+			prev := s.candles[j-1]
+			if c.Close <= prev.Close || c.Open < prev.Open || c.Open > prev.Close {
+				return 0, false
+			}
+		}
+		if minBody < 0 || body(c) < minBody {
+			minBody = body(c)
+		}
+		maxShadow = math.Max(maxShadow, upperShadow(c)/body(c))
+	}
+
+	confidence := 0.3 + 0.3*clamp(minBody/avg) + 0.2*(1-maxShadow/0.3) + 0.2*s.downtrendScore(i-2)
+	return confidence, true
+}
@@ -0,0 +1,204 @@
+package patterns
+
+import "math"
+
+// detectChartPatterns deteta os padrões gráficos. Tal como nos padrões de velas, os detetores
+// procuram a forma de alta (fundos) e os topos são detetados sobre a série espelhada.
+func detectChartPatterns(s *series) []Match {
+	matches := []Match{}
+	for _, orientation := range []struct {
+		series      *series
+		implication string
+		double      string
+		shoulders   string
+		sign        float64
+	}{
+		{s, Bullish, DoubleBottom, InverseHeadAndShoulders, 1},
+		{s.mirror(), Bearish, DoubleTop, HeadAndShoulders, -1},
+	} {
+		pivots := orientation.series.pivotLows()
+		for _, match := range orientation.series.doubleBottoms(pivots) {
+			match.Pattern = orientation.double
+			match.Implication = orientation.implication
+			match.Level *= orientation.sign
+			matches = append(matches, match)
+		}
+		for _, match := range orientation.series.inverseHeadAndShoulders(pivots) {
+			match.Pattern = orientation.shoulders
+			match.Implication = orientation.implication
+			match.Level *= orientation.sign
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// pivotLows retorna os mínimos locais: as velas reais cujo mínimo é o menor das PivotWindow
+// velas de cada lado. As últimas PivotWindow velas ainda não podem ser mínimos locais.
+func (s *series) pivotLows() []int {
+	w := s.opts.PivotWindow
+	pivots := []int{}
+	for i := w; i+w < len(s.candles); i++ {
+		if s.candles[i].Filled {
+			continue
+		}
+		pivot := true
+		for j := i - w; j <= i+w && pivot; j++ {
+			// Em caso de empate, o mínimo local é a primeira vela
+			if j < i && s.candles[j].Low <= s.candles[i].Low || j > i && s.candles[j].Low < s.candles[i].Low {
+				pivot = false
+			}
+		}
+		if pivot {
+			pivots = append(pivots, i)
+		}
+	}
+	return pivots
+}
+
+// doubleBottoms deteta fundos duplos: dois mínimos locais seguidos a preços semelhantes (até
+// Tolerance de diferença), separados por uma subida de pelo menos Tolerance. O padrão confirma-se
+// quando um fecho ultrapassa o máximo entre os dois fundos (a linha de pescoço) antes de o preço
+// descer mais de Tolerance abaixo dos fundos, dentro de um período igual à distância entre eles.
+func (s *series) doubleBottoms(pivots []int) []Match {
+	tolerance := s.opts.Tolerance
+	matches := []Match{}
+	for k := 1; k < len(pivots); k++ {
+		a, b := pivots[k-1], pivots[k]
+		lowA, lowB := s.candles[a].Low, s.candles[b].Low
+		difference := relative(lowA, lowB)
+		if difference > tolerance {
+			continue
+		}
+
+		neckline := s.highestHigh(a+1, b-1)
+		bottom := math.Min(lowA, lowB)
+		depth := (neckline - math.Max(lowA, lowB)) / math.Abs(bottom)
+		if depth < tolerance {
+			continue
+		}
+
+		end, confirmed, ok := s.breakout(b, b-a, func(int) float64 { return neckline }, bottom-tolerance*math.Abs(bottom))
+		if !ok {
+			continue
+		}
+
+		confidence := 0.35*(1-difference/tolerance) + 0.25*clamp(depth/(4*tolerance))
+		if confirmed {
+			confidence += 0.4
+		}
+		matches = append(matches, Match{
+			Kind:       KindChart,
+			Confidence: confidence,
+			StartIndex: a,
+			EndIndex:   end,
+			Level:      neckline,
+			Confirmed:  confirmed,
+		})
+	}
+	return matches
+}
+
+// inverseHeadAndShoulders deteta cabeças e ombros invertidos: três mínimos locais seguidos em que
+// o do meio (a cabeça) está pelo menos Tolerance abaixo dos outros dois (os ombros), que diferem
+// até 2*Tolerance entre si. A linha de pescoço une os máximos entre os ombros e a cabeça, e o
+// padrão confirma-se quando um fecho a ultrapassa antes de o preço descer abaixo da cabeça.
+func (s *series) inverseHeadAndShoulders(pivots []int) []Match {
+	tolerance := s.opts.Tolerance
+	matches := []Match{}
+	for k := 2; k < len(pivots); k++ {
+		left, head, right := pivots[k-2], pivots[k-1], pivots[k]
+		lowLeft, lowHead, lowRight := s.candles[left].Low, s.candles[head].Low, s.candles[right].Low
+
+		shoulders := relative(lowLeft, lowRight)
+		if shoulders > 2*tolerance {
+			continue
+		}
+		headDepth := (math.Min(lowLeft, lowRight) - lowHead) / math.Abs(lowHead)
+		if headDepth < tolerance {
+			continue
+		}
+
+		// Linha de pescoço pelos máximos entre o ombro esquerdo e a cabeça e entre a cabeça e o
+		// ombro direito
+		firstPeak, secondPeak := s.highestIndex(left+1, head-1), s.highestIndex(head+1, right-1)
+		if firstPeak < 0 || secondPeak < 0 {
+			continue
+		}
+		first, second := s.candles[firstPeak].High, s.candles[secondPeak].High
+		neckline := func(i int) float64 {
+			return first + (second-first)*float64(i-firstPeak)/float64(secondPeak-firstPeak)
+		}
+		if neckline(right) <= math.Max(lowLeft, lowRight) {
+			continue
+		}
+
+		end, confirmed, ok := s.breakout(right, right-left, neckline, lowHead)
+		if !ok {
+			continue
+		}
+
+		confidence := 0.3*(1-shoulders/(2*tolerance)) + 0.3*clamp(headDepth/(3*tolerance))
+		if confirmed {
+			confidence += 0.4
+		}
+		matches = append(matches, Match{
+			Kind:       KindChart,
+			Confidence: confidence,
+			StartIndex: left,
+			EndIndex:   end,
+			Level:      neckline(end),
+			Confirmed:  confirmed,
+		})
+	}
+	return matches
+}
+
+// breakout procura, nas window velas depois de from, o primeiro fecho acima do nível. Retorna a
+// vela do rompimento e true; sem rompimento, a vela from e false. O padrão deixa de ser válido
+// (ok falso) se o preço descer abaixo de invalidation antes do rompimento.
+func (s *series) breakout(from, window int, level func(int) float64, invalidation float64) (end int, confirmed, ok bool) {
+	for i := from + 1; i <= from+window && i < len(s.candles); i++ {
+		if s.candles[i].Filled {
+			continue
+		}
+		if s.candles[i].Low < invalidation {
+			return 0, false, false
+		}
+		if s.candles[i].Close > level(i) {
+			return i, true, true
+		}
+	}
+	return from, false, true
+}
+
+// highestHigh retorna o maior máximo das velas reais de from a to (inclusive)
+func (s *series) highestHigh(from, to int) float64 {
+	if i := s.highestIndex(from, to); i >= 0 {
+		return s.candles[i].High
+	}
+	return math.Inf(-1)
+}
+
+// highestIndex retorna a vela real com o maior máximo de from a to (inclusive), ou -1
+func (s *series) highestIndex(from, to int) int {
+	best := -1
+	for i := from; i <= to && i < len(s.candles); i++ {
+		if i < 0 || s.candles[i].Filled {
+			continue
+		}
+		if best < 0 || s.candles[i].High > s.candles[best].High {
+			best = i
+		}
+	}
+	return best
+}
+
+// relative é a diferença relativa entre dois preços, face à sua média
+func relative(a, b float64) float64 {
+	mean := (math.Abs(a) + math.Abs(b)) / 2
+	if mean == 0 {
+		return 0
+	}
+	return math.Abs(a-b) / mean
+}
@@ -0,0 +1,233 @@
+// Package patterns deteta padrões de velas (doji, martelo, engolfo, estrelas da manhã e da
+// tarde, três soldados e três corvos) e padrões gráficos (topo e fundo duplos, cabeça e ombros)
+// numa série de velas OHLCV.
+//
+// A deteção não depende de serviços externos: recebe as velas e devolve as ocorrências, cada uma
+// com um grau de confiança entre 0 e 1, pelo que pode ser usada pela API, pelo agendador ou por
+// alertas. As velas sintéticas (models.Candle.Filled) nunca fazem parte de um padrão.
+package patterns
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// Tipos de padrão
+const (
+	KindCandlestick = "candlestick"
+	KindChart       = "chart"
+)
+
+// Implicações de um padrão para o preço
+const (
+	Bullish = "bullish"
+	Bearish = "bearish"
+	Neutral = "neutral"
+)
+
+// Identificadores dos padrões
+const (
+	Doji                    = "doji"
+	Hammer                  = "hammer"
+	ShootingStar            = "shooting_star"
+	BullishEngulfing        = "bullish_engulfing"
+	BearishEngulfing        = "bearish_engulfing"
+	MorningStar             = "morning_star"
+	EveningStar             = "evening_star"
+	ThreeWhiteSoldiers      = "three_white_soldiers"
+	ThreeBlackCrows         = "three_black_crows"
+	DoubleTop               = "double_top"
+	DoubleBottom            = "double_bottom"
+	HeadAndShoulders        = "head_and_shoulders"
+	InverseHeadAndShoulders = "inverse_head_and_shoulders"
+)
+
+// names são os nomes apresentados de cada padrão
+var names = map[string]string{
+	Doji:                    "Doji",
+	Hammer:                  "Martelo",
+	ShootingStar:            "Estrela Cadente",
+	BullishEngulfing:        "Engolfo de Alta",
+	BearishEngulfing:        "Engolfo de Baixa",
+	MorningStar:             "Estrela da Manhã",
+	EveningStar:             "Estrela da Tarde",
+	ThreeWhiteSoldiers:      "Três Soldados Brancos",
+	ThreeBlackCrows:         "Três Corvos Negros",
+	DoubleTop:               "Topo Duplo",
+	DoubleBottom:            "Fundo Duplo",
+	HeadAndShoulders:        "Cabeça e Ombros",
+	InverseHeadAndShoulders: "Cabeça e Ombros Invertido",
+}
+
+// Match é uma ocorrência de um padrão entre as velas StartIndex e EndIndex (inclusive)
+type Match struct {
+	Pattern     string    `json:"pattern"`
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Implication string    `json:"implication"`
+	Confidence  float64   `json:"confidence"` // 0-1
+	StartIndex  int       `json:"startIndex"`
+	EndIndex    int       `json:"endIndex"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	// Padrões gráficos: nível de confirmação (linha de pescoço) e se o fecho já o atravessou
+	Level     float64 `json:"level,omitempty"`
+	Confirmed bool    `json:"confirmed,omitempty"`
+}
+
+// Options são os parâmetros da deteção. Campos a zero usam os valores de DefaultOptions.
+type Options struct {
+	TrendLookback int     // velas usadas para avaliar a tendência anterior a um padrão de velas
+	RangePeriod   int     // velas usadas para a amplitude média, que serve de referência de tamanho
+	PivotWindow   int     // velas de cada lado de um máximo ou mínimo local (padrões gráficos)
+	Tolerance     float64 // diferença relativa máxima entre dois topos, fundos ou ombros "iguais"
+	MinConfidence float64 // confiança mínima das ocorrências devolvidas
+}
+
+// DefaultOptions são os parâmetros por omissão da deteção
+var DefaultOptions = Options{
+	TrendLookback: 5,
+	RangePeriod:   14,
+	PivotWindow:   3,
+	Tolerance:     0.03,
+	MinConfidence: 0.3,
+}
+
+// withDefaults preenche os campos a zero com os valores por omissão
+func (o Options) withDefaults() Options {
+	if o.TrendLookback <= 0 {
+		o.TrendLookback = DefaultOptions.TrendLookback
+	}
+	if o.RangePeriod <= 0 {
+		o.RangePeriod = DefaultOptions.RangePeriod
+	}
+	if o.PivotWindow <= 0 {
+		o.PivotWindow = DefaultOptions.PivotWindow
+	}
+	if o.Tolerance <= 0 {
+		o.Tolerance = DefaultOptions.Tolerance
+	}
+	if o.MinConfidence <= 0 {
+		o.MinConfidence = DefaultOptions.MinConfidence
+	}
+	return o
+}
+
+// Detect deteta os padrões de velas e gráficos na série, por ordem cronológica
+func Detect(candles models.Candles, opts Options) []Match {
+	opts = opts.withDefaults()
+	series := newSeries(candles, opts)
+
+	matches := []Match{}
+	for _, match := range append(detectCandlesticks(series), detectChartPatterns(series)...) {
+		if match.Confidence < opts.MinConfidence {
+			continue
+		}
+		match.Name = names[match.Pattern]
+		match.Start = candles[match.StartIndex].Time
+		match.End = candles[match.EndIndex].Time
+		match.Confidence = math.Round(match.Confidence*100) / 100
+		matches = append(matches, match)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].EndIndex != matches[j].EndIndex {
+			return matches[i].EndIndex < matches[j].EndIndex
+		}
+		return matches[i].StartIndex < matches[j].StartIndex
+	})
+	return matches
+}
+
+// Since retorna as ocorrências que terminam em since ou depois, por exemplo as da última vela
+func Since(matches []Match, since time.Time) []Match {
+	result := []Match{}
+	for _, match := range matches {
+		if !match.End.Before(since) {
+			result = append(result, match)
+		}
+	}
+	return result
+}
+
+// series são as velas com as medidas usadas pelos detetores
+type series struct {
+	candles  models.Candles
+	opts     Options
+	avgRange []float64 // amplitude média das velas anteriores a cada vela
+}
+
+// newSeries calcula a amplitude média móvel das velas reais anteriores a cada vela
+func newSeries(candles models.Candles, opts Options) *series {
+	s := &series{candles: candles, opts: opts, avgRange: make([]float64, len(candles))}
+	for i := range candles {
+		total, count := 0.0, 0
+		for j := i - 1; j >= 0 && count < opts.RangePeriod; j-- {
+			if candles[j].Filled {
+				continue
+			}
+			total += candles[j].High - candles[j].Low
+			count++
+		}
+		if count > 0 {
+			s.avgRange[i] = total / float64(count)
+		}
+	}
+	return s
+}
+
+// real indica se as velas de from a to (inclusive) existem e não são sintéticas
+func (s *series) real(from, to int) bool {
+	if from < 0 || to >= len(s.candles) {
+		return false
+	}
+	for i := from; i <= to; i++ {
+		if s.candles[i].Filled {
+			return false
+		}
+	}
+	return true
+}
+
+// trend mede a tendência antes da vela i: a variação do fecho nas TrendLookback velas anteriores,
+// em amplitudes médias. Positivo numa subida, negativo numa descida, 0 sem dados suficientes.
+func (s *series) trend(i int) float64 {
+	from, to := i-1-s.opts.TrendLookback, i-1
+	if from < 0 || s.avgRange[i] == 0 {
+		return 0
+	}
+	return (s.candles[to].Close - s.candles[from].Close) / s.avgRange[i]
+}
+
+// body é o tamanho do corpo de uma vela
+func body(c models.Candle) float64 {
+	return math.Abs(c.Close - c.Open)
+}
+
+// upperShadow é a sombra acima do corpo de uma vela
+func upperShadow(c models.Candle) float64 {
+	return c.High - math.Max(c.Open, c.Close)
+}
+
+// lowerShadow é a sombra abaixo do corpo de uma vela
+func lowerShadow(c models.Candle) float64 {
+	return math.Min(c.Open, c.Close) - c.Low
+}
+
+// bullish indica se a vela fecha acima da abertura
+func bullish(c models.Candle) bool {
+	return c.Close > c.Open
+}
+
+// bearish indica se a vela fecha abaixo da abertura
+func bearish(c models.Candle) bool {
+	return c.Close < c.Open
+}
+
+// clamp limita value ao intervalo [0, 1]
+func clamp(value float64) float64 {
+	return math.Max(0, math.Min(1, value))
+}
@@ -0,0 +1,169 @@
+package patterns
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// start é o instante da primeira vela das séries de teste
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fromPath constrói velas horárias que abrem no fecho anterior e fecham em cada preço do
+// caminho, com sombras de 0,5 para cada lado
+func fromPath(prices ...float64) models.Candles {
+	candles := make(models.Candles, len(prices))
+	open := prices[0]
+	for i, price := range prices {
+		candles[i] = models.Candle{
+			Time:  start.Add(time.Duration(i) * time.Hour),
+			Open:  open,
+			High:  math.Max(open, price) + 0.5,
+			Low:   math.Min(open, price) - 0.5,
+			Close: price,
+		}
+		open = price
+	}
+	return candles
+}
+
+// reflect espelha os preços em torno de level/2: as subidas passam a descidas e os máximos a
+// mínimos, mantendo os preços positivos
+func reflect(candles models.Candles, level float64) models.Candles {
+	reflected := make(models.Candles, len(candles))
+	for i, c := range candles {
+		reflected[i] = models.Candle{
+			Time:  c.Time,
+			Open:  level - c.Open,
+			High:  level - c.Low,
+			Low:   level - c.High,
+			Close: level - c.Close,
+		}
+	}
+	return reflected
+}
+
+// find retorna a ocorrência do padrão indicado, se existir
+func find(matches []Match, pattern string) (Match, bool) {
+	for _, match := range matches {
+		if match.Pattern == pattern {
+			return match, true
+		}
+	}
+	return Match{}, false
+}
+
+// TestEngulfingMirror verifica um engolfo de alta depois de uma descida e o engolfo de baixa
+// da mesma série espelhada, com a mesma confiança
+func TestEngulfingMirror(t *testing.T) {
+	// Sete velas de baixa com amplitude 1,4, seguidas de uma vela de alta com corpo 1,7 que
+	// cobre o corpo da última
+	var candles models.Candles
+	for k := 0; k < 7; k++ {
+		open := 110 - float64(k)
+		candles = append(candles, models.Candle{Open: open, High: open + 0.2, Low: open - 1.2, Close: open - 1})
+	}
+	candles = append(candles, models.Candle{Open: 102.8, High: 104.6, Low: 102.7, Close: 104.5})
+	for i := range candles {
+		candles[i].Time = start.Add(time.Duration(i) * time.Hour)
+	}
+
+	// 0,2 + 0,3·(1,7/1 - 1) + 0,2·1 + 0,3·1, com uma descida de 5 em amplitudes de 1,4
+	tests := []struct {
+		name           string
+		candles        models.Candles
+		want, opposite string
+		implication    string
+	}{
+		{"alta", candles, BullishEngulfing, BearishEngulfing, Bullish},
+		{"baixa", reflect(candles, 220), BearishEngulfing, BullishEngulfing, Bearish},
+	}
+
+	for _, tt := range tests {
+		matches := Detect(tt.candles, Options{})
+		match, ok := find(matches, tt.want)
+		if !ok {
+			t.Fatalf("%s: sem %s em %+v", tt.name, tt.want, matches)
+		}
+		if match.Implication != tt.implication || match.StartIndex != 6 || match.EndIndex != 7 || match.Confidence != 0.91 {
+			t.Errorf("%s: %+v, esperado velas 6 a 7 com confiança 0,91", tt.name, match)
+		}
+		if !match.End.Equal(tt.candles[7].Time) || match.Name != names[tt.want] {
+			t.Errorf("%s: fim %v e nome %q", tt.name, match.End, match.Name)
+		}
+		if _, ok := find(matches, tt.opposite); ok {
+			t.Errorf("%s: %s detetado na mesma série", tt.name, tt.opposite)
+		}
+	}
+}
+
+// TestDoubleTop verifica um topo duplo em 110,5 e 111 com a linha de pescoço no mínimo de 99,5
+// entre eles, antes e depois de um fecho abaixo dela
+func TestDoubleTop(t *testing.T) {
+	path := []float64{100, 102, 104, 106, 108, 110, 108, 105, 102, 100, 102, 105, 108, 110.5, 108, 105, 102, 99, 97}
+
+	tests := []struct {
+		name           string
+		candles        models.Candles
+		wantEnd        int
+		wantConfirmed  bool
+		wantConfidence float64
+	}{
+		// 0,35·(1 - 0,5/110,75/0,03) + 0,25·(11/111)/0,12, mais 0,4 com a confirmação
+		{"sem confirmação", fromPath(path[:17]...), 13, false, 0.50},
+		{"confirmado", fromPath(path...), 17, true, 0.90},
+	}
+
+	for _, tt := range tests {
+		matches := Detect(tt.candles, Options{})
+		match, ok := find(matches, DoubleTop)
+		if !ok {
+			t.Fatalf("%s: sem topo duplo em %+v", tt.name, matches)
+		}
+		if match.Implication != Bearish || match.StartIndex != 5 || match.EndIndex != tt.wantEnd ||
+			match.Confirmed != tt.wantConfirmed || match.Confidence != tt.wantConfidence || match.Level != 99.5 {
+			t.Errorf("%s: %+v, esperado velas 5 a %d, confirmado %v, confiança %v e nível 99,5",
+				tt.name, match, tt.wantEnd, tt.wantConfirmed, tt.wantConfidence)
+		}
+		if _, ok := find(matches, DoubleBottom); ok {
+			t.Errorf("%s: fundo duplo detetado num topo duplo", tt.name)
+		}
+	}
+}
+
+// TestHeadAndShoulders verifica um ombro esquerdo em 109,5, a cabeça em 115,5 e o ombro direito
+// em 110, com a linha de pescoço de 100,5 a 101,5 entre as velas 6 e 14, e a sua versão invertida
+func TestHeadAndShoulders(t *testing.T) {
+	candles := fromPath(100, 103, 106, 109, 106, 103, 101, 104, 108, 112, 115, 112, 108, 104, 102,
+		105, 108, 109.5, 106, 103, 100, 98)
+
+	// A linha de pescoço sobe 0,125 por vela e o fecho de 100 na vela 20 fica abaixo de 102,25.
+	// As diferenças são relativas aos preços, pelo que a confiança muda com o espelho.
+	tests := []struct {
+		name        string
+		candles     models.Candles
+		pattern     string
+		implication string
+		level       float64
+		confidence  float64
+	}{
+		// 0,3·(1 - 0,5/109,75/0,06) + 0,3·(5,5/115,5)/0,09 + 0,4
+		{"topo", candles, HeadAndShoulders, Bearish, 102.25, 0.84},
+		// 0,3·(1 - 0,5/110,25/0,06) + 0,3·(5,5/104,5)/0,09 + 0,4
+		{"fundo", reflect(candles, 220), InverseHeadAndShoulders, Bullish, 220 - 102.25, 0.85},
+	}
+
+	for _, tt := range tests {
+		matches := Detect(tt.candles, Options{})
+		match, ok := find(matches, tt.pattern)
+		if !ok {
+			t.Fatalf("%s: sem %s em %+v", tt.name, tt.pattern, matches)
+		}
+		if match.Implication != tt.implication || match.StartIndex != 3 || match.EndIndex != 20 || !match.Confirmed ||
+			match.Confidence != tt.confidence || math.Abs(match.Level-tt.level) > 1e-9 {
+			t.Errorf("%s: %+v, esperado velas 3 a 20, confirmado, confiança %v e nível %v", tt.name, match, tt.confidence, tt.level)
+		}
+	}
+}
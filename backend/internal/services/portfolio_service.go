@@ -2,6 +2,9 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"gofolio/backend/internal/models"
@@ -9,81 +12,480 @@ import (
 	"github.com/google/uuid"
 )
 
+// Erros de validação do serviço de portfólio
+var (
+	ErrPortfolioNameRequired = errors.New("o nome do portfólio é obrigatório")
+	ErrInvalidAsset          = errors.New("ativo inválido: símbolo obrigatório, quantidade e preço não podem ser negativos")
+)
+
 // PortfolioService gerencia operações relacionadas ao portfólio
 type PortfolioService struct {
-	// TODO: Adicionar dependências como repositório de dados
+	repo    models.PortfolioRepository
+	market  MarketDataSource
+	history models.HistoricalDataRepository
+	rates   CurrencyConverter
 }
 
-// NewPortfolioService cria uma nova instância do serviço de portfólio
-func NewPortfolioService() *PortfolioService {
-	return &PortfolioService{}
+// NewPortfolioService cria uma nova instância do serviço de portfólio. Sem fonte de
+// dados de mercado (nil), os ativos são reportados sem preço; sem histórico de preços
+// (nil), GetPortfolioHistory retorna ErrHistoryUnavailable; sem taxas de câmbio (nil),
+// os valores só podem ser pedidos em models.DefaultQuoteCurrency.
+func NewPortfolioService(repo models.PortfolioRepository, market MarketDataSource, history models.HistoricalDataRepository, rates CurrencyConverter) *PortfolioService {
+	return &PortfolioService{repo: repo, market: market, history: history, rates: rates}
 }
 
-// CreatePortfolio cria um novo portfólio
-func (s *PortfolioService) CreatePortfolio(userID string, name, description string) (*models.Portfolio, error) {
+// CreatePortfolio cria um novo portfólio. Um método de custo vazio corresponde a models.DefaultCostBasisMethod.
+func (s *PortfolioService) CreatePortfolio(userID string, name, description string, method models.CostBasisMethod) (*models.Portfolio, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrPortfolioNameRequired
+	}
+
+	if method == "" {
+		method = models.DefaultCostBasisMethod
+	}
+	if !method.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCostBasisMethod, method)
+	}
+
+	now := time.Now()
 	portfolio := &models.Portfolio{
-		ID:          uuid.New().String(),
-		UserID:      userID,
-		Name:        name,
-		Description: description,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-		Assets:      []models.Asset{},
+		ID:              uuid.New().String(),
+		UserID:          userID,
+		Name:            name,
+		Description:     description,
+		CostBasisMethod: method,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		Assets:          []models.Asset{},
+	}
+
+	if err := s.repo.CreatePortfolio(portfolio); err != nil {
+		return nil, err
 	}
 
-	// TODO: Salvar no banco de dados
 	return portfolio, nil
 }
 
 // GetPortfolio retorna um portfólio pelo ID
 func (s *PortfolioService) GetPortfolio(id string) (*models.Portfolio, error) {
-	// TODO: Buscar do banco de dados
-	return nil, errors.New("not implemented")
+	return s.repo.GetPortfolio(id)
 }
 
-// UpdatePortfolio atualiza um portfólio existente
-func (s *PortfolioService) UpdatePortfolio(id string, name, description string) (*models.Portfolio, error) {
-	// TODO: Atualizar no banco de dados
-	return nil, errors.New("not implemented")
+// ListPortfolios retorna os portfólios de um usuário
+func (s *PortfolioService) ListPortfolios(userID string) ([]models.Portfolio, error) {
+	return s.repo.ListPortfolios(userID)
+}
+
+// UpdatePortfolio atualiza um portfólio existente. Um método de custo vazio mantém o atual.
+func (s *PortfolioService) UpdatePortfolio(id string, name, description string, method models.CostBasisMethod) (*models.Portfolio, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrPortfolioNameRequired
+	}
+
+	if method != "" && !method.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCostBasisMethod, method)
+	}
+
+	portfolio, err := s.repo.GetPortfolio(id)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolio.Name = name
+	portfolio.Description = description
+	if method != "" {
+		portfolio.CostBasisMethod = method
+	}
+	portfolio.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdatePortfolio(portfolio); err != nil {
+		return nil, err
+	}
+
+	return portfolio, nil
 }
 
 // DeletePortfolio remove um portfólio
 func (s *PortfolioService) DeletePortfolio(id string) error {
-	// TODO: Remover do banco de dados
-	return errors.New("not implemented")
+	return s.repo.DeletePortfolio(id)
 }
 
-// AddAsset adiciona um novo ativo ao portfólio
-func (s *PortfolioService) AddAsset(portfolioID, symbol string, amount, purchasePrice float64) (*models.Asset, error) {
+// AddAsset adiciona um novo ativo ao portfólio. Uma data de compra vazia corresponde ao momento atual.
+func (s *PortfolioService) AddAsset(portfolioID, symbol string, amount, purchasePrice float64, purchaseDate time.Time) (*models.Asset, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" || amount < 0 || purchasePrice < 0 {
+		return nil, ErrInvalidAsset
+	}
+
+	now := time.Now()
+	if purchaseDate.IsZero() {
+		purchaseDate = now
+	}
+
 	asset := &models.Asset{
 		ID:            uuid.New().String(),
 		PortfolioID:   portfolioID,
 		Symbol:        symbol,
 		Amount:        amount,
 		PurchasePrice: purchasePrice,
-		PurchaseDate:  time.Now(),
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+		PurchaseDate:  purchaseDate,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if err := s.repo.AddAsset(asset); err != nil {
+		return nil, err
 	}
 
-	// TODO: Salvar no banco de dados
 	return asset, nil
 }
 
-// GetPortfolioStats retorna estatísticas do portfólio
-func (s *PortfolioService) GetPortfolioStats(portfolioID string) (*models.PortfolioStats, error) {
-	// TODO: Calcular estatísticas baseadas nos ativos
-	return nil, errors.New("not implemented")
+// UpdateAsset atualiza a quantidade, o preço e a data de compra de um ativo do portfólio.
+// Uma data de compra vazia mantém a data atual.
+func (s *PortfolioService) UpdateAsset(portfolioID, assetID string, amount, purchasePrice float64, purchaseDate time.Time) (*models.Asset, error) {
+	if amount < 0 || purchasePrice < 0 {
+		return nil, ErrInvalidAsset
+	}
+
+	asset, err := s.getPortfolioAsset(portfolioID, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	asset.Amount = amount
+	asset.PurchasePrice = purchasePrice
+	if !purchaseDate.IsZero() {
+		asset.PurchaseDate = purchaseDate
+	}
+	asset.UpdatedAt = time.Now()
+
+	// Garantir que as vendas posteriores continuam cobertas
+	opening := openingTransactions([]models.Asset{*asset})
+	var replacement *models.Transaction
+	if len(opening) > 0 {
+		replacement = &opening[0]
+	}
+	if err := s.checkLedgerChange(portfolioID, asset.ID, replacement); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateAsset(asset); err != nil {
+		return nil, err
+	}
+
+	return asset, nil
+}
+
+// RemoveAsset remove um ativo do portfólio
+func (s *PortfolioService) RemoveAsset(portfolioID, assetID string) error {
+	if _, err := s.getPortfolioAsset(portfolioID, assetID); err != nil {
+		return err
+	}
+
+	if err := s.checkLedgerChange(portfolioID, assetID, nil); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteAsset(assetID)
 }
 
-// GetPortfolioForecast gera previsões para o portfólio
-func (s *PortfolioService) GetPortfolioForecast(portfolioID string, timeFrame string) (*models.PortfolioForecast, error) {
-	// TODO: Implementar lógica de previsão
-	return nil, errors.New("not implemented")
+// getPortfolioAsset obtém um ativo, garantindo que pertence ao portfólio indicado
+func (s *PortfolioService) getPortfolioAsset(portfolioID, assetID string) (*models.Asset, error) {
+	asset, err := s.repo.GetAsset(assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if asset.PortfolioID != portfolioID {
+		return nil, models.ErrAssetNotFound
+	}
+
+	return asset, nil
+}
+
+// ListTransactions retorna o livro de transações de um portfólio por ordem cronológica
+func (s *PortfolioService) ListTransactions(portfolioID string) ([]models.Transaction, error) {
+	return s.repo.ListTransactions(portfolioID)
 }
 
-// SimulateTransaction simula uma transação no portfólio
-func (s *PortfolioService) SimulateTransaction(portfolioID, symbol string, amount, price float64, transactionType string) (*models.TransactionSimulation, error) {
-	// TODO: Implementar simulação
-	return nil, errors.New("not implemented")
+// AddTransaction regista uma transação no livro do portfólio. Saídas que excedam
+// o saldo disponível na data da transação são rejeitadas com ErrInsufficientBalance.
+func (s *PortfolioService) AddTransaction(portfolioID string, tx models.Transaction) (*models.Transaction, error) {
+	if err := normalizeTransaction(&tx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tx.ID = uuid.New().String()
+	tx.PortfolioID = portfolioID
+	tx.CreatedAt = now
+	tx.UpdatedAt = now
+
+	if err := s.checkLedgerChange(portfolioID, "", &tx); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.AddTransaction(&tx); err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
+
+// UpdateTransaction substitui os dados de uma transação do portfólio
+func (s *PortfolioService) UpdateTransaction(portfolioID, transactionID string, tx models.Transaction) (*models.Transaction, error) {
+	existing, err := s.getPortfolioTransaction(portfolioID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := normalizeTransaction(&tx); err != nil {
+		return nil, err
+	}
+
+	tx.ID = existing.ID
+	tx.PortfolioID = existing.PortfolioID
+	tx.ImportID = existing.ImportID
+	tx.CreatedAt = existing.CreatedAt
+	tx.UpdatedAt = time.Now()
+
+	if err := s.checkLedgerChange(portfolioID, tx.ID, &tx); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateTransaction(&tx); err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
+
+// DeleteTransaction remove uma transação do portfólio
+func (s *PortfolioService) DeleteTransaction(portfolioID, transactionID string) error {
+	if _, err := s.getPortfolioTransaction(portfolioID, transactionID); err != nil {
+		return err
+	}
+
+	if err := s.checkLedgerChange(portfolioID, transactionID, nil); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteTransaction(transactionID)
+}
+
+// GetHoldings retorna as posições atuais do portfólio, derivadas do livro de transações
+// com o método de custo do portfólio
+func (s *PortfolioService) GetHoldings(portfolioID string) ([]models.Holding, error) {
+	portfolio, ledger, err := s.ledger(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ComputeHoldings(ledger, portfolioMethod(portfolio))
+}
+
+// GetCostBasis associa as alienações aos lotes do portfólio e retorna os lotes em aberto,
+// as alienações e o P&L realizado, na moeda indicada (vazia para a moeda por omissão).
+// Um método vazio corresponde ao método do portfólio.
+func (s *PortfolioService) GetCostBasis(portfolioID string, method models.CostBasisMethod, currency string) (*CostBasisReport, error) {
+	portfolio, ledger, currency, err := s.ledgerIn(portfolioID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if method == "" {
+		method = portfolioMethod(portfolio)
+	}
+
+	engine, err := NewCostBasisEngine(method)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := engine.Run(ledger)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := s.currentPrices(holdingSymbols(report.Holdings))
+	if err := s.convertPrices(prices, currency); err != nil {
+		return nil, err
+	}
+
+	report.Currency = currency
+	report.ApplyPrices(prices)
+	return report, nil
+}
+
+// ledger retorna o portfólio e o seu livro completo: os ativos registados diretamente
+// (como compras de abertura) seguidos das transações
+func (s *PortfolioService) ledger(portfolioID string) (*models.Portfolio, []models.Transaction, error) {
+	portfolio, err := s.repo.GetPortfolio(portfolioID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transactions, err := s.repo.ListTransactions(portfolioID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return portfolio, append(openingTransactions(portfolio.Assets), transactions...), nil
+}
+
+// portfolioMethod retorna o método de custo do portfólio, ou o método por omissão
+// para portfólios criados antes de o método ser configurável
+func portfolioMethod(portfolio *models.Portfolio) models.CostBasisMethod {
+	if portfolio.CostBasisMethod.Valid() {
+		return portfolio.CostBasisMethod
+	}
+	return models.DefaultCostBasisMethod
+}
+
+// checkLedgerChange verifica se o livro continua consistente depois de remover a
+// entrada removeID (se indicada) e de acrescentar replacement (se indicada)
+func (s *PortfolioService) checkLedgerChange(portfolioID, removeID string, replacement *models.Transaction) error {
+	_, ledger, err := s.ledger(portfolioID)
+	if err != nil {
+		return err
+	}
+
+	changed := make([]models.Transaction, 0, len(ledger)+1)
+	for _, tx := range ledger {
+		if removeID == "" || tx.ID != removeID {
+			changed = append(changed, tx)
+		}
+	}
+	if replacement != nil {
+		changed = append(changed, *replacement)
+	}
+
+	_, err = ComputeHoldings(changed, models.DefaultCostBasisMethod)
+	return err
+}
+
+// getPortfolioTransaction obtém uma transação, garantindo que pertence ao portfólio indicado
+func (s *PortfolioService) getPortfolioTransaction(portfolioID, transactionID string) (*models.Transaction, error) {
+	tx, err := s.repo.GetTransaction(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx.PortfolioID != portfolioID {
+		return nil, models.ErrTransactionNotFound
+	}
+
+	return tx, nil
+}
+
+// GetPortfolioStats valoriza as posições do portfólio aos preços de mercado atuais.
+// Ativos sem preço ficam assinalados e fora dos totais; preços desatualizados são usados,
+// mas assinalados, e em ambos os casos PricesComplete é falso. Os valores são expressos na
+// moeda indicada (vazia para a moeda por omissão): o custo à taxa da data de cada transação
+// e o valor à taxa atual.
+func (s *PortfolioService) GetPortfolioStats(portfolioID, currency string) (*models.PortfolioStats, error) {
+	portfolio, ledger, currency, err := s.ledgerIn(portfolioID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	engine, err := NewCostBasisEngine(portfolioMethod(portfolio))
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := engine.Run(ledger)
+	if err != nil {
+		return nil, err
+	}
+
+	realized := map[string]float64{}
+	for _, disposal := range report.Disposals {
+		realized[disposal.Symbol] += disposal.RealizedPnL
+	}
+
+	now := time.Now()
+	quotes := s.currentQuotes(holdingSymbols(report.Holdings))
+	if err := s.convertQuotes(quotes, currency); err != nil {
+		return nil, err
+	}
+	changes := map[string]float64{} // variação do valor de cada ativo nas últimas 24h
+
+	stats := &models.PortfolioStats{
+		Currency:       currency,
+		RealizedPnL:    report.RealizedPnL,
+		AssetCount:     len(report.Holdings),
+		PricesComplete: true,
+		Assets:         make([]models.AssetStats, 0, len(report.Holdings)),
+		LastUpdated:    now,
+	}
+
+	for _, holding := range report.Holdings {
+		quote, ok := quotes[holding.Symbol]
+		asset := models.AssetStats{
+			Symbol:      holding.Symbol,
+			Quantity:    holding.Quantity,
+			CostBasis:   holding.CostBasis,
+			AverageCost: holding.AverageCost,
+			RealizedPnL: realized[holding.Symbol],
+			PriceStatus: quoteStatus(quote, ok, now),
+		}
+
+		if asset.PriceStatus != models.PriceOK {
+			stats.PricesComplete = false
+		}
+
+		if asset.PriceStatus != models.PriceMissing {
+			asset.Price = quote.CurrentPrice
+			asset.Value = holding.Quantity * quote.CurrentPrice
+			asset.PriceChange24h = quote.PriceChangePercentage24h
+			asset.UnrealizedPnL = asset.Value - holding.CostBasis
+			if holding.CostBasis > 0 {
+				asset.UnrealizedPnLPercent = asset.UnrealizedPnL / holding.CostBasis * 100
+			}
+			if !quote.LastUpdated.IsZero() {
+				updatedAt := quote.LastUpdated
+				asset.PriceUpdatedAt = &updatedAt
+			}
+
+			stats.TotalValue += asset.Value
+			stats.TotalCost += holding.CostBasis
+			// Valor há 24h = valor / (1 + variação)
+			if growth := 1 + asset.PriceChange24h/100; growth > 0 {
+				changes[holding.Symbol] = asset.Value - asset.Value/growth
+				stats.Change24h += changes[holding.Symbol]
+			}
+		}
+
+		stats.Assets = append(stats.Assets, asset)
+	}
+
+	stats.TotalProfit = stats.TotalValue - stats.TotalCost
+	if stats.TotalCost > 0 {
+		stats.ProfitPercentage = stats.TotalProfit / stats.TotalCost * 100
+	}
+
+	// As contribuições são medidas face ao valor de há 24h, para que somem a variação do portfólio
+	previousValue := stats.TotalValue - stats.Change24h
+	for i := range stats.Assets {
+		asset := &stats.Assets[i]
+		if stats.TotalValue > 0 {
+			asset.Weight = asset.Value / stats.TotalValue
+		}
+		if previousValue > 0 {
+			asset.Change24hContribution = changes[asset.Symbol] / previousValue * 100
+		}
+	}
+	if previousValue > 0 {
+		stats.Change24hPercentage = stats.Change24h / previousValue * 100
+	}
+
+	// Maiores posições primeiro; ativos sem preço no fim
+	sort.SliceStable(stats.Assets, func(i, j int) bool {
+		return stats.Assets[i].Value > stats.Assets[j].Value
+	})
+
+	return stats, nil
 }
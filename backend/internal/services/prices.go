@@ -0,0 +1,94 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// MarketDataSource fornece as cotações atuais dos ativos. É implementada por market.Service.
+type MarketDataSource interface {
+	GetMarketData(currency string, limit int, page int, ids []string) ([]models.CryptoData, error)
+}
+
+// marketDataLimit é o número de ativos pedidos ao mercado para encontrar as cotações do portfólio
+const marketDataLimit = 250
+
+// priceStaleAfter é a idade a partir da qual uma cotação é considerada desatualizada
+const priceStaleAfter = 30 * time.Minute
+
+// currentQuotes obtém as cotações atuais dos símbolos indicados, na moeda de cotação por omissão.
+// Os dados de mercado são identificados por ID (ex.: "bitcoin"), por isso a associação é feita
+// pelo símbolo; se vários ativos partilharem o símbolo, prevalece o de maior capitalização.
+// Símbolos sem cotação ficam fora do mapa.
+func (s *PortfolioService) currentQuotes(symbols []string) map[string]models.CryptoData {
+	quotes := map[string]models.CryptoData{}
+	if s.market == nil || len(symbols) == 0 {
+		return quotes
+	}
+
+	data, err := s.market.GetMarketData(strings.ToLower(models.DefaultQuoteCurrency), marketDataLimit, 1, nil)
+	if err != nil {
+		log.Printf("Erro ao obter cotações para o portfólio: %v\n", err)
+		return quotes
+	}
+
+	wanted := map[string]bool{}
+	for _, symbol := range symbols {
+		wanted[strings.ToUpper(symbol)] = true
+	}
+
+	for _, crypto := range data {
+		symbol := strings.ToUpper(crypto.Symbol)
+		if !wanted[symbol] || crypto.CurrentPrice <= 0 {
+			continue
+		}
+		if existing, ok := quotes[symbol]; ok && !higherRanked(crypto, existing) {
+			continue
+		}
+		quotes[symbol] = crypto
+	}
+
+	return quotes
+}
+
+// currentPrices é como currentQuotes, mas devolve apenas o preço de cada símbolo
+func (s *PortfolioService) currentPrices(symbols []string) map[string]float64 {
+	prices := map[string]float64{}
+	for symbol, quote := range s.currentQuotes(symbols) {
+		prices[symbol] = quote.CurrentPrice
+	}
+	return prices
+}
+
+// higherRanked indica se a tem melhor posição no ranking de capitalização do que b
+func higherRanked(a, b models.CryptoData) bool {
+	if b.MarketCapRank <= 0 {
+		return a.MarketCapRank > 0
+	}
+	return a.MarketCapRank > 0 && a.MarketCapRank < b.MarketCapRank
+}
+
+// quoteStatus classifica uma cotação. Cotações sem data de atualização são tratadas como atuais,
+// já que os scrapers nem sempre a preenchem.
+func quoteStatus(quote models.CryptoData, ok bool, now time.Time) models.PriceStatus {
+	switch {
+	case !ok || quote.CurrentPrice <= 0:
+		return models.PriceMissing
+	case !quote.LastUpdated.IsZero() && now.Sub(quote.LastUpdated) > priceStaleAfter:
+		return models.PriceStale
+	default:
+		return models.PriceOK
+	}
+}
+
+// holdingSymbols retorna os símbolos das posições
+func holdingSymbols(holdings []models.Holding) []string {
+	symbols := make([]string, 0, len(holdings))
+	for _, holding := range holdings {
+		symbols = append(symbols, holding.Symbol)
+	}
+	return symbols
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// Erros dos pesos alvo e do rebalanceamento
+var (
+	ErrInvalidTargets          = errors.New("pesos alvo inválidos")
+	ErrNoTargets               = errors.New("o portfólio não tem pesos alvo definidos")
+	ErrInvalidRebalanceOptions = errors.New("opções de rebalanceamento inválidas")
+	ErrTargetPriceMissing      = errors.New("sem preço de mercado para ativos com peso alvo")
+)
+
+// DefaultDriftThreshold é a margem de desvio usada quando o peso alvo não indica outra
+const DefaultDriftThreshold = 0.05
+
+// targetWeightTolerance é o erro admitido na soma dos pesos alvo
+const targetWeightTolerance = 1e-6
+
+// rebalanceDust é o valor abaixo do qual uma transação do plano é sempre ignorada
+const rebalanceDust = 0.01
+
+// RebalanceOptions configura o plano de rebalanceamento
+type RebalanceOptions struct {
+	MinTradeSize float64 // valor mínimo de cada transação, na moeda de cotação
+	Cash         float64 // dinheiro novo a investir
+	CashOnly     bool    // investir apenas o dinheiro novo, sem vendas
+}
+
+// GetTargets retorna os pesos alvo do portfólio
+func (s *PortfolioService) GetTargets(portfolioID string) ([]models.AllocationTarget, error) {
+	return s.repo.GetTargets(portfolioID)
+}
+
+// SetTargets substitui os pesos alvo do portfólio. Os pesos são frações do valor total e devem
+// somar 1; uma margem de desvio vazia corresponde a DefaultDriftThreshold. Uma lista vazia
+// remove os pesos alvo.
+func (s *PortfolioService) SetTargets(portfolioID string, targets []models.AllocationTarget) ([]models.AllocationTarget, error) {
+	normalized := make([]models.AllocationTarget, 0, len(targets))
+	seen := map[string]bool{}
+	var sum float64
+	for _, target := range targets {
+		target.Symbol = strings.ToUpper(strings.TrimSpace(target.Symbol))
+		switch {
+		case target.Symbol == "":
+			return nil, fmt.Errorf("%w: símbolo obrigatório", ErrInvalidTargets)
+		case seen[target.Symbol]:
+			return nil, fmt.Errorf("%w: %s repetido", ErrInvalidTargets, target.Symbol)
+		case target.Weight <= 0 || target.Weight > 1:
+			return nil, fmt.Errorf("%w: o peso de %s deve estar entre 0 e 1", ErrInvalidTargets, target.Symbol)
+		case target.DriftThreshold < 0 || target.DriftThreshold >= 1:
+			return nil, fmt.Errorf("%w: a margem de desvio de %s deve estar entre 0 e 1", ErrInvalidTargets, target.Symbol)
+		}
+		if target.DriftThreshold == 0 {
+			target.DriftThreshold = DefaultDriftThreshold
+		}
+
+		seen[target.Symbol] = true
+		sum += target.Weight
+		normalized = append(normalized, target)
+	}
+
+	if len(normalized) > 0 && math.Abs(sum-1) > targetWeightTolerance {
+		return nil, fmt.Errorf("%w: os pesos somam %.4f em vez de 1", ErrInvalidTargets, sum)
+	}
+
+	if err := s.repo.SetTargets(portfolioID, normalized); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetTargets(portfolioID)
+}
+
+// GetRebalancePlan calcula as transações que repõem os pesos alvo do portfólio aos preços de
+// mercado atuais. O plano só tem transações se algum ativo estiver fora da sua margem de desvio
+// ou se houver dinheiro novo. Cada ativo é negociado no máximo uma vez, num só sentido, e as
+// transações abaixo do valor mínimo são descartadas. Posições sem peso alvo são vendidas.
+// No modo CashOnly não há vendas: o dinheiro novo é distribuído pelos ativos abaixo do alvo,
+// em proporção ao que lhes falta.
+func (s *PortfolioService) GetRebalancePlan(portfolioID string, options RebalanceOptions) (*models.RebalancePlan, error) {
+	switch {
+	case options.MinTradeSize < 0:
+		return nil, fmt.Errorf("%w: o valor mínimo das transações não pode ser negativo", ErrInvalidRebalanceOptions)
+	case options.Cash < 0:
+		return nil, fmt.Errorf("%w: o dinheiro novo não pode ser negativo", ErrInvalidRebalanceOptions)
+	case options.CashOnly && options.Cash == 0:
+		return nil, fmt.Errorf("%w: o modo só com dinheiro novo requer um valor a investir", ErrInvalidRebalanceOptions)
+	}
+
+	targets, err := s.repo.GetTargets(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, ErrNoTargets
+	}
+
+	holdings, err := s.GetHoldings(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	targetSymbols := make([]string, 0, len(targets))
+	for _, target := range targets {
+		targetSymbols = append(targetSymbols, target.Symbol)
+	}
+	prices := s.currentPrices(ledgerSymbols(nil, append(holdingSymbols(holdings), targetSymbols...)...))
+
+	var missing []string
+	for _, symbol := range targetSymbols {
+		if _, ok := prices[symbol]; !ok {
+			missing = append(missing, symbol)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("%w: %s", ErrTargetPriceMissing, strings.Join(missing, ", "))
+	}
+
+	plan := &models.RebalancePlan{
+		PortfolioID:  portfolioID,
+		NewCash:      options.Cash,
+		CashOnly:     options.CashOnly,
+		MinTradeSize: options.MinTradeSize,
+		Trades:       []models.RebalanceTrade{},
+		GeneratedAt:  time.Now(),
+	}
+
+	// Posições com preço e pesos alvo, por símbolo
+	assets := map[string]*models.RebalanceAsset{}
+	for _, target := range targets {
+		assets[target.Symbol] = &models.RebalanceAsset{
+			Symbol:         target.Symbol,
+			Price:          prices[target.Symbol],
+			TargetWeight:   target.Weight,
+			DriftThreshold: target.DriftThreshold,
+		}
+	}
+	for _, holding := range holdings {
+		price, ok := prices[holding.Symbol]
+		if !ok {
+			plan.MissingSymbols = append(plan.MissingSymbols, holding.Symbol)
+			continue
+		}
+		asset, ok := assets[holding.Symbol]
+		if !ok {
+			asset = &models.RebalanceAsset{Symbol: holding.Symbol, Price: price, DriftThreshold: DefaultDriftThreshold}
+			assets[holding.Symbol] = asset
+		}
+		asset.Quantity = holding.Quantity
+		asset.Value = holding.Quantity * price
+		plan.TotalValue += asset.Value
+	}
+
+	for _, asset := range assets {
+		if plan.TotalValue > 0 {
+			asset.Weight = asset.Value / plan.TotalValue
+		}
+		asset.Drift = asset.Weight - asset.TargetWeight
+		asset.OutOfBand = plan.TotalValue > 0 && math.Abs(asset.Drift) > asset.DriftThreshold
+		plan.NeedsRebalance = plan.NeedsRebalance || asset.OutOfBand
+	}
+
+	// Diferença de cada ativo face ao valor alvo, depois de investido o dinheiro novo
+	targetTotal := plan.TotalValue + options.Cash
+	minimum := math.Max(options.MinTradeSize, rebalanceDust)
+	sells := map[string]float64{}
+	wanted := map[string]float64{}
+	budget := options.Cash
+	if plan.NeedsRebalance || options.Cash > 0 {
+		for symbol, asset := range assets {
+			diff := asset.TargetWeight*targetTotal - asset.Value
+			switch {
+			case diff > 0:
+				wanted[symbol] = diff
+			case diff < 0 && !options.CashOnly && -diff >= minimum:
+				sells[symbol] = -diff
+				budget += -diff
+			}
+		}
+	}
+	buys := allocateBuys(wanted, budget, minimum)
+
+	plan.UnallocatedCash = budget
+	for symbol, value := range buys {
+		plan.UnallocatedCash -= value
+		plan.Trades = append(plan.Trades, rebalanceTrade(assets[symbol], models.TransactionBuy, value))
+	}
+	for symbol, value := range sells {
+		plan.Trades = append(plan.Trades, rebalanceTrade(assets[symbol], models.TransactionSell, value))
+	}
+	if plan.UnallocatedCash < rebalanceDust {
+		plan.UnallocatedCash = 0
+	}
+
+	// Vendas primeiro, porque financiam as compras; depois por valor
+	sort.Slice(plan.Trades, func(i, j int) bool {
+		if plan.Trades[i].Type != plan.Trades[j].Type {
+			return plan.Trades[i].Type == models.TransactionSell
+		}
+		if plan.Trades[i].Value != plan.Trades[j].Value {
+			return plan.Trades[i].Value > plan.Trades[j].Value
+		}
+		return plan.Trades[i].Symbol < plan.Trades[j].Symbol
+	})
+
+	totalAfter := targetTotal - plan.UnallocatedCash
+	plan.Assets = make([]models.RebalanceAsset, 0, len(assets))
+	for symbol, asset := range assets {
+		if totalAfter > 0 {
+			asset.WeightAfter = (asset.Value + buys[symbol] - sells[symbol]) / totalAfter
+		}
+		plan.Assets = append(plan.Assets, *asset)
+	}
+	sort.Slice(plan.Assets, func(i, j int) bool {
+		if plan.Assets[i].TargetWeight != plan.Assets[j].TargetWeight {
+			return plan.Assets[i].TargetWeight > plan.Assets[j].TargetWeight
+		}
+		if plan.Assets[i].Value != plan.Assets[j].Value {
+			return plan.Assets[i].Value > plan.Assets[j].Value
+		}
+		return plan.Assets[i].Symbol < plan.Assets[j].Symbol
+	})
+
+	return plan, nil
+}
+
+// allocateBuys distribui o orçamento pelas compras pretendidas (valor por símbolo). Se o orçamento
+// não chegar, as compras são reduzidas na mesma proporção; as que ficam abaixo do mínimo são
+// descartadas e o orçamento é redistribuído pelas restantes.
+func allocateBuys(wanted map[string]float64, budget, minimum float64) map[string]float64 {
+	active := map[string]float64{}
+	for symbol, value := range wanted {
+		active[symbol] = value
+	}
+
+	for {
+		var total float64
+		for _, value := range active {
+			total += value
+		}
+		scale := 1.0
+		if total > budget {
+			scale = budget / total
+		}
+
+		dropped := false
+		for symbol, value := range active {
+			if value*scale < minimum {
+				delete(active, symbol)
+				dropped = true
+			}
+		}
+		if dropped {
+			continue
+		}
+
+		buys := make(map[string]float64, len(active))
+		for symbol, value := range active {
+			buys[symbol] = value * scale
+		}
+		return buys
+	}
+}
+
+// rebalanceTrade cria a transação do plano para o valor indicado. As vendas nunca excedem a
+// posição, para que as posições sem peso alvo sejam vendidas na totalidade sem resíduos.
+func rebalanceTrade(asset *models.RebalanceAsset, tradeType models.TransactionType, value float64) models.RebalanceTrade {
+	quantity := value / asset.Price
+	if tradeType == models.TransactionSell && (asset.TargetWeight == 0 || quantity > asset.Quantity) {
+		quantity = asset.Quantity
+	}
+
+	return models.RebalanceTrade{
+		Symbol:   asset.Symbol,
+		Type:     tradeType,
+		Quantity: quantity,
+		Price:    asset.Price,
+		Value:    quantity * asset.Price,
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// ReturnsBenchmark é o ativo com que os retornos do portfólio são comparados
+const ReturnsBenchmark = "BTC"
+
+// cashFlow é um fluxo de capital na perspetiva do investidor: investimentos negativos, resgates positivos
+type cashFlow struct {
+	at     time.Time
+	amount float64
+}
+
+// GetPortfolioReturns calcula os retornos ponderados pelo tempo (TWR) e pelo capital (MWR) do
+// portfólio entre from e to, descontando as entradas e saídas de capital do livro. Um from
+// vazio corresponde à primeira transação e um to vazio ao instante atual. Os retornos são
+// medidos na moeda indicada (vazia para a moeda por omissão).
+func (s *PortfolioService) GetPortfolioReturns(portfolioID string, from, to time.Time, currency string) (*models.PortfolioReturns, error) {
+	if s.history == nil {
+		return nil, ErrHistoryUnavailable
+	}
+
+	portfolio, ledger, currency, err := s.ledgerIn(portfolioID, currency)
+	if err != nil {
+		return nil, err
+	}
+	ledger = sortTransactions(ledger)
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to
+		if len(ledger) > 0 {
+			from = ledger[0].Timestamp
+		}
+	}
+	if from.After(to) {
+		return nil, fmt.Errorf("%w: início depois do fim", ErrInvalidHistoryRange)
+	}
+
+	valuation, err := s.newValuation(portfolio, ledger, currency, from.Add(-minPriceTolerance), to, minPriceTolerance, ReturnsBenchmark)
+	if err != nil {
+		return nil, err
+	}
+
+	return valuation.returns(from, to)
+}
+
+// periodGrowth é o resultado de percorrer os fluxos externos de um período
+type periodGrowth struct {
+	start    models.PortfolioHistoryPoint
+	end      models.PortfolioHistoryPoint
+	growth   float64    // fator de crescimento ponderado pelo tempo (1 = sem variação)
+	netFlows float64    // capital que entrou (positivo) ou saiu (negativo)
+	flows    []cashFlow // fluxos na perspetiva do investidor, incluindo os valores inicial e final
+	complete bool       // falso se faltar algum preço nos instantes valorizados
+}
+
+// period encadeia os retornos dos subperíodos entre from e to delimitados pelos fluxos externos
+func (v *valuation) period(from, to time.Time) (*periodGrowth, error) {
+	start, err := v.at(from, true)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &periodGrowth{
+		start:    start,
+		growth:   1,
+		flows:    []cashFlow{{at: from, amount: -start.Value}},
+		complete: len(start.MissingSymbols) == 0,
+	}
+	previous := start.Value
+
+	i := sort.Search(len(v.ledger), func(i int) bool { return v.ledger[i].Timestamp.After(from) })
+	for i < len(v.ledger) && !v.ledger[i].Timestamp.After(to) {
+		// Transações no mesmo instante formam um único fluxo
+		t := v.ledger[i].Timestamp
+		var flow float64
+		for ; i < len(v.ledger) && v.ledger[i].Timestamp.Equal(t); i++ {
+			flow += v.externalFlow(v.ledger[i])
+		}
+		if flow == 0 {
+			continue
+		}
+
+		before, err := v.at(t, false)
+		if err != nil {
+			return nil, err
+		}
+		after, err := v.at(t, true)
+		if err != nil {
+			return nil, err
+		}
+
+		if previous > 0 {
+			result.growth *= before.Value / previous
+		}
+		previous = after.Value
+
+		result.netFlows += flow
+		result.complete = result.complete && len(before.MissingSymbols) == 0 && len(after.MissingSymbols) == 0
+		result.flows = append(result.flows, cashFlow{at: t, amount: -flow})
+	}
+
+	end, err := v.at(to, true)
+	if err != nil {
+		return nil, err
+	}
+	if previous > 0 {
+		result.growth *= end.Value / previous
+	}
+	result.end = end
+	result.complete = result.complete && len(end.MissingSymbols) == 0
+	result.flows = append(result.flows, cashFlow{at: to, amount: end.Value})
+
+	return result, nil
+}
+
+// returns calcula os retornos entre from e to. O TWR encadeia os retornos dos subperíodos
+// delimitados pelos fluxos externos; o MWR é a taxa interna de rentabilidade desses fluxos.
+// Ambos são do período; as versões anualizadas só são preenchidas a partir de um ano.
+func (v *valuation) returns(from, to time.Time) (*models.PortfolioReturns, error) {
+	period, err := v.period(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	returns := &models.PortfolioReturns{
+		From:               from,
+		To:                 to,
+		StartValue:         period.start.Value,
+		EndValue:           period.end.Value,
+		NetFlows:           period.netFlows,
+		TimeWeightedReturn: (period.growth - 1) * 100,
+		Benchmark:          ReturnsBenchmark,
+		PricesComplete:     period.complete,
+	}
+
+	years := float64(to.Sub(from)) / float64(year)
+	if years >= 1 {
+		annualized := (math.Pow(period.growth, 1/years) - 1) * 100
+		returns.AnnualizedTWR = &annualized
+	}
+
+	if rate, ok := xirr(period.flows); ok {
+		periodReturn := (math.Pow(1+rate, years) - 1) * 100
+		returns.MoneyWeightedReturn = &periodReturn
+		if years >= 1 {
+			annualized := rate * 100
+			returns.AnnualizedMWR = &annualized
+		}
+	}
+
+	if change, ok := v.priceChange(ReturnsBenchmark, from, to); ok {
+		change *= 100
+		returns.BenchmarkReturn = &change
+	}
+
+	return returns, nil
+}
+
+// priceChange retorna a variação relativa do preço de um símbolo entre from e to
+func (v *valuation) priceChange(symbol string, from, to time.Time) (float64, bool) {
+	startPrice, ok := v.prices[symbol].at(from, v.tolerance)
+	if !ok {
+		return 0, false
+	}
+	endPrice, ok := v.prices[symbol].at(to, v.tolerance)
+	if !ok {
+		return 0, false
+	}
+	return endPrice/startPrice - 1, true
+}
+
+// externalFlow retorna o capital que a transação traz para o portfólio (positivo) ou retira
+// (negativo). Recompensas de staking, taxas e transferências não são fluxos externos: o seu
+// efeito no valor conta como retorno. Entradas e saídas sem preço são valorizadas ao preço
+// histórico do ativo nessa data.
+func (v *valuation) externalFlow(tx models.Transaction) float64 {
+	price := tx.Price
+	if price == 0 {
+		price, _ = v.prices[tx.Symbol].at(tx.Timestamp, v.tolerance)
+	}
+
+	var quoteFee float64
+	if tx.Fee > 0 && isQuoteFee(tx) {
+		quoteFee = tx.Fee
+	}
+
+	switch tx.Type {
+	case models.TransactionBuy:
+		return tx.Quantity*price + quoteFee
+	case models.TransactionDeposit:
+		return tx.Quantity * price
+	case models.TransactionSell:
+		return -(tx.Quantity*price - quoteFee)
+	case models.TransactionWithdrawal:
+		return -tx.Quantity * price
+	}
+	return 0
+}
+
+// xirr calcula a taxa interna de rentabilidade anual de fluxos com datas irregulares, por
+// bisseção. Retorna false se os fluxos não mudarem de sinal ou se não houver solução.
+func xirr(flows []cashFlow) (float64, bool) {
+	if len(flows) < 2 {
+		return 0, false
+	}
+
+	start := flows[0].at
+	npv := func(rate float64) float64 {
+		var total float64
+		for _, flow := range flows {
+			years := float64(flow.at.Sub(start)) / float64(year)
+			total += flow.amount / math.Pow(1+rate, years)
+		}
+		return total
+	}
+
+	low, high := -0.9999, 1.0
+	for npv(low)*npv(high) > 0 {
+		if high > 1e6 {
+			return 0, false
+		}
+		high *= 2
+	}
+
+	for i := 0; i < 200 && high-low > 1e-10; i++ {
+		mid := (low + high) / 2
+		if npv(low)*npv(mid) <= 0 {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+
+	rate := (low + high) / 2
+	if math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return 0, false
+	}
+	return rate, true
+}
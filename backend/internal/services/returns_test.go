@@ -0,0 +1,184 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// TestValuationReturns compara o TWR e o MWR de um ano com fluxos a meio do período. Com os fluxos
+// em 0, ½ e 1 ano, x = (1 + MWR)^½ é a raiz positiva de uma equação do segundo grau.
+func TestValuationReturns(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	middle := start.Add(year / 2)
+	end := start.Add(year)
+
+	tests := []struct {
+		name      string
+		ledger    []models.Transaction
+		prices    []float64 // preço do BTC no início, a meio e no fim
+		wantTWR   float64
+		wantMWR   float64
+		wantFlows float64
+	}{
+		{
+			// 100 → 120 antes da compra, 240 → 180 depois: 1,2 × 0,75.
+			// MWR: -100 - 120/x + 180/x² = 0, x = 0,6(√6 - 1)
+			name: "compra a meio",
+			ledger: []models.Transaction{
+				tradeAt("b1", models.TransactionBuy, 1, 100, start),
+				tradeAt("b2", models.TransactionBuy, 1, 120, middle),
+			},
+			prices:    []float64{100, 120, 90},
+			wantTWR:   -10,
+			wantMWR:   (0.36*(7-2*math.Sqrt(6)) - 1) * 100,
+			wantFlows: 120,
+		},
+		{
+			// 200 → 300 antes da venda, 150 → 120 depois: 1,5 × 0,8.
+			// MWR: -200 + 150/x + 120/x² = 0, x = (15 + √1185) / 40
+			name: "venda a meio",
+			ledger: []models.Transaction{
+				tradeAt("b1", models.TransactionBuy, 2, 100, start),
+				tradeAt("s1", models.TransactionSell, 1, 150, middle),
+			},
+			prices:    []float64{100, 150, 120},
+			wantTWR:   20,
+			wantMWR:   (math.Pow((15+math.Sqrt(1185))/40, 2) - 1) * 100,
+			wantFlows: -150,
+		},
+		{
+			name:      "sem fluxos",
+			ledger:    []models.Transaction{tradeAt("b1", models.TransactionBuy, 1, 100, start)},
+			prices:    []float64{100, 50, 130},
+			wantTWR:   30,
+			wantMWR:   30,
+			wantFlows: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := testValuation(t, tt.ledger, map[string]priceSeries{
+				"BTC": priceSeriesAt("BTC", []time.Time{start, middle, end}, tt.prices),
+			})
+
+			returns, err := v.returns(start, end)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !within(returns.TimeWeightedReturn, tt.wantTWR, 1e-9) {
+				t.Errorf("TWR = %v, esperado %v", returns.TimeWeightedReturn, tt.wantTWR)
+			}
+			if returns.AnnualizedTWR == nil || !within(*returns.AnnualizedTWR, tt.wantTWR, 1e-9) {
+				t.Errorf("TWR anualizado = %v, esperado %v", returns.AnnualizedTWR, tt.wantTWR)
+			}
+			if returns.MoneyWeightedReturn == nil || !within(*returns.MoneyWeightedReturn, tt.wantMWR, 1e-6) {
+				t.Errorf("MWR = %v, esperado %v", returns.MoneyWeightedReturn, tt.wantMWR)
+			}
+			if returns.AnnualizedMWR == nil || !within(*returns.AnnualizedMWR, tt.wantMWR, 1e-6) {
+				t.Errorf("MWR anualizado = %v, esperado %v", returns.AnnualizedMWR, tt.wantMWR)
+			}
+			if !within(returns.NetFlows, tt.wantFlows, 1e-9) {
+				t.Errorf("fluxos = %v, esperado %v", returns.NetFlows, tt.wantFlows)
+			}
+
+			wantBenchmark := (tt.prices[2]/tt.prices[0] - 1) * 100
+			if returns.BenchmarkReturn == nil || !within(*returns.BenchmarkReturn, wantBenchmark, 1e-9) {
+				t.Errorf("benchmark = %v, esperado %v", returns.BenchmarkReturn, wantBenchmark)
+			}
+			if !returns.PricesComplete {
+				t.Error("preços incompletos")
+			}
+		})
+	}
+}
+
+// TestValuationReturnsShortPeriod verifica que num período inferior a um ano só os retornos do
+// período são preenchidos, com o MWR de um único investimento igual ao TWR
+func TestValuationReturnsShortPeriod(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+
+	v := testValuation(t, []models.Transaction{tradeAt("b1", models.TransactionBuy, 1, 100, start)}, map[string]priceSeries{
+		"BTC": priceSeriesAt("BTC", []time.Time{start, end}, []float64{100, 105}),
+	})
+
+	returns, err := v.returns(start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !within(returns.TimeWeightedReturn, 5, 1e-9) || returns.MoneyWeightedReturn == nil || !within(*returns.MoneyWeightedReturn, 5, 1e-6) {
+		t.Errorf("TWR = %v, MWR = %v, esperado 5", returns.TimeWeightedReturn, returns.MoneyWeightedReturn)
+	}
+	if returns.AnnualizedTWR != nil || returns.AnnualizedMWR != nil {
+		t.Error("retornos anualizados num período inferior a um ano")
+	}
+}
+
+// TestXIRR verifica a taxa interna de rentabilidade anual
+func TestXIRR(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		flows []cashFlow
+		want  float64
+		ok    bool
+	}{
+		{"um ano", []cashFlow{{start, -100}, {start.Add(year), 110}}, 0.10, true},
+		{"dois anos", []cashFlow{{start, -100}, {start.Add(2 * year), 121}}, 0.10, true},
+		// -100 + 60v + 60v² = 0 com v = 1/(1+r), logo v = (√69 - 3) / 6
+		{"dois recebimentos", []cashFlow{{start, -100}, {start.Add(year), 60}, {start.Add(2 * year), 60}}, 6/(math.Sqrt(69)-3) - 1, true},
+		{"perda", []cashFlow{{start, -100}, {start.Add(year), 80}}, -0.20, true},
+		{"sem mudança de sinal", []cashFlow{{start, -100}, {start.Add(year), -10}}, 0, false},
+		{"um só fluxo", []cashFlow{{start, -100}}, 0, false},
+	}
+
+	for _, tt := range tests {
+		rate, ok := xirr(tt.flows)
+		if ok != tt.ok || (ok && !within(rate, tt.want, 1e-8)) {
+			t.Errorf("%s: %v (%v), esperado %v (%v)", tt.name, rate, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// testValuation prepara a valorização de um livro com os preços indicados
+func testValuation(t *testing.T, ledger []models.Transaction, prices map[string]priceSeries) *valuation {
+	t.Helper()
+	engine, err := NewCostBasisEngine(models.CostBasisFIFO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &valuation{
+		ledger:    sortTransactions(ledger),
+		engine:    engine,
+		prices:    prices,
+		tolerance: minPriceTolerance,
+		applied:   -1,
+	}
+}
+
+// tradeAt constrói uma transação de BTC cotada em USD no instante indicado
+func tradeAt(id string, txType models.TransactionType, quantity, price float64, at time.Time) models.Transaction {
+	tx := trade(id, txType, quantity, price, 1)
+	tx.Timestamp = at
+	return tx
+}
+
+// priceSeriesAt constrói uma série de preços de um símbolo
+func priceSeriesAt(symbol string, times []time.Time, prices []float64) priceSeries {
+	series := make(priceSeries, len(times))
+	for i := range times {
+		series[i] = models.HistoricalData{Symbol: symbol, Price: prices[i], Timestamp: times[i]}
+	}
+	return series
+}
+
+// within compara dois valores com a tolerância indicada
+func within(got, want, tolerance float64) bool {
+	return math.Abs(got-want) < tolerance
+}
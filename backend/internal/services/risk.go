@@ -0,0 +1,241 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/stats"
+)
+
+// Erros das métricas de risco
+var (
+	ErrInsufficientHistory = errors.New("histórico insuficiente para calcular as métricas de risco")
+	ErrInvalidRiskOptions  = errors.New("opções de risco inválidas")
+)
+
+// Valores por omissão das métricas de risco
+const (
+	DefaultRiskRange      = "1y"
+	DefaultRiskInterval   = "1d"
+	DefaultRiskConfidence = 0.95
+)
+
+// minRiskObservations é o número mínimo de retornos para calcular as métricas de risco
+const minRiskObservations = 2
+
+// RiskOptions configura o cálculo das métricas de risco. Campos vazios usam os valores por omissão.
+type RiskOptions struct {
+	Range        string  // período analisado, ex.: "1y" ou HistoryRangeAll
+	Interval     string  // passo dos retornos, ex.: "1d"
+	Benchmark    string  // símbolo de referência para o beta (por omissão ReturnsBenchmark)
+	RiskFreeRate float64 // taxa sem risco anual, em percentagem
+	Confidence   float64 // nível de confiança do VaR, entre 0 e 1 (por omissão DefaultRiskConfidence)
+}
+
+// GetPortfolioRisk calcula as métricas de risco do portfólio sobre os retornos por intervalo da
+// série de valor reconstruída. Os retornos de cada intervalo descontam as entradas e saídas de
+// capital (ver GetPortfolioReturns), e só contam intervalos em que o portfólio tinha valor.
+func (s *PortfolioService) GetPortfolioRisk(portfolioID string, options RiskOptions) (*models.PortfolioRisk, error) {
+	if s.history == nil {
+		return nil, ErrHistoryUnavailable
+	}
+
+	benchmark := strings.ToUpper(strings.TrimSpace(options.Benchmark))
+	if benchmark == "" {
+		benchmark = ReturnsBenchmark
+	}
+	confidence := options.Confidence
+	if confidence == 0 {
+		confidence = DefaultRiskConfidence
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return nil, fmt.Errorf("%w: o nível de confiança deve estar entre 0 e 1", ErrInvalidRiskOptions)
+	}
+
+	portfolio, ledger, currency, err := s.ledgerIn(portfolioID, "")
+	if err != nil {
+		return nil, err
+	}
+	ledger = sortTransactions(ledger)
+
+	window, err := newSeriesWindow(options.Range, options.Interval, DefaultRiskRange, DefaultRiskInterval, ledger, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	valuation, err := s.newValuation(portfolio, ledger, currency, window.from().Add(-window.tolerance), window.to(), window.tolerance, benchmark)
+	if err != nil {
+		return nil, err
+	}
+
+	risk := &models.PortfolioRisk{
+		PortfolioID:    portfolioID,
+		Range:          window.rangeParam,
+		Interval:       window.interval,
+		From:           window.from(),
+		To:             window.to(),
+		RiskFreeRate:   options.RiskFreeRate,
+		Benchmark:      benchmark,
+		PricesComplete: true,
+	}
+
+	// Retornos do portfólio e do benchmark por intervalo, com o instante em que termina cada um
+	var returns, portfolioPaired, benchmarkPaired []float64
+	var ends []time.Time
+	for i := 1; i < len(window.timestamps); i++ {
+		start, end := window.timestamps[i-1], window.timestamps[i]
+		period, err := valuation.period(start, end)
+		if err != nil {
+			return nil, err
+		}
+		if period.start.Value <= 0 {
+			continue
+		}
+
+		r := period.growth - 1
+		returns = append(returns, r)
+		ends = append(ends, end)
+		risk.PricesComplete = risk.PricesComplete && period.complete
+
+		if change, ok := valuation.priceChange(benchmark, start, end); ok {
+			portfolioPaired = append(portfolioPaired, r)
+			benchmarkPaired = append(benchmarkPaired, change)
+		}
+	}
+
+	if len(returns) < minRiskObservations {
+		return nil, ErrInsufficientHistory
+	}
+
+	annualizeReturns(risk, returns, float64(year)/float64(window.step), options.RiskFreeRate)
+
+	risk.MaxDrawdown = maxDrawdown(window.from(), ends, returns)
+
+	if len(benchmarkPaired) >= minRiskObservations {
+		if beta, ok := regressionBeta(portfolioPaired, benchmarkPaired); ok {
+			risk.Beta = &beta
+		}
+		if coefficient, ok := correlation(portfolioPaired, benchmarkPaired); ok {
+			risk.Correlation = &coefficient
+		}
+	}
+
+	risk.ValueAtRisk = valueAtRisk(returns, confidence, window.interval)
+
+	return risk, nil
+}
+
+// annualizeReturns preenche o retorno e a volatilidade anualizados e os rácios de Sharpe e
+// Sortino, sobre o excesso de retorno face à taxa sem risco (anual, em percentagem)
+func annualizeReturns(risk *models.PortfolioRisk, returns []float64, periodsPerYear, riskFreeRate float64) {
+	mean, stdDev := stats.MeanStdDev(returns)
+	risk.Observations = len(returns)
+	risk.AnnualizedReturn = mean * periodsPerYear * 100
+	risk.AnnualizedVolatility = stdDev * math.Sqrt(periodsPerYear) * 100
+
+	excess := risk.AnnualizedReturn - riskFreeRate
+	if risk.AnnualizedVolatility > 0 {
+		sharpe := excess / risk.AnnualizedVolatility
+		risk.SharpeRatio = &sharpe
+	}
+	if downside := downsideDeviation(returns, riskFreeRate/100/periodsPerYear) * math.Sqrt(periodsPerYear) * 100; downside > 0 {
+		sortino := excess / downside
+		risk.SortinoRatio = &sortino
+	}
+}
+
+// downsideDeviation retorna o desvio dos retornos abaixo do alvo (semidesvio), por período
+func downsideDeviation(returns []float64, target float64) float64 {
+	var squares float64
+	for _, r := range returns {
+		if r < target {
+			squares += (r - target) * (r - target)
+		}
+	}
+	return math.Sqrt(squares / float64(len(returns)))
+}
+
+// maxDrawdown encontra a maior queda do índice de retornos acumulados face ao máximo anterior.
+// O índice começa em 1 no instante start; ends[i] é o instante em que termina returns[i].
+func maxDrawdown(start time.Time, ends []time.Time, returns []float64) models.Drawdown {
+	times := append([]time.Time{start}, ends...)
+	index := make([]float64, len(times))
+	index[0] = 1
+	for i, r := range returns {
+		index[i+1] = index[i] * (1 + r)
+	}
+	return stats.MaxDrawdown(times, index)
+}
+
+// regressionBeta retorna a sensibilidade dos retornos do portfólio aos do benchmark
+func regressionBeta(portfolio, benchmark []float64) (float64, bool) {
+	portfolioMean, _ := stats.MeanStdDev(portfolio)
+	benchmarkMean, _ := stats.MeanStdDev(benchmark)
+
+	var covariance, variance float64
+	for i := range portfolio {
+		covariance += (portfolio[i] - portfolioMean) * (benchmark[i] - benchmarkMean)
+		variance += (benchmark[i] - benchmarkMean) * (benchmark[i] - benchmarkMean)
+	}
+	if variance == 0 {
+		return 0, false
+	}
+	return covariance / variance, true
+}
+
+// correlation retorna o coeficiente de correlação de Pearson entre duas séries
+func correlation(a, b []float64) (float64, bool) {
+	_, stdDevA := stats.MeanStdDev(a)
+	_, stdDevB := stats.MeanStdDev(b)
+	if stdDevA == 0 || stdDevB == 0 {
+		return 0, false
+	}
+
+	beta, ok := regressionBeta(a, b)
+	if !ok {
+		return 0, false
+	}
+	return beta * stdDevB / stdDevA, true
+}
+
+// valueAtRisk calcula o VaR e o CVaR de um intervalo, histórico e paramétrico (normal),
+// como perdas percentuais positivas
+func valueAtRisk(returns []float64, confidence float64, horizon string) models.ValueAtRisk {
+	sorted := make([]float64, len(returns))
+	copy(sorted, returns)
+	sort.Float64s(sorted)
+
+	// Quantil histórico: o pior retorno que não é excedido em (1 - confiança) dos casos. A folga
+	// evita que 1 - 0,9 = 0,0999… deixe de fora um dos retornos da cauda
+	tail := int(math.Floor((1-confidence)*float64(len(sorted)) + 1e-9))
+	if tail < 1 {
+		tail = 1
+	}
+	var tailSum float64
+	for _, r := range sorted[:tail] {
+		tailSum += r
+	}
+
+	mean, stdDev := stats.MeanStdDev(returns)
+	z := normalQuantile(1 - confidence) // negativo
+	density := math.Exp(-z*z/2) / math.Sqrt(2*math.Pi)
+
+	return models.ValueAtRisk{
+		Confidence:     confidence,
+		Horizon:        horizon,
+		HistoricalVaR:  -sorted[tail-1] * 100,
+		HistoricalCVaR: -tailSum / float64(tail) * 100,
+		ParametricVaR:  -(mean + z*stdDev) * 100,
+		ParametricCVaR: -(mean - stdDev*density/(1-confidence)) * 100,
+	}
+}
+
+// normalQuantile retorna o quantil p (0-1) da distribuição normal padrão
+func normalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
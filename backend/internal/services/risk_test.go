@@ -0,0 +1,145 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// TestAnnualizeReturns verifica o retorno e a volatilidade anualizados e os rácios de Sharpe e
+// Sortino de quatro retornos trimestrais, com média 0,5% e desvio-padrão amostral √(0,0017/3)
+func TestAnnualizeReturns(t *testing.T) {
+	returns := []float64{0.02, -0.01, 0.03, -0.02}
+	volatility := math.Sqrt(0.0017/3) * 2 * 100
+
+	tests := []struct {
+		name         string
+		riskFreeRate float64
+		wantSharpe   float64
+		wantSortino  float64
+	}{
+		// Semidesvio abaixo de 0: √((0,01² + 0,02²) / 4) × 2 × 100 = √5
+		{"sem taxa sem risco", 0, 2 / volatility, 2 / math.Sqrt(5)},
+		// Alvo de 0,25% por trimestre: √((0,0125² + 0,0225²) / 4) × 2 × 100
+		{"taxa sem risco de 1%", 1, 1 / volatility, 1 / (math.Sqrt(0.0006625/4) * 200)},
+	}
+
+	for _, tt := range tests {
+		var risk models.PortfolioRisk
+		annualizeReturns(&risk, returns, 4, tt.riskFreeRate)
+
+		if risk.Observations != 4 || !within(risk.AnnualizedReturn, 2, 1e-9) || !within(risk.AnnualizedVolatility, volatility, 1e-9) {
+			t.Errorf("%s: %d retornos, retorno %v, volatilidade %v; esperado 4, 2, %v",
+				tt.name, risk.Observations, risk.AnnualizedReturn, risk.AnnualizedVolatility, volatility)
+		}
+		if risk.SharpeRatio == nil || !within(*risk.SharpeRatio, tt.wantSharpe, 1e-9) {
+			t.Errorf("%s: Sharpe = %v, esperado %v", tt.name, risk.SharpeRatio, tt.wantSharpe)
+		}
+		if risk.SortinoRatio == nil || !within(*risk.SortinoRatio, tt.wantSortino, 1e-9) {
+			t.Errorf("%s: Sortino = %v, esperado %v", tt.name, risk.SortinoRatio, tt.wantSortino)
+		}
+	}
+
+	// Sem volatilidade nem perdas, os rácios ficam por definir
+	var risk models.PortfolioRisk
+	annualizeReturns(&risk, []float64{0.01, 0.01, 0.01}, 12, 0)
+	if risk.SharpeRatio != nil || risk.SortinoRatio != nil {
+		t.Errorf("Sharpe = %v, Sortino = %v, esperado nil", risk.SharpeRatio, risk.SortinoRatio)
+	}
+}
+
+// TestMaxDrawdownDates verifica a maior queda do índice de retornos 1 → 1,1 → 0,88 → 0,792 →
+// 0,99 → 1,287 e as datas do máximo, do mínimo e da recuperação
+func TestMaxDrawdownDates(t *testing.T) {
+	ends := []time.Time{day(2), day(3), day(4), day(5), day(6)}
+
+	drawdown := maxDrawdown(day(1), ends, []float64{0.10, -0.20, -0.10, 0.25, 0.30})
+	if !within(drawdown.Depth, (0.792/1.1-1)*100, 1e-9) {
+		t.Errorf("queda = %v, esperado %v", drawdown.Depth, (0.792/1.1-1)*100)
+	}
+	if !drawdown.PeakAt.Equal(day(2)) || !drawdown.TroughAt.Equal(day(4)) {
+		t.Errorf("queda entre %v e %v, esperado dias 2 e 4", drawdown.PeakAt, drawdown.TroughAt)
+	}
+	if drawdown.RecoveredAt == nil || !drawdown.RecoveredAt.Equal(day(6)) {
+		t.Errorf("recuperação = %v, esperado dia 6", drawdown.RecoveredAt)
+	}
+
+	// Uma queda desde o início, sem recuperação
+	drawdown = maxDrawdown(day(1), ends[:2], []float64{-0.5, 0.5})
+	if !within(drawdown.Depth, -50, 1e-9) || !drawdown.PeakAt.Equal(day(1)) || !drawdown.TroughAt.Equal(day(2)) || drawdown.RecoveredAt != nil {
+		t.Errorf("queda = %+v, esperado -50 entre os dias 1 e 2, sem recuperação", drawdown)
+	}
+}
+
+// TestRegressionBetaAndCorrelation verifica o beta e a correlação face ao benchmark
+func TestRegressionBetaAndCorrelation(t *testing.T) {
+	tests := []struct {
+		name            string
+		portfolio       []float64
+		benchmark       []float64
+		wantBeta        float64
+		wantCorrelation float64
+	}{
+		{"o dobro do benchmark", []float64{0.03, 0.05, -0.01}, []float64{0.01, 0.02, -0.01}, 2, 1},
+		{"metade, em sentido contrário", []float64{-0.005, -0.01, 0.005}, []float64{0.01, 0.02, -0.01}, -0.5, -1},
+		// Covariância 3 e variâncias 5 (em pontos percentuais, a menos de n - 1)
+		{"parcialmente correlacionado", []float64{0.02, 0.01, 0.04, 0.03}, []float64{0.01, 0.02, 0.03, 0.04}, 0.6, 0.6},
+	}
+
+	for _, tt := range tests {
+		beta, ok := regressionBeta(tt.portfolio, tt.benchmark)
+		if !ok || !within(beta, tt.wantBeta, 1e-9) {
+			t.Errorf("%s: beta = %v (%v), esperado %v", tt.name, beta, ok, tt.wantBeta)
+		}
+		coefficient, ok := correlation(tt.portfolio, tt.benchmark)
+		if !ok || !within(coefficient, tt.wantCorrelation, 1e-9) {
+			t.Errorf("%s: correlação = %v (%v), esperado %v", tt.name, coefficient, ok, tt.wantCorrelation)
+		}
+	}
+
+	if _, ok := regressionBeta([]float64{0.01, 0.02}, []float64{0.01, 0.01}); ok {
+		t.Error("beta com benchmark constante: esperado indefinido")
+	}
+}
+
+// TestValueAtRisk verifica o VaR e o CVaR históricos e paramétricos de 20 retornos com média 0:
+// as cinco perdas de 1% a 5% e 15 ganhos de 1%, com desvio-padrão amostral √(0,007/19)
+func TestValueAtRisk(t *testing.T) {
+	returns := []float64{-0.03, 0.01, -0.05, 0.01, 0.01, -0.01, 0.01, 0.01, -0.04, 0.01,
+		0.01, 0.01, -0.02, 0.01, 0.01, 0.01, 0.01, 0.01, 0.01, 0.01}
+	stdDev := math.Sqrt(0.007 / 19)
+
+	tests := []struct {
+		confidence         float64
+		wantHistoricalVaR  float64
+		wantHistoricalCVaR float64
+		z, density         float64 // quantil da normal padrão em 1 - confiança e a sua densidade
+	}{
+		// Os 2 piores de 20 retornos: -4% e a média de -5% e -4%
+		{0.90, 4, 4.5, -1.2815515655446004, 0.17549833193248685},
+		{0.95, 5, 5, -1.6448536269514722, 0.10313564037537128},
+		// Menos de um retorno na cauda: conta o pior
+		{0.99, 5, 5, -2.3263478740408408, 0.02665214220345808},
+	}
+
+	for _, tt := range tests {
+		v := valueAtRisk(returns, tt.confidence, "1d")
+
+		if v.Confidence != tt.confidence || v.Horizon != "1d" {
+			t.Errorf("%v: confiança %v e horizonte %q", tt.confidence, v.Confidence, v.Horizon)
+		}
+		if !within(v.HistoricalVaR, tt.wantHistoricalVaR, 1e-9) || !within(v.HistoricalCVaR, tt.wantHistoricalCVaR, 1e-9) {
+			t.Errorf("%v: VaR e CVaR históricos %v e %v, esperado %v e %v",
+				tt.confidence, v.HistoricalVaR, v.HistoricalCVaR, tt.wantHistoricalVaR, tt.wantHistoricalCVaR)
+		}
+
+		wantVaR := -tt.z * stdDev * 100
+		wantCVaR := stdDev * tt.density / (1 - tt.confidence) * 100
+		if !within(v.ParametricVaR, wantVaR, 1e-9) || !within(v.ParametricCVaR, wantCVaR, 1e-9) {
+			t.Errorf("%v: VaR e CVaR paramétricos %v e %v, esperado %v e %v",
+				tt.confidence, v.ParametricVaR, v.ParametricCVaR, wantVaR, wantCVaR)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/indicators"
+)
+
+// maxPeriod limita os períodos dos indicadores, que nunca excedem as velas disponíveis
+const maxPeriod = 1000
+
+// function é um indicador disponível nas regras
+type function struct {
+	defaults []float64                                                   // parâmetros por omissão, que definem quantos são aceites
+	periods  int                                                         // os primeiros parâmetros que são períodos (inteiros)
+	outputs  []string                                                    // saídas, a primeira é a por omissão
+	validate func(args []float64) error                                  // verificações próprias do indicador (opcional)
+	lookback func(args []float64) int                                    // velas de aquecimento
+	compute  func(c models.Candles, args []float64) ([][]float64, error) // uma série por saída
+}
+
+// functions são os indicadores das regras, pelo nome em minúsculas
+var functions = map[string]function{
+	"sma": movingAverage(indicators.SMA),
+	"ema": movingAverage(indicators.EMA),
+	"wma": movingAverage(indicators.WMA),
+	"rsi": {
+		defaults: []float64{14},
+		periods:  1,
+		outputs:  []string{"value"},
+		lookback: func(args []float64) int { return int(args[0]) + 1 },
+		compute: func(c models.Candles, args []float64) ([][]float64, error) {
+			return single(indicators.RSI(c.Closes(), int(args[0])))
+		},
+	},
+	"macd": {
+		defaults: []float64{12, 26, 9},
+		periods:  3,
+		outputs:  []string{"macd", "signal", "histogram"},
+		validate: fastBelowSlow,
+		lookback: func(args []float64) int { return int(args[1] + args[2]) },
+		compute: func(c models.Candles, args []float64) ([][]float64, error) {
+			macd, err := indicators.MACD(c.Closes(), int(args[0]), int(args[1]), int(args[2]))
+			if err != nil {
+				return nil, err
+			}
+			return [][]float64{macd.MACD, macd.Signal, macd.Histogram}, nil
+		},
+	},
+	"bb": {
+		defaults: []float64{20, 2},
+		periods:  1,
+		outputs:  []string{"middle", "upper", "lower"},
+		lookback: func(args []float64) int { return int(args[0]) },
+		compute: func(c models.Candles, args []float64) ([][]float64, error) {
+			bands, err := indicators.BollingerBands(c.Closes(), int(args[0]), args[1])
+			if err != nil {
+				return nil, err
+			}
+			return [][]float64{bands.Middle, bands.Upper, bands.Lower}, nil
+		},
+	},
+	"stoch": {
+		defaults: []float64{14, 3, 3},
+		periods:  3,
+		outputs:  []string{"k", "d"},
+		lookback: func(args []float64) int { return int(args[0] + args[1] + args[2]) },
+		compute: func(c models.Candles, args []float64) ([][]float64, error) {
+			stochastic, err := indicators.Stochastic(c.Highs(), c.Lows(), c.Closes(), int(args[0]), int(args[1]), int(args[2]))
+			if err != nil {
+				return nil, err
+			}
+			return [][]float64{stochastic.K, stochastic.D}, nil
+		},
+	},
+	"adx": {
+		defaults: []float64{14},
+		periods:  1,
+		outputs:  []string{"adx", "plusdi", "minusdi"},
+		lookback: func(args []float64) int { return 2 * int(args[0]) },
+		compute: func(c models.Candles, args []float64) ([][]float64, error) {
+			adx, err := indicators.ADX(c.Highs(), c.Lows(), c.Closes(), int(args[0]))
+			if err != nil {
+				return nil, err
+			}
+			return [][]float64{adx.ADX, adx.PlusDI, adx.MinusDI}, nil
+		},
+	},
+	"atr": {
+		defaults: []float64{14},
+		periods:  1,
+		outputs:  []string{"value"},
+		lookback: func(args []float64) int { return int(args[0]) + 1 },
+		compute: func(c models.Candles, args []float64) ([][]float64, error) {
+			return single(indicators.ATR(c.Highs(), c.Lows(), c.Closes(), int(args[0])))
+		},
+	},
+	"obv": {
+		outputs:  []string{"value"},
+		lookback: func([]float64) int { return 1 },
+		compute: func(c models.Candles, _ []float64) ([][]float64, error) {
+			return single(indicators.OBV(c.Closes(), c.Volumes()))
+		},
+	},
+	"vwap": {
+		outputs:  []string{"value"},
+		lookback: func([]float64) int { return 1 },
+		compute: func(c models.Candles, _ []float64) ([][]float64, error) {
+			return single(indicators.VWAP(c.Highs(), c.Lows(), c.Closes(), c.Volumes()))
+		},
+	},
+}
+
+// movingAverage é uma média móvel dos fechos, por omissão de 20 velas
+func movingAverage(average func([]float64, int) ([]float64, error)) function {
+	return function{
+		defaults: []float64{20},
+		periods:  1,
+		outputs:  []string{"value"},
+		lookback: func(args []float64) int { return int(args[0]) },
+		compute: func(c models.Candles, args []float64) ([][]float64, error) {
+			return single(average(c.Closes(), int(args[0])))
+		},
+	}
+}
+
+// fastBelowSlow verifica que o período rápido é menor que o lento
+func fastBelowSlow(args []float64) error {
+	if args[0] >= args[1] {
+		return fmt.Errorf("o período rápido (%g) deve ser menor que o lento (%g)", args[0], args[1])
+	}
+	return nil
+}
+
+// single adapta um indicador com uma única saída
+func single(series []float64, err error) ([][]float64, error) {
+	if err != nil {
+		return nil, err
+	}
+	return [][]float64{series}, nil
+}
+
+// call é a utilização de um indicador numa regra, com os parâmetros completos e a saída
+type call struct {
+	name   string
+	fn     function
+	args   []float64
+	output int
+}
+
+// newCall valida os parâmetros e a saída de um indicador, completando os parâmetros em falta
+// com os valores por omissão
+func newCall(name string, fn function, args []float64, output string) (*call, error) {
+	upper := strings.ToUpper(name)
+	if len(args) > len(fn.defaults) {
+		return nil, fmt.Errorf("%s aceita até %d parâmetros", upper, len(fn.defaults))
+	}
+	args = append(args, fn.defaults[len(args):]...)
+
+	for i, arg := range args {
+		if arg <= 0 {
+			return nil, fmt.Errorf("os parâmetros de %s devem ser positivos", upper)
+		}
+		if i < fn.periods && (arg != math.Trunc(arg) || arg > maxPeriod) {
+			return nil, fmt.Errorf("os períodos de %s devem ser inteiros até %d", upper, maxPeriod)
+		}
+	}
+	if fn.validate != nil {
+		if err := fn.validate(args); err != nil {
+			return nil, fmt.Errorf("%s: %v", upper, err)
+		}
+	}
+
+	for i, name := range fn.outputs {
+		if name == output {
+			return &call{name: upper, fn: fn, args: args, output: i}, nil
+		}
+	}
+	return nil, fmt.Errorf("saída desconhecida %q de %s (disponíveis: %s)", output, upper, strings.Join(fn.outputs, ", "))
+}
+
+// key identifica o indicador e os parâmetros, sem a saída
+func (c *call) key() string {
+	return fmt.Sprintf("%s%v", c.name, c.args)
+}
+
+func (c *call) series(e *evaluation) ([]float64, error) {
+	key := fmt.Sprintf("%s.%d", c.key(), c.output)
+	if cached, ok := e.cache[key]; ok {
+		return cached, nil
+	}
+
+	outputs, err := c.fn.compute(e.candles, c.args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.key(), err)
+	}
+	for i, series := range outputs {
+		e.cache[fmt.Sprintf("%s.%d", c.key(), i)] = series
+	}
+	return outputs[c.output], nil
+}
+
+func (c *call) lookback() int {
+	return c.fn.lookback(c.args)
+}
+
+// defined indica se todos os valores estão definidos
+func defined(values ...float64) bool {
+	for _, v := range values {
+		if !indicators.Valid(v) {
+			return false
+		}
+	}
+	return true
+}
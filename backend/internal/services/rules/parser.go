@@ -0,0 +1,278 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Tipos de token
+const (
+	tokenEOF = iota
+	tokenIdent
+	tokenNumber
+	tokenOperator // <, <=, >, >=
+	tokenLParen
+	tokenRParen
+	tokenComma
+	tokenDot
+)
+
+// token é um elemento da regra, com a posição (a partir de 1) onde começa
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// tokenize divide a regra em tokens. Os números podem ter sinal negativo, já que a linguagem
+// não tem subtração.
+func tokenize(source string) ([]token, error) {
+	runes := []rune(source)
+	tokens := []token{}
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, string(runes[start:i]), start + 1})
+			continue
+		case unicode.IsDigit(r) || r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[start:i]), start + 1})
+			continue
+		case r == '<' || r == '>':
+			i++
+			if i < len(runes) && runes[i] == '=' {
+				i++
+			}
+			tokens = append(tokens, token{tokenOperator, string(runes[start:i]), start + 1})
+			continue
+		}
+
+		kinds := map[rune]int{'(': tokenLParen, ')': tokenRParen, ',': tokenComma, '.': tokenDot}
+		kind, ok := kinds[r]
+		if !ok {
+			return nil, invalid(start+1, "carácter inesperado %q", r)
+		}
+		tokens = append(tokens, token{kind, string(r), start + 1})
+		i++
+	}
+	return append(tokens, token{tokenEOF, "", len(runes) + 1}), nil
+}
+
+// parser constrói a árvore de uma regra a partir dos tokens:
+//
+//	regra      = termo { OR termo }
+//	termo      = fator { AND fator }
+//	fator      = NOT fator | "(" regra ")" | comparação
+//	comparação = operando ( "<" | "<=" | ">" | ">=" | CROSSES ABOVE | CROSSES BELOW ) operando
+//	operando   = número | campo | indicador [ "(" [ número { "," número } ] ")" ] [ "." saída ]
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword indica se o próximo token é a palavra reservada indicada
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokenIdent && strings.EqualFold(t.text, word)
+}
+
+func (p *parser) expect(kind int, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, unexpected(t, what)
+	}
+	return t, nil
+}
+
+func (p *parser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (condition, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseFactor() (condition, error) {
+	switch {
+	case p.keyword("NOT"):
+		p.next()
+		inner, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &negation{inner: inner}, nil
+	case p.peek().kind == tokenLParen:
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen, `")"`); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (condition, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	var op string
+	switch t := p.next(); {
+	case t.kind == tokenOperator:
+		op = t.text
+	case t.kind == tokenIdent && strings.EqualFold(t.text, "CROSSES"):
+		direction := p.next()
+		switch {
+		case direction.kind == tokenIdent && strings.EqualFold(direction.text, "ABOVE"):
+			op = crossesAbove
+		case direction.kind == tokenIdent && strings.EqualFold(direction.text, "BELOW"):
+			op = crossesBelow
+		default:
+			return nil, unexpected(direction, "ABOVE ou BELOW")
+		}
+	default:
+		return nil, unexpected(t, "um operador de comparação")
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &comparison{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, invalid(t.pos, "número inválido %q", t.text)
+		}
+		return constant(value), nil
+	case tokenIdent:
+	default:
+		return nil, unexpected(t, "um número, um campo ou um indicador")
+	}
+
+	name := strings.ToLower(t.text)
+	if reserved[name] {
+		return nil, unexpected(t, "um número, um campo ou um indicador")
+	}
+	if fields[name] {
+		return field(name), nil
+	}
+
+	fn, ok := functions[name]
+	if !ok {
+		return nil, invalid(t.pos, "indicador desconhecido %q", t.text)
+	}
+
+	var args []float64
+	if p.peek().kind == tokenLParen {
+		p.next()
+		for p.peek().kind != tokenRParen {
+			if len(args) > 0 {
+				if _, err := p.expect(tokenComma, `"," ou ")"`); err != nil {
+					return nil, err
+				}
+			}
+			number, err := p.expect(tokenNumber, "um número")
+			if err != nil {
+				return nil, err
+			}
+			value, err := strconv.ParseFloat(number.text, 64)
+			if err != nil {
+				return nil, invalid(number.pos, "número inválido %q", number.text)
+			}
+			args = append(args, value)
+		}
+		p.next()
+	}
+
+	output := fn.outputs[0]
+	if p.peek().kind == tokenDot {
+		p.next()
+		selector, err := p.expect(tokenIdent, "o nome de uma saída")
+		if err != nil {
+			return nil, err
+		}
+		output = strings.ToLower(selector.text)
+	}
+
+	c, err := newCall(name, fn, args, output)
+	if err != nil {
+		return nil, invalid(t.pos, "%v", err)
+	}
+	return c, nil
+}
+
+// reserved são as palavras reservadas, que não podem ser operandos
+var reserved = map[string]bool{"and": true, "or": true, "not": true, "crosses": true, "above": true, "below": true}
+
+// unexpected é o erro de um token inesperado
+func unexpected(t token, expected string) error {
+	if t.kind == tokenEOF {
+		return invalid(t.pos, "fim inesperado, esperava %s", expected)
+	}
+	return invalid(t.pos, "esperava %s em vez de %q", expected, t.text)
+}
+
+// invalid é um erro de sintaxe na posição indicada
+func invalid(pos int, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s (posição %d)", ErrInvalidRule, fmt.Sprintf(format, args...), pos)
+}
@@ -0,0 +1,239 @@
+// Package rules implementa a linguagem das condições das estratégias declarativas: comparações
+// entre campos das velas, indicadores técnicos e números, combinadas com AND, OR, NOT e
+// parênteses.
+//
+// Exemplo: "RSI(14) < 30 AND close CROSSES ABOVE EMA(50)". As comparações são <, <=, >, >=,
+// CROSSES ABOVE e CROSSES BELOW. Os campos são open, high, low, close e volume, e os indicadores
+// (com os parâmetros por omissão e as saídas, a primeira por omissão) são SMA, EMA e WMA (20),
+// RSI (14), MACD (12, 26, 9; macd, signal, histogram), BB (20, 2; middle, upper, lower),
+// STOCH (14, 3, 3; k, d), ADX (14; adx, plusdi, minusdi), ATR (14), OBV e VWAP. A saída é
+// escolhida com um ponto, como em MACD(12, 26, 9).signal. Palavras reservadas, campos e
+// indicadores não distinguem maiúsculas de minúsculas.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gofolio/backend/internal/models"
+)
+
+// ErrInvalidRule é retornado quando uma regra não é válida
+var ErrInvalidRule = errors.New("regra inválida")
+
+// Operadores de cruzamento: o operando da esquerda passa de abaixo (ou igual) para acima do da
+// direita, ou o inverso, entre a vela anterior e a atual
+const (
+	crossesAbove = "CROSSES ABOVE"
+	crossesBelow = "CROSSES BELOW"
+)
+
+// Rule é uma regra validada, que pode ser avaliada sobre qualquer série de velas
+type Rule struct {
+	source string
+	root   condition
+}
+
+// Parse valida uma regra e constrói a sua árvore
+func Parse(source string) (*Rule, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("%w: regra vazia", ErrInvalidRule)
+	}
+
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, unexpected(t, "AND, OR ou o fim da regra")
+	}
+
+	return &Rule{source: strings.TrimSpace(source), root: root}, nil
+}
+
+// String retorna o texto da regra
+func (r *Rule) String() string {
+	return r.source
+}
+
+// Lookback é o número de velas necessárias antes de a regra poder ser verdadeira, isto é, o
+// maior período de aquecimento dos seus indicadores (mais uma vela nos cruzamentos)
+func (r *Rule) Lookback() int {
+	return r.root.lookback()
+}
+
+// Evaluate avalia a regra em cada vela, usando apenas essa vela e as anteriores. Uma comparação
+// é falsa enquanto algum dos operandos não estiver definido.
+func (r *Rule) Evaluate(candles models.Candles) ([]bool, error) {
+	e := &evaluation{candles: candles, cache: map[string][]float64{}}
+	return r.root.eval(e)
+}
+
+// evaluation guarda as séries já calculadas numa avaliação, para que um indicador usado em
+// várias condições só seja calculado uma vez
+type evaluation struct {
+	candles models.Candles
+	cache   map[string][]float64
+}
+
+// condition é um nó booleano da árvore
+type condition interface {
+	eval(e *evaluation) ([]bool, error)
+	lookback() int
+}
+
+// operand é um operando numérico de uma comparação
+type operand interface {
+	series(e *evaluation) ([]float64, error)
+	lookback() int
+}
+
+// logical é uma conjunção (and) ou disjunção de duas condições
+type logical struct {
+	and         bool
+	left, right condition
+}
+
+func (l *logical) eval(e *evaluation) ([]bool, error) {
+	left, err := l.left.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	right, err := l.right.eval(e)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]bool, len(left))
+	for i := range result {
+		if l.and {
+			result[i] = left[i] && right[i]
+		} else {
+			result[i] = left[i] || right[i]
+		}
+	}
+	return result, nil
+}
+
+func (l *logical) lookback() int {
+	return longest(l.left.lookback(), l.right.lookback())
+}
+
+// negation é a negação de uma condição. É verdadeira enquanto os indicadores da condição não
+// estão definidos.
+type negation struct {
+	inner condition
+}
+
+func (n *negation) eval(e *evaluation) ([]bool, error) {
+	inner, err := n.inner.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]bool, len(inner))
+	for i, value := range inner {
+		result[i] = !value
+	}
+	return result, nil
+}
+
+func (n *negation) lookback() int {
+	return n.inner.lookback()
+}
+
+// comparison compara dois operandos em cada vela
+type comparison struct {
+	op          string
+	left, right operand
+}
+
+func (c *comparison) eval(e *evaluation) ([]bool, error) {
+	left, err := c.left.series(e)
+	if err != nil {
+		return nil, err
+	}
+	right, err := c.right.series(e)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]bool, len(e.candles))
+	for i := range result {
+		if !defined(left[i], right[i]) {
+			continue
+		}
+		switch c.op {
+		case "<":
+			result[i] = left[i] < right[i]
+		case "<=":
+			result[i] = left[i] <= right[i]
+		case ">":
+			result[i] = left[i] > right[i]
+		case ">=":
+			result[i] = left[i] >= right[i]
+		case crossesAbove:
+			result[i] = i > 0 && defined(left[i-1], right[i-1]) && left[i-1] <= right[i-1] && left[i] > right[i]
+		case crossesBelow:
+			result[i] = i > 0 && defined(left[i-1], right[i-1]) && left[i-1] >= right[i-1] && left[i] < right[i]
+		}
+	}
+	return result, nil
+}
+
+func (c *comparison) lookback() int {
+	n := longest(c.left.lookback(), c.right.lookback())
+	if c.op == crossesAbove || c.op == crossesBelow {
+		n++
+	}
+	return n
+}
+
+// constant é um número
+type constant float64
+
+func (c constant) series(e *evaluation) ([]float64, error) {
+	result := make([]float64, len(e.candles))
+	for i := range result {
+		result[i] = float64(c)
+	}
+	return result, nil
+}
+
+func (constant) lookback() int { return 0 }
+
+// fields são os campos das velas que podem ser usados como operandos
+var fields = map[string]bool{"open": true, "high": true, "low": true, "close": true, "volume": true}
+
+// field é um campo das velas
+type field string
+
+func (f field) series(e *evaluation) ([]float64, error) {
+	switch f {
+	case "open":
+		return e.candles.Opens(), nil
+	case "high":
+		return e.candles.Highs(), nil
+	case "low":
+		return e.candles.Lows(), nil
+	case "volume":
+		return e.candles.Volumes(), nil
+	}
+	return e.candles.Closes(), nil
+}
+
+func (field) lookback() int { return 1 }
+
+// longest retorna o maior de dois períodos de aquecimento
+func longest(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,124 @@
+package rules
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// TestParse verifica regras válidas e o seu período de aquecimento
+func TestParse(t *testing.T) {
+	tests := []struct {
+		source   string
+		lookback int
+	}{
+		{"RSI(14) < 30 AND close CROSSES ABOVE EMA(50)", 51},
+		{"RSI(14) < 30 AND close crosses above EMA(50)", 51},
+		{"rsi < 30 or Close Crosses Below sma", 21},
+		{"NOT (MACD(12, 26, 9).signal > 0 OR BB(20, 2).upper <= close)", 35},
+		{"STOCH.d >= 80 AND ADX(14).plusdi > ADX(14).minusdi", 28},
+		{"volume > OBV AND VWAP < high AND ATR(7) > 1.5", 8},
+		{"close > -1", 1},
+	}
+
+	for _, tt := range tests {
+		rule, err := Parse(tt.source)
+		if err != nil {
+			t.Errorf("%q: %v", tt.source, err)
+			continue
+		}
+		if rule.Lookback() != tt.lookback {
+			t.Errorf("%q: aquecimento %d, esperado %d", tt.source, rule.Lookback(), tt.lookback)
+		}
+	}
+}
+
+// TestParseErrors verifica as mensagens de erro e a posição indicada
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"   ", "regra vazia"},
+		{"RSI(14) <", "fim inesperado, esperava um número, um campo ou um indicador (posição 10)"},
+		{"RSI(14) < 30 AND", "fim inesperado, esperava um número, um campo ou um indicador (posição 17)"},
+		{"close CROSSES OVER 10", `esperava ABOVE ou BELOW em vez de "OVER" (posição 15)`},
+		{"close 10", `esperava um operador de comparação em vez de "10" (posição 7)`},
+		{"close = 10", `carácter inesperado '=' (posição 7)`},
+		{"close > and", `esperava um número, um campo ou um indicador em vez de "and" (posição 9)`},
+		{"close > 10 )", `esperava AND, OR ou o fim da regra em vez de ")" (posição 12)`},
+		{"(close > 10", `fim inesperado, esperava ")" (posição 12)`},
+		{"FOO(3) > 1", `indicador desconhecido "FOO" (posição 1)`},
+		{"close > SMA(20 30)", `esperava "," ou ")" em vez de "30" (posição 16)`},
+		{"SMA(20, 3) > 1", "SMA aceita até 1 parâmetros (posição 1)"},
+		{"SMA(2.5) > 1", "os períodos de SMA devem ser inteiros até 1000"},
+		{"RSI(-14) < 30", "os parâmetros de RSI devem ser positivos"},
+		{"MACD(26, 12) > 0", "MACD: o período rápido (26) deve ser menor que o lento (12)"},
+		{"MACD.foo > 0", `saída desconhecida "foo" de MACD (disponíveis: macd, signal, histogram)`},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.source)
+		if err == nil {
+			t.Errorf("%q: esperado erro", tt.source)
+			continue
+		}
+		if !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("%q: erro %v não é ErrInvalidRule", tt.source, err)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: erro %q, esperado %q", tt.source, err, tt.want)
+		}
+	}
+}
+
+// TestEvaluate verifica as comparações, os cruzamentos e a precedência dos operadores lógicos
+func TestEvaluate(t *testing.T) {
+	closes := []float64{1, 2, 3, 2, 1, 2}
+	candles := make(models.Candles, len(closes))
+	for i, c := range closes {
+		candles[i] = models.Candle{
+			Time:  time.Date(2024, 1, 1+i, 0, 0, 0, 0, time.UTC),
+			Open:  c,
+			High:  c + 0.5,
+			Low:   c - 0.5,
+			Close: c,
+		}
+	}
+
+	tests := []struct {
+		source string
+		want   []bool
+	}{
+		{"close >= 2", []bool{false, true, true, true, false, true}},
+		{"close CROSSES ABOVE 1.5", []bool{false, true, false, false, false, true}},
+		{"close CROSSES BELOW 2", []bool{false, false, false, false, true, false}},
+		{"high > 2 AND low < 2", []bool{false, true, false, true, false, true}},
+		{"close > 2 OR close < 2 AND close < 3", []bool{true, false, true, false, true, false}},
+		{"(close > 2 OR close < 2) AND close < 3", []bool{true, false, false, false, true, false}},
+		{"NOT close > 1", []bool{true, false, false, false, true, false}},
+		// SMA(3) só está definida a partir da terceira vela
+		{"SMA(3) > 0", []bool{false, false, true, true, true, true}},
+		{"NOT SMA(3) > 2", []bool{true, true, true, false, true, true}},
+	}
+
+	for _, tt := range tests {
+		rule, err := Parse(tt.source)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.source, err)
+		}
+		got, err := rule.Evaluate(candles)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.source, err)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("%q: %v, esperado %v", tt.source, got, tt.want)
+				break
+			}
+		}
+	}
+}
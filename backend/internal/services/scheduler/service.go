@@ -2,38 +2,202 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/tiagofernandes/gofolio/internal/models"
-	"github.com/tiagofernandes/gofolio/internal/services/scraper"
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/patterns"
+	"gofolio/backend/internal/services/scraper"
+	"gofolio/backend/internal/services/strategy"
 )
 
+// Nomes das tarefas agendadas
+const (
+	JobMarketData        = "market_data"
+	JobTechnicalAnalysis = "technical_analysis"
+	JobSentimentAnalysis = "sentiment_analysis"
+	JobDataCleanup       = "data_cleanup"
+	JobStrategySignals   = "strategy_signals"
+)
+
+// patternInterval é o intervalo das velas usadas na deteção periódica de padrões
+const patternInterval = "1d"
+
+// Símbolos populares usados nas análises periódicas
+var defaultSymbols = []string{"BTC", "ETH", "BNB", "XRP", "ADA", "SOL", "DOGE", "DOT"}
+
+// JobStatus representa o estado de uma tarefa agendada
+type JobStatus struct {
+	Name         string        `json:"name"`
+	Interval     string        `json:"interval"`
+	Running      bool          `json:"running"`
+	LastRun      time.Time     `json:"lastRun,omitempty"`
+	LastDuration time.Duration `json:"lastDuration"`
+	LastError    string        `json:"lastError,omitempty"`
+	RunCount     int           `json:"runCount"`
+	NextRun      time.Time     `json:"nextRun,omitempty"`
+}
+
 // SchedulerService gerencia a coleta periódica de dados
 type SchedulerService struct {
 	scraper    *scraper.ScraperService
 	repository models.HistoricalDataRepository
+	strategies *strategy.Service // opcional
 	stopChan   chan struct{}
+	started    bool
+	jobs       map[string]*JobStatus
+	mu         sync.RWMutex
 }
 
-// NewSchedulerService cria um novo serviço de agendamento
-func NewSchedulerService(scraper *scraper.ScraperService, repository models.HistoricalDataRepository) *SchedulerService {
-	return &SchedulerService{
+// NewSchedulerService cria um novo serviço de agendamento. Sem serviço de estratégias (nil), a
+// tarefa de sinais das estratégias não faz nada.
+func NewSchedulerService(scraper *scraper.ScraperService, repository models.HistoricalDataRepository, strategies *strategy.Service) *SchedulerService {
+	s := &SchedulerService{
 		scraper:    scraper,
 		repository: repository,
+		strategies: strategies,
 		stopChan:   make(chan struct{}),
+		jobs:       make(map[string]*JobStatus),
+	}
+
+	s.jobs[JobMarketData] = &JobStatus{Name: JobMarketData, Interval: (15 * time.Minute).String()}
+	s.jobs[JobTechnicalAnalysis] = &JobStatus{Name: JobTechnicalAnalysis, Interval: (1 * time.Hour).String()}
+	s.jobs[JobSentimentAnalysis] = &JobStatus{Name: JobSentimentAnalysis, Interval: (30 * time.Minute).String()}
+	s.jobs[JobDataCleanup] = &JobStatus{Name: JobDataCleanup, Interval: (24 * time.Hour).String()}
+	s.jobs[JobStrategySignals] = &JobStatus{Name: JobStrategySignals, Interval: (1 * time.Hour).String()}
+
+	return s
+}
+
+// Status retorna o estado de todas as tarefas agendadas
+func (s *SchedulerService) Status() []JobStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := []string{JobMarketData, JobTechnicalAnalysis, JobSentimentAnalysis, JobDataCleanup, JobStrategySignals}
+	result := make([]JobStatus, 0, len(names))
+	for _, name := range names {
+		result = append(result, *s.jobs[name])
+	}
+
+	return result
+}
+
+// IsRunning indica se o agendador foi iniciado
+func (s *SchedulerService) IsRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.started
+}
+
+// RunJob executa imediatamente uma tarefa em segundo plano, fora do agendamento
+func (s *SchedulerService) RunJob(name string) error {
+	var fn func()
+	switch name {
+	case JobMarketData:
+		fn = s.collectAndStoreMarketData
+	case JobTechnicalAnalysis:
+		fn = func() { s.calculateTechnicalIndicators(defaultSymbols) }
+	case JobSentimentAnalysis:
+		fn = func() { s.collectSentimentData(defaultSymbols) }
+	case JobDataCleanup:
+		fn = s.cleanupOldData
+	case JobStrategySignals:
+		fn = s.generateStrategySignals
+	default:
+		return fmt.Errorf("tarefa desconhecida: %s", name)
+	}
+
+	// Marcar como em execução já aqui para evitar execuções duplicadas
+	if !s.claimJob(name) {
+		return fmt.Errorf("a tarefa %s já está em execução", name)
+	}
+
+	go s.runJob(name, 0, fn)
+
+	return nil
+}
+
+// trackJob executa uma tarefa agendada. Se a tarefa ainda estiver em execução, por exemplo
+// por ter sido lançada com RunJob, esta execução é ignorada.
+func (s *SchedulerService) trackJob(name string, interval time.Duration, fn func()) {
+	if !s.claimJob(name) {
+		log.Printf("Tarefa %s ainda em execução, execução agendada ignorada", name)
+		s.setNextRun(name, time.Now().Add(interval))
+		return
+	}
+
+	s.runJob(name, interval, fn)
+}
+
+// claimJob marca uma tarefa como em execução e indica se estava livre
+func (s *SchedulerService) claimJob(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.jobs[name]
+	if job.Running {
+		return false
+	}
+	job.Running = true
+	job.LastError = ""
+	return true
+}
+
+// runJob executa uma tarefa já marcada por claimJob, registando a duração, o erro e a próxima
+// execução. As tarefas sinalizam falhas através de recordJobError.
+func (s *SchedulerService) runJob(name string, interval time.Duration, fn func()) {
+	start := time.Now()
+
+	fn()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.jobs[name]
+	job.Running = false
+	job.LastRun = start
+	job.LastDuration = time.Since(start)
+	job.RunCount++
+	if interval > 0 {
+		job.NextRun = start.Add(interval)
 	}
 }
 
+// recordJobError regista o erro da última execução de uma tarefa
+func (s *SchedulerService) recordJobError(name string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[name].LastError = err.Error()
+}
+
+// setNextRun regista quando uma tarefa voltará a ser executada
+func (s *SchedulerService) setNextRun(name string, next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[name].NextRun = next
+}
+
 // Start inicia todos os agendamentos
 func (s *SchedulerService) Start() {
 	log.Println("Iniciando agendador de coleta de dados...")
+
+	s.mu.Lock()
+	s.started = true
+	s.mu.Unlock()
 	
 	// Iniciar coleta periódica de dados
 	go s.scheduleMarketDataCollection()
 	go s.scheduleTechnicalAnalysis()
 	go s.scheduleSentimentAnalysis()
 	go s.scheduleDataCleanup()
+	go s.scheduleStrategySignals()
 	
 	log.Println("Agendador iniciado com sucesso")
 }
@@ -41,22 +205,29 @@ func (s *SchedulerService) Start() {
 // Stop interrompe todos os agendamentos
 func (s *SchedulerService) Stop() {
 	log.Println("Parando agendador...")
+
+	s.mu.Lock()
+	s.started = false
+	s.mu.Unlock()
+
 	close(s.stopChan)
 }
 
 // ScheduleMarketDataCollection agenda coleta de dados de mercado
 func (s *SchedulerService) scheduleMarketDataCollection() {
+	interval := 15 * time.Minute
+
 	// Coletar imediatamente na inicialização
-	s.collectAndStoreMarketData()
+	s.trackJob(JobMarketData, interval, s.collectAndStoreMarketData)
 	
 	// Agendar coletas periódicas
-	ticker := time.NewTicker(15 * time.Minute)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	
 	for {
 		select {
 		case <-ticker.C:
-			s.collectAndStoreMarketData()
+			s.trackJob(JobMarketData, interval, s.collectAndStoreMarketData)
 		case <-s.stopChan:
 			log.Println("Agendamento de coleta de dados de mercado parado")
 			return
@@ -74,18 +245,20 @@ func (s *SchedulerService) collectAndStoreMarketData() {
 	data, err := s.scraper.GetMarketData(ctx)
 	if err != nil {
 		log.Printf("Erro ao coletar dados de mercado: %v", err)
+		s.recordJobError(JobMarketData, err)
 		return
 	}
 	
 	log.Printf("Dados coletados para %d criptomoedas", len(data))
 	
-	// Converter para formato de histórico
+	// Converter para formato de histórico. A CoinGecko retorna os símbolos em minúsculas e as
+	// consultas do histórico usam-nos em maiúsculas.
 	historicalData := make([]models.HistoricalData, len(data))
 	now := time.Now()
 	
 	for i, d := range data {
 		historicalData[i] = models.HistoricalData{
-			Symbol:    d.Symbol,
+			Symbol:    strings.ToUpper(d.Symbol),
 			Price:     d.CurrentPrice,
 			Volume:    d.TotalVolume,
 			MarketCap: d.MarketCap,
@@ -97,6 +270,7 @@ func (s *SchedulerService) collectAndStoreMarketData() {
 	// Salvar no repositório
 	if err := s.repository.SaveHistoricalData(historicalData); err != nil {
 		log.Printf("Erro ao salvar dados históricos: %v", err)
+		s.recordJobError(JobMarketData, err)
 		return
 	}
 	
@@ -106,16 +280,15 @@ func (s *SchedulerService) collectAndStoreMarketData() {
 // ScheduleTechnicalAnalysis agenda cálculo de análise técnica
 func (s *SchedulerService) scheduleTechnicalAnalysis() {
 	// Agendar análises periódicas
-	ticker := time.NewTicker(1 * time.Hour)
+	interval := 1 * time.Hour
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
-	
-	// Lista de símbolos populares para analisar
-	symbols := []string{"BTC", "ETH", "BNB", "XRP", "ADA", "SOL", "DOGE", "DOT"}
+	s.setNextRun(JobTechnicalAnalysis, time.Now().Add(interval))
 	
 	for {
 		select {
 		case <-ticker.C:
-			s.calculateTechnicalIndicators(symbols)
+			s.trackJob(JobTechnicalAnalysis, interval, func() { s.calculateTechnicalIndicators(defaultSymbols) })
 		case <-s.stopChan:
 			log.Println("Agendamento de análise técnica parado")
 			return
@@ -134,11 +307,23 @@ func (s *SchedulerService) calculateTechnicalIndicators(symbols []string) {
 		_, err := s.scraper.GetTechnicalAnalysis(ctx, symbol)
 		if err != nil {
 			log.Printf("Erro ao calcular indicadores técnicos para %s: %v", symbol, err)
+			s.recordJobError(JobTechnicalAnalysis, fmt.Errorf("%s: %w", symbol, err))
 			continue
 		}
 		
 		log.Printf("Indicadores técnicos calculados para %s", symbol)
 		
+		// Detetar padrões nas velas diárias e registar os que terminaram no último dia
+		analysis, err := s.scraper.GetPatterns(ctx, symbol, patternInterval)
+		if err != nil {
+			log.Printf("Erro ao detetar padrões para %s: %v", symbol, err)
+			s.recordJobError(JobTechnicalAnalysis, fmt.Errorf("%s: %w", symbol, err))
+		} else {
+			for _, match := range patterns.Since(analysis.Patterns, time.Now().Add(-24*time.Hour)) {
+				log.Printf("Padrão %s (%s) detetado para %s com confiança %.2f", match.Name, match.Implication, symbol, match.Confidence)
+			}
+		}
+		
 		// Pequeno delay para não sobrecarregar as APIs
 		time.Sleep(5 * time.Second)
 	}
@@ -147,16 +332,15 @@ func (s *SchedulerService) calculateTechnicalIndicators(symbols []string) {
 // ScheduleSentimentAnalysis agenda coleta de análise de sentimento
 func (s *SchedulerService) scheduleSentimentAnalysis() {
 	// Agendar análises periódicas
-	ticker := time.NewTicker(30 * time.Minute)
+	interval := 30 * time.Minute
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
-	
-	// Lista de símbolos populares para analisar
-	symbols := []string{"BTC", "ETH", "BNB", "XRP", "ADA", "SOL", "DOGE", "DOT"}
+	s.setNextRun(JobSentimentAnalysis, time.Now().Add(interval))
 	
 	for {
 		select {
 		case <-ticker.C:
-			s.collectSentimentData(symbols)
+			s.trackJob(JobSentimentAnalysis, interval, func() { s.collectSentimentData(defaultSymbols) })
 		case <-s.stopChan:
 			log.Println("Agendamento de análise de sentimento parado")
 			return
@@ -175,6 +359,7 @@ func (s *SchedulerService) collectSentimentData(symbols []string) {
 		_, err := s.scraper.GetSentimentAnalysis(ctx, symbol)
 		if err != nil {
 			log.Printf("Erro ao coletar dados de sentimento para %s: %v", symbol, err)
+			s.recordJobError(JobSentimentAnalysis, fmt.Errorf("%s: %w", symbol, err))
 			continue
 		}
 		
@@ -188,13 +373,15 @@ func (s *SchedulerService) collectSentimentData(symbols []string) {
 // ScheduleDataCleanup agenda limpeza de dados antigos
 func (s *SchedulerService) scheduleDataCleanup() {
 	// Executar limpeza uma vez por dia
-	ticker := time.NewTicker(24 * time.Hour)
+	interval := 24 * time.Hour
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
+	s.setNextRun(JobDataCleanup, time.Now().Add(interval))
 	
 	for {
 		select {
 		case <-ticker.C:
-			s.cleanupOldData()
+			s.trackJob(JobDataCleanup, interval, s.cleanupOldData)
 		case <-s.stopChan:
 			log.Println("Agendamento de limpeza de dados parado")
 			return
@@ -212,8 +399,63 @@ func (s *SchedulerService) cleanupOldData() {
 	err := s.repository.DeleteOldData(cutoffDate)
 	if err != nil {
 		log.Printf("Erro ao limpar dados antigos: %v", err)
+		s.recordJobError(JobDataCleanup, err)
 		return
 	}
 	
 	log.Println("Dados antigos limpos com sucesso")
-} 
\ No newline at end of file
+} 
+
+// ScheduleStrategySignals agenda a geração dos sinais das estratégias declarativas
+func (s *SchedulerService) scheduleStrategySignals() {
+	interval := 1 * time.Hour
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	s.setNextRun(JobStrategySignals, time.Now().Add(interval))
+
+	for {
+		select {
+		case <-ticker.C:
+			s.trackJob(JobStrategySignals, interval, s.generateStrategySignals)
+		case <-s.stopChan:
+			log.Println("Agendamento de sinais das estratégias parado")
+			return
+		}
+	}
+}
+
+// generateStrategySignals avalia as estratégias declarativas nos ativos que acompanham e regista
+// os sinais de compra e venda
+func (s *SchedulerService) generateStrategySignals() {
+	if s.strategies == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	log.Println("Gerando sinais das estratégias...")
+
+	watched, err := s.strategies.Watched()
+	if err != nil {
+		log.Printf("Erro ao obter estratégias: %v", err)
+		s.recordJobError(JobStrategySignals, err)
+		return
+	}
+
+	for i := range watched {
+		definition := watched[i].Definition
+		for _, symbol := range definition.Symbols {
+			signal, err := s.strategies.Evaluate(ctx, &watched[i], symbol)
+			if err != nil {
+				log.Printf("Erro ao avaliar a estratégia %s para %s: %v", definition.Name, symbol, err)
+				s.recordJobError(JobStrategySignals, fmt.Errorf("%s (%s): %w", definition.Name, symbol, err))
+				continue
+			}
+
+			if signal.Action != strategy.ActionNeutral {
+				log.Printf("Sinal de %s da estratégia %s (usuário %s) para %s a %.2f", signal.Action, definition.Name, watched[i].UserID, symbol, signal.Price)
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/candles"
+)
+
+// cryptoCompareMaxLimit é o número máximo de velas devolvidas pelo CryptoCompare num pedido
+const cryptoCompareMaxLimit = 2000
+
+// maxHistoryCacheDuration é o tempo máximo em cache de um histórico
+const maxHistoryCacheDuration = 2 * time.Hour
+
+// historyCacheDuration retorna o tempo em cache de um histórico: a duração de uma vela, para
+// que a vela em curso seja atualizada, até ao máximo de maxHistoryCacheDuration
+func historyCacheDuration(interval candles.Interval) time.Duration {
+	if interval.Duration() < maxHistoryCacheDuration {
+		return interval.Duration()
+	}
+	return maxHistoryCacheDuration
+}
+
+// cryptoCompareEndpoint retorna o endpoint do CryptoCompare com as velas de base para o
+// intervalo pedido e a duração dessas velas
+func cryptoCompareEndpoint(interval candles.Interval) (string, time.Duration) {
+	switch {
+	case interval.Duration() < time.Hour:
+		return "histominute", time.Minute
+	case interval.Duration() < 24*time.Hour:
+		return "histohour", time.Hour
+	default:
+		return "histoday", 24 * time.Hour
+	}
+}
+
+// fetchCryptoCompareHistoricalData obtém velas de base (minuto, hora ou dia) do CryptoCompare e
+// agrega-as no intervalo pedido. É pedida uma vela a mais, porque a primeira pode estar incompleta.
+func (s *ScraperService) fetchCryptoCompareHistoricalData(ctx context.Context, symbol string, interval candles.Interval, limit int) (models.Candles, error) {
+	endpoint, base := cryptoCompareEndpoint(interval)
+	count := (limit + 1) * int(interval.Duration()/base)
+	if count > cryptoCompareMaxLimit {
+		count = cryptoCompareMaxLimit
+	}
+
+	url := fmt.Sprintf("%sv2/%s?fsym=%s&tsym=%s&limit=%d&extraParams=GoFolio", CRYPTOCOMPARE_API, endpoint, strings.ToUpper(symbol), QUOTE_CURRENCY, count)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status inválido: %d", resp.StatusCode)
+	}
+
+	var rawData struct {
+		Response string `json:"Response"`
+		Message  string `json:"Message"`
+		Data     struct {
+			Data []struct {
+				Time     int64   `json:"time"`
+				Open     float64 `json:"open"`
+				High     float64 `json:"high"`
+				Low      float64 `json:"low"`
+				Close    float64 `json:"close"`
+				VolumeTo float64 `json:"volumeto"` // volume na moeda de cotação
+			} `json:"Data"`
+		} `json:"Data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rawData); err != nil {
+		return nil, err
+	}
+	if rawData.Response == "Error" {
+		return nil, fmt.Errorf("erro do CryptoCompare: %s", rawData.Message)
+	}
+
+	// O CryptoCompare devolve velas vazias antes do início da negociação do ativo
+	data := make(models.Candles, 0, len(rawData.Data.Data))
+	for _, point := range rawData.Data.Data {
+		if point.Open == 0 && point.Close == 0 && point.VolumeTo == 0 {
+			continue
+		}
+		data = append(data, models.Candle{
+			Time:   time.Unix(point.Time, 0).UTC(),
+			Open:   point.Open,
+			High:   point.High,
+			Low:    point.Low,
+			Close:  point.Close,
+			Volume: point.VolumeTo,
+		})
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("sem dados históricos para %s", symbol)
+	}
+
+	return candles.Resample(data, interval, candles.GapFill)
+}
+
+// coinGeckoGranularity retorna o espaçamento das cotações do CoinGecko para um número de dias:
+// 5 minutos para um dia, uma hora até 90 dias e um dia acima disso
+func coinGeckoGranularity(days int) time.Duration {
+	switch {
+	case days <= 1:
+		return 5 * time.Minute
+	case days <= 90:
+		return time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// fetchCoinGeckoHistoricalData obtém as cotações e volumes de 24 horas do CoinGecko e agrega-os
+// em velas do intervalo pedido. O CoinGecko identifica os ativos por ID, que é obtido dos dados
+// de mercado pelo símbolo.
+func (s *ScraperService) fetchCoinGeckoHistoricalData(ctx context.Context, symbol string, interval candles.Interval, limit int) (models.Candles, error) {
+	days := int(math.Ceil(float64(time.Duration(limit+1)*interval.Duration()) / float64(24*time.Hour)))
+	if granularity := coinGeckoGranularity(days); interval.Duration() < granularity {
+		return nil, fmt.Errorf("o CoinGecko não tem cotações a cada %s para %d dias", interval, days)
+	}
+
+	id, err := s.coinGeckoID(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/coins/%s/market_chart?vs_currency=%s&days=%d", COINGECKO_API, id, strings.ToLower(QUOTE_CURRENCY), days)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status inválido: %d", resp.StatusCode)
+	}
+
+	var rawData struct {
+		Prices       [][2]float64 `json:"prices"`        // [timestamp em ms, preço]
+		TotalVolumes [][2]float64 `json:"total_volumes"` // [timestamp em ms, volume de 24 horas]
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rawData); err != nil {
+		return nil, err
+	}
+	if len(rawData.Prices) == 0 {
+		return nil, fmt.Errorf("sem dados históricos para %s", symbol)
+	}
+
+	// Os volumes vêm alinhados com as cotações
+	history := make([]models.HistoricalData, len(rawData.Prices))
+	for i, point := range rawData.Prices {
+		history[i] = models.HistoricalData{
+			Symbol:    strings.ToUpper(symbol),
+			Price:     point[1],
+			Timestamp: time.UnixMilli(int64(point[0])).UTC(),
+		}
+		if i < len(rawData.TotalVolumes) {
+			history[i].Volume = rawData.TotalVolumes[i][1]
+		}
+	}
+
+	return candles.FromHistory(history, interval, candles.GapFill)
+}
+
+// coinGeckoID obtém o ID do CoinGecko de um símbolo a partir dos dados de mercado. Se vários
+// ativos partilharem o símbolo, prevalece o de maior capitalização.
+func (s *ScraperService) coinGeckoID(ctx context.Context, symbol string) (string, error) {
+	data, err := s.GetMarketData(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	id, rank := "", 0
+	for _, crypto := range data {
+		if crypto.ID == "" || !strings.EqualFold(crypto.Symbol, symbol) {
+			continue
+		}
+		if id == "" || (crypto.MarketCapRank > 0 && (rank == 0 || crypto.MarketCapRank < rank)) {
+			id, rank = crypto.ID, crypto.MarketCapRank
+		}
+	}
+	if id == "" {
+		return "", fmt.Errorf("ativo desconhecido no CoinGecko: %s", symbol)
+	}
+	return id, nil
+}
@@ -0,0 +1,53 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gofolio/backend/internal/services/candles"
+	"gofolio/backend/internal/services/patterns"
+)
+
+// patternLookback é o número de velas analisadas na deteção de padrões
+const patternLookback = 120
+
+// PatternAnalysis representa os padrões detetados nas velas de uma criptomoeda
+type PatternAnalysis struct {
+	Symbol      string           `json:"symbol"`
+	Interval    string           `json:"interval"`
+	Patterns    []patterns.Match `json:"patterns"`
+	LastUpdated time.Time        `json:"last_updated"`
+}
+
+// GetPatterns deteta padrões de velas e gráficos nas últimas velas de uma criptomoeda no
+// intervalo indicado
+func (s *ScraperService) GetPatterns(ctx context.Context, symbol string, interval string) (*PatternAnalysis, error) {
+	candleInterval, err := candles.ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tentar buscar do cache primeiro
+	cacheKey := fmt.Sprintf("patterns_%s_%s", symbol, interval)
+	if cachedData, found := s.cache.Get(cacheKey); found {
+		return cachedData.(*PatternAnalysis), nil
+	}
+
+	historicalData, err := s.GetHistoricalData(ctx, symbol, interval, patternLookback)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao obter dados históricos: %w", err)
+	}
+
+	analysis := &PatternAnalysis{
+		Symbol:      symbol,
+		Interval:    interval,
+		Patterns:    patterns.Detect(historicalData, patterns.Options{}),
+		LastUpdated: time.Now(),
+	}
+
+	// Armazenar em cache tanto quanto o histórico
+	s.cache.Set(cacheKey, analysis, historyCacheDuration(candleInterval))
+
+	return analysis, nil
+}
@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
-	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/candles"
 )
 
 // Constantes para URLs de APIs gratuitas
@@ -20,6 +23,10 @@ const (
 	CRYPTOCOMPARE_API  = "https://min-api.cryptocompare.com/data/"
 )
 
+// QUOTE_CURRENCY é a moeda dos preços recolhidos, a mesma do histórico e dos portfólios
+// (models.DefaultQuoteCurrency). As outras moedas são obtidas com as taxas de câmbio.
+const QUOTE_CURRENCY = "USD"
+
 // CryptoData representa os dados de uma criptomoeda
 type CryptoData struct {
 	ID                 string  `json:"id"`
@@ -36,6 +43,7 @@ type CryptoData struct {
 	ATH                float64 `json:"ath"`
 	ATHChangePercent   float64 `json:"ath_change_percentage"`
 	LastUpdated        string  `json:"last_updated"`
+	Currency           string  `json:"currency"` // moeda dos preços e volumes (QUOTE_CURRENCY)
 }
 
 // TechnicalIndicator representa um indicador técnico
@@ -214,9 +222,30 @@ func (s *ScraperService) GetMarketData(ctx context.Context) ([]CryptoData, error
 	return data, nil
 }
 
+// RefreshMarketData ignora o cache e obtém dados de mercado atualizados
+func (s *ScraperService) RefreshMarketData(ctx context.Context) ([]CryptoData, error) {
+	s.cache.Delete("market_data")
+	return s.GetMarketData(ctx)
+}
+
+// RefreshSymbol ignora o cache e recalcula a análise técnica e de sentimento de um ativo
+func (s *ScraperService) RefreshSymbol(ctx context.Context, symbol string) error {
+	s.cache.Delete(fmt.Sprintf("technical_%s", symbol))
+	s.cache.Delete(fmt.Sprintf("sentiment_%s", symbol))
+
+	if _, err := s.GetTechnicalAnalysis(ctx, symbol); err != nil {
+		return fmt.Errorf("falha ao atualizar análise técnica: %w", err)
+	}
+	if _, err := s.GetSentimentAnalysis(ctx, symbol); err != nil {
+		return fmt.Errorf("falha ao atualizar análise de sentimento: %w", err)
+	}
+
+	return nil
+}
+
 // FetchCoinGeckoMarketData obtém dados da API do CoinGecko
 func (s *ScraperService) fetchCoinGeckoMarketData(ctx context.Context) ([]CryptoData, error) {
-	url := fmt.Sprintf("%s/coins/markets?vs_currency=eur&order=market_cap_desc&per_page=100", COINGECKO_API)
+	url := fmt.Sprintf("%s/coins/markets?vs_currency=%s&order=market_cap_desc&per_page=100", COINGECKO_API, strings.ToLower(QUOTE_CURRENCY))
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -237,13 +266,16 @@ func (s *ScraperService) fetchCoinGeckoMarketData(ctx context.Context) ([]Crypto
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
 		return nil, err
 	}
+	for i := range data {
+		data[i].Currency = QUOTE_CURRENCY
+	}
 
 	return data, nil
 }
 
 // FetchCryptoCompareMarketData obtém dados da API do CryptoCompare
 func (s *ScraperService) fetchCryptoCompareMarketData(ctx context.Context) ([]CryptoData, error) {
-	url := fmt.Sprintf("%sprice?fsym=BTC,ETH,BNB,XRP,ADA,SOL,DOGE,DOT&tsyms=EUR&extraParams=GoFolio", CRYPTOCOMPARE_API)
+	url := fmt.Sprintf("%sprice?fsym=BTC,ETH,BNB,XRP,ADA,SOL,DOGE,DOT&tsyms=%s&extraParams=GoFolio", CRYPTOCOMPARE_API, QUOTE_CURRENCY)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -271,8 +303,9 @@ func (s *ScraperService) fetchCryptoCompareMarketData(ctx context.Context) ([]Cr
 	for symbol, prices := range rawData {
 		data := CryptoData{
 			Symbol:       symbol,
-			CurrentPrice: prices["EUR"],
+			CurrentPrice: prices[QUOTE_CURRENCY],
 			LastUpdated:  time.Now().Format(time.RFC3339),
+			Currency:     QUOTE_CURRENCY,
 		}
 		result = append(result, data)
 	}
@@ -302,11 +335,13 @@ func (s *ScraperService) fetchAlternativeMeMarketData(ctx context.Context) ([]Cr
 	// Adaptar a resposta para o formato CryptoData
 	var rawData struct {
 		Data map[string]struct {
-			ID               string `json:"id"`
-			Name             string `json:"name"`
-			Symbol           string `json:"symbol"`
-			PriceEur         string `json:"price_eur"`
-			PercentChange24h string `json:"percent_change_24h"`
+			ID     string `json:"id"`
+			Name   string `json:"name"`
+			Symbol string `json:"symbol"`
+			Quotes map[string]struct {
+				Price            float64 `json:"price"`
+				PercentChange24h float64 `json:"percentage_change_24h"`
+			} `json:"quotes"`
 		} `json:"data"`
 	}
 
@@ -317,17 +352,16 @@ func (s *ScraperService) fetchAlternativeMeMarketData(ctx context.Context) ([]Cr
 	// Converter para nosso formato
 	result := make([]CryptoData, 0, len(rawData.Data))
 	for _, crypto := range rawData.Data {
-		// Converter strings para float64
-		price, _ := strconv.ParseFloat(crypto.PriceEur, 64)
-		change, _ := strconv.ParseFloat(crypto.PercentChange24h, 64)
+		quote := crypto.Quotes[QUOTE_CURRENCY]
 
 		data := CryptoData{
 			ID:                 crypto.ID,
 			Name:               crypto.Name,
 			Symbol:             crypto.Symbol,
-			CurrentPrice:       price,
-			PriceChangePercent: change,
+			CurrentPrice:       quote.Price,
+			PriceChangePercent: quote.PercentChange24h,
 			LastUpdated:        time.Now().Format(time.RFC3339),
+			Currency:           QUOTE_CURRENCY,
 		}
 		result = append(result, data)
 	}
@@ -344,75 +378,25 @@ func (s *ScraperService) GetTechnicalAnalysis(ctx context.Context, symbol string
 	}
 
 	// Obter dados históricos para calcular indicadores
-	historicalData, err := s.GetHistoricalData(ctx, symbol, "1d", 30)
+	historicalData, err := s.GetHistoricalData(ctx, symbol, "1d", technicalLookback)
 	if err != nil {
 		return nil, fmt.Errorf("falha ao obter dados históricos: %w", err)
 	}
 
 	// Calcular indicadores técnicos
+	indicatorList, err := technicalIndicators(historicalData)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao calcular indicadores técnicos: %w", err)
+	}
+
 	analysis := &TechnicalAnalysis{
 		Symbol:      symbol,
-		Indicators:  make([]TechnicalIndicator, 0),
+		Indicators:  indicatorList,
 		LastUpdated: time.Now(),
 	}
 
-	// Calcular RSI
-	rsi := s.calculateRSI(historicalData)
-	rsiSignal := "neutral"
-	if rsi < 30 {
-		rsiSignal = "buy"
-	} else if rsi > 70 {
-		rsiSignal = "sell"
-	}
-
-	analysis.Indicators = append(analysis.Indicators, TechnicalIndicator{
-		Name:   "RSI",
-		Value:  rsi,
-		Signal: rsiSignal,
-	})
-
-	// Calcular MACD
-	macd, signal := s.calculateMACD(historicalData)
-	macdSignal := "neutral"
-	if macd > signal {
-		macdSignal = "buy"
-	} else if macd < signal {
-		macdSignal = "sell"
-	}
-
-	analysis.Indicators = append(analysis.Indicators, TechnicalIndicator{
-		Name:   "MACD",
-		Value:  macd,
-		Signal: macdSignal,
-	})
-
-	// Adicionar outros indicadores aqui
-
 	// Calcular resumo geral
-	buySignals := 0
-	sellSignals := 0
-
-	for _, indicator := range analysis.Indicators {
-		if indicator.Signal == "buy" {
-			buySignals++
-		} else if indicator.Signal == "sell" {
-			sellSignals++
-		}
-	}
-
-	if buySignals > sellSignals {
-		analysis.Summary.Signal = "buy"
-		analysis.Summary.Strength = float64(buySignals) / float64(len(analysis.Indicators))
-		analysis.Summary.Description = fmt.Sprintf("%d/%d indicadores sugerem compra", buySignals, len(analysis.Indicators))
-	} else if sellSignals > buySignals {
-		analysis.Summary.Signal = "sell"
-		analysis.Summary.Strength = float64(sellSignals) / float64(len(analysis.Indicators))
-		analysis.Summary.Description = fmt.Sprintf("%d/%d indicadores sugerem venda", sellSignals, len(analysis.Indicators))
-	} else {
-		analysis.Summary.Signal = "neutral"
-		analysis.Summary.Strength = 0.5
-		analysis.Summary.Description = "Indicadores técnicos estão mistos"
-	}
+	summarize(analysis)
 
 	// Armazenar em cache por 1 hora
 	s.cache.Set(cacheKey, analysis, 1*time.Hour)
@@ -420,59 +404,39 @@ func (s *ScraperService) GetTechnicalAnalysis(ctx context.Context, symbol string
 	return analysis, nil
 }
 
-// GetHistoricalData obtém dados históricos para uma criptomoeda
-func (s *ScraperService) GetHistoricalData(ctx context.Context, symbol string, interval string, limit int) ([]map[string]interface{}, error) {
+// GetHistoricalData obtém as últimas limit velas de uma criptomoeda no intervalo indicado
+// ("1m", "5m", "1h", "4h", "1d" ou "1w"). As velas são agregadas a partir dos dados do fornecedor,
+// e os intervalos sem dados são preenchidos com o fecho anterior.
+func (s *ScraperService) GetHistoricalData(ctx context.Context, symbol string, interval string, limit int) (models.Candles, error) {
+	candleInterval, err := candles.ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+
 	// Tentar buscar do cache primeiro
 	cacheKey := fmt.Sprintf("history_%s_%s_%d", symbol, interval, limit)
 	if cachedData, found := s.cache.Get(cacheKey); found {
-		return cachedData.([]map[string]interface{}), nil
+		return cachedData.(models.Candles), nil
 	}
 
-	// Tentar CoinGecko primeiro
-	data, err := s.fetchCoinGeckoHistoricalData(ctx, symbol, interval, limit)
+	// Tentar CryptoCompare primeiro, que fornece velas OHLCV
+	data, err := s.fetchCryptoCompareHistoricalData(ctx, symbol, candleInterval, limit)
 	if err != nil {
-		// Fallback para CryptoCompare
-		data, err = s.fetchCryptoCompareHistoricalData(ctx, symbol, interval, limit)
+		// Fallback para CoinGecko, que fornece apenas cotações
+		data, err = s.fetchCoinGeckoHistoricalData(ctx, symbol, candleInterval, limit)
 		if err != nil {
 			return nil, fmt.Errorf("falha ao obter dados históricos: %w", err)
 		}
 	}
 
-	// Armazenar em cache por 2 horas
-	s.cache.Set(cacheKey, data, 2*time.Hour)
-
-	return data, nil
-}
-
-// Implementações de cálculo de indicadores técnicos e obtenção de dados históricos
-// Essas são simplificações e devem ser expandidas com algoritmos reais
-
-func (s *ScraperService) calculateRSI(data []map[string]interface{}) float64 {
-	// Simulação simplificada de cálculo de RSI
-	// Em um caso real, implementaríamos o algoritmo completo do RSI
-
-	if len(data) < 14 {
-		return 50.0 // Valor neutro para dados insuficientes
-	}
-
-	// Simulação para demonstração
-	// Valor aleatório entre 0 e 100, tendendo para o meio (30-70)
-	return 30.0 + (70.0 * rand.Float64())
-}
-
-func (s *ScraperService) calculateMACD(data []map[string]interface{}) (float64, float64) {
-	// Simulação simplificada de cálculo de MACD
-	// Em um caso real, implementaríamos o algoritmo completo do MACD
-
-	if len(data) < 26 {
-		return 0.0, 0.0 // Valores neutros para dados insuficientes
+	if len(data) > limit {
+		data = data[len(data)-limit:]
 	}
 
-	// Simulação para demonstração
-	macd := -1.0 + (2.0 * rand.Float64())   // Valor entre -1 e 1
-	signal := -1.0 + (2.0 * rand.Float64()) // Valor entre -1 e 1
+	// Armazenar em cache até à próxima vela, no máximo 2 horas
+	s.cache.Set(cacheKey, data, historyCacheDuration(candleInterval))
 
-	return macd, signal
+	return data, nil
 }
 
 // GetSentimentAnalysis obtém análise de sentimento para uma criptomoeda
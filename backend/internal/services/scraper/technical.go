@@ -0,0 +1,203 @@
+package scraper
+
+import (
+	"fmt"
+	"math"
+
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/indicators"
+)
+
+// technicalLookback é o número de pontos diários usados na análise técnica, suficiente para o
+// indicador mais longo (a nuvem do Ichimoku, 52 pontos deslocados 26 para a frente)
+const technicalLookback = 120
+
+// Limiares dos sinais dos osciladores
+const (
+	rsiOversold          = 30
+	rsiOverbought        = 70
+	stochasticOversold   = 20
+	stochasticOverbought = 80
+	adxTrending          = 25 // ADX a partir do qual há tendência
+)
+
+// technicalIndicators calcula os indicadores da análise técnica e o sinal de cada um no último
+// ponto. Indicadores sem dados suficientes ficam de fora.
+func technicalIndicators(history models.Candles) ([]TechnicalIndicator, error) {
+	result := []TechnicalIndicator{}
+	if len(history) == 0 {
+		return result, nil
+	}
+
+	highs, lows, closes, volumes := history.Highs(), history.Lows(), history.Closes(), history.Volumes()
+	last := len(closes) - 1
+	close := closes[last]
+	add := func(name string, value float64, signal string) {
+		if indicators.Valid(value) {
+			result = append(result, TechnicalIndicator{Name: name, Value: value, Signal: signal})
+		}
+	}
+
+	rsi, err := indicators.RSI(closes, 14)
+	if err != nil {
+		return nil, err
+	}
+	add("RSI", rsi[last], thresholdSignal(rsi[last], rsiOversold, rsiOverbought))
+
+	macd, err := indicators.MACD(closes, 12, 26, 9)
+	if err != nil {
+		return nil, err
+	}
+	add("MACD", macd.MACD[last], compareSignal(macd.MACD[last], macd.Signal[last]))
+
+	for _, average := range []struct {
+		name   string
+		period int
+		series func([]float64, int) ([]float64, error)
+	}{
+		{"SMA50", 50, indicators.SMA},
+		{"EMA20", 20, indicators.EMA},
+	} {
+		values, err := average.series(closes, average.period)
+		if err != nil {
+			return nil, err
+		}
+		add(average.name, values[last], compareSignal(close, values[last]))
+	}
+
+	bands, err := indicators.BollingerBands(closes, 20, 2)
+	if err != nil {
+		return nil, err
+	}
+	if indicators.Valid(bands.Middle[last]) {
+		signal := "neutral"
+		if close < bands.Lower[last] {
+			signal = "buy"
+		} else if close > bands.Upper[last] {
+			signal = "sell"
+		}
+		add("BollingerBands", bands.PercentB(close, last), signal)
+	}
+
+	stochastic, err := indicators.Stochastic(highs, lows, closes, 14, 3, 3)
+	if err != nil {
+		return nil, err
+	}
+	add("Stochastic", stochastic.K[last], thresholdSignal(stochastic.K[last], stochasticOversold, stochasticOverbought))
+
+	adx, err := indicators.ADX(highs, lows, closes, 14)
+	if err != nil {
+		return nil, err
+	}
+	adxSignal := "neutral"
+	if adx.ADX[last] >= adxTrending {
+		adxSignal = compareSignal(adx.PlusDI[last], adx.MinusDI[last])
+	}
+	add("ADX", adx.ADX[last], adxSignal)
+
+	ichimoku, err := indicators.Ichimoku(highs, lows, closes, 9, 26, 52)
+	if err != nil {
+		return nil, err
+	}
+	if spanA, spanB := ichimoku.SenkouA[last], ichimoku.SenkouB[last]; indicators.Valid(spanA) && indicators.Valid(spanB) {
+		signal := "neutral"
+		if close > math.Max(spanA, spanB) {
+			signal = "buy"
+		} else if close < math.Min(spanA, spanB) {
+			signal = "sell"
+		}
+		add("Ichimoku", (spanA+spanB)/2, signal)
+	}
+
+	atr, err := indicators.ATR(highs, lows, closes, 14)
+	if err != nil {
+		return nil, err
+	}
+	add("ATR", atr[last], "neutral")
+
+	// Os indicadores de volume só fazem sentido se o histórico tiver volumes
+	hasVolume := false
+	for _, volume := range volumes {
+		hasVolume = hasVolume || volume > 0
+	}
+	if !hasVolume {
+		return result, nil
+	}
+
+	obv, err := indicators.OBV(closes, volumes)
+	if err != nil {
+		return nil, err
+	}
+	obvAverage, err := indicators.SMA(obv, 20)
+	if err != nil {
+		return nil, err
+	}
+	if indicators.Valid(obvAverage[last]) {
+		add("OBV", obv[last], compareSignal(obv[last], obvAverage[last]))
+	}
+
+	vwap, err := indicators.VWAP(highs, lows, closes, volumes)
+	if err != nil {
+		return nil, err
+	}
+	add("VWAP", vwap[last], compareSignal(close, vwap[last]))
+
+	return result, nil
+}
+
+// thresholdSignal retorna o sinal de um oscilador: compra abaixo de oversold, venda acima de overbought
+func thresholdSignal(value, oversold, overbought float64) string {
+	switch {
+	case value < oversold:
+		return "buy"
+	case value > overbought:
+		return "sell"
+	default:
+		return "neutral"
+	}
+}
+
+// compareSignal retorna compra se value estiver acima de reference e venda se estiver abaixo
+func compareSignal(value, reference float64) string {
+	switch {
+	case value > reference:
+		return "buy"
+	case value < reference:
+		return "sell"
+	default:
+		return "neutral"
+	}
+}
+
+// summarize preenche o resumo da análise com o sinal mais frequente entre os indicadores
+func summarize(analysis *TechnicalAnalysis) {
+	buySignals := 0
+	sellSignals := 0
+	for _, indicator := range analysis.Indicators {
+		if indicator.Signal == "buy" {
+			buySignals++
+		} else if indicator.Signal == "sell" {
+			sellSignals++
+		}
+	}
+
+	total := len(analysis.Indicators)
+	switch {
+	case buySignals > sellSignals:
+		analysis.Summary.Signal = "buy"
+		analysis.Summary.Strength = float64(buySignals) / float64(total)
+		analysis.Summary.Description = fmt.Sprintf("%d/%d indicadores sugerem compra", buySignals, total)
+	case sellSignals > buySignals:
+		analysis.Summary.Signal = "sell"
+		analysis.Summary.Strength = float64(sellSignals) / float64(total)
+		analysis.Summary.Description = fmt.Sprintf("%d/%d indicadores sugerem venda", sellSignals, total)
+	case total == 0:
+		analysis.Summary.Signal = "neutral"
+		analysis.Summary.Strength = 0.5
+		analysis.Summary.Description = "Dados históricos insuficientes para os indicadores técnicos"
+	default:
+		analysis.Summary.Signal = "neutral"
+		analysis.Summary.Strength = 0.5
+		analysis.Summary.Description = "Indicadores técnicos estão mistos"
+	}
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// simulationTransactionID identifica a transação hipotética nos resultados do motor de custo
+const simulationTransactionID = "simulation"
+
+// simulationConfidence é o nível de confiança do VaR no impacto de risco da simulação
+const simulationConfidence = 0.95
+
+// SimulateTransaction calcula o efeito de uma compra ou venda hipotética no portfólio: posições
+// e pesos resultantes, P&L realizado segundo o método de custo do portfólio, impacto da taxa e
+// variação do risco estimado. Um preço vazio corresponde ao preço de mercado atual. Nada é gravado.
+func (s *PortfolioService) SimulateTransaction(portfolioID string, tx models.Transaction) (*models.TransactionSimulation, error) {
+	if tx.Type != models.TransactionBuy && tx.Type != models.TransactionSell {
+		return nil, fmt.Errorf("%w: só é possível simular compras e vendas", ErrInvalidTransaction)
+	}
+
+	portfolio, ledger, err := s.ledger(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A simulação é feita no instante atual; sem preço, usa-se o de mercado
+	tx.Timestamp = time.Time{}
+	tx.Symbol = strings.ToUpper(strings.TrimSpace(tx.Symbol))
+	tx.FeeCurrency = strings.ToUpper(strings.TrimSpace(tx.FeeCurrency))
+	prices := s.currentPrices(ledgerSymbols(ledger, tx.Symbol, tx.FeeCurrency))
+	if tx.Price == 0 {
+		tx.Price = prices[tx.Symbol]
+	}
+	if err := normalizeTransaction(&tx); err != nil {
+		return nil, err
+	}
+
+	// A transação é avaliada ao seu próprio preço
+	prices[tx.Symbol] = tx.Price
+
+	tx.ID = simulationTransactionID
+	tx.PortfolioID = portfolioID
+	tx.CreatedAt = tx.Timestamp
+
+	engine, err := NewCostBasisEngine(portfolioMethod(portfolio))
+	if err != nil {
+		return nil, err
+	}
+	before, err := engine.Run(ledger)
+	if err != nil {
+		return nil, err
+	}
+	changed := make([]models.Transaction, 0, len(ledger)+1)
+	changed = append(append(changed, ledger...), tx)
+	after, err := engine.Run(changed)
+	if err != nil {
+		return nil, err
+	}
+
+	simulation := &models.TransactionSimulation{
+		PortfolioID:    portfolioID,
+		Symbol:         tx.Symbol,
+		Amount:         tx.Quantity,
+		Price:          tx.Price,
+		Type:           string(tx.Type),
+		Fee:            tx.Fee,
+		FeeCurrency:    tx.FeeCurrency,
+		TradeValue:     tx.Quantity * tx.Price,
+		Disposals:      []models.Disposal{},
+		Holdings:       after.Holdings,
+		PricesComplete: true,
+	}
+
+	for _, disposal := range after.Disposals {
+		if disposal.TransactionID != tx.ID {
+			continue
+		}
+		simulation.Disposals = append(simulation.Disposals, disposal)
+		if disposal.Type == models.TransactionSell {
+			simulation.SimulatedProfit += disposal.RealizedPnL
+		}
+	}
+
+	switch {
+	case isQuoteFee(tx):
+		simulation.FeeImpact = tx.Fee
+	case tx.Fee > 0:
+		feePrice, ok := prices[tx.FeeCurrency]
+		if !ok {
+			simulation.PricesComplete = false
+		}
+		simulation.FeeImpact = tx.Fee * feePrice
+	}
+	if simulation.TradeValue > 0 {
+		simulation.FeePercentage = simulation.FeeImpact / simulation.TradeValue * 100
+	}
+
+	simulation.Allocation, simulation.CurrentValue, simulation.SimulatedValue = allocationChanges(before.Holdings, after.Holdings, prices)
+	for _, change := range simulation.Allocation {
+		if _, ok := prices[change.Symbol]; !ok {
+			simulation.PricesComplete = false
+		}
+	}
+
+	if s.history != nil {
+		simulation.Risk, err = s.riskImpact(before.Holdings, after.Holdings, prices)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return simulation, nil
+}
+
+// allocationChanges compara as posições antes e depois de uma transação, aos preços indicados.
+// Retorna as alterações por ativo e o valor total antes e depois. Ativos sem preço contam com valor nulo.
+func allocationChanges(before, after []models.Holding, prices map[string]float64) ([]models.AllocationChange, float64, float64) {
+	changes := map[string]*models.AllocationChange{}
+	change := func(symbol string) *models.AllocationChange {
+		if changes[symbol] == nil {
+			changes[symbol] = &models.AllocationChange{Symbol: symbol}
+		}
+		return changes[symbol]
+	}
+
+	var totalBefore, totalAfter float64
+	for _, holding := range before {
+		c := change(holding.Symbol)
+		c.QuantityBefore = holding.Quantity
+		c.ValueBefore = holding.Quantity * prices[holding.Symbol]
+		totalBefore += c.ValueBefore
+	}
+	for _, holding := range after {
+		c := change(holding.Symbol)
+		c.QuantityAfter = holding.Quantity
+		c.ValueAfter = holding.Quantity * prices[holding.Symbol]
+		totalAfter += c.ValueAfter
+	}
+
+	result := make([]models.AllocationChange, 0, len(changes))
+	for _, c := range changes {
+		if totalBefore > 0 {
+			c.WeightBefore = c.ValueBefore / totalBefore
+		}
+		if totalAfter > 0 {
+			c.WeightAfter = c.ValueAfter / totalAfter
+		}
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ValueAfter != result[j].ValueAfter {
+			return result[i].ValueAfter > result[j].ValueAfter
+		}
+		return result[i].Symbol < result[j].Symbol
+	})
+
+	return result, totalBefore, totalAfter
+}
+
+// riskImpact estima o risco das posições antes e depois de uma transação a partir da média e da
+// covariância dos retornos diários dos ativos no último ano. Ativos sem histórico suficiente contam
+// com risco nulo e são listados em UnmodeledSymbols.
+func (s *PortfolioService) riskImpact(before, after []models.Holding, prices map[string]float64) (*models.RiskImpact, error) {
+	now := time.Now()
+	symbols := ledgerSymbols(nil, append(holdingSymbols(before), holdingSymbols(after)...)...)
+	history, err := s.priceHistory(ledgerSymbols(nil, append(symbols, ReturnsBenchmark)...), now.AddDate(0, 0, -forecastLookbackDays-1), now)
+	if err != nil {
+		return nil, err
+	}
+
+	impact := &models.RiskImpact{Benchmark: ReturnsBenchmark}
+	var modeled []string
+	for _, symbol := range symbols {
+		if len(dailyLogReturns(history, []string{symbol}, now)) < minForecastObservations {
+			impact.UnmodeledSymbols = append(impact.UnmodeledSymbols, symbol)
+			continue
+		}
+		modeled = append(modeled, symbol)
+	}
+
+	// O benchmark entra na estimativa para o beta, se tiver histórico
+	benchmark := -1
+	if len(dailyLogReturns(history, []string{ReturnsBenchmark}, now)) >= minForecastObservations {
+		for i, symbol := range modeled {
+			if symbol == ReturnsBenchmark {
+				benchmark = i
+			}
+		}
+		if benchmark < 0 {
+			modeled = append(modeled, ReturnsBenchmark)
+			benchmark = len(modeled) - 1
+		}
+	}
+
+	returns := dailyLogReturns(history, modeled, now)
+	if len(modeled) == 0 || len(returns) < minForecastObservations {
+		impact.UnmodeledSymbols = symbols
+		return impact, nil
+	}
+
+	mean, covariance := meanCovariance(returns)
+	impact.Before = riskSnapshot(before, modeled, prices, mean, covariance, benchmark)
+	impact.After = riskSnapshot(after, modeled, prices, mean, covariance, benchmark)
+
+	return impact, nil
+}
+
+// riskSnapshot calcula a volatilidade, o VaR paramétrico e o beta de um conjunto de posições,
+// dados a média e a covariância dos retornos diários dos ativos modelados
+func riskSnapshot(holdings []models.Holding, modeled []string, prices map[string]float64, mean []float64, covariance [][]float64, benchmark int) models.RiskSnapshot {
+	var total float64
+	values := map[string]float64{}
+	for _, holding := range holdings {
+		values[holding.Symbol] = holding.Quantity * prices[holding.Symbol]
+		total += values[holding.Symbol]
+	}
+
+	var snapshot models.RiskSnapshot
+	if total <= 0 {
+		return snapshot
+	}
+
+	weights := make([]float64, len(modeled))
+	for i, symbol := range modeled {
+		weights[i] = values[symbol] / total
+	}
+
+	var expected, variance, benchmarkCovariance float64
+	for i := range weights {
+		expected += weights[i] * mean[i]
+		for j := range weights {
+			variance += weights[i] * weights[j] * covariance[i][j]
+		}
+		if benchmark >= 0 {
+			benchmarkCovariance += weights[i] * covariance[i][benchmark]
+		}
+	}
+
+	daily := math.Sqrt(math.Max(variance, 0))
+	snapshot.AnnualizedVolatility = daily * math.Sqrt(float64(year)/float64(24*time.Hour)) * 100
+	snapshot.ValueAtRisk = -(expected + normalQuantile(1-simulationConfidence)*daily) * 100
+	if benchmark >= 0 && covariance[benchmark][benchmark] > 0 {
+		beta := benchmarkCovariance / covariance[benchmark][benchmark]
+		snapshot.Beta = &beta
+	}
+
+	return snapshot
+}
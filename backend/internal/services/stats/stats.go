@@ -0,0 +1,63 @@
+// Package stats reúne as estatísticas partilhadas pelas métricas de risco dos portfólios e pelos
+// backtests.
+package stats
+
+import (
+	"math"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// MeanStdDev retorna a média e o desvio-padrão amostral. Com menos de dois valores o desvio é 0.
+func MeanStdDev(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	if len(values) < 2 {
+		return mean, 0
+	}
+
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)-1))
+}
+
+// MaxDrawdown encontra a maior queda de uma série de valores face ao máximo anterior;
+// values[i] é o valor no instante times[i]. A recuperação é o primeiro instante, depois do
+// mínimo, em que o valor volta ao máximo anterior à queda.
+func MaxDrawdown(times []time.Time, values []float64) models.Drawdown {
+	var drawdown models.Drawdown
+	peak, worstPeak, worstTrough := 0, 0, 0
+	for i := range values {
+		if values[i] >= values[peak] {
+			peak = i
+			continue
+		}
+		if depth := (values[i]/values[peak] - 1) * 100; depth < drawdown.Depth {
+			drawdown.Depth = depth
+			worstPeak, worstTrough = peak, i
+		}
+	}
+
+	if drawdown.Depth == 0 {
+		return drawdown
+	}
+
+	drawdown.PeakAt = times[worstPeak]
+	drawdown.TroughAt = times[worstTrough]
+	for i := worstTrough + 1; i < len(values); i++ {
+		if values[i] >= values[worstPeak] {
+			recovered := times[i]
+			drawdown.RecoveredAt = &recovered
+			break
+		}
+	}
+
+	return drawdown
+}
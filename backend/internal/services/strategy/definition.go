@@ -0,0 +1,175 @@
+// Package strategy gere as estratégias declarativas dos usuários: a leitura em JSON ou YAML, a
+// validação das regras, o armazenamento e a geração de sinais sobre as velas mais recentes. As
+// mesmas estratégias podem ser simuladas pelo pacote backtest.
+package strategy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"gofolio/backend/internal/models"
+	"gofolio/backend/internal/services/candles"
+	"gofolio/backend/internal/services/rules"
+)
+
+// Erros das estratégias declarativas
+var (
+	ErrInvalidStrategy    = errors.New("estratégia inválida")
+	ErrUnsupportedFormat  = errors.New("formato de estratégia não suportado")
+	ErrCandlesUnavailable = errors.New("velas indisponíveis")
+)
+
+// Formatos das definições
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// DefaultInterval é o intervalo das velas quando a definição não o indica
+const DefaultInterval = "1d"
+
+// maxNameLength é o comprimento máximo do nome de uma estratégia
+const maxNameLength = 100
+
+// Decode lê uma definição em JSON ou YAML. Os campos desconhecidos são rejeitados, para que um
+// erro de escrita não passe despercebido. A definição não é validada (ver Compile).
+func Decode(data []byte, format string) (*models.StrategyDefinition, error) {
+	var definition models.StrategyDefinition
+	switch format {
+	case FormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&definition); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStrategy, err)
+		}
+	case FormatYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&definition); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStrategy, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	return &definition, nil
+}
+
+// Encode escreve uma definição em JSON ou YAML
+func Encode(definition models.StrategyDefinition, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(definition, "", "  ")
+	case FormatYAML:
+		return yaml.Marshal(definition)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// Compiled é uma estratégia validada, com as regras prontas a avaliar
+type Compiled struct {
+	Definition models.StrategyDefinition // definição normalizada
+	Interval   candles.Interval
+	Entry      *rules.Rule
+	Exit       *rules.Rule // nil sem regra de saída
+}
+
+// Compile valida e normaliza uma definição: o nome e a regra de entrada são obrigatórios, o
+// intervalo por omissão é DefaultInterval e os símbolos ficam em maiúsculas, sem repetições.
+func Compile(definition models.StrategyDefinition) (*Compiled, error) {
+	definition.Name = strings.TrimSpace(definition.Name)
+	definition.Description = strings.TrimSpace(definition.Description)
+	if definition.Name == "" {
+		return nil, fmt.Errorf("%w: nome obrigatório", ErrInvalidStrategy)
+	}
+	if len(definition.Name) > maxNameLength {
+		return nil, fmt.Errorf("%w: o nome tem mais de %d caracteres", ErrInvalidStrategy, maxNameLength)
+	}
+
+	definition.Interval = strings.ToLower(strings.TrimSpace(definition.Interval))
+	if definition.Interval == "" {
+		definition.Interval = DefaultInterval
+	}
+	interval, err := candles.ParseInterval(definition.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStrategy, err)
+	}
+
+	symbols := []string{}
+	seen := map[string]bool{}
+	for _, symbol := range definition.Symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		symbols = append(symbols, symbol)
+	}
+	definition.Symbols = symbols
+
+	compiled := &Compiled{Interval: interval}
+	definition.Entry = strings.TrimSpace(definition.Entry)
+	if definition.Entry == "" {
+		return nil, fmt.Errorf("%w: regra de entrada obrigatória", ErrInvalidStrategy)
+	}
+	if compiled.Entry, err = rules.Parse(definition.Entry); err != nil {
+		return nil, fmt.Errorf("%w: entrada: %v", ErrInvalidStrategy, err)
+	}
+	definition.Exit = strings.TrimSpace(definition.Exit)
+	if definition.Exit != "" {
+		if compiled.Exit, err = rules.Parse(definition.Exit); err != nil {
+			return nil, fmt.Errorf("%w: saída: %v", ErrInvalidStrategy, err)
+		}
+	}
+
+	switch {
+	case definition.StopLoss < 0 || definition.StopLoss >= 1:
+		return nil, fmt.Errorf("%w: o stop-loss deve estar entre 0 e 1", ErrInvalidStrategy)
+	case definition.TakeProfit < 0:
+		return nil, fmt.Errorf("%w: o take-profit não pode ser negativo", ErrInvalidStrategy)
+	case compiled.Exit == nil && definition.StopLoss == 0 && definition.TakeProfit == 0:
+		return nil, fmt.Errorf("%w: é preciso uma regra de saída, um stop-loss ou um take-profit", ErrInvalidStrategy)
+	}
+
+	if sizing := definition.Sizing; sizing != nil {
+		switch {
+		case sizing.Mode == models.SizingPercent && (sizing.Value <= 0 || sizing.Value > 1):
+			return nil, fmt.Errorf("%w: a fração do capital deve estar entre 0 e 1", ErrInvalidStrategy)
+		case sizing.Mode == models.SizingFixed && sizing.Value <= 0:
+			return nil, fmt.Errorf("%w: o valor de cada entrada deve ser positivo", ErrInvalidStrategy)
+		case sizing.Mode != models.SizingPercent && sizing.Mode != models.SizingFixed:
+			return nil, fmt.Errorf("%w: dimensionamento desconhecido: %s", ErrInvalidStrategy, sizing.Mode)
+		}
+	}
+
+	compiled.Definition = definition
+	return compiled, nil
+}
+
+// Lookback é o número de velas necessárias antes de as regras poderem ser verdadeiras
+func (c *Compiled) Lookback() int {
+	lookback := c.Entry.Lookback()
+	if c.Exit != nil && c.Exit.Lookback() > lookback {
+		lookback = c.Exit.Lookback()
+	}
+	return lookback
+}
+
+// Evaluate avalia as regras de entrada e de saída em cada vela. Sem regra de saída, exits é
+// sempre falso.
+func (c *Compiled) Evaluate(series models.Candles) (entries, exits []bool, err error) {
+	if entries, err = c.Entry.Evaluate(series); err != nil {
+		return nil, nil, err
+	}
+	if c.Exit == nil {
+		return entries, make([]bool, len(series)), nil
+	}
+	if exits, err = c.Exit.Evaluate(series); err != nil {
+		return nil, nil, err
+	}
+	return entries, exits, nil
+}
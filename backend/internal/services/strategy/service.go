@@ -0,0 +1,221 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gofolio/backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Limites das velas pedidas para gerar um sinal: pelo menos minSignalCandles, ou o dobro do
+// aquecimento das regras, sem exceder o que os fornecedores devolvem de uma vez
+const (
+	minSignalCandles = 100
+	maxSignalCandles = 2000
+)
+
+// Ações de um sinal, com o vocabulário da análise técnica
+const (
+	ActionBuy     = "buy"
+	ActionSell    = "sell"
+	ActionNeutral = "neutral"
+)
+
+// CandleSource obtém as velas mais recentes de um ativo (implementado pelo
+// scraper.ScraperService)
+type CandleSource interface {
+	GetHistoricalData(ctx context.Context, symbol string, interval string, limit int) (models.Candles, error)
+}
+
+// Signal é o resultado das regras de uma estratégia na última vela de um ativo
+type Signal struct {
+	StrategyID string    `json:"strategyId"`
+	Strategy   string    `json:"strategy"`
+	Symbol     string    `json:"symbol"`
+	Interval   string    `json:"interval"`
+	Time       time.Time `json:"time"`  // início da última vela
+	Price      float64   `json:"price"` // fecho da última vela
+	Action     string    `json:"action"`
+	Entry      bool      `json:"entry"`                // a regra de entrada é verdadeira
+	Exit       bool      `json:"exit"`                 // a regra de saída é verdadeira
+	StopLoss   float64   `json:"stopLoss,omitempty"`   // nível do stop-loss de uma entrada ao preço atual
+	TakeProfit float64   `json:"takeProfit,omitempty"` // nível do take-profit de uma entrada ao preço atual
+}
+
+// Service gere as estratégias declarativas dos usuários e gera os seus sinais
+type Service struct {
+	repo    models.StrategyRepository
+	candles CandleSource
+}
+
+// NewService cria o serviço de estratégias. Sem fonte de velas (nil), os sinais falham com
+// ErrCandlesUnavailable.
+func NewService(repo models.StrategyRepository, candles CandleSource) *Service {
+	return &Service{repo: repo, candles: candles}
+}
+
+// Create valida e guarda uma nova estratégia
+func (s *Service) Create(userID string, definition models.StrategyDefinition) (*models.Strategy, error) {
+	compiled, err := Compile(definition)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	strategy := &models.Strategy{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		Definition: compiled.Definition,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.repo.CreateStrategy(strategy); err != nil {
+		return nil, err
+	}
+
+	return strategy, nil
+}
+
+// Get obtém uma estratégia do usuário
+func (s *Service) Get(userID, id string) (*models.Strategy, error) {
+	strategy, err := s.repo.GetStrategy(id)
+	if err != nil {
+		return nil, err
+	}
+	if strategy.UserID != userID {
+		return nil, models.ErrStrategyNotFound
+	}
+	return strategy, nil
+}
+
+// List obtém as estratégias do usuário
+func (s *Service) List(userID string) ([]models.Strategy, error) {
+	return s.repo.ListStrategies(userID)
+}
+
+// Update valida e substitui a definição de uma estratégia do usuário
+func (s *Service) Update(userID, id string, definition models.StrategyDefinition) (*models.Strategy, error) {
+	strategy, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled, err := Compile(definition)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy.Definition = compiled.Definition
+	strategy.UpdatedAt = time.Now()
+	if err := s.repo.UpdateStrategy(strategy); err != nil {
+		return nil, err
+	}
+
+	return strategy, nil
+}
+
+// Delete remove uma estratégia do usuário
+func (s *Service) Delete(userID, id string) error {
+	if _, err := s.Get(userID, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteStrategy(id)
+}
+
+// Watched obtém as estratégias de todos os usuários que acompanham algum ativo, para os sinais
+// periódicos
+func (s *Service) Watched() ([]models.Strategy, error) {
+	return s.repo.ListWatchedStrategies()
+}
+
+// Signals gera os sinais de uma estratégia do usuário para os ativos indicados ou, sem ativos,
+// para os que a estratégia acompanha
+func (s *Service) Signals(ctx context.Context, userID, id string, symbols []string) ([]Signal, error) {
+	strategy, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(symbols) == 0 {
+		symbols = strategy.Definition.Symbols
+	}
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("%w: a estratégia não acompanha nenhum ativo", ErrInvalidStrategy)
+	}
+
+	signals := make([]Signal, 0, len(symbols))
+	for _, symbol := range symbols {
+		signal, err := s.Evaluate(ctx, strategy, symbol)
+		if err != nil {
+			return nil, err
+		}
+		signals = append(signals, *signal)
+	}
+	return signals, nil
+}
+
+// Evaluate gera o sinal de uma estratégia para um ativo, avaliando as regras sobre as velas
+// mais recentes. A ação é compra quando só a regra de entrada é verdadeira, venda quando só a de
+// saída o é, e neutra nos outros casos.
+func (s *Service) Evaluate(ctx context.Context, strategy *models.Strategy, symbol string) (*Signal, error) {
+	compiled, err := Compile(strategy.Definition)
+	if err != nil {
+		return nil, err
+	}
+	if s.candles == nil {
+		return nil, ErrCandlesUnavailable
+	}
+
+	limit := 2 * compiled.Lookback()
+	if limit < minSignalCandles {
+		limit = minSignalCandles
+	}
+	if limit > maxSignalCandles {
+		limit = maxSignalCandles
+	}
+
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	series, err := s.candles.GetHistoricalData(ctx, symbol, compiled.Definition.Interval, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrCandlesUnavailable, symbol, err)
+	}
+	if len(series) == 0 {
+		return nil, fmt.Errorf("%w: %s sem velas", ErrCandlesUnavailable, symbol)
+	}
+
+	entries, exits, err := compiled.Evaluate(series)
+	if err != nil {
+		return nil, err
+	}
+
+	last := len(series) - 1
+	signal := &Signal{
+		StrategyID: strategy.ID,
+		Strategy:   compiled.Definition.Name,
+		Symbol:     symbol,
+		Interval:   compiled.Definition.Interval,
+		Time:       series[last].Time,
+		Price:      series[last].Close,
+		Action:     ActionNeutral,
+		Entry:      entries[last],
+		Exit:       exits[last],
+	}
+	switch {
+	case signal.Entry && !signal.Exit:
+		signal.Action = ActionBuy
+		if compiled.Definition.StopLoss > 0 {
+			signal.StopLoss = signal.Price * (1 - compiled.Definition.StopLoss)
+		}
+		if compiled.Definition.TakeProfit > 0 {
+			signal.TakeProfit = signal.Price * (1 + compiled.Definition.TakeProfit)
+		}
+	case signal.Exit && !signal.Entry:
+		signal.Action = ActionSell
+	}
+
+	return signal, nil
+}
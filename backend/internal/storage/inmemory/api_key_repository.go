@@ -0,0 +1,100 @@
+package inmemory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"gofolio/backend/internal/auth"
+)
+
+// APIKeyRepository implementa a interface auth.APIKeyRepository com armazenamento em memória
+type APIKeyRepository struct {
+	keys   map[string]auth.APIKey // id -> chave
+	byHash map[string]string      // hash -> id
+	mu     sync.RWMutex
+}
+
+// NewAPIKeyRepository cria uma nova instância do repositório de chaves de API em memória
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{
+		keys:   make(map[string]auth.APIKey),
+		byHash: make(map[string]string),
+	}
+}
+
+// Create adiciona uma nova chave de API
+func (r *APIKeyRepository) Create(key *auth.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[key.ID] = *key
+	r.byHash[key.KeyHash] = key.ID
+
+	return nil
+}
+
+// GetByHash obtém uma chave de API pelo hash
+func (r *APIKeyRepository) GetByHash(keyHash string) (*auth.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byHash[keyHash]
+	if !ok {
+		return nil, auth.ErrAPIKeyNotFound
+	}
+
+	key := r.keys[id]
+	return &key, nil
+}
+
+// ListByUser obtém as chaves de API de um usuário, ordenadas pela data de criação
+func (r *APIKeyRepository) ListByUser(userID string) ([]auth.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []auth.APIKey{}
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			result = append(result, key)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+
+	return result, nil
+}
+
+// Delete remove uma chave de API de um usuário
+func (r *APIKeyRepository) Delete(userID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.UserID != userID {
+		return auth.ErrAPIKeyNotFound
+	}
+
+	delete(r.byHash, key.KeyHash)
+	delete(r.keys, id)
+
+	return nil
+}
+
+// TouchLastUsed atualiza a data do último uso de uma chave
+func (r *APIKeyRepository) TouchLastUsed(id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return auth.ErrAPIKeyNotFound
+	}
+
+	key.LastUsedAt = &at
+	r.keys[id] = key
+
+	return nil
+}
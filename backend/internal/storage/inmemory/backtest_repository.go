@@ -0,0 +1,77 @@
+package inmemory
+
+import (
+	"sort"
+	"sync"
+
+	"gofolio/backend/internal/models"
+)
+
+// BacktestRepository implementa a interface models.BacktestRepository com armazenamento em memória
+type BacktestRepository struct {
+	backtests map[string]models.Backtest
+	mu        sync.RWMutex
+}
+
+// NewBacktestRepository cria uma nova instância do repositório de backtests em memória
+func NewBacktestRepository() *BacktestRepository {
+	return &BacktestRepository{
+		backtests: make(map[string]models.Backtest),
+	}
+}
+
+// SaveBacktest grava um novo backtest
+func (r *BacktestRepository) SaveBacktest(backtest *models.Backtest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.backtests[backtest.ID] = *backtest
+	return nil
+}
+
+// GetBacktest obtém um backtest completo
+func (r *BacktestRepository) GetBacktest(id string) (*models.Backtest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	backtest, ok := r.backtests[id]
+	if !ok {
+		return nil, models.ErrBacktestNotFound
+	}
+	return &backtest, nil
+}
+
+// ListBacktests obtém os backtests de um usuário, do mais recente para o mais antigo, apenas
+// com as métricas do resultado
+func (r *BacktestRepository) ListBacktests(userID string) ([]models.Backtest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []models.Backtest{}
+	for _, backtest := range r.backtests {
+		if backtest.UserID != userID {
+			continue
+		}
+		backtest.Result.Trades = nil
+		backtest.Result.Equity = nil
+		result = append(result, backtest)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+
+	return result, nil
+}
+
+// DeleteBacktest remove um backtest
+func (r *BacktestRepository) DeleteBacktest(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.backtests[id]; !ok {
+		return models.ErrBacktestNotFound
+	}
+	delete(r.backtests, id)
+	return nil
+}
@@ -6,7 +6,7 @@ import (
 	"sync"
 	"time"
 
-	"github.com/tiagofernandes/gofolio/internal/models"
+	"gofolio/backend/internal/models"
 )
 
 // CryptoRepository implementa a interface models.CryptoRepository com armazenamento em memória
@@ -14,7 +14,7 @@ import (
 type CryptoRepository struct {
 	marketData     map[string]models.CryptoData
 	coinDetails    map[string]models.CoinDetails
-	historicalData map[string]models.HistoricalData
+	historicalData map[string]models.MarketHistoricalData
 	globalData     *models.GlobalMarketData
 	mu             sync.RWMutex
 }
@@ -24,7 +24,7 @@ func NewCryptoRepository() *CryptoRepository {
 	return &CryptoRepository{
 		marketData:     make(map[string]models.CryptoData),
 		coinDetails:    make(map[string]models.CoinDetails),
-		historicalData: make(map[string]models.HistoricalData),
+		historicalData: make(map[string]models.MarketHistoricalData),
 		globalData:     nil,
 	}
 }
@@ -99,7 +99,7 @@ func (r *CryptoRepository) GetCoinDetails(id string) (*models.CoinDetails, error
 }
 
 // GetHistoricalData obtém dados históricos de preço para uma criptomoeda
-func (r *CryptoRepository) GetHistoricalData(id, currency string, days int) (*models.HistoricalData, error) {
+func (r *CryptoRepository) GetHistoricalData(id, currency string, days int) (*models.MarketHistoricalData, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -169,7 +169,7 @@ func (r *CryptoRepository) SaveCoinDetails(data *models.CoinDetails) error {
 }
 
 // SaveHistoricalData salva dados históricos de preço
-func (r *CryptoRepository) SaveHistoricalData(data *models.HistoricalData) error {
+func (r *CryptoRepository) SaveHistoricalData(data *models.MarketHistoricalData) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -0,0 +1,64 @@
+package inmemory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"gofolio/backend/internal/models"
+)
+
+// FXRateRepository implementa a interface models.FXRateRepository com armazenamento em memória
+type FXRateRepository struct {
+	rates map[fxRateKey]models.FXRate
+	mu    sync.RWMutex
+}
+
+// fxRateKey identifica a taxa de uma moeda numa data
+type fxRateKey struct {
+	base     string
+	currency string
+	date     time.Time
+}
+
+// NewFXRateRepository cria uma nova instância do repositório de taxas de câmbio em memória
+func NewFXRateRepository() *FXRateRepository {
+	return &FXRateRepository{
+		rates: make(map[fxRateKey]models.FXRate),
+	}
+}
+
+// SaveRates grava as taxas, substituindo as existentes para a mesma moeda e data
+func (r *FXRateRepository) SaveRates(rates []models.FXRate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rate := range rates {
+		rate.Date = rate.Date.UTC()
+		r.rates[fxRateKey{base: rate.Base, currency: rate.Currency, date: rate.Date}] = rate
+	}
+
+	return nil
+}
+
+// GetRates retorna as taxas de base entre from e to (inclusive), por ordem cronológica
+func (r *FXRateRepository) GetRates(base string, from, to time.Time) ([]models.FXRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []models.FXRate
+	for key, rate := range r.rates {
+		if key.base == base && !key.date.Before(from) && !key.date.After(to) {
+			result = append(result, rate)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Date.Equal(result[j].Date) {
+			return result[i].Date.Before(result[j].Date)
+		}
+		return result[i].Currency < result[j].Currency
+	})
+
+	return result, nil
+}
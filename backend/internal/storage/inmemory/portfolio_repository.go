@@ -0,0 +1,319 @@
+package inmemory
+
+import (
+	"sort"
+	"sync"
+
+	"gofolio/backend/internal/models"
+)
+
+// PortfolioRepository implementa a interface models.PortfolioRepository com armazenamento em memória
+type PortfolioRepository struct {
+	portfolios   map[string]models.Portfolio // sem os ativos, guardados à parte
+	assets       map[string]models.Asset
+	transactions map[string]models.Transaction
+	targets      map[string][]models.AllocationTarget // por portfólio
+	mu           sync.RWMutex
+}
+
+// NewPortfolioRepository cria uma nova instância do repositório de portfólios em memória
+func NewPortfolioRepository() *PortfolioRepository {
+	return &PortfolioRepository{
+		portfolios:   make(map[string]models.Portfolio),
+		assets:       make(map[string]models.Asset),
+		transactions: make(map[string]models.Transaction),
+		targets:      make(map[string][]models.AllocationTarget),
+	}
+}
+
+// CreatePortfolio adiciona um novo portfólio
+func (r *PortfolioRepository) CreatePortfolio(portfolio *models.Portfolio) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := *portfolio
+	p.Assets = nil
+	r.portfolios[p.ID] = p
+
+	for _, asset := range portfolio.Assets {
+		r.assets[asset.ID] = asset
+	}
+
+	return nil
+}
+
+// GetPortfolio obtém um portfólio e os seus ativos
+func (r *PortfolioRepository) GetPortfolio(id string) (*models.Portfolio, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.portfolios[id]
+	if !ok {
+		return nil, models.ErrPortfolioNotFound
+	}
+
+	p.Assets = r.assetsOf(id)
+	return &p, nil
+}
+
+// ListPortfolios obtém os portfólios de um usuário, ordenados pela data de criação
+func (r *PortfolioRepository) ListPortfolios(userID string) ([]models.Portfolio, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []models.Portfolio{}
+	for _, p := range r.portfolios {
+		if p.UserID == userID {
+			p.Assets = r.assetsOf(p.ID)
+			result = append(result, p)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+
+	return result, nil
+}
+
+// UpdatePortfolio atualiza o nome, a descrição e o método de custo de um portfólio
+func (r *PortfolioRepository) UpdatePortfolio(portfolio *models.Portfolio) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.portfolios[portfolio.ID]
+	if !ok {
+		return models.ErrPortfolioNotFound
+	}
+
+	p.Name = portfolio.Name
+	p.Description = portfolio.Description
+	p.CostBasisMethod = portfolio.CostBasisMethod
+	p.UpdatedAt = portfolio.UpdatedAt
+	r.portfolios[p.ID] = p
+
+	return nil
+}
+
+// DeletePortfolio remove um portfólio, os seus ativos e as suas transações
+func (r *PortfolioRepository) DeletePortfolio(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.portfolios[id]; !ok {
+		return models.ErrPortfolioNotFound
+	}
+
+	for assetID, asset := range r.assets {
+		if asset.PortfolioID == id {
+			delete(r.assets, assetID)
+		}
+	}
+	for txID, tx := range r.transactions {
+		if tx.PortfolioID == id {
+			delete(r.transactions, txID)
+		}
+	}
+	delete(r.targets, id)
+	delete(r.portfolios, id)
+
+	return nil
+}
+
+// AddAsset adiciona um ativo a um portfólio existente
+func (r *PortfolioRepository) AddAsset(asset *models.Asset) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.portfolios[asset.PortfolioID]; !ok {
+		return models.ErrPortfolioNotFound
+	}
+
+	r.assets[asset.ID] = *asset
+	return nil
+}
+
+// GetAsset obtém um ativo pelo ID
+func (r *PortfolioRepository) GetAsset(id string) (*models.Asset, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	asset, ok := r.assets[id]
+	if !ok {
+		return nil, models.ErrAssetNotFound
+	}
+
+	return &asset, nil
+}
+
+// UpdateAsset atualiza um ativo existente
+func (r *PortfolioRepository) UpdateAsset(asset *models.Asset) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.assets[asset.ID]
+	if !ok {
+		return models.ErrAssetNotFound
+	}
+
+	updated := *asset
+	updated.PortfolioID = existing.PortfolioID
+	updated.CreatedAt = existing.CreatedAt
+	r.assets[asset.ID] = updated
+
+	return nil
+}
+
+// DeleteAsset remove um ativo
+func (r *PortfolioRepository) DeleteAsset(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.assets[id]; !ok {
+		return models.ErrAssetNotFound
+	}
+
+	delete(r.assets, id)
+	return nil
+}
+
+// AddTransaction adiciona uma transação ao livro de um portfólio existente
+func (r *PortfolioRepository) AddTransaction(tx *models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.portfolios[tx.PortfolioID]; !ok {
+		return models.ErrPortfolioNotFound
+	}
+
+	r.transactions[tx.ID] = *tx
+	return nil
+}
+
+// AddTransactions adiciona várias transações ao livro de um portfólio existente
+func (r *PortfolioRepository) AddTransactions(transactions []models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, tx := range transactions {
+		if _, ok := r.portfolios[tx.PortfolioID]; !ok {
+			return models.ErrPortfolioNotFound
+		}
+	}
+
+	for _, tx := range transactions {
+		r.transactions[tx.ID] = tx
+	}
+	return nil
+}
+
+// GetTransaction obtém uma transação pelo ID
+func (r *PortfolioRepository) GetTransaction(id string) (*models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tx, ok := r.transactions[id]
+	if !ok {
+		return nil, models.ErrTransactionNotFound
+	}
+
+	return &tx, nil
+}
+
+// ListTransactions obtém o livro de transações de um portfólio por ordem cronológica
+func (r *PortfolioRepository) ListTransactions(portfolioID string) ([]models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []models.Transaction{}
+	for _, tx := range r.transactions {
+		if tx.PortfolioID == portfolioID {
+			result = append(result, tx)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Timestamp.Equal(result[j].Timestamp) {
+			return result[i].Timestamp.Before(result[j].Timestamp)
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+
+	return result, nil
+}
+
+// UpdateTransaction atualiza uma transação existente
+func (r *PortfolioRepository) UpdateTransaction(tx *models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.transactions[tx.ID]
+	if !ok {
+		return models.ErrTransactionNotFound
+	}
+
+	updated := *tx
+	updated.PortfolioID = existing.PortfolioID
+	updated.CreatedAt = existing.CreatedAt
+	r.transactions[tx.ID] = updated
+
+	return nil
+}
+
+// DeleteTransaction remove uma transação
+func (r *PortfolioRepository) DeleteTransaction(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.transactions[id]; !ok {
+		return models.ErrTransactionNotFound
+	}
+
+	delete(r.transactions, id)
+	return nil
+}
+
+// GetTargets obtém os pesos alvo de um portfólio, por ordem decrescente de peso
+func (r *PortfolioRepository) GetTargets(portfolioID string) ([]models.AllocationTarget, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := append([]models.AllocationTarget{}, r.targets[portfolioID]...)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Weight != result[j].Weight {
+			return result[i].Weight > result[j].Weight
+		}
+		return result[i].Symbol < result[j].Symbol
+	})
+
+	return result, nil
+}
+
+// SetTargets substitui os pesos alvo de um portfólio
+func (r *PortfolioRepository) SetTargets(portfolioID string, targets []models.AllocationTarget) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.portfolios[portfolioID]; !ok {
+		return models.ErrPortfolioNotFound
+	}
+
+	r.targets[portfolioID] = append([]models.AllocationTarget{}, targets...)
+	return nil
+}
+
+// assetsOf retorna os ativos de um portfólio. Deve ser chamado com o lock obtido.
+func (r *PortfolioRepository) assetsOf(portfolioID string) []models.Asset {
+	assets := []models.Asset{}
+	for _, asset := range r.assets {
+		if asset.PortfolioID == portfolioID {
+			assets = append(assets, asset)
+		}
+	}
+
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i].CreatedAt.Before(assets[j].CreatedAt)
+	})
+
+	return assets
+}
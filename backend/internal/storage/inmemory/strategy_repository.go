@@ -0,0 +1,102 @@
+package inmemory
+
+import (
+	"sort"
+	"sync"
+
+	"gofolio/backend/internal/models"
+)
+
+// StrategyRepository implementa a interface models.StrategyRepository com armazenamento em memória
+type StrategyRepository struct {
+	strategies map[string]models.Strategy
+	mu         sync.RWMutex
+}
+
+// NewStrategyRepository cria uma nova instância do repositório de estratégias em memória
+func NewStrategyRepository() *StrategyRepository {
+	return &StrategyRepository{
+		strategies: make(map[string]models.Strategy),
+	}
+}
+
+// CreateStrategy grava uma nova estratégia
+func (r *StrategyRepository) CreateStrategy(strategy *models.Strategy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.strategies[strategy.ID] = *strategy
+	return nil
+}
+
+// GetStrategy obtém uma estratégia
+func (r *StrategyRepository) GetStrategy(id string) (*models.Strategy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	strategy, ok := r.strategies[id]
+	if !ok {
+		return nil, models.ErrStrategyNotFound
+	}
+	return &strategy, nil
+}
+
+// ListStrategies obtém as estratégias de um usuário, por nome
+func (r *StrategyRepository) ListStrategies(userID string) ([]models.Strategy, error) {
+	return r.list(func(strategy models.Strategy) bool {
+		return strategy.UserID == userID
+	}), nil
+}
+
+// ListWatchedStrategies obtém as estratégias de todos os usuários que acompanham algum ativo
+func (r *StrategyRepository) ListWatchedStrategies() ([]models.Strategy, error) {
+	return r.list(func(strategy models.Strategy) bool {
+		return len(strategy.Definition.Symbols) > 0
+	}), nil
+}
+
+// UpdateStrategy substitui a definição de uma estratégia
+func (r *StrategyRepository) UpdateStrategy(strategy *models.Strategy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.strategies[strategy.ID]; !ok {
+		return models.ErrStrategyNotFound
+	}
+	r.strategies[strategy.ID] = *strategy
+	return nil
+}
+
+// DeleteStrategy remove uma estratégia
+func (r *StrategyRepository) DeleteStrategy(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.strategies[id]; !ok {
+		return models.ErrStrategyNotFound
+	}
+	delete(r.strategies, id)
+	return nil
+}
+
+// list retorna as estratégias que satisfazem o filtro, por usuário e nome
+func (r *StrategyRepository) list(filter func(models.Strategy) bool) []models.Strategy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []models.Strategy{}
+	for _, strategy := range r.strategies {
+		if filter(strategy) {
+			result = append(result, strategy)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].UserID != result[j].UserID {
+			return result[i].UserID < result[j].UserID
+		}
+		return result[i].Definition.Name < result[j].Definition.Name
+	})
+
+	return result
+}
@@ -0,0 +1,190 @@
+package inmemory
+
+import (
+	"sync"
+	"time"
+
+	"gofolio/backend/internal/auth"
+)
+
+// TokenStore implementa a interface auth.TokenStore com armazenamento em memória
+// Os tokens perdem-se ao reiniciar o servidor, obrigando os usuários a fazer login novamente.
+type TokenStore struct {
+	refreshTokens map[string]auth.RefreshToken // tokenHash -> token
+	revoked       map[string]time.Time         // jti -> expiração do access token
+	mfaAttempts   map[string]mfaAttempts       // chave -> tentativas de 2FA
+	mu            sync.RWMutex
+}
+
+// mfaAttempts é um contador de tentativas de 2FA
+type mfaAttempts struct {
+	count     int
+	expiresAt time.Time
+}
+
+// NewTokenStore cria uma nova instância do armazenamento de tokens em memória
+func NewTokenStore() *TokenStore {
+	store := &TokenStore{
+		refreshTokens: make(map[string]auth.RefreshToken),
+		revoked:       make(map[string]time.Time),
+		mfaAttempts:   make(map[string]mfaAttempts),
+	}
+
+	// Iniciar rotina para limpar tokens expirados
+	go store.janitor()
+
+	return store
+}
+
+// SaveRefreshToken salva um novo refresh token
+func (s *TokenStore) SaveRefreshToken(token *auth.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refreshTokens[token.TokenHash] = *token
+
+	return nil
+}
+
+// GetRefreshToken obtém um refresh token válido pelo hash
+func (s *TokenStore) GetRefreshToken(tokenHash string) (*auth.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.refreshTokens[tokenHash]
+	if !ok || time.Now().After(token.ExpiresAt) {
+		return nil, auth.ErrRefreshTokenNotFound
+	}
+
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed marca um refresh token como já trocado
+func (s *TokenStore) MarkRefreshTokenUsed(tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.refreshTokens[tokenHash]
+	if !ok {
+		return auth.ErrRefreshTokenNotFound
+	}
+	if token.Used {
+		return auth.ErrRefreshTokenReused
+	}
+
+	token.Used = true
+	s.refreshTokens[tokenHash] = token
+
+	return nil
+}
+
+// DeleteRefreshTokenFamily remove todos os refresh tokens de uma família
+func (s *TokenStore) DeleteRefreshTokenFamily(familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, token := range s.refreshTokens {
+		if token.FamilyID == familyID {
+			delete(s.refreshTokens, hash)
+		}
+	}
+
+	return nil
+}
+
+// DeleteUserRefreshTokens remove todos os refresh tokens de um usuário
+func (s *TokenStore) DeleteUserRefreshTokens(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, token := range s.refreshTokens {
+		if token.UserID == userID {
+			delete(s.refreshTokens, hash)
+		}
+	}
+
+	return nil
+}
+
+// RevokeToken adiciona o jti de um access token à lista de revogação
+func (s *TokenStore) RevokeToken(jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revoked[jti] = expiresAt
+
+	return nil
+}
+
+// IsTokenRevoked verifica se o jti está na lista de revogação
+func (s *TokenStore) IsTokenRevoked(jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, revoked := s.revoked[jti]
+
+	return revoked, nil
+}
+
+// IncrementMFAAttempts soma uma tentativa ao contador indicado e devolve a contagem resultante
+func (s *TokenStore) IncrementMFAAttempts(key string, expiresAt time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts, ok := s.mfaAttempts[key]
+	if !ok || time.Now().After(attempts.expiresAt) {
+		attempts = mfaAttempts{expiresAt: expiresAt}
+	}
+	attempts.count++
+	s.mfaAttempts[key] = attempts
+
+	return attempts.count, nil
+}
+
+// ResetMFAAttempts apaga o contador indicado
+func (s *TokenStore) ResetMFAAttempts(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.mfaAttempts, key)
+
+	return nil
+}
+
+// Janitor limpa tokens expirados periodicamente
+func (s *TokenStore) janitor() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		s.deleteExpired()
+	}
+}
+
+// DeleteExpired remove refresh tokens, revogações e contadores de 2FA já expirados.
+// Um jti revogado pode ser esquecido quando o access token correspondente expira.
+func (s *TokenStore) deleteExpired() {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, token := range s.refreshTokens {
+		if now.After(token.ExpiresAt) {
+			delete(s.refreshTokens, hash)
+		}
+	}
+
+	for jti, expiresAt := range s.revoked {
+		if now.After(expiresAt) {
+			delete(s.revoked, jti)
+		}
+	}
+
+	for key, attempts := range s.mfaAttempts {
+		if now.After(attempts.expiresAt) {
+			delete(s.mfaAttempts, key)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package inmemory
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gofolio/backend/internal/auth"
+)
+
+// UserRepository implementa a interface auth.UserRepository com armazenamento em memória
+// Útil para desenvolvimento e testes. Os dados perdem-se ao reiniciar o servidor.
+type UserRepository struct {
+	users   map[string]auth.User
+	byEmail map[string]string // email -> id
+	mu      sync.RWMutex
+}
+
+// NewUserRepository cria uma nova instância do repositório de usuários em memória
+func NewUserRepository() *UserRepository {
+	return &UserRepository{
+		users:   make(map[string]auth.User),
+		byEmail: make(map[string]string),
+	}
+}
+
+// Create adiciona um novo usuário, rejeitando emails duplicados
+func (r *UserRepository) Create(user *auth.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	email := strings.ToLower(user.Email)
+	if _, exists := r.byEmail[email]; exists {
+		return auth.ErrEmailAlreadyExists
+	}
+
+	r.users[user.ID] = *user
+	r.byEmail[email] = user.ID
+
+	return nil
+}
+
+// GetByID obtém um usuário pelo ID
+func (r *UserRepository) GetByID(id string) (*auth.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, auth.ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+// GetByEmail obtém um usuário pelo email
+func (r *UserRepository) GetByEmail(email string) (*auth.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byEmail[strings.ToLower(email)]
+	if !ok {
+		return nil, auth.ErrUserNotFound
+	}
+
+	user := r.users[id]
+	return &user, nil
+}
+
+// UpdateProfile atualiza o nome e o email de um usuário
+func (r *UserRepository) UpdateProfile(user *auth.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[user.ID]
+	if !ok {
+		return auth.ErrUserNotFound
+	}
+
+	// Atualizar o índice de emails se o email mudou
+	oldEmail := strings.ToLower(existing.Email)
+	newEmail := strings.ToLower(user.Email)
+	if oldEmail != newEmail {
+		if _, exists := r.byEmail[newEmail]; exists {
+			return auth.ErrEmailAlreadyExists
+		}
+		delete(r.byEmail, oldEmail)
+		r.byEmail[newEmail] = user.ID
+	}
+
+	existing.Email = user.Email
+	existing.Name = user.Name
+	existing.UpdatedAt = time.Now()
+	r.users[user.ID] = existing
+
+	return nil
+}
+
+// UpdatePreferences atualiza as preferências de um usuário
+func (r *UserRepository) UpdatePreferences(user *auth.User) error {
+	return r.update(user.ID, func(existing *auth.User) {
+		existing.Preferences = user.Preferences
+		existing.Preferences.DashboardWidgets = append([]string(nil), user.Preferences.DashboardWidgets...)
+	})
+}
+
+// UpdateAccess atualiza os papéis e o estado da conta de um usuário
+func (r *UserRepository) UpdateAccess(user *auth.User) error {
+	return r.update(user.ID, func(existing *auth.User) {
+		existing.Roles = append([]string(nil), user.Roles...)
+		existing.Disabled = user.Disabled
+	})
+}
+
+// UpdateMFA atualiza o estado do 2FA de um usuário. O último passo TOTP usado nunca recua.
+func (r *UserRepository) UpdateMFA(user *auth.User) error {
+	return r.update(user.ID, func(existing *auth.User) {
+		existing.MFAEnabled = user.MFAEnabled
+		existing.MFASecret = user.MFASecret
+		existing.MFARecoveryCodes = append([]string(nil), user.MFARecoveryCodes...)
+		existing.MFALastUsedStep = max(existing.MFALastUsedStep, user.MFALastUsedStep)
+	})
+}
+
+// ConsumeMFAStep regista o passo TOTP usado, se for posterior ao último aceite
+func (r *UserRepository) ConsumeMFAStep(userID string, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[userID]
+	if !ok {
+		return false, auth.ErrUserNotFound
+	}
+	if step <= existing.MFALastUsedStep {
+		return false, nil
+	}
+
+	existing.MFALastUsedStep = step
+	r.users[userID] = existing
+
+	return true, nil
+}
+
+// ConsumeRecoveryCode remove um código de recuperação, se ainda estiver por usar
+func (r *UserRepository) ConsumeRecoveryCode(userID, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[userID]
+	if !ok {
+		return false, auth.ErrUserNotFound
+	}
+
+	for i, stored := range existing.MFARecoveryCodes {
+		if stored == codeHash {
+			existing.MFARecoveryCodes = append(existing.MFARecoveryCodes[:i:i], existing.MFARecoveryCodes[i+1:]...)
+			r.users[userID] = existing
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// update aplica uma alteração a um usuário existente
+func (r *UserRepository) update(id string, apply func(existing *auth.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[id]
+	if !ok {
+		return auth.ErrUserNotFound
+	}
+
+	apply(&existing)
+	existing.UpdatedAt = time.Now()
+	r.users[id] = existing
+
+	return nil
+}
+
+// List obtém usuários ordenados pela data de criação
+func (r *UserRepository) List(limit, offset int) ([]auth.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]auth.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].CreatedAt.Before(users[j].CreatedAt)
+	})
+
+	// Aplicar paginação
+	if offset >= len(users) {
+		return []auth.User{}, nil
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(users) {
+		end = len(users)
+	}
+
+	return users[offset:end], nil
+}
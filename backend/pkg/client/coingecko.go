@@ -9,7 +9,7 @@ import (
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/tiagofernandes/gofolio/internal/models"
+	"gofolio/backend/internal/models"
 )
 
 // CoinGeckoScraper é um cliente para extrair dados do CoinGecko
@@ -358,7 +358,7 @@ func (s *CoinGeckoScraper) GetGlobalMarketData() (*models.GlobalMarketData, erro
 }
 
 // GetHistoricalData obtém dados históricos de preço do CoinGecko
-func (s *CoinGeckoScraper) GetHistoricalData(id, currency string, days int) (*models.HistoricalData, error) {
+func (s *CoinGeckoScraper) GetHistoricalData(id, currency string, days int) (*models.MarketHistoricalData, error) {
 	// Nota: CoinGecko limita o acesso a dados históricos via web scraping
 	// Para uma solução mais completa, seria necessário usar a API oficial
 	url := fmt.Sprintf("%s/en/coins/%s", s.baseURL, id)
@@ -380,7 +380,7 @@ func (s *CoinGeckoScraper) GetHistoricalData(id, currency string, days int) (*mo
 		return nil, fmt.Errorf("erro ao parsear HTML: %w", err)
 	}
 
-	historicalData := &models.HistoricalData{
+	historicalData := &models.MarketHistoricalData{
 		ID:     id,
 		Symbol: strings.ToLower(strings.TrimSpace(doc.Find("h1[data-cy='coin-title'] span.tw-ml-2").Text())),
 		Source: "coingecko",
@@ -10,7 +10,7 @@ import (
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/tiagofernandes/gofolio/internal/models"
+	"gofolio/backend/internal/models"
 )
 
 // CoinMarketCapScraper é um cliente para extrair dados do CoinMarketCap
@@ -352,13 +352,13 @@ func (s *CoinMarketCapScraper) GetGlobalMarketData() (*models.GlobalMarketData,
 // GetHistoricalData obtém dados históricos de preço do CoinMarketCap
 // Nota: O CoinMarketCap não fornece dados históricos facilmente via scraping
 // Esta é uma implementação simplificada que retorna dados limitados
-func (s *CoinMarketCapScraper) GetHistoricalData(id, currency string, days int) (*models.HistoricalData, error) {
+func (s *CoinMarketCapScraper) GetHistoricalData(id, currency string, days int) (*models.MarketHistoricalData, error) {
 	// Para dados históricos reais, seria necessário usar a API oficial do CoinMarketCap
 	// ou outra fonte como o CoinGecko
 	log.Println("Aviso: Dados históricos via scraping do CoinMarketCap são limitados")
 	
 	// Retornar estrutura básica com dados simulados
-	historicalData := &models.HistoricalData{
+	historicalData := &models.MarketHistoricalData{
 		ID:     id,
 		Source: "coinmarketcap",
 		Prices: make([][2]float64, 0),